@@ -0,0 +1,57 @@
+package http
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/sbreitf1/errors"
+)
+
+var (
+	// ErrBadRequest is answered with 400 Bad Request, e.g. when the request body of a JSONBodyHandler cannot be decoded or a request DTO is invalid.
+	ErrBadRequest = errors.New("Bad request").Safe().HTTPCode(400)
+)
+
+// JSONHandler adapts a handler returning a result to a gin handler that answers with the result serialized as JSON and 200 OK. Errors are answered by the error mapper of the server.
+func JSONHandler[T any](handler func(*gin.Context) (T, errors.Error)) gin.HandlerFunc {
+	return JSONHandlerWithStatus(200, handler)
+}
+
+// JSONHandlerWithStatus adapts a handler like JSONHandler, but answers successful requests with the given status code, e.g. 201 Created.
+func JSONHandlerWithStatus[T any](status int, handler func(*gin.Context) (T, errors.Error)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		result, err := handler(c)
+		respondJSON(c, status, result, err)
+	}
+}
+
+// JSONBodyHandler adapts a handler like JSONHandler and passes the decoded JSON request body. Malformed bodies and bodies violating the binding tags of In are answered with ErrBadRequest.
+func JSONBodyHandler[In, Out any](handler func(*gin.Context, In) (Out, errors.Error)) gin.HandlerFunc {
+	return JSONBodyHandlerWithStatus(200, handler)
+}
+
+// JSONBodyHandlerWithStatus adapts a handler like JSONBodyHandler, but answers successful requests with the given status code.
+func JSONBodyHandlerWithStatus[In, Out any](status int, handler func(*gin.Context, In) (Out, errors.Error)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var in In
+		if err := c.ShouldBindJSON(&in); err != nil {
+			abortWithError(c, ErrBadRequest.Msg("Invalid request body: %s", err.Error()).Make())
+			return
+		}
+		result, err := handler(c, in)
+		respondJSON(c, status, result, err)
+	}
+}
+
+func respondJSON(c *gin.Context, status int, result interface{}, err errors.Error) {
+	if err != nil {
+		abortWithError(c, err)
+	} else if !c.Writer.Written() {
+		// handlers may write custom responses, e.g. redirects
+		writeJSON(c, status, envelope(c, result, nil))
+	}
+}
+
+// abortWithError attaches err to the request for the error mapper and skips remaining handlers.
+func abortWithError(c *gin.Context, err error) {
+	c.Error(err)
+	c.Abort()
+}
@@ -0,0 +1,59 @@
+package http
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sbreitf1/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type testItem struct {
+	ID   string `json:"id"`
+	Name string `json:"name" binding:"required"`
+}
+
+func TestJSONHandlers(t *testing.T) {
+	server, err := NewServer(&ServerConfig{GinMode: gin.TestMode})
+	errors.AssertNil(t, err)
+	server.ErrorMapper().Map(errTestNotFound, 404)
+
+	errors.AssertNil(t, server.RegisterService("items", &routesService{func(e *gin.Engine) {
+		e.GET("/items/:id", JSONHandler(func(c *gin.Context) (*testItem, errors.Error) {
+			if c.Param("id") != "1" {
+				return nil, errTestNotFound.Make()
+			}
+			return &testItem{ID: "1", Name: "first"}, nil
+		}))
+		e.GET("/redirect", JSONHandler(func(c *gin.Context) (interface{}, errors.Error) {
+			c.Redirect(302, "/items/1")
+			return nil, nil
+		}))
+		e.POST("/items", JSONBodyHandlerWithStatus(201, func(c *gin.Context, item testItem) (testItem, errors.Error) {
+			item.ID = "2"
+			return item, nil
+		}))
+	}}))
+
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		server.Handler().ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return w
+	}
+
+	w := serve("GET", "/items/1", "")
+	assert.Equal(t, 200, w.Code)
+	assert.JSONEq(t, `{"id":"1","name":"first"}`, w.Body.String())
+	assert.Equal(t, 404, serve("GET", "/items/2", "").Code)
+	assert.Equal(t, 302, serve("GET", "/redirect", "").Code)
+
+	w = serve("POST", "/items", `{"name":"second"}`)
+	assert.Equal(t, 201, w.Code)
+	assert.JSONEq(t, `{"id":"2","name":"second"}`, w.Body.String())
+	w = serve("POST", "/items", `{"id":"3"}`)
+	assert.Equal(t, 400, w.Code)
+	assert.Contains(t, w.Body.String(), "Invalid request body")
+	assert.Equal(t, 400, serve("POST", "/items", `{`).Code)
+}
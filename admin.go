@@ -0,0 +1,127 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+const redacted = "[REDACTED]"
+
+// secretKeywords mark configuration fields whose values are redacted by /debug/config.
+var secretKeywords = []string{"secret", "password", "token", "key", "credential"}
+
+type routeInfo struct {
+	Method  string `json:"method"`
+	Path    string `json:"path"`
+	Handler string `json:"handler"`
+	Service string `json:"service,omitempty"`
+}
+
+// AdminEngine returns the gin engine of the admin listener to register administrative routes, e.g. feature flag toggles. It returns nil if no admin listen address is configured.
+func (server *Server) AdminEngine() *gin.Engine {
+	return server.adminEngine
+}
+
+// newAdminEngine creates the engine of the admin listener including the opt-in debug endpoints.
+func (server *Server) newAdminEngine() *gin.Engine {
+	engine := gin.New()
+	engine.Use(requestIDMiddleware, ginLogger)
+	if server.config.EnableDebugEndpoints {
+		engine.GET("/debug/routes", server.handleGetDebugRoutes)
+		engine.GET("/debug/config", server.handleGetDebugConfig)
+	}
+	return engine
+}
+
+// runAdmin starts the admin listener in the background. Failures are logged, because they do not affect serving of the public endpoints.
+func (server *Server) runAdmin() {
+	if server.adminEngine == nil {
+		return
+	}
+	server.adminServer = &http.Server{
+		Addr:              server.config.AdminListenAddress,
+		Handler:           server.adminEngine,
+		ReadHeaderTimeout: timeoutOrDefault(server.config.ReadHeaderTimeout, DefaultReadHeaderTimeout),
+		IdleTimeout:       timeoutOrDefault(server.config.IdleTimeout, DefaultIdleTimeout),
+	}
+	go func(adminServer *http.Server) {
+		if err := adminServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.WithFields(log.Fields{"component": "admin"}).Errorf("Admin listener failed: %s", err)
+		}
+	}(server.adminServer)
+}
+
+// HandleGetDebugRoutes returns the route table of the public engine sorted by path and method.
+func (server *Server) handleGetDebugRoutes(c *gin.Context) {
+	routes := make([]routeInfo, 0)
+	for _, r := range server.engine.Routes() {
+		info := routeInfo{Method: r.Method, Path: r.Path, Handler: r.Handler}
+		if mounted, ok := server.mountedRoutes[r.Method+" "+r.Path]; ok {
+			info.Handler, info.Service = mounted.handler, mounted.service
+		}
+		routes = append(routes, info)
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Path != routes[j].Path {
+			return routes[i].Path < routes[j].Path
+		}
+		return routes[i].Method < routes[j].Method
+	})
+	c.JSON(200, routes)
+}
+
+// HandleGetDebugConfig returns the effective server configuration with secret values redacted.
+func (server *Server) handleGetDebugConfig(c *gin.Context) {
+	config, err := redactConfig(server.config)
+	if err != nil {
+		c.AbortWithStatus(500)
+		return
+	}
+	c.JSON(200, config)
+}
+
+// redactConfig returns the JSON representation of config with the values of all fields named like secrets replaced.
+func redactConfig(config interface{}) (interface{}, error) {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+	return redactValue(value), nil
+}
+
+func redactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if isSecretKey(key) && field != nil && field != "" {
+				v[key] = redacted
+			} else {
+				v[key] = redactValue(field)
+			}
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = redactValue(v[i])
+		}
+	}
+	return value
+}
+
+func isSecretKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, keyword := range secretKeywords {
+		if strings.Contains(key, keyword) {
+			return true
+		}
+	}
+	return false
+}
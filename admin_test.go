@@ -0,0 +1,94 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sbreitf1/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDebugEndpoints(t *testing.T) {
+	server, err := NewServer(&ServerConfig{
+		ListenAddress:        "127.0.0.1:18089",
+		AdminListenAddress:   "127.0.0.1:18090",
+		EnableDebugEndpoints: true,
+		GinMode:              gin.TestMode,
+	})
+	errors.AssertNil(t, err)
+	server.Engine().GET("/items/:id", func(c *gin.Context) {})
+	errors.AssertNil(t, server.RunAsync(nil))
+	defer server.Shutdown()
+
+	t.Run("routes", func(t *testing.T) {
+		resp, err := http.Get("http://127.0.0.1:18090/debug/routes")
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer resp.Body.Close()
+		var routes []routeInfo
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&routes))
+		paths := make([]string, 0)
+		for _, r := range routes {
+			paths = append(paths, r.Method+" "+r.Path)
+		}
+		assert.Contains(t, paths, "GET /healthz")
+		assert.Contains(t, paths, "GET /items/:id")
+	})
+
+	t.Run("config", func(t *testing.T) {
+		resp, err := http.Get("http://127.0.0.1:18090/debug/config")
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer resp.Body.Close()
+		var config map[string]interface{}
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&config))
+		assert.Equal(t, "127.0.0.1:18089", config["listenAddress"])
+	})
+
+	t.Run("not public", func(t *testing.T) {
+		resp, err := http.Get("http://127.0.0.1:18089/debug/routes")
+		if assert.NoError(t, err) {
+			resp.Body.Close()
+			assert.Equal(t, 404, resp.StatusCode)
+		}
+	})
+}
+
+func TestDebugEndpointsOptIn(t *testing.T) {
+	server, err := NewServer(&ServerConfig{ListenAddress: "127.0.0.1:18091", AdminListenAddress: "127.0.0.1:18092", GinMode: gin.TestMode})
+	errors.AssertNil(t, err)
+	errors.AssertNil(t, server.RunAsync(nil))
+	defer server.Shutdown()
+
+	resp, getErr := http.Get("http://127.0.0.1:18092/debug/config")
+	if assert.NoError(t, getErr) {
+		resp.Body.Close()
+		assert.Equal(t, 404, resp.StatusCode)
+	}
+
+	noAdmin, err := NewServer(&ServerConfig{GinMode: gin.TestMode})
+	errors.AssertNil(t, err)
+	assert.Nil(t, noAdmin.AdminEngine())
+}
+
+func TestRedactConfig(t *testing.T) {
+	config, err := redactConfig(map[string]interface{}{
+		"tlsKeyFile": "/etc/tls/key.pem",
+		"apiToken":   "abc",
+		"password":   "",
+		"nested":     map[string]interface{}{"clientSecret": "xyz", "name": "value"},
+		"list":       []interface{}{map[string]interface{}{"Password": "p"}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"tlsKeyFile": redacted,
+		"apiToken":   redacted,
+		"password":   "",
+		"nested":     map[string]interface{}{"clientSecret": redacted, "name": "value"},
+		"list":       []interface{}{map[string]interface{}{"Password": redacted}},
+	}, config)
+}
@@ -0,0 +1,144 @@
+package http
+
+import (
+	"math"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sbreitf1/errors"
+)
+
+var (
+	admissionMetricsOnce  sync.Once
+	admissionQueueDepth   *prometheus.GaugeVec
+	admissionInFlight     *prometheus.GaugeVec
+	admissionRejectsTotal *prometheus.CounterVec
+)
+
+// AdmissionConfig contains the parameters of an admission queue.
+type AdmissionConfig struct {
+	// Name labels the metrics of the queue. Defaults to "default".
+	Name string `json:"name,omitempty"`
+	// MaxConcurrent limits the number of requests that are handled at once.
+	MaxConcurrent int `json:"maxConcurrent"`
+	// MaxQueue limits the number of requests waiting for admission. Further requests are rejected immediately. Defaults to MaxConcurrent.
+	MaxQueue int `json:"maxQueue,omitempty"`
+	// QueueTimeout denotes how long a request waits for admission before it is rejected. Defaults to 1 second.
+	QueueTimeout Duration `json:"queueTimeout,omitempty"`
+	// RetryAfter is returned in the Retry-After header of rejected requests. Defaults to 1 second.
+	RetryAfter Duration `json:"retryAfter,omitempty"`
+}
+
+// registerAdmissionMetrics creates the queue depth, in-flight and rejection metrics shared by all admission queues, which are distinguished by the "queue" label.
+func registerAdmissionMetrics() {
+	admissionMetricsOnce.Do(func() {
+		admissionQueueDepth = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Subsystem: "admission",
+			Name:      "queue_depth",
+			Help:      "Number of requests waiting for admission.",
+		}, []string{"queue"})
+		admissionInFlight = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Subsystem: "admission",
+			Name:      "in_flight",
+			Help:      "Number of admitted requests that are currently handled.",
+		}, []string{"queue"})
+		admissionRejectsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+			Subsystem: "admission",
+			Name:      "rejected_total",
+			Help:      "Number of requests rejected with 429 Too Many Requests.",
+		}, []string{"queue"})
+		prometheus.MustRegister(admissionQueueDepth, admissionInFlight, admissionRejectsTotal)
+	})
+}
+
+type admissionQueue struct {
+	config  AdmissionConfig
+	slots   chan struct{}
+	waiting int64
+
+	depth    prometheus.Gauge
+	inFlight prometheus.Gauge
+	rejects  prometheus.Counter
+}
+
+// NewAdmissionMiddleware returns a middleware that limits the number of concurrently handled requests. Requests exceeding the limit wait for a free slot up to the queue timeout and are rejected with 429 Too Many Requests and a Retry-After header afterwards or when the queue is full.
+func NewAdmissionMiddleware(config *AdmissionConfig) (gin.HandlerFunc, errors.Error) {
+	if config.MaxConcurrent <= 0 {
+		return nil, ErrInvalidConfig.Msg("Admission queue requires a positive concurrency limit").Make()
+	}
+	q := &admissionQueue{config: *config}
+	if len(q.config.Name) == 0 {
+		q.config.Name = "default"
+	}
+	if q.config.MaxQueue <= 0 {
+		q.config.MaxQueue = q.config.MaxConcurrent
+	}
+	if q.config.QueueTimeout <= 0 {
+		q.config.QueueTimeout = Duration(time.Second)
+	}
+	if q.config.RetryAfter <= 0 {
+		q.config.RetryAfter = Duration(time.Second)
+	}
+	q.slots = make(chan struct{}, q.config.MaxConcurrent)
+
+	registerAdmissionMetrics()
+	q.depth = admissionQueueDepth.WithLabelValues(q.config.Name)
+	q.inFlight = admissionInFlight.WithLabelValues(q.config.Name)
+	q.rejects = admissionRejectsTotal.WithLabelValues(q.config.Name)
+	return q.handle, nil
+}
+
+func (q *admissionQueue) handle(c *gin.Context) {
+	if !q.acquire(c) {
+		return
+	}
+	q.inFlight.Inc()
+	defer func() {
+		q.inFlight.Dec()
+		<-q.slots
+	}()
+	c.Next()
+}
+
+// acquire waits for a free slot and aborts the request if none could be obtained.
+func (q *admissionQueue) acquire(c *gin.Context) bool {
+	select {
+	case q.slots <- struct{}{}:
+		return true
+	default:
+	}
+
+	if atomic.AddInt64(&q.waiting, 1) > int64(q.config.MaxQueue) {
+		atomic.AddInt64(&q.waiting, -1)
+		q.reject(c)
+		return false
+	}
+	q.depth.Inc()
+	defer func() {
+		atomic.AddInt64(&q.waiting, -1)
+		q.depth.Dec()
+	}()
+
+	timer := time.NewTimer(time.Duration(q.config.QueueTimeout))
+	defer timer.Stop()
+	select {
+	case q.slots <- struct{}{}:
+		return true
+	case <-timer.C:
+		q.reject(c)
+	case <-c.Request.Context().Done():
+		// the client is gone, no response required
+		c.Abort()
+	}
+	return false
+}
+
+func (q *admissionQueue) reject(c *gin.Context) {
+	q.rejects.Inc()
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(time.Duration(q.config.RetryAfter).Seconds()))))
+	c.AbortWithStatus(429)
+}
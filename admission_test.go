@@ -0,0 +1,89 @@
+package http
+
+import (
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/sbreitf1/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdmissionQueue(t *testing.T) {
+	middleware, err := NewAdmissionMiddleware(&AdmissionConfig{Name: "test", MaxConcurrent: 1, MaxQueue: 1, QueueTimeout: Duration(100 * time.Millisecond), RetryAfter: Duration(1500 * time.Millisecond)})
+	errors.AssertNil(t, err)
+
+	release := make(chan struct{})
+	started := make(chan struct{}, 3)
+	engine := gin.New()
+	engine.Use(middleware)
+	engine.GET("/", func(c *gin.Context) {
+		started <- struct{}{}
+		<-release
+		c.Status(204)
+	})
+
+	serve := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		return w
+	}
+
+	metric := &dto.Metric{}
+	assert.NoError(t, admissionRejectsTotal.WithLabelValues("test").Write(metric))
+	rejects := metric.GetCounter().GetValue()
+
+	var wg sync.WaitGroup
+	codes := make(chan int, 3)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		codes <- serve().Code
+	}()
+	<-started
+
+	// waits in the queue until the first request completes
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		codes <- serve().Code
+	}()
+	time.Sleep(20 * time.Millisecond)
+	assert.NoError(t, admissionQueueDepth.WithLabelValues("test").Write(metric))
+	assert.Equal(t, float64(1), metric.GetGauge().GetValue())
+
+	// the queue is full
+	w := serve()
+	assert.Equal(t, 429, w.Code)
+	assert.Equal(t, "2", w.Header().Get("Retry-After"))
+
+	release <- struct{}{}
+	<-started
+	release <- struct{}{}
+	wg.Wait()
+	assert.Equal(t, 204, <-codes)
+	assert.Equal(t, 204, <-codes)
+
+	// the queue timeout is exceeded
+	go func() {
+		codes <- serve().Code
+	}()
+	<-started
+	w = serve()
+	assert.Equal(t, 429, w.Code)
+	close(release)
+	assert.Equal(t, 204, <-codes)
+
+	assert.NoError(t, admissionRejectsTotal.WithLabelValues("test").Write(metric))
+	assert.Equal(t, rejects+2, metric.GetCounter().GetValue())
+	assert.NoError(t, admissionQueueDepth.WithLabelValues("test").Write(metric))
+	assert.Equal(t, float64(0), metric.GetGauge().GetValue())
+}
+
+func TestAdmissionInvalidConfig(t *testing.T) {
+	_, err := NewAdmissionMiddleware(&AdmissionConfig{})
+	errors.Assert(t, ErrInvalidConfig, err)
+}
@@ -0,0 +1,215 @@
+package http
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	asyncLogMetricsOnce sync.Once
+	asyncLogDropped     prometheus.Counter
+
+	accessLogMutex      sync.RWMutex
+	accessLogOutput     io.Writer
+	accessLogBufferSize int
+	accessLogger        *log.Logger
+	accessLogWriter     *AsyncWriter
+)
+
+// registerAsyncLogMetrics creates the counter of entries dropped by any AsyncWriter.
+func registerAsyncLogMetrics() {
+	asyncLogMetricsOnce.Do(func() {
+		asyncLogDropped = prometheus.NewCounter(prometheus.CounterOpts{
+			Subsystem: "log",
+			Name:      "dropped_entries_total",
+			Help:      "Number of log entries dropped because the buffer of an async writer was full.",
+		})
+		prometheus.MustRegister(asyncLogDropped)
+	})
+}
+
+type asyncEntry struct {
+	data []byte
+	// flushed is closed when all previous entries have been written.
+	flushed chan struct{}
+}
+
+// AsyncWriter writes to another writer in a background goroutine, so slow destinations like disks or a congested stderr do not block the writing goroutine. Writes exceeding the buffer are dropped and counted.
+type AsyncWriter struct {
+	w       io.Writer
+	entries chan asyncEntry
+	done    chan struct{}
+	dropped uint64
+
+	mutex  sync.RWMutex
+	closed bool
+}
+
+// NewAsyncWriter returns a writer that buffers up to bufferSize writes for w. The buffer defaults to 1024 writes.
+func NewAsyncWriter(w io.Writer, bufferSize int) *AsyncWriter {
+	if bufferSize <= 0 {
+		bufferSize = 1024
+	}
+	registerAsyncLogMetrics()
+	aw := &AsyncWriter{w: w, entries: make(chan asyncEntry, bufferSize), done: make(chan struct{})}
+	go aw.run()
+	return aw
+}
+
+func (w *AsyncWriter) run() {
+	defer close(w.done)
+	for entry := range w.entries {
+		if entry.flushed != nil {
+			close(entry.flushed)
+			continue
+		}
+		w.w.Write(entry.data)
+	}
+}
+
+// Write enqueues a copy of p without waiting for the underlying writer. It never fails, p is dropped if the buffer is full or the writer has been closed.
+func (w *AsyncWriter) Write(p []byte) (int, error) {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+	if !w.closed {
+		select {
+		case w.entries <- asyncEntry{data: append([]byte(nil), p...)}:
+			return len(p), nil
+		default:
+		}
+	}
+	atomic.AddUint64(&w.dropped, 1)
+	asyncLogDropped.Inc()
+	return len(p), nil
+}
+
+// Dropped returns the number of writes dropped so far.
+func (w *AsyncWriter) Dropped() uint64 {
+	return atomic.LoadUint64(&w.dropped)
+}
+
+// Flush waits until all previous writes have been passed to the underlying writer.
+func (w *AsyncWriter) Flush() {
+	w.mutex.RLock()
+	if w.closed {
+		w.mutex.RUnlock()
+		return
+	}
+	flushed := make(chan struct{})
+	w.entries <- asyncEntry{flushed: flushed}
+	w.mutex.RUnlock()
+	<-flushed
+}
+
+// Close writes all pending entries and stops the background goroutine. Subsequent writes are dropped.
+func (w *AsyncWriter) Close() error {
+	w.mutex.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.entries)
+	}
+	w.mutex.Unlock()
+	<-w.done
+	return nil
+}
+
+// SetAccessLogBufferSize writes the access log entries of all servers asynchronously through a buffer of the given number of entries, see ServerConfig.AccessLogBufferSize. Pending entries of a previous buffer are written before it is replaced. Entries are written synchronously again when 0.
+func SetAccessLogBufferSize(bufferSize int) {
+	accessLogMutex.Lock()
+	defer accessLogMutex.Unlock()
+	if bufferSize < 0 {
+		bufferSize = 0
+	}
+	if bufferSize == accessLogBufferSize {
+		return
+	}
+	accessLogBufferSize = bufferSize
+	updateAccessLogger()
+}
+
+// CloseAccessLog writes all buffered access log entries and stops asynchronous access logging, e.g. before the process exits. Subsequent entries are written synchronously.
+func CloseAccessLog() {
+	SetAccessLogBufferSize(0)
+}
+
+// SetAccessLogOutput directs the access log entries of all servers to w instead of the output of the standard logger, e.g. a RotatingFileWriter or a lumberjack.Logger. Nil restores the output of the standard logger.
+func SetAccessLogOutput(w io.Writer) {
+	accessLogMutex.Lock()
+	defer accessLogMutex.Unlock()
+	accessLogOutput = w
+	updateAccessLogger()
+}
+
+// standardOutput writes to the current output of the standard logger.
+type standardOutput struct{}
+
+func (standardOutput) Write(p []byte) (int, error) {
+	return log.StandardLogger().Out.Write(p)
+}
+
+// updateAccessLogger creates the access logger for the configured output and buffer. The formatter and a copy of the hooks of the standard logger are used.
+func updateAccessLogger() {
+	if accessLogWriter != nil {
+		// write pending entries to the previous output
+		accessLogWriter.Close()
+		accessLogWriter = nil
+	}
+	out := accessLogOutput
+	if accessLogBufferSize > 0 {
+		if out == nil {
+			out = standardOutput{}
+		}
+		accessLogWriter = NewAsyncWriter(out, accessLogBufferSize)
+		out = accessLogWriter
+	}
+	if out == nil {
+		accessLogger = nil
+		return
+	}
+	std := log.StandardLogger()
+	accessLogger = &log.Logger{Out: out, Formatter: std.Formatter, Hooks: standardHooks(), Level: std.GetLevel(), ExitFunc: std.ExitFunc}
+}
+
+// standardHooks returns a copy of the hooks of the standard logger, so the access logger does not read the map while AddHook modifies it.
+func standardHooks() log.LevelHooks {
+	hooks := make(log.LevelHooks)
+	for level, levelHooks := range log.StandardLogger().Hooks {
+		hooks[level] = append([]log.Hook(nil), levelHooks...)
+	}
+	return hooks
+}
+
+// addLogHook adds hook to the standard logger and the access logger.
+func addLogHook(hook log.Hook) {
+	accessLogMutex.Lock()
+	defer accessLogMutex.Unlock()
+	log.AddHook(hook)
+	if accessLogger != nil {
+		accessLogger.AddHook(hook)
+	}
+}
+
+// getAccessLogger returns the logger for access log entries.
+func getAccessLogger() *log.Logger {
+	accessLogMutex.RLock()
+	defer accessLogMutex.RUnlock()
+	if accessLogger == nil {
+		return log.StandardLogger()
+	}
+	// follow level changes, e.g. on configuration reload
+	accessLogger.SetLevel(log.GetLevel())
+	return accessLogger
+}
+
+// flushAccessLog waits until all access log entries have been written.
+func flushAccessLog() {
+	accessLogMutex.RLock()
+	defer accessLogMutex.RUnlock()
+	if accessLogWriter != nil {
+		accessLogWriter.Flush()
+	}
+}
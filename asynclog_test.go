@@ -0,0 +1,83 @@
+package http
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sbreitf1/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+// blockingWriter records writes after release has been closed.
+type blockingWriter struct {
+	release chan struct{}
+	mutex   sync.Mutex
+	buffer  bytes.Buffer
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	<-w.release
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.buffer.Write(p)
+}
+
+func (w *blockingWriter) String() string {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.buffer.String()
+}
+
+func TestAsyncWriter(t *testing.T) {
+	target := &blockingWriter{release: make(chan struct{})}
+	w := NewAsyncWriter(target, 2)
+	dropped := testutil.ToFloat64(asyncLogDropped)
+
+	// the first write is taken by the background goroutine that blocks, two further writes fill the buffer
+	for _, line := range []string{"a\n", "b\n", "c\n", "d\n", "e\n"} {
+		n, err := w.Write([]byte(line))
+		assert.NoError(t, err)
+		assert.Equal(t, 2, n)
+	}
+	assert.True(t, w.Dropped() >= 1)
+	close(target.release)
+	w.Flush()
+	assert.Equal(t, int(5-w.Dropped()), bytes.Count([]byte(target.String()), []byte("\n")))
+	assert.Equal(t, float64(w.Dropped()), testutil.ToFloat64(asyncLogDropped)-dropped)
+
+	w.Write([]byte("f\n"))
+	assert.NoError(t, w.Close())
+	assert.Contains(t, target.String(), "f\n")
+	w.Write([]byte("g\n"))
+	w.Flush()
+	assert.NotContains(t, target.String(), "g\n")
+}
+
+func TestAsyncAccessLog(t *testing.T) {
+	var buffer bytes.Buffer
+	defer log.SetOutput(log.StandardLogger().Out)
+	log.SetOutput(&buffer)
+	defer CloseAccessLog()
+
+	server, err := NewServer(&ServerConfig{GinMode: gin.TestMode, AccessLogBufferSize: 16})
+	errors.AssertNil(t, err)
+	assert.NotNil(t, accessLogWriter)
+	server.Engine().GET("/async", func(c *gin.Context) {
+		c.String(200, "ok")
+	})
+
+	server.Handler().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/async", nil))
+	flushAccessLog()
+	assert.Contains(t, buffer.String(), "200 - GET - /async")
+
+	// pending entries are written when closed
+	server.Handler().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/async?closed", nil))
+	CloseAccessLog()
+	assert.Nil(t, accessLogWriter)
+	assert.Contains(t, buffer.String(), "/async?closed")
+}
@@ -0,0 +1,42 @@
+package http
+
+import (
+	"encoding/base64"
+
+	"github.com/sbreitf1/errors"
+)
+
+// SetBasicAuth sends the given credentials using basic authentication with every request.
+func (client *Client) SetBasicAuth(username, password string) {
+	client.DefaultHeader.Set("Authorization", basicAuth(username, password))
+}
+
+// SetBearerToken sends the given token as bearer token with every request.
+func (client *Client) SetBearerToken(token string) {
+	client.DefaultHeader.Set("Authorization", "Bearer "+token)
+}
+
+// ClearAuth removes default credentials set by SetBasicAuth or SetBearerToken.
+func (client *Client) ClearAuth() {
+	client.DefaultHeader.Del("Authorization")
+}
+
+// WithBasicAuth returns a request callback that sets basic authentication credentials, overriding the default credentials of the client.
+func WithBasicAuth(username, password string) func(*Request) errors.Error {
+	return func(req *Request) errors.Error {
+		req.Header.Set("Authorization", basicAuth(username, password))
+		return nil
+	}
+}
+
+// WithBearerToken returns a request callback that sets a bearer token, overriding the default credentials of the client.
+func WithBearerToken(token string) func(*Request) errors.Error {
+	return func(req *Request) errors.Error {
+		req.Header.Set("Authorization", "Bearer "+token)
+		return nil
+	}
+}
+
+func basicAuth(username, password string) string {
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+password))
+}
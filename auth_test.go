@@ -0,0 +1,39 @@
+package http
+
+import (
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sbreitf1/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthHelpers(t *testing.T) {
+	assert.Equal(t, 4, withTestServer(func(url string, f *gin.HandlerFunc) {
+		client := NewClient()
+		var expectedAuth string
+		*f = func(c *gin.Context) {
+			assert.Equal(t, []string{expectedAuth}, c.Request.Header["Authorization"])
+			c.Status(200)
+		}
+
+		client.SetBasicAuth("user", "pass")
+		expectedAuth = "Basic dXNlcjpwYXNz"
+		_, err := client.Do(MethodGet, url, nil)
+		errors.AssertNil(t, err)
+
+		client.SetBearerToken("token123")
+		expectedAuth = "Bearer token123"
+		_, err = client.Do(MethodGet, url, nil)
+		errors.AssertNil(t, err)
+
+		expectedAuth = "Bearer other"
+		_, err = client.Do(MethodGet, url, WithBearerToken("other"))
+		errors.AssertNil(t, err)
+
+		client.ClearAuth()
+		expectedAuth = "Basic YWRtaW46"
+		_, err = client.Do(MethodGet, url, WithBasicAuth("admin", ""))
+		errors.AssertNil(t, err)
+	}))
+}
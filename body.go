@@ -0,0 +1,56 @@
+package http
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+
+	"github.com/sbreitf1/errors"
+)
+
+const (
+	// maxDrainBytes limits the number of bytes that are discarded to allow connection reuse before a body is closed.
+	maxDrainBytes = 64 * 1024
+)
+
+// ReadResponseBody reads and closes the body of the given response.
+func ReadResponseBody(response *Response) ([]byte, errors.Error) {
+	if response == nil || response.Body == nil {
+		return nil, nil
+	}
+	defer response.Body.Close()
+
+	data, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, ErrInvalidBody.Make().Cause(err)
+	}
+	return data, nil
+}
+
+// ReadResponseString reads and closes the body of the given response and returns it as string.
+func ReadResponseString(response *Response) (string, errors.Error) {
+	data, err := ReadResponseBody(response)
+	return string(data), err
+}
+
+// DecodeJSON decodes the JSON body of the given response into out and closes the body. An empty body leaves out untouched.
+func DecodeJSON(response *Response, out interface{}) errors.Error {
+	if response == nil || response.Body == nil {
+		return nil
+	}
+	defer DrainAndClose(response)
+
+	if err := json.NewDecoder(response.Body).Decode(out); err != nil && err != io.EOF {
+		return ErrInvalidBody.Make().Cause(err)
+	}
+	return nil
+}
+
+// DrainAndClose discards the remaining response body and closes it, so the underlying connection can be reused. It is safe to call on nil responses and already closed bodies.
+func DrainAndClose(response *Response) {
+	if response == nil || response.Body == nil {
+		return
+	}
+	io.Copy(ioutil.Discard, io.LimitReader(response.Body, maxDrainBytes))
+	response.Body.Close()
+}
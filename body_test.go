@@ -0,0 +1,57 @@
+package http
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/sbreitf1/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type trackingBody struct {
+	*strings.Reader
+	closed bool
+}
+
+func (b *trackingBody) Close() error {
+	b.closed = true
+	return nil
+}
+
+func TestResponseBodyHelpers(t *testing.T) {
+	newResponse := func(body string) (*Response, *trackingBody) {
+		b := &trackingBody{Reader: strings.NewReader(body)}
+		return &Response{StatusCode: 200, Body: b}, b
+	}
+
+	t.Run("ReadResponseString", func(t *testing.T) {
+		response, body := newResponse("hello")
+		str, err := ReadResponseString(response)
+		errors.AssertNil(t, err)
+		assert.Equal(t, "hello", str)
+		assert.True(t, body.closed)
+	})
+
+	t.Run("DecodeJSON", func(t *testing.T) {
+		response, body := newResponse(`{"name":"alice"} trailing`)
+		var out struct{ Name string }
+		errors.AssertNil(t, DecodeJSON(response, &out))
+		assert.Equal(t, "alice", out.Name)
+		assert.True(t, body.closed)
+		assert.Equal(t, 0, body.Len(), "Remaining body should be drained")
+
+		response, _ = newResponse("not json")
+		errors.Assert(t, ErrInvalidBody, DecodeJSON(response, &out))
+	})
+
+	t.Run("DrainAndClose", func(t *testing.T) {
+		response, body := newResponse("unread")
+		DrainAndClose(response)
+		assert.True(t, body.closed)
+		assert.Equal(t, 0, body.Len())
+
+		DrainAndClose(nil)
+		DrainAndClose(&Response{Body: ioutil.NopCloser(strings.NewReader(""))})
+	})
+}
@@ -0,0 +1,104 @@
+package http
+
+import (
+	"sync"
+	"time"
+
+	"github.com/sbreitf1/errors"
+)
+
+var (
+	// ErrCircuitOpen is returned without sending the request when the circuit breaker of the target host is open.
+	ErrCircuitOpen = errors.New("Circuit breaker open")
+)
+
+// CircuitBreaker tracks consecutive failures per host and rejects requests to hosts that exceeded the failure threshold. After the open duration a single trial request is allowed to probe the host again.
+type CircuitBreaker struct {
+	// FailureThreshold denotes the number of consecutive failures that open the circuit.
+	FailureThreshold int
+	// OpenDuration denotes how long requests are rejected before a trial request is allowed.
+	OpenDuration time.Duration
+
+	mutex sync.Mutex
+	hosts map[string]*circuit
+}
+
+type circuit struct {
+	failures  int
+	openUntil time.Time
+	trial     bool
+}
+
+// NewCircuitBreaker returns a new circuit breaker that opens after threshold consecutive failures for the given duration.
+func NewCircuitBreaker(threshold int, openDuration time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{FailureThreshold: threshold, OpenDuration: openDuration, hosts: make(map[string]*circuit)}
+}
+
+// Allow returns ErrCircuitOpen if requests to the given host should be rejected.
+func (cb *CircuitBreaker) Allow(host string) errors.Error {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	c, ok := cb.hosts[host]
+	if !ok || c.failures < cb.FailureThreshold {
+		return nil
+	}
+	if time.Now().Before(c.openUntil) || c.trial {
+		return ErrCircuitOpen.Msg("Circuit breaker for host %q is open", host).Make()
+	}
+	// half-open: only a single trial request is allowed
+	c.trial = true
+	return nil
+}
+
+// Report records the result of a request to the given host.
+func (cb *CircuitBreaker) Report(host string, success bool) {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	c, ok := cb.hosts[host]
+	if !ok {
+		if success {
+			return
+		}
+		c = &circuit{}
+		if cb.hosts == nil {
+			// breakers can be declared as literal without constructor
+			cb.hosts = make(map[string]*circuit)
+		}
+		cb.hosts[host] = c
+	}
+
+	c.trial = false
+	if success {
+		delete(cb.hosts, host)
+		return
+	}
+	c.failures++
+	if c.failures >= cb.FailureThreshold {
+		c.openUntil = time.Now().Add(cb.OpenDuration)
+	}
+}
+
+// State returns true if the circuit for the given host is currently open.
+func (cb *CircuitBreaker) State(host string) bool {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	c, ok := cb.hosts[host]
+	return ok && c.failures >= cb.FailureThreshold && time.Now().Before(c.openUntil)
+}
+
+// Interceptor returns an interceptor that rejects requests to hosts with open circuit and reports the results of all other requests. Responses with status 5xx count as failure.
+func (cb *CircuitBreaker) Interceptor() Interceptor {
+	return func(next Responder) Responder {
+		return func(req *Request) (*Response, errors.Error) {
+			if err := cb.Allow(req.URL.Host); err != nil {
+				return nil, err
+			}
+			response, err := next(req)
+			cb.Report(req.URL.Host, err == nil && response.StatusCode < 500)
+			return response, err
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package http
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sbreitf1/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	client := NewClient()
+	client.CircuitBreaker = NewCircuitBreaker(2, 50*time.Millisecond)
+	status := 500
+	calls := 0
+	client.RequestResponder = func(req *Request) (*Response, errors.Error) {
+		calls++
+		if req.URL.Host == "down" {
+			return nil, errors.GenericError.Msg("connection refused").Make()
+		}
+		return &Response{StatusCode: status, Body: ioutil.NopCloser(strings.NewReader(""))}, nil
+	}
+
+	for i := 0; i < 2; i++ {
+		_, err := client.Do(MethodGet, "http://flaky/", nil)
+		errors.AssertNil(t, err)
+	}
+	assert.True(t, client.CircuitBreaker.State("flaky"))
+
+	_, err := client.Do(MethodGet, "http://flaky/", nil)
+	errors.Assert(t, ErrCircuitOpen, err)
+	assert.Equal(t, 2, calls)
+
+	// other hosts are not affected
+	_, err = client.Do(MethodGet, "http://down/", nil)
+	errors.Assert(t, ErrRequestFailed, err)
+	assert.Equal(t, 3, calls)
+
+	// trial request after open duration closes the circuit on success
+	time.Sleep(60 * time.Millisecond)
+	status = 200
+	_, err = client.Do(MethodGet, "http://flaky/", nil)
+	errors.AssertNil(t, err)
+	assert.False(t, client.CircuitBreaker.State("flaky"))
+	_, err = client.Do(MethodGet, "http://flaky/", nil)
+	errors.AssertNil(t, err)
+	assert.Equal(t, 5, calls)
+}
+
+func TestCircuitBreakerHalfOpen(t *testing.T) {
+	cb := NewCircuitBreaker(1, 10*time.Millisecond)
+	cb.Report("host", false)
+	errors.Assert(t, ErrCircuitOpen, cb.Allow("host"))
+
+	time.Sleep(20 * time.Millisecond)
+	errors.AssertNil(t, cb.Allow("host"))
+	// only one trial request at a time
+	errors.Assert(t, ErrCircuitOpen, cb.Allow("host"))
+
+	cb.Report("host", false)
+	errors.Assert(t, ErrCircuitOpen, cb.Allow("host"))
+}
+
+func TestCircuitBreakerLiteral(t *testing.T) {
+	cb := &CircuitBreaker{FailureThreshold: 2, OpenDuration: time.Minute}
+	errors.AssertNil(t, cb.Allow("host"))
+	assert.False(t, cb.State("host"))
+	cb.Report("host", true)
+	cb.Report("host", false)
+	cb.Report("host", false)
+	errors.Assert(t, ErrCircuitOpen, cb.Allow("host"))
+}
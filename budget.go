@@ -0,0 +1,80 @@
+package http
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	retryBudgetBuckets = 10
+)
+
+// RetryBudget limits the number of retries to a fraction of the requests sent within a sliding window. Share a single budget between clients to cap the retry amplification of the whole application.
+type RetryBudget struct {
+	// Ratio denotes the maximum number of retries relative to the number of initial requests (e.g. 0.2 for at most 20% extra requests).
+	Ratio float64
+	// MinRetries are always allowed within the window, so retries remain possible at low traffic.
+	MinRetries int
+	// Window denotes the duration of the sliding window.
+	Window time.Duration
+
+	mutex   sync.Mutex
+	buckets [retryBudgetBuckets]retryBudgetBucket
+}
+
+type retryBudgetBucket struct {
+	start    time.Time
+	requests int
+	retries  int
+}
+
+// NewRetryBudget returns a new retry budget with the given ratio, minimum retries and window.
+func NewRetryBudget(ratio float64, minRetries int, window time.Duration) *RetryBudget {
+	return &RetryBudget{Ratio: ratio, MinRetries: minRetries, Window: window}
+}
+
+// bucket returns the bucket for the current time and resets it if it belongs to a previous window.
+func (budget *RetryBudget) bucket(now time.Time) *retryBudgetBucket {
+	width := budget.Window / retryBudgetBuckets
+	if width <= 0 {
+		width = 1
+	}
+	start := now.Truncate(width)
+	b := &budget.buckets[(start.UnixNano()/int64(width))%retryBudgetBuckets]
+	if !b.start.Equal(start) {
+		*b = retryBudgetBucket{start: start}
+	}
+	return b
+}
+
+func (budget *RetryBudget) totals(now time.Time) (int, int) {
+	requests, retries := 0, 0
+	for _, b := range budget.buckets {
+		if now.Sub(b.start) < budget.Window {
+			requests += b.requests
+			retries += b.retries
+		}
+	}
+	return requests, retries
+}
+
+// RecordRequest registers an initial request in the budget.
+func (budget *RetryBudget) RecordRequest() {
+	budget.mutex.Lock()
+	defer budget.mutex.Unlock()
+	budget.bucket(time.Now()).requests++
+}
+
+// TryRetry returns true and withdraws a retry from the budget if the budget allows another retry.
+func (budget *RetryBudget) TryRetry() bool {
+	budget.mutex.Lock()
+	defer budget.mutex.Unlock()
+
+	now := time.Now()
+	requests, retries := budget.totals(now)
+	if float64(retries) >= budget.Ratio*float64(requests)+float64(budget.MinRetries) {
+		return false
+	}
+	budget.bucket(now).retries++
+	return true
+}
@@ -0,0 +1,50 @@
+package http
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sbreitf1/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryBudget(t *testing.T) {
+	budget := NewRetryBudget(0.2, 1, time.Minute)
+	assert.True(t, budget.TryRetry(), "MinRetries should be available without requests")
+	assert.False(t, budget.TryRetry())
+
+	for i := 0; i < 10; i++ {
+		budget.RecordRequest()
+	}
+	assert.True(t, budget.TryRetry())
+	assert.True(t, budget.TryRetry())
+	assert.False(t, budget.TryRetry())
+}
+
+func TestRetryBudgetWindow(t *testing.T) {
+	budget := NewRetryBudget(0, 1, 50*time.Millisecond)
+	assert.True(t, budget.TryRetry())
+	assert.False(t, budget.TryRetry())
+	time.Sleep(60 * time.Millisecond)
+	assert.True(t, budget.TryRetry())
+}
+
+func TestRetryBudgetClient(t *testing.T) {
+	client := NewClient()
+	client.Retry = &RetryPolicy{MaxAttempts: 5, InitialBackoff: time.Millisecond, Budget: NewRetryBudget(0.5, 0, time.Minute)}
+	calls := 0
+	client.RequestResponder = func(req *Request) (*Response, errors.Error) {
+		calls++
+		return &Response{StatusCode: 503, Body: ioutil.NopCloser(strings.NewReader(""))}, nil
+	}
+
+	// 2 requests allow a single retry
+	for i := 0; i < 2; i++ {
+		response, err := client.Do(MethodGet, "http://localhost/", nil)
+		errors.AssertNil(t, err)
+		assert.Equal(t, 503, response.StatusCode)
+	}
+	assert.Equal(t, 3, calls)
+}
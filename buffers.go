@@ -0,0 +1,66 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// maxPooledBufferSize limits the capacity of buffers returned to the pool, so single large responses do not retain memory.
+	maxPooledBufferSize = 64 << 10
+)
+
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		return new(bytes.Buffer)
+	},
+}
+
+// getBuffer returns an empty buffer from the pool, return it using putBuffer.
+func getBuffer() *bytes.Buffer {
+	return bufferPool.Get().(*bytes.Buffer)
+}
+
+// putBuffer returns buf to the pool. The buffer must not be used afterwards.
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBufferSize {
+		return
+	}
+	buf.Reset()
+	bufferPool.Put(buf)
+}
+
+// writeJSON answers the request with body serialized as JSON like c.JSON, but encodes into a pooled buffer to reduce allocations per request.
+func writeJSON(c *gin.Context, status int, body interface{}) {
+	buf := getBuffer()
+	defer putBuffer(buf)
+	if err := json.NewEncoder(buf).Encode(body); err != nil {
+		c.Error(err)
+		c.AbortWithStatus(500)
+		return
+	}
+	// omit the trailing newline of the encoder
+	c.Data(status, "application/json; charset=utf-8", buf.Bytes()[:buf.Len()-1])
+}
+
+// accessLogMessage formats the access log line of a finished request using a pooled buffer.
+func accessLogMessage(c *gin.Context, duration time.Duration) string {
+	buf := getBuffer()
+	defer putBuffer(buf)
+	buf.WriteString(c.Request.RemoteAddr)
+	buf.WriteString(" - ")
+	buf.Write(strconv.AppendInt(buf.AvailableBuffer(), int64(c.Writer.Status()), 10))
+	buf.WriteString(" - ")
+	buf.WriteString(c.Request.Method)
+	buf.WriteString(" - ")
+	buf.WriteString(c.Request.RequestURI)
+	buf.WriteString(" (")
+	buf.WriteString(duration.String())
+	buf.WriteString(")")
+	return buf.String()
+}
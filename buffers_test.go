@@ -0,0 +1,108 @@
+package http
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+var benchmarkBody = func() map[string]interface{} {
+	items := make([]testItem, 100)
+	for i := range items {
+		items[i] = testItem{ID: strconv.Itoa(i), Name: "item <" + strconv.Itoa(i) + ">"}
+	}
+	return map[string]interface{}{"items": items, "total": len(items)}
+}()
+
+func TestWriteJSON(t *testing.T) {
+	expected := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(expected)
+	c.JSON(201, benchmarkBody)
+
+	w := httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	writeJSON(c, 201, benchmarkBody)
+	assert.Equal(t, 201, w.Code)
+	assert.Equal(t, expected.Header().Get("Content-Type"), w.Header().Get("Content-Type"))
+	assert.Equal(t, expected.Body.String(), w.Body.String())
+
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	writeJSON(c, 200, func() {})
+	assert.Equal(t, 500, w.Code)
+	assert.Len(t, c.Errors, 1)
+}
+
+func TestPutBuffer(t *testing.T) {
+	buf := getBuffer()
+	buf.WriteString("content")
+	putBuffer(buf)
+	assert.Equal(t, 0, buf.Len())
+
+	// large buffers are not reset for reuse
+	large := bytes.NewBuffer(make([]byte, 0, 2*maxPooledBufferSize))
+	large.WriteString("content")
+	putBuffer(large)
+	assert.Equal(t, "content", large.String())
+}
+
+func TestAccessLogMessage(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/items?id=1", nil)
+	c.String(404, "")
+	assert.Equal(t, "192.0.2.1:1234 - 404 - GET - /items?id=1 (1.5ms)", accessLogMessage(c, 1500*time.Microsecond))
+}
+
+// discardResponseWriter drops all responses to measure the allocations of handlers only.
+type discardResponseWriter struct {
+	header http.Header
+}
+
+func (w *discardResponseWriter) Header() http.Header         { return w.header }
+func (w *discardResponseWriter) Write(p []byte) (int, error) { return len(p), nil }
+func (w *discardResponseWriter) WriteHeader(int)             {}
+
+func BenchmarkWriteJSON(b *testing.B) {
+	engine := gin.New()
+	engine.GET("/pooled", func(c *gin.Context) {
+		writeJSON(c, 200, benchmarkBody)
+	})
+	engine.GET("/gin", func(c *gin.Context) {
+		c.JSON(200, benchmarkBody)
+	})
+
+	for _, path := range []string{"/pooled", "/gin"} {
+		b.Run(path[1:], func(b *testing.B) {
+			w := &discardResponseWriter{make(http.Header)}
+			r := httptest.NewRequest("GET", path, nil)
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				engine.ServeHTTP(w, r)
+			}
+		})
+	}
+}
+
+func BenchmarkAccessLogMessage(b *testing.B) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/items?id=1", nil)
+	b.Run("pooled", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			accessLogMessage(c, time.Millisecond)
+		}
+	})
+	b.Run("sprintf", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_ = fmt.Sprintf("%s - %d - %s - %s (%s)", c.Request.RemoteAddr, c.Writer.Status(), c.Request.Method, c.Request.RequestURI, time.Millisecond)
+		}
+	})
+}
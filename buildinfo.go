@@ -0,0 +1,80 @@
+package http
+
+import (
+	"runtime"
+	"runtime/debug"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	runtimeMetricsOnce sync.Once
+	buildInfoGauge     *prometheus.GaugeVec
+)
+
+// BuildInfo describes the running binary. It is exported as build_info metric.
+type BuildInfo struct {
+	// Version of the application, e.g. injected using -ldflags. Defaults to the version of the main module.
+	Version string
+	// Commit the binary was built from. Defaults to the VCS revision recorded by the go tool.
+	Commit string
+	// GoVersion defaults to the version of the Go runtime.
+	GoVersion string
+}
+
+// withDefaults fills empty fields with the information recorded in the binary.
+func (info BuildInfo) withDefaults() BuildInfo {
+	if len(info.GoVersion) == 0 {
+		info.GoVersion = runtime.Version()
+	}
+	if buildInfo, ok := debug.ReadBuildInfo(); ok {
+		if len(info.Version) == 0 {
+			info.Version = buildInfo.Main.Version
+		}
+		for _, setting := range buildInfo.Settings {
+			if setting.Key == "vcs.revision" && len(info.Commit) == 0 {
+				info.Commit = setting.Value
+			}
+		}
+	}
+	if len(info.Version) == 0 {
+		info.Version = "unknown"
+	}
+	if len(info.Commit) == 0 {
+		info.Commit = "unknown"
+	}
+	return info
+}
+
+// registerRuntimeMetrics registers the Go runtime and process collectors as well as the build_info gauge on the default prometheus registry.
+func registerRuntimeMetrics() {
+	runtimeMetricsOnce.Do(func() {
+		buildInfoGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "build_info",
+			Help: "Always 1, labeled with version, commit and Go version of the running binary.",
+		}, []string{"version", "commit", "goversion"})
+
+		for _, collector := range []prometheus.Collector{
+			prometheus.NewGoCollector(),
+			prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+			buildInfoGauge,
+		} {
+			if err := prometheus.Register(collector); err != nil {
+				// the default registry already contains the standard collectors
+				if _, ok := err.(prometheus.AlreadyRegisteredError); !ok {
+					log.WithFields(log.Fields{"component": "metrics"}).Errorf("Could not register collector: %s", err)
+				}
+			}
+		}
+	})
+}
+
+// setBuildInfo exports the given build information. Only the most recent information is kept.
+func setBuildInfo(info BuildInfo) {
+	registerRuntimeMetrics()
+	info = info.withDefaults()
+	buildInfoGauge.Reset()
+	buildInfoGauge.WithLabelValues(info.Version, info.Commit, info.GoVersion).Set(1)
+}
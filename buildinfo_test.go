@@ -0,0 +1,40 @@
+package http
+
+import (
+	"io/ioutil"
+	"net/http"
+	"runtime"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sbreitf1/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildInfoMetrics(t *testing.T) {
+	server, err := NewServer(&ServerConfig{ListenAddress: "127.0.0.1:18093", GinMode: gin.TestMode, BuildInfo: BuildInfo{Version: "1.2.3", Commit: "abc123"}})
+	errors.AssertNil(t, err)
+	errors.AssertNil(t, server.RunAsync(nil))
+	defer server.Shutdown()
+
+	resp, getErr := http.Get("http://127.0.0.1:18093/metrics")
+	if !assert.NoError(t, getErr) {
+		return
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+	assert.Contains(t, string(body), `build_info{commit="abc123",goversion="`+runtime.Version()+`",version="1.2.3"} 1`)
+	assert.Contains(t, string(body), "go_goroutines")
+	assert.Contains(t, string(body), "go_memstats_heap_alloc_bytes")
+	assert.Contains(t, string(body), "process_")
+}
+
+func TestBuildInfoDefaults(t *testing.T) {
+	info := BuildInfo{}.withDefaults()
+	assert.Equal(t, runtime.Version(), info.GoVersion)
+	assert.NotEmpty(t, info.Version)
+	assert.NotEmpty(t, info.Commit)
+
+	info = BuildInfo{Version: "v1", Commit: "c", GoVersion: "go"}.withDefaults()
+	assert.Equal(t, BuildInfo{Version: "v1", Commit: "c", GoVersion: "go"}, info)
+}
@@ -0,0 +1,429 @@
+package http
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sbreitf1/errors"
+)
+
+const (
+	// HeaderCache is added to responses of clients with cache to indicate whether the response has been served from cache (HIT), revalidated (REVALIDATED) or requested from the server (MISS).
+	HeaderCache = "X-Cache"
+
+	// DefaultHTTPCacheMaxEntrySize denotes the maximum body size of responses stored by an HTTPCache.
+	DefaultHTTPCacheMaxEntrySize = 1 << 20
+)
+
+// CacheStorage stores serialized cache entries.
+type CacheStorage interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, data []byte)
+	Delete(key string)
+}
+
+// Store is a cache storage with expiring entries and atomic insertion. Stores shared by multiple replicas (e.g. RedisStore) allow caches, idempotency records and rate limits to be enforced fleet-wide.
+type Store interface {
+	CacheStorage
+	// SetWithTTL stores an entry for key that expires after ttl.
+	SetWithTTL(key string, data []byte, ttl time.Duration)
+	// SetIfAbsent stores an entry for key that expires after ttl only if no entry exists and reports whether it has been stored.
+	SetIfAbsent(key string, data []byte, ttl time.Duration) (bool, errors.Error)
+}
+
+// ConditionalStore is a Store that deletes entries only if they still hold the expected data. It releases locks acquired with SetIfAbsent without removing the lock of another owner that acquired it after the previous lock expired.
+type ConditionalStore interface {
+	Store
+	// DeleteIfEqual removes the entry stored for key if it equals data and reports whether it has been removed.
+	DeleteIfEqual(key string, data []byte) (bool, errors.Error)
+}
+
+// MemoryCacheStorage keeps cache entries in memory. It implements Store for use within a single process.
+type MemoryCacheStorage struct {
+	mutex   sync.RWMutex
+	entries map[string][]byte
+	expires map[string]time.Time
+	sets    int
+}
+
+// NewMemoryCacheStorage returns an empty in-memory cache storage.
+func NewMemoryCacheStorage() *MemoryCacheStorage {
+	return &MemoryCacheStorage{entries: make(map[string][]byte), expires: make(map[string]time.Time)}
+}
+
+// Get returns the entry stored for key.
+func (s *MemoryCacheStorage) Get(key string) ([]byte, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	if s.expired(key, time.Now()) {
+		return nil, false
+	}
+	data, ok := s.entries[key]
+	return data, ok
+}
+
+// Set stores an entry for key.
+func (s *MemoryCacheStorage) Set(key string, data []byte) {
+	s.SetWithTTL(key, data, 0)
+}
+
+// SetWithTTL stores an entry for key that expires after ttl. Entries without positive ttl do not expire.
+func (s *MemoryCacheStorage) SetWithTTL(key string, data []byte, ttl time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.set(key, data, ttl)
+}
+
+// SetIfAbsent stores an entry for key that expires after ttl only if no entry exists and reports whether it has been stored.
+func (s *MemoryCacheStorage) SetIfAbsent(key string, data []byte, ttl time.Duration) (bool, errors.Error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if _, ok := s.entries[key]; ok && !s.expired(key, time.Now()) {
+		return false, nil
+	}
+	s.set(key, data, ttl)
+	return true, nil
+}
+
+// Delete removes the entry stored for key.
+func (s *MemoryCacheStorage) Delete(key string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.entries, key)
+	delete(s.expires, key)
+}
+
+// DeleteIfEqual removes the entry stored for key if it equals data and reports whether it has been removed.
+func (s *MemoryCacheStorage) DeleteIfEqual(key string, data []byte) (bool, errors.Error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if existing, ok := s.entries[key]; !ok || s.expired(key, time.Now()) || !bytes.Equal(existing, data) {
+		return false, nil
+	}
+	delete(s.entries, key)
+	delete(s.expires, key)
+	return true, nil
+}
+
+func (s *MemoryCacheStorage) set(key string, data []byte, ttl time.Duration) {
+	s.entries[key] = data
+	if ttl > 0 {
+		s.expires[key] = time.Now().Add(ttl)
+	} else {
+		delete(s.expires, key)
+	}
+
+	// remove expired entries from time to time to bound memory usage
+	if s.sets++; s.sets%1000 == 0 {
+		now := time.Now()
+		for k := range s.expires {
+			if s.expired(k, now) {
+				delete(s.entries, k)
+				delete(s.expires, k)
+			}
+		}
+	}
+}
+
+func (s *MemoryCacheStorage) expired(key string, now time.Time) bool {
+	expires, ok := s.expires[key]
+	return ok && !now.Before(expires)
+}
+
+// DiskCacheStorage keeps cache entries as files in a directory.
+type DiskCacheStorage struct {
+	dir string
+}
+
+// NewDiskCacheStorage returns a cache storage that writes entries to the given directory, which is created if necessary.
+func NewDiskCacheStorage(dir string) (*DiskCacheStorage, errors.Error) {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, errors.Wrap(err)
+	}
+	return &DiskCacheStorage{dir: dir}, nil
+}
+
+// Get returns the entry stored for key.
+func (s *DiskCacheStorage) Get(key string) ([]byte, bool) {
+	data, err := ioutil.ReadFile(s.path(key))
+	return data, err == nil
+}
+
+// Set stores an entry for key. Write errors are ignored, the entry is simply not cached.
+func (s *DiskCacheStorage) Set(key string, data []byte) {
+	tmp := s.path(key) + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err == nil {
+		os.Rename(tmp, s.path(key))
+	}
+}
+
+// Delete removes the entry stored for key.
+func (s *DiskCacheStorage) Delete(key string) {
+	os.Remove(s.path(key))
+}
+
+func (s *DiskCacheStorage) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:]))
+}
+
+// HTTPCache is a private HTTP cache following RFC 7234. Fresh responses are served from cache, stale responses are revalidated using If-None-Match and If-Modified-Since.
+type HTTPCache struct {
+	Storage CacheStorage
+	// HeuristicFraction denotes the fraction of the time since Last-Modified that responses without explicit expiration are considered fresh. Defaults to 10% when zero.
+	HeuristicFraction float64
+	// MaxEntrySize limits the body size of stored responses. Larger responses are streamed to the caller without being stored. Defaults to DefaultHTTPCacheMaxEntrySize when zero.
+	MaxEntrySize int64
+
+	now func() time.Time
+}
+
+type cacheEntry struct {
+	StatusCode    int       `json:"status"`
+	Header        Header    `json:"header"`
+	Body          []byte    `json:"body"`
+	RequestHeader Header    `json:"requestHeader,omitempty"`
+	Stored        time.Time `json:"stored"`
+}
+
+// NewHTTPCache returns a cache using the given storage.
+func NewHTTPCache(storage CacheStorage) *HTTPCache {
+	return &HTTPCache{Storage: storage}
+}
+
+// Interceptor returns an interceptor that answers GET requests from cache and stores cacheable responses. Successful unsafe requests invalidate the cached response of their URL.
+func (cache *HTTPCache) Interceptor() Interceptor {
+	return func(next Responder) Responder {
+		return func(req *Request) (*Response, errors.Error) {
+			key := req.URL.String()
+			if req.Method != http.MethodGet {
+				response, err := next(req)
+				if err == nil && req.Method != http.MethodHead && req.Method != http.MethodOptions && response.StatusCode < 400 {
+					cache.Storage.Delete(key)
+				}
+				return response, err
+			}
+
+			requestCC := parseCacheControl(req.Header)
+			if _, ok := requestCC["no-store"]; ok {
+				return next(req)
+			}
+
+			entry := cache.load(key, req)
+			if entry != nil {
+				_, noCache := requestCC["no-cache"]
+				if !noCache && cache.age(entry) < cache.freshness(entry) {
+					return entry.response(req, "HIT", cache.age(entry)), nil
+				}
+			}
+
+			// validators are set on a copy, so retries and the caller are not affected
+			condReq := *req
+			condReq.Header = req.Header.Clone()
+			if entry != nil {
+				if etag := entry.Header.Get("ETag"); len(etag) > 0 && len(condReq.Header.Get("If-None-Match")) == 0 {
+					condReq.Header.Set("If-None-Match", etag)
+				}
+				if lastModified := entry.Header.Get("Last-Modified"); len(lastModified) > 0 && len(condReq.Header.Get("If-Modified-Since")) == 0 {
+					condReq.Header.Set("If-Modified-Since", lastModified)
+				}
+			}
+
+			response, err := next(&condReq)
+			if err != nil {
+				return nil, err
+			}
+
+			if entry != nil && response.StatusCode == http.StatusNotModified && len(req.Header.Get("If-None-Match")) == 0 && len(req.Header.Get("If-Modified-Since")) == 0 {
+				DrainAndClose(response)
+				for h, values := range response.Header {
+					entry.Header[h] = values
+				}
+				entry.Stored = cache.clock()
+				cache.store(key, entry)
+				return entry.response(req, "REVALIDATED", 0), nil
+			}
+
+			return cache.storeResponse(key, req, response)
+		}
+	}
+}
+
+func (cache *HTTPCache) storeResponse(key string, req *Request, response *Response) (*Response, errors.Error) {
+	if !isCacheableStatus(response.StatusCode) {
+		response.Header.Set(HeaderCache, "MISS")
+		return response, nil
+	}
+	responseCC := parseCacheControl(response.Header)
+	if _, ok := responseCC["no-store"]; ok || response.Header.Get("Vary") == "*" {
+		response.Header.Set(HeaderCache, "MISS")
+		return response, nil
+	}
+
+	// the body is only read if the response is stored
+	entry := &cacheEntry{StatusCode: response.StatusCode, Header: response.Header.Clone(), Stored: cache.clock()}
+	maxSize := cache.MaxEntrySize
+	if maxSize <= 0 {
+		maxSize = DefaultHTTPCacheMaxEntrySize
+	}
+	if (cache.freshness(entry) <= 0 && len(entry.Header.Get("ETag")) == 0 && len(entry.Header.Get("Last-Modified")) == 0) || response.ContentLength > maxSize {
+		response.Header.Set(HeaderCache, "MISS")
+		return response, nil
+	}
+
+	body, readErr := ioutil.ReadAll(io.LimitReader(response.Body, maxSize+1))
+	if readErr != nil {
+		response.Body.Close()
+		return nil, ErrInvalidBody.Make().Cause(readErr)
+	}
+	response.Header.Set(HeaderCache, "MISS")
+	if int64(len(body)) > maxSize {
+		// the remaining body is streamed without being stored
+		response.Body = readCloser{io.MultiReader(bytes.NewReader(body), response.Body), response.Body}
+		return response, nil
+	}
+	response.Body.Close()
+	response.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	entry.Body = body
+	for _, h := range varyHeaders(response.Header) {
+		if entry.RequestHeader == nil {
+			entry.RequestHeader = make(Header)
+		}
+		entry.RequestHeader[h] = req.Header.Values(h)
+	}
+	cache.store(key, entry)
+	return response, nil
+}
+
+func (cache *HTTPCache) load(key string, req *Request) *cacheEntry {
+	data, ok := cache.Storage.Get(key)
+	if !ok {
+		return nil
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil
+	}
+	for _, h := range varyHeaders(entry.Header) {
+		if strings.Join(entry.RequestHeader.Values(h), ",") != strings.Join(req.Header.Values(h), ",") {
+			return nil
+		}
+	}
+	return &entry
+}
+
+func (cache *HTTPCache) store(key string, entry *cacheEntry) {
+	if data, err := json.Marshal(entry); err == nil {
+		cache.Storage.Set(key, data)
+	}
+}
+
+func (cache *HTTPCache) clock() time.Time {
+	if cache.now != nil {
+		return cache.now()
+	}
+	return time.Now()
+}
+
+// age returns the current age of the entry including the age reported by the server.
+func (cache *HTTPCache) age(entry *cacheEntry) time.Duration {
+	age := cache.clock().Sub(entry.Stored)
+	if seconds, err := strconv.Atoi(entry.Header.Get("Age")); err == nil && seconds > 0 {
+		age += time.Duration(seconds) * time.Second
+	}
+	return age
+}
+
+// freshness returns the freshness lifetime of the entry.
+func (cache *HTTPCache) freshness(entry *cacheEntry) time.Duration {
+	cc := parseCacheControl(entry.Header)
+	if _, ok := cc["no-cache"]; ok {
+		return 0
+	}
+	if maxAge, ok := cc["max-age"]; ok {
+		seconds, err := strconv.Atoi(maxAge)
+		if err != nil {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+
+	date, dateErr := http.ParseTime(entry.Header.Get("Date"))
+	if dateErr != nil {
+		date = entry.Stored
+	}
+	if expiresHeader := entry.Header.Get("Expires"); len(expiresHeader) > 0 {
+		expires, err := http.ParseTime(expiresHeader)
+		if err != nil {
+			return 0
+		}
+		return expires.Sub(date)
+	}
+	if lastModified, err := http.ParseTime(entry.Header.Get("Last-Modified")); err == nil {
+		fraction := cache.HeuristicFraction
+		if fraction <= 0 {
+			fraction = 0.1
+		}
+		return time.Duration(float64(date.Sub(lastModified)) * fraction)
+	}
+	return 0
+}
+
+func (entry *cacheEntry) response(req *Request, state string, age time.Duration) *Response {
+	header := entry.Header.Clone()
+	header.Set(HeaderCache, state)
+	if age > 0 {
+		header.Set("Age", strconv.Itoa(int(age.Seconds())))
+	}
+	return newMockResponse(req, entry.StatusCode, header, entry.Body)
+}
+
+func isCacheableStatus(status int) bool {
+	switch status {
+	case 200, 203, 204, 300, 301, 404, 405, 410, 414, 501:
+		return true
+	}
+	return false
+}
+
+func parseCacheControl(header Header) map[string]string {
+	cc := make(map[string]string)
+	for _, value := range header.Values("Cache-Control") {
+		for _, directive := range strings.Split(value, ",") {
+			kv := strings.SplitN(strings.TrimSpace(directive), "=", 2)
+			if len(kv[0]) == 0 {
+				continue
+			}
+			if len(kv) == 2 {
+				cc[strings.ToLower(kv[0])] = strings.Trim(kv[1], `"`)
+			} else {
+				cc[strings.ToLower(kv[0])] = ""
+			}
+		}
+	}
+	return cc
+}
+
+func varyHeaders(header Header) []string {
+	var headers []string
+	for _, value := range header.Values("Vary") {
+		for _, h := range strings.Split(value, ",") {
+			if h = strings.TrimSpace(h); len(h) > 0 {
+				headers = append(headers, http.CanonicalHeaderKey(h))
+			}
+		}
+	}
+	return headers
+}
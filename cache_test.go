@@ -0,0 +1,173 @@
+package http
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sbreitf1/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPCache(t *testing.T) {
+	requests := 0
+	conditional := 0
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch r.URL.Path {
+		case "/fresh":
+			w.Header().Set("Cache-Control", "max-age=60")
+		case "/etag":
+			w.Header().Set("ETag", `"v1"`)
+			w.Header().Set("Cache-Control", "no-cache")
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				conditional++
+				w.WriteHeader(304)
+				return
+			}
+		case "/nostore":
+			w.Header().Set("Cache-Control", "no-store")
+		case "/vary":
+			w.Header().Set("Cache-Control", "max-age=60")
+			w.Header().Set("Vary", "Accept-Language")
+			w.Write([]byte(r.Header.Get("Accept-Language")))
+			return
+		case "/large":
+			w.Header().Set("Cache-Control", "max-age=60")
+			w.Write([]byte(strings.Repeat("x", 100)))
+			return
+		case "/chunked":
+			w.Header().Set("Cache-Control", "max-age=60")
+			for i := 0; i < 10; i++ {
+				w.Write([]byte(strings.Repeat("x", 10)))
+				w.(http.Flusher).Flush()
+			}
+			return
+		case "/stream":
+			w.Write([]byte("first"))
+			w.(http.Flusher).Flush()
+			<-release
+		}
+		w.Write([]byte(r.URL.Path))
+	}))
+	defer server.Close()
+
+	now := time.Now()
+	cache := NewHTTPCache(NewMemoryCacheStorage())
+	cache.now = func() time.Time { return now }
+	client := NewClient()
+	client.Cache = cache
+
+	get := func(path string, f func(*Request) errors.Error) (string, string) {
+		response, err := client.Get(server.URL+path, f)
+		errors.AssertNil(t, err)
+		body, err := ReadResponseString(response)
+		errors.AssertNil(t, err)
+		return body, response.Header.Get(HeaderCache)
+	}
+
+	t.Run("Fresh", func(t *testing.T) {
+		requests = 0
+		body, state := get("/fresh", nil)
+		assert.Equal(t, "/fresh", body)
+		assert.Equal(t, "MISS", state)
+		body, state = get("/fresh", nil)
+		assert.Equal(t, "/fresh", body)
+		assert.Equal(t, "HIT", state)
+		assert.Equal(t, 1, requests)
+
+		now = now.Add(2 * time.Minute)
+		_, state = get("/fresh", nil)
+		assert.Equal(t, "MISS", state, "Stale entries without validator are requested again")
+		assert.Equal(t, 2, requests)
+
+		_, state = get("/fresh", WithHeader("Cache-Control", "no-cache"))
+		assert.Equal(t, "MISS", state)
+	})
+
+	t.Run("Revalidate", func(t *testing.T) {
+		requests, conditional = 0, 0
+		get("/etag", nil)
+		body, state := get("/etag", nil)
+		assert.Equal(t, "/etag", body)
+		assert.Equal(t, "REVALIDATED", state)
+		assert.Equal(t, 2, requests)
+		assert.Equal(t, 1, conditional)
+	})
+
+	t.Run("NoStore", func(t *testing.T) {
+		requests = 0
+		get("/nostore", nil)
+		_, state := get("/nostore", nil)
+		assert.Equal(t, "MISS", state)
+		assert.Equal(t, 2, requests)
+	})
+
+	t.Run("Vary", func(t *testing.T) {
+		requests = 0
+		body, _ := get("/vary", WithHeader("Accept-Language", "de"))
+		assert.Equal(t, "de", body)
+		body, _ = get("/vary", WithHeader("Accept-Language", "en"))
+		assert.Equal(t, "en", body)
+		body, state := get("/vary", WithHeader("Accept-Language", "en"))
+		assert.Equal(t, "en", body)
+		assert.Equal(t, "HIT", state)
+		assert.Equal(t, 2, requests)
+	})
+
+	t.Run("Invalidate", func(t *testing.T) {
+		requests = 0
+		get("/fresh", nil)
+		response, err := client.Post(server.URL+"/fresh", "text/plain", strings.NewReader(""), nil)
+		errors.AssertNil(t, err)
+		DrainAndClose(response)
+		_, state := get("/fresh", nil)
+		assert.Equal(t, "MISS", state)
+	})
+
+	t.Run("MaxEntrySize", func(t *testing.T) {
+		cache.MaxEntrySize = 50
+		defer func() { cache.MaxEntrySize = 0 }()
+		for _, path := range []string{"/large", "/chunked"} {
+			requests = 0
+			body, state := get(path, nil)
+			assert.Equal(t, strings.Repeat("x", 100), body)
+			assert.Equal(t, "MISS", state)
+			_, state = get(path, nil)
+			assert.Equal(t, "MISS", state)
+			assert.Equal(t, 2, requests)
+		}
+	})
+
+	t.Run("NotStorable", func(t *testing.T) {
+		// the body is passed through before the server completes it
+		defer close(release)
+		response, err := client.Get(server.URL+"/stream", nil)
+		errors.AssertNil(t, err)
+		defer response.Body.Close()
+		first := make([]byte, 5)
+		_, readErr := io.ReadFull(response.Body, first)
+		assert.NoError(t, readErr)
+		assert.Equal(t, "first", string(first))
+		assert.Equal(t, "MISS", response.Header.Get(HeaderCache))
+	})
+}
+
+func TestDiskCacheStorage(t *testing.T) {
+	storage, err := NewDiskCacheStorage(t.TempDir())
+	errors.AssertNil(t, err)
+
+	_, ok := storage.Get("key")
+	assert.False(t, ok)
+	storage.Set("key", []byte("value"))
+	data, ok := storage.Get("key")
+	assert.True(t, ok)
+	assert.Equal(t, "value", string(data))
+	storage.Delete("key")
+	_, ok = storage.Get("key")
+	assert.False(t, ok)
+}
@@ -0,0 +1,93 @@
+package http
+
+import (
+	"math"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// HeaderCanary can be set to "canary" or "stable" to select the handler version of a CanaryRoute. The selected version is returned in the same response header.
+	HeaderCanary = "X-Canary"
+	// CanaryVersionKey denotes the gin context key that contains the version ("canary" or "stable") selected by a CanaryRoute, e.g. for logging.
+	CanaryVersionKey = "canaryVersion"
+
+	canaryVersion = "canary"
+	stableVersion = "stable"
+)
+
+// CanaryConfig contains the parameters of a CanaryRoute.
+type CanaryConfig struct {
+	// Weight denotes the share of requests (0 to 100) that is served by the canary handler.
+	Weight float64 `json:"weight"`
+	// Cookie denotes a cookie that selects the version like the X-Canary header. Cookies are not evaluated when empty.
+	Cookie string `json:"cookie,omitempty"`
+	// Sticky sets the cookie to the randomly selected version, so subsequent requests of the same client are served by the same version.
+	Sticky bool `json:"sticky,omitempty"`
+}
+
+// CanaryRoute splits requests of a route between a stable and a canary handler.
+type CanaryRoute struct {
+	stable, canary gin.HandlerFunc
+	config         CanaryConfig
+	weight         uint64
+}
+
+// NewCanaryRoute returns a route that serves the configured share of requests by the canary handler and all other requests by the stable handler. Register the Handle method of the route in the engine. Clients can force a version using the X-Canary header or the configured cookie.
+func NewCanaryRoute(stable, canary gin.HandlerFunc, config *CanaryConfig) *CanaryRoute {
+	route := &CanaryRoute{stable: stable, canary: canary, config: *config}
+	route.SetWeight(config.Weight)
+	return route
+}
+
+// SetWeight changes the share of requests (0 to 100) that is served by the canary handler, e.g. for a gradual rollout.
+func (route *CanaryRoute) SetWeight(weight float64) {
+	weight = math.Max(0, math.Min(100, weight))
+	atomic.StoreUint64(&route.weight, math.Float64bits(weight))
+}
+
+// Weight returns the share of requests (0 to 100) that is served by the canary handler.
+func (route *CanaryRoute) Weight() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&route.weight))
+}
+
+// Handle passes the request to the selected handler version.
+func (route *CanaryRoute) Handle(c *gin.Context) {
+	version := route.override(c)
+	if len(version) == 0 {
+		version = stableVersion
+		if rand.Float64()*100 < route.Weight() {
+			version = canaryVersion
+		}
+		if route.config.Sticky && len(route.config.Cookie) > 0 {
+			http.SetCookie(c.Writer, &http.Cookie{Name: route.config.Cookie, Value: version, Path: "/", HttpOnly: true})
+		}
+	}
+
+	c.Set(CanaryVersionKey, version)
+	c.Header(HeaderCanary, version)
+	if version == canaryVersion {
+		route.canary(c)
+	} else {
+		route.stable(c)
+	}
+}
+
+// override returns the version requested by header or cookie.
+func (route *CanaryRoute) override(c *gin.Context) string {
+	value := c.GetHeader(HeaderCanary)
+	if len(value) == 0 && len(route.config.Cookie) > 0 {
+		value, _ = c.Cookie(route.config.Cookie)
+	}
+	switch strings.ToLower(value) {
+	case canaryVersion, "true", "1":
+		return canaryVersion
+	case stableVersion, "false", "0":
+		return stableVersion
+	}
+	return ""
+}
@@ -0,0 +1,57 @@
+package http
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanaryRoute(t *testing.T) {
+	handler := func(name string) gin.HandlerFunc {
+		return func(c *gin.Context) { c.String(200, name+" "+c.GetString(CanaryVersionKey)) }
+	}
+	route := NewCanaryRoute(handler("v1"), handler("v2"), &CanaryConfig{Weight: 25, Cookie: "canary", Sticky: true})
+	engine := gin.New()
+	engine.GET("/items", route.Handle)
+
+	serve := func(header, cookie string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/items", nil)
+		if len(header) > 0 {
+			req.Header.Set(HeaderCanary, header)
+		}
+		if len(cookie) > 0 {
+			req.Header.Set("Cookie", "canary="+cookie)
+		}
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		return w
+	}
+
+	canary := 0
+	for i := 0; i < 1000; i++ {
+		w := serve("", "")
+		if w.Body.String() == "v2 canary" {
+			canary++
+			assert.Contains(t, w.Header().Get("Set-Cookie"), "canary=canary")
+		} else {
+			assert.Equal(t, "v1 stable", w.Body.String())
+			assert.Contains(t, w.Header().Get("Set-Cookie"), "canary=stable")
+		}
+	}
+	assert.InDelta(t, 250, canary, 60)
+
+	w := serve("canary", "")
+	assert.Equal(t, "v2 canary", w.Body.String())
+	assert.Equal(t, "canary", w.Header().Get(HeaderCanary))
+	assert.Empty(t, w.Header().Get("Set-Cookie"))
+	assert.Equal(t, "v1 stable", serve("", "stable").Body.String())
+	assert.Equal(t, "v2 canary", serve("1", "stable").Body.String())
+
+	route.SetWeight(150)
+	assert.Equal(t, float64(100), route.Weight())
+	assert.Equal(t, "v2 canary", serve("", "").Body.String())
+	route.SetWeight(0)
+	assert.Equal(t, "v1 stable", serve("", "").Body.String())
+}
@@ -2,9 +2,14 @@ package http
 
 import (
 	"context"
+	"io"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
+	neturl "net/url"
 	"os"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -87,7 +92,156 @@ func TestClient(t *testing.T) {
 	}))
 }
 
+func TestMethodHelpers(t *testing.T) {
+	assert.Equal(t, 6, withTestServer(func(url string, f *gin.HandlerFunc) {
+		var expectedMethod, expectedBody string
+		*f = func(c *gin.Context) {
+			assert.Equal(t, expectedMethod, c.Request.Method)
+			assert.Equal(t, "yes", c.GetHeader("X-Test"))
+			if len(expectedBody) > 0 {
+				assert.Equal(t, "text/plain", c.GetHeader("Content-Type"))
+				data, _ := ioutil.ReadAll(c.Request.Body)
+				assert.Equal(t, expectedBody, string(data))
+			}
+			c.Status(200)
+		}
+		header := WithHeader("X-Test", "yes")
+
+		for _, m := range []struct {
+			method RequestMethod
+			do     func() (*Response, errors.Error)
+		}{
+			{MethodGet, func() (*Response, errors.Error) { return Get(url, header) }},
+			{MethodHead, func() (*Response, errors.Error) { return Head(url, header) }},
+			{MethodDelete, func() (*Response, errors.Error) { return Delete(url, header) }},
+		} {
+			expectedMethod, expectedBody = m.method.String(), ""
+			_, err := m.do()
+			errors.AssertNil(t, err)
+		}
+
+		for _, m := range []struct {
+			method RequestMethod
+			do     func(string, string, io.Reader, func(*Request) errors.Error) (*Response, errors.Error)
+		}{
+			{MethodPost, Post},
+			{MethodPut, Put},
+			{MethodPatch, Patch},
+		} {
+			expectedMethod, expectedBody = m.method.String(), "body of "+m.method.String()
+			_, err := m.do(url, "text/plain", strings.NewReader(expectedBody), WithHeaders(Header{"X-Test": {"yes"}}))
+			errors.AssertNil(t, err)
+		}
+	}))
+}
+
+func TestDoContext(t *testing.T) {
+	// the aborted request is not guaranteed to be counted before shutdown
+	withTestServer(func(url string, f *gin.HandlerFunc) {
+		*f = func(c *gin.Context) {
+			select {
+			case <-c.Request.Context().Done():
+			case <-time.After(2 * time.Second):
+			}
+			c.Status(200)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		_, err := NewClient().DoContext(ctx, MethodGet, url+"/hang", nil, nil)
+		errors.Assert(t, ErrRequestFailed, err)
+		assert.True(t, time.Since(start) < time.Second, "Request should be aborted by context deadline")
+	})
+}
+
+func TestTimeouts(t *testing.T) {
+	withTestServer(func(url string, f *gin.HandlerFunc) {
+		*f = func(c *gin.Context) {
+			time.Sleep(200 * time.Millisecond)
+			c.Status(200)
+		}
+
+		client := NewClient()
+		client.ResponseHeaderTimeout = 50 * time.Millisecond
+		_, err := client.Do(MethodGet, url+"/slow", nil)
+		errors.Assert(t, ErrRequestFailed, err)
+
+		client = NewClient()
+		client.Timeout = 50 * time.Millisecond
+		_, err = client.Do(MethodGet, url+"/slow", nil)
+		errors.Assert(t, ErrRequestFailed, err)
+
+		client = NewClient()
+		client.Timeout = time.Second
+		client.DialTimeout = time.Second
+		client.TLSHandshakeTimeout = time.Second
+		_, err = client.Do(MethodGet, url+"/slow", nil)
+		errors.AssertNil(t, err)
+	})
+}
+
+func TestConnectionReuse(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.RemoteAddr))
+	}))
+	defer ts.Close()
+
+	client := NewClient()
+	client.MaxIdleConnsPerHost = 1
+	remoteAddr := func() string {
+		response, err := client.Do(MethodGet, ts.URL, nil)
+		errors.AssertNil(t, err)
+		str, err := ReadResponseString(response)
+		errors.AssertNil(t, err)
+		return str
+	}
+
+	first := remoteAddr()
+	assert.Equal(t, first, remoteAddr(), "Connection should be reused")
+	client.ResetTransport()
+	assert.NotEqual(t, first, remoteAddr(), "Connection should be closed on reset")
+}
+
+func TestBaseURL(t *testing.T) {
+	assert.Equal(t, 3, withTestServer(func(url string, f *gin.HandlerFunc) {
+		client := NewClient()
+		client.BaseURL = url + "/api/"
+
+		*f = func(c *gin.Context) {
+			assert.Equal(t, "/api/v1/users?id=1", c.Request.RequestURI)
+			c.Status(200)
+		}
+		_, err := client.Do(MethodGet, "/v1/users?id=1", nil)
+		errors.AssertNil(t, err)
+		_, err = client.Do(MethodGet, "v1/users?id=1", nil)
+		errors.AssertNil(t, err)
+
+		*f = func(c *gin.Context) {
+			assert.Equal(t, "/absolute", c.Request.RequestURI)
+			c.Status(200)
+		}
+		_, err = client.Do(MethodGet, url+"/absolute", nil)
+		errors.AssertNil(t, err)
+	}))
+}
+
+func TestPostForm(t *testing.T) {
+	assert.Equal(t, 1, withTestServer(func(url string, f *gin.HandlerFunc) {
+		*f = func(c *gin.Context) {
+			assert.Equal(t, "POST", c.Request.Method)
+			assert.Equal(t, "application/x-www-form-urlencoded", c.Request.Header.Get("Content-Type"))
+			assert.Equal(t, "client_credentials", c.PostForm("grant_type"))
+			assert.Equal(t, []string{"a b", "c&d"}, c.PostFormArray("scope"))
+			c.String(200, "token")
+		}
+		response, err := NewClient().PostForm(url+"/token", neturl.Values{"grant_type": {"client_credentials"}, "scope": {"a b", "c&d"}}, nil)
+		errors.AssertNil(t, err)
+		assertResponse(t, 200, "token", response)
+	}))
+}
+
 func assertResponse(t *testing.T, expectedCode int, expectedBody string, r *Response) bool {
+	defer DrainAndClose(r)
 	if !assert.Equal(t, expectedCode, r.StatusCode) {
 		return false
 	}
@@ -108,11 +262,11 @@ func assertResponse(t *testing.T, expectedCode int, expectedBody string, r *Resp
 
 func withTestServer(f func(url string, f *gin.HandlerFunc)) int {
 	var handler gin.HandlerFunc
-	requestCount := 0
+	var requestCount int32
 	e := gin.New()
 	e.Any("*route", func(c *gin.Context) {
 		handler(c)
-		requestCount++
+		atomic.AddInt32(&requestCount, 1)
 	})
 
 	port := os.Getenv("TEST_HTTP_PORT")
@@ -130,13 +284,58 @@ func withTestServer(f func(url string, f *gin.HandlerFunc)) int {
 	}()
 	time.Sleep(100 * time.Millisecond)
 
-	defer func() {
+	shutdown := func() {
 		context, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
 		server.Shutdown(context)
-	}()
+	}
+	defer shutdown()
 
 	f("http://localhost:"+port, &handler)
 
-	return requestCount
+	// handlers of requests the client gave up on are still counted
+	shutdown()
+	return int(atomic.LoadInt32(&requestCount))
+}
+
+func TestInterceptors(t *testing.T) {
+	client := NewClient()
+	client.Retry = &RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond}
+	trace := make([]string, 0)
+	tracer := func(name string) Interceptor {
+		return func(next Responder) Responder {
+			return func(req *Request) (*Response, errors.Error) {
+				trace = append(trace, name+">")
+				response, err := next(req)
+				trace = append(trace, "<"+name)
+				return response, err
+			}
+		}
+	}
+	client.Use(tracer("a"), tracer("b"))
+	client.RequestResponder = func(req *Request) (*Response, errors.Error) {
+		trace = append(trace, "send")
+		return &Response{StatusCode: 503, Body: ioutil.NopCloser(strings.NewReader(""))}, nil
+	}
+
+	_, err := client.Do(MethodGet, "http://localhost/", nil)
+	errors.AssertNil(t, err)
+	assert.Equal(t, []string{"a>", "b>", "send", "<b", "<a", "a>", "b>", "send", "<b", "<a"}, trace)
+}
+
+func TestInterceptorShortCircuit(t *testing.T) {
+	client := NewClient()
+	client.Use(func(next Responder) Responder {
+		return func(req *Request) (*Response, errors.Error) {
+			return &Response{StatusCode: 418, Body: ioutil.NopCloser(strings.NewReader(""))}, nil
+		}
+	})
+	client.RequestResponder = func(req *Request) (*Response, errors.Error) {
+		t.Error("Request should not reach the responder")
+		return nil, nil
+	}
+
+	response, err := client.Do(MethodGet, "http://localhost/", nil)
+	errors.AssertNil(t, err)
+	assert.Equal(t, 418, response.StatusCode)
 }
@@ -0,0 +1,251 @@
+package http
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/sbreitf1/errors"
+)
+
+var (
+	// ErrInvalidClientConfig is returned by NewClientFromConfig for invalid configuration values.
+	ErrInvalidClientConfig = errors.New("Invalid client configuration")
+)
+
+// Duration is a time.Duration that is written to and read from config files in the notation of time.ParseDuration, e.g. "1m30s".
+type Duration time.Duration
+
+// MarshalText returns the duration formatted like "1m30s".
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// UnmarshalText parses a duration like "1m30s".
+func (d *Duration) UnmarshalText(text []byte) error {
+	duration, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(duration)
+	return nil
+}
+
+// UnmarshalJSON parses a duration string or a number of seconds.
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		var text string
+		if err := json.Unmarshal(data, &text); err != nil {
+			return err
+		}
+		return d.UnmarshalText([]byte(text))
+	}
+	var seconds float64
+	if err := json.Unmarshal(data, &seconds); err != nil {
+		return err
+	}
+	*d = Duration(seconds * float64(time.Second))
+	return nil
+}
+
+// ClientConfig describes a client in application config files, see NewClientFromConfig.
+type ClientConfig struct {
+	// BaseURL is prepended to all request URLs that are not absolute, see Client.BaseURL for SRV discovery.
+	BaseURL string `json:"baseUrl,omitempty" yaml:"baseUrl,omitempty"`
+	// SRVRefreshInterval denotes how long SRV records are used before they are looked up again.
+	SRVRefreshInterval Duration `json:"srvRefreshInterval,omitempty" yaml:"srvRefreshInterval,omitempty"`
+	// DefaultHeaders are sent with every request.
+	DefaultHeaders map[string]string `json:"defaultHeaders,omitempty" yaml:"defaultHeaders,omitempty"`
+	// Protocol selects the HTTP protocol versions: "auto" (default), "http1", "http2" or "h2c".
+	Protocol string `json:"protocol,omitempty" yaml:"protocol,omitempty"`
+
+	Timeout               Duration `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	DialTimeout           Duration `json:"dialTimeout,omitempty" yaml:"dialTimeout,omitempty"`
+	TLSHandshakeTimeout   Duration `json:"tlsHandshakeTimeout,omitempty" yaml:"tlsHandshakeTimeout,omitempty"`
+	ResponseHeaderTimeout Duration `json:"responseHeaderTimeout,omitempty" yaml:"responseHeaderTimeout,omitempty"`
+	IdleConnTimeout       Duration `json:"idleConnTimeout,omitempty" yaml:"idleConnTimeout,omitempty"`
+	MaxIdleConns          int      `json:"maxIdleConns,omitempty" yaml:"maxIdleConns,omitempty"`
+	MaxIdleConnsPerHost   int      `json:"maxIdleConnsPerHost,omitempty" yaml:"maxIdleConnsPerHost,omitempty"`
+	MaxConnsPerHost       int      `json:"maxConnsPerHost,omitempty" yaml:"maxConnsPerHost,omitempty"`
+
+	TLS   ClientTLSConfig   `json:"tls,omitempty" yaml:"tls,omitempty"`
+	Proxy ClientProxyConfig `json:"proxy,omitempty" yaml:"proxy,omitempty"`
+	// Retry enables automatic retries of failed requests. Requests are not retried when nil.
+	Retry *RetryConfig `json:"retry,omitempty" yaml:"retry,omitempty"`
+	// LoadBalancer distributes requests across replicated endpoints instead of using BaseURL. Disabled when nil.
+	LoadBalancer *LoadBalancerConfig `json:"loadBalancer,omitempty" yaml:"loadBalancer,omitempty"`
+
+	// MaxRedirects limits the number of redirects that are followed, see Client.MaxRedirects.
+	MaxRedirects        int  `json:"maxRedirects,omitempty" yaml:"maxRedirects,omitempty"`
+	StripAuthOnRedirect bool `json:"stripAuthOnRedirect,omitempty" yaml:"stripAuthOnRedirect,omitempty"`
+	DisableCompression  bool `json:"disableCompression,omitempty" yaml:"disableCompression,omitempty"`
+	CompressRequests    bool `json:"compressRequests,omitempty" yaml:"compressRequests,omitempty"`
+	PropagateTrace      bool `json:"propagateTrace,omitempty" yaml:"propagateTrace,omitempty"`
+	Metrics             bool `json:"metrics,omitempty" yaml:"metrics,omitempty"`
+}
+
+// ClientTLSConfig contains the TLS settings of a ClientConfig.
+type ClientTLSConfig struct {
+	// InsecureSkipVerify accepts invalid and self-signed certificates.
+	InsecureSkipVerify bool   `json:"insecureSkipVerify,omitempty" yaml:"insecureSkipVerify,omitempty"`
+	CertFile           string `json:"certFile,omitempty" yaml:"certFile,omitempty"`
+	KeyFile            string `json:"keyFile,omitempty" yaml:"keyFile,omitempty"`
+	RootCAFile         string `json:"rootCaFile,omitempty" yaml:"rootCaFile,omitempty"`
+}
+
+// ClientProxyConfig contains the proxy settings of a ClientConfig.
+type ClientProxyConfig struct {
+	// URL denotes an explicit proxy for all requests, credentials can be passed as user info.
+	URL string `json:"url,omitempty" yaml:"url,omitempty"`
+	// IgnoreEnvironment disables the proxy configuration from the environment variables HTTP_PROXY, HTTPS_PROXY and NO_PROXY.
+	IgnoreEnvironment bool `json:"ignoreEnvironment,omitempty" yaml:"ignoreEnvironment,omitempty"`
+}
+
+// RetryConfig contains the retry settings of a ClientConfig. Unset values are taken from DefaultRetryPolicy.
+type RetryConfig struct {
+	MaxAttempts        int      `json:"maxAttempts,omitempty" yaml:"maxAttempts,omitempty"`
+	InitialBackoff     Duration `json:"initialBackoff,omitempty" yaml:"initialBackoff,omitempty"`
+	MaxBackoff         Duration `json:"maxBackoff,omitempty" yaml:"maxBackoff,omitempty"`
+	Jitter             float64  `json:"jitter,omitempty" yaml:"jitter,omitempty"`
+	RetryNonIdempotent bool     `json:"retryNonIdempotent,omitempty" yaml:"retryNonIdempotent,omitempty"`
+}
+
+// LoadBalancerConfig contains the load balancing settings of a ClientConfig. Unset values are taken from NewLoadBalancer.
+type LoadBalancerConfig struct {
+	// Endpoints lists the base URLs of all replicas.
+	Endpoints []string `json:"endpoints" yaml:"endpoints"`
+	// Strategy selects the endpoint of each request: "roundRobin" (default) or "leastFailures".
+	Strategy          string   `json:"strategy,omitempty" yaml:"strategy,omitempty"`
+	FailureThreshold  int      `json:"failureThreshold,omitempty" yaml:"failureThreshold,omitempty"`
+	ExclusionDuration Duration `json:"exclusionDuration,omitempty" yaml:"exclusionDuration,omitempty"`
+	// HealthCheck enables background health checks of all endpoints, see LoadBalancer.StartHealthChecks. Disabled when nil.
+	HealthCheck *LoadBalancerHealthCheckConfig `json:"healthCheck,omitempty" yaml:"healthCheck,omitempty"`
+}
+
+// LoadBalancerHealthCheckConfig contains the health check settings of a LoadBalancerConfig. Unset values are taken from LoadBalancerHealthCheck.
+type LoadBalancerHealthCheckConfig struct {
+	Path     string   `json:"path,omitempty" yaml:"path,omitempty"`
+	Interval Duration `json:"interval,omitempty" yaml:"interval,omitempty"`
+	Timeout  Duration `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+}
+
+// NewClientFromConfig returns a new client configured according to config. The TLS configuration is loaded immediately, so invalid certificate files are reported here instead of on the first request. Health checks of a load balancer are started immediately and run until Client.Close is called.
+func NewClientFromConfig(config ClientConfig) (*Client, errors.Error) {
+	client := NewClient()
+
+	if len(config.BaseURL) > 0 {
+		if _, err := url.Parse(config.BaseURL); err != nil {
+			return nil, ErrInvalidClientConfig.Msg("Invalid base URL %q", config.BaseURL).Make().Cause(err)
+		}
+		client.BaseURL = config.BaseURL
+	}
+	for name, value := range config.DefaultHeaders {
+		client.DefaultHeader.Set(name, value)
+	}
+
+	switch strings.ToLower(config.Protocol) {
+	case "", "auto":
+		client.Protocol = ProtocolAuto
+	case "http1":
+		client.Protocol = ProtocolHTTP1
+	case "http2":
+		client.Protocol = ProtocolHTTP2
+	case "h2c":
+		client.Protocol = ProtocolH2C
+	default:
+		return nil, ErrInvalidClientConfig.Msg("Unknown protocol %q", config.Protocol).Make()
+	}
+
+	client.SRVRefreshInterval = time.Duration(config.SRVRefreshInterval)
+	client.Timeout = time.Duration(config.Timeout)
+	client.DialTimeout = time.Duration(config.DialTimeout)
+	client.TLSHandshakeTimeout = time.Duration(config.TLSHandshakeTimeout)
+	client.ResponseHeaderTimeout = time.Duration(config.ResponseHeaderTimeout)
+	client.IdleConnTimeout = time.Duration(config.IdleConnTimeout)
+	client.MaxIdleConns = config.MaxIdleConns
+	client.MaxIdleConnsPerHost = config.MaxIdleConnsPerHost
+	client.MaxConnsPerHost = config.MaxConnsPerHost
+
+	client.DisableSSLCheck = config.TLS.InsecureSkipVerify
+	client.ClientCertFile = config.TLS.CertFile
+	client.ClientKeyFile = config.TLS.KeyFile
+	client.RootCAFile = config.TLS.RootCAFile
+
+	if len(config.Proxy.URL) > 0 {
+		if _, err := url.Parse(config.Proxy.URL); err != nil {
+			return nil, ErrInvalidClientConfig.Msg("Invalid proxy URL").Make().Cause(err)
+		}
+		client.ProxyURL = config.Proxy.URL
+	}
+	client.IgnoreEnvironmentProxy = config.Proxy.IgnoreEnvironment
+
+	if config.Retry != nil {
+		policy := DefaultRetryPolicy()
+		if config.Retry.MaxAttempts > 0 {
+			policy.MaxAttempts = config.Retry.MaxAttempts
+		}
+		if config.Retry.InitialBackoff > 0 {
+			policy.InitialBackoff = time.Duration(config.Retry.InitialBackoff)
+		}
+		if config.Retry.MaxBackoff > 0 {
+			policy.MaxBackoff = time.Duration(config.Retry.MaxBackoff)
+		}
+		if config.Retry.Jitter > 0 {
+			policy.Jitter = config.Retry.Jitter
+		}
+		policy.RetryNonIdempotent = config.Retry.RetryNonIdempotent
+		client.Retry = policy
+	}
+
+	if config.LoadBalancer != nil {
+		if len(config.LoadBalancer.Endpoints) == 0 {
+			return nil, ErrInvalidClientConfig.Msg("Load balancer requires at least one endpoint").Make()
+		}
+		for _, endpoint := range config.LoadBalancer.Endpoints {
+			if _, err := url.Parse(endpoint); err != nil {
+				return nil, ErrInvalidClientConfig.Msg("Invalid load balancer endpoint %q", endpoint).Make().Cause(err)
+			}
+		}
+		lb := NewLoadBalancer(config.LoadBalancer.Endpoints...)
+		switch strings.ToLower(config.LoadBalancer.Strategy) {
+		case "", "roundrobin":
+			lb.Strategy = RoundRobin
+		case "leastfailures":
+			lb.Strategy = LeastFailures
+		default:
+			return nil, ErrInvalidClientConfig.Msg("Unknown load balancing strategy %q", config.LoadBalancer.Strategy).Make()
+		}
+		if config.LoadBalancer.FailureThreshold > 0 {
+			lb.FailureThreshold = config.LoadBalancer.FailureThreshold
+		}
+		if config.LoadBalancer.ExclusionDuration > 0 {
+			lb.ExclusionDuration = time.Duration(config.LoadBalancer.ExclusionDuration)
+		}
+		client.LoadBalancer = lb
+	}
+
+	client.MaxRedirects = config.MaxRedirects
+	client.StripAuthOnRedirect = config.StripAuthOnRedirect
+	client.DisableCompression = config.DisableCompression
+	client.CompressRequests = config.CompressRequests
+	client.PropagateTrace = config.PropagateTrace
+	client.Metrics = config.Metrics
+
+	httpClient, err := client.getHTTPClient("")
+	if err != nil {
+		return nil, err
+	}
+	if config.LoadBalancer != nil && config.LoadBalancer.HealthCheck != nil {
+		// checks share the transport to apply the TLS and proxy configuration, but bypass headers, interceptors and load balancing of the client
+		checkClient := NewClient()
+		checkClient.Transport = httpClient.Transport
+		client.LoadBalancer.StartHealthChecks(&LoadBalancerHealthCheck{
+			Path:     config.LoadBalancer.HealthCheck.Path,
+			Interval: time.Duration(config.LoadBalancer.HealthCheck.Interval),
+			Timeout:  time.Duration(config.LoadBalancer.HealthCheck.Timeout),
+			Client:   checkClient,
+		})
+	}
+	return client, nil
+}
@@ -0,0 +1,114 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sbreitf1/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewClientFromConfig(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.Path + " " + r.Header.Get("X-Api-Version")))
+	}))
+	defer server.Close()
+
+	var config ClientConfig
+	assert.NoError(t, json.Unmarshal([]byte(`{
+		"baseUrl": "`+server.URL+`",
+		"defaultHeaders": {"X-Api-Version": "2"},
+		"protocol": "http1",
+		"timeout": "30s",
+		"dialTimeout": 2.5,
+		"proxy": {"ignoreEnvironment": true},
+		"retry": {"maxAttempts": 5, "initialBackoff": "10ms"}
+	}`), &config))
+
+	client, err := NewClientFromConfig(config)
+	errors.AssertNil(t, err)
+	assert.Equal(t, 30*time.Second, client.Timeout)
+	assert.Equal(t, 2500*time.Millisecond, client.DialTimeout)
+	assert.Equal(t, ProtocolHTTP1, client.Protocol)
+	assert.True(t, client.IgnoreEnvironmentProxy)
+	assert.Equal(t, 5, client.Retry.MaxAttempts)
+	assert.Equal(t, 10*time.Millisecond, client.Retry.InitialBackoff)
+	assert.Equal(t, DefaultRetryPolicy().MaxBackoff, client.Retry.MaxBackoff)
+
+	response, err := client.Get("/items", nil)
+	errors.AssertNil(t, err)
+	body, err := ReadResponseString(response)
+	errors.AssertNil(t, err)
+	assert.Equal(t, "/items 2", body)
+}
+
+func TestNewClientFromConfigHealthChecks(t *testing.T) {
+	var checks, checksWithHeader int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/healthz" {
+			atomic.AddInt32(&checks, 1)
+			if len(r.Header.Get("X-Api-Version")) > 0 {
+				atomic.AddInt32(&checksWithHeader, 1)
+			}
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClientFromConfig(ClientConfig{
+		DefaultHeaders: map[string]string{"X-Api-Version": "2"},
+		LoadBalancer:   &LoadBalancerConfig{Endpoints: []string{server.URL}, HealthCheck: &LoadBalancerHealthCheckConfig{Interval: Duration(10 * time.Millisecond)}},
+	})
+	errors.AssertNil(t, err)
+	var intercepted int32
+	client.Use(func(next Responder) Responder {
+		return func(req *Request) (*Response, errors.Error) {
+			atomic.AddInt32(&intercepted, 1)
+			return next(req)
+		}
+	})
+	time.Sleep(50 * time.Millisecond)
+
+	// checks are sent by a bare client
+	assert.True(t, atomic.LoadInt32(&checks) >= 2)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&checksWithHeader))
+	assert.Equal(t, int32(0), atomic.LoadInt32(&intercepted))
+
+	client.Close()
+	// a check in progress may still complete
+	time.Sleep(10 * time.Millisecond)
+	stopped := atomic.LoadInt32(&checks)
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, stopped, atomic.LoadInt32(&checks))
+}
+
+func TestNewClientFromConfigInvalid(t *testing.T) {
+	_, err := NewClientFromConfig(ClientConfig{Protocol: "spdy"})
+	errors.Assert(t, ErrInvalidClientConfig, err)
+
+	_, err = NewClientFromConfig(ClientConfig{Proxy: ClientProxyConfig{URL: "http://proxy:port"}})
+	errors.Assert(t, ErrInvalidClientConfig, err)
+
+	_, err = NewClientFromConfig(ClientConfig{TLS: ClientTLSConfig{RootCAFile: "does-not-exist.pem"}})
+	errors.Assert(t, ErrInvalidTLSConfig, err)
+
+	_, err = NewClientFromConfig(ClientConfig{LoadBalancer: &LoadBalancerConfig{}})
+	errors.Assert(t, ErrInvalidClientConfig, err)
+
+	_, err = NewClientFromConfig(ClientConfig{LoadBalancer: &LoadBalancerConfig{Endpoints: []string{"http://a"}, Strategy: "random"}})
+	errors.Assert(t, ErrInvalidClientConfig, err)
+
+	var config ClientConfig
+	assert.Error(t, json.Unmarshal([]byte(`{"timeout": "soon"}`), &config))
+}
+
+func TestDurationMarshal(t *testing.T) {
+	data, err := json.Marshal(struct {
+		Timeout Duration `json:"timeout"`
+	}{Duration(90 * time.Second)})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"timeout":"1m30s"}`, string(data))
+}
@@ -0,0 +1,85 @@
+package http
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Principal denotes the authenticated caller of a request.
+type Principal struct {
+	ID    string
+	Roles []string
+	// Claims optionally contains further attributes of the principal, e.g. the claims of a verified token.
+	Claims map[string]interface{}
+}
+
+// HasRole reports whether the principal has been granted the given role. It returns false for a nil principal, so the result of PrincipalFromContext can be checked directly.
+func (p *Principal) HasRole(role string) bool {
+	if p == nil {
+		return false
+	}
+	for _, r := range p.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+type principalContextKey struct{}
+type tenantContextKey struct{}
+type localeContextKey struct{}
+
+// ContextWithPrincipal returns a copy of ctx carrying the given principal.
+func ContextWithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalContextKey{}, principal)
+}
+
+// PrincipalFromContext returns the principal stored in ctx or nil for anonymous requests.
+func PrincipalFromContext(ctx context.Context) *Principal {
+	principal, _ := ctx.Value(principalContextKey{}).(*Principal)
+	return principal
+}
+
+// ContextWithTenant returns a copy of ctx carrying the given tenant ID.
+func ContextWithTenant(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, tenantContextKey{}, tenantID)
+}
+
+// TenantFromContext returns the tenant ID stored in ctx or an empty string.
+func TenantFromContext(ctx context.Context) string {
+	tenantID, _ := ctx.Value(tenantContextKey{}).(string)
+	return tenantID
+}
+
+// ContextWithLocale returns a copy of ctx carrying the given locale as BCP 47 language tag, e.g. "de-DE".
+func ContextWithLocale(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, localeContextKey{}, locale)
+}
+
+// LocaleFromContext returns the locale stored in ctx or an empty string.
+func LocaleFromContext(ctx context.Context) string {
+	locale, _ := ctx.Value(localeContextKey{}).(string)
+	return locale
+}
+
+// SetPrincipal stores the authenticated principal in the request context, so subsequent handlers and adapted DTO handlers can obtain it using PrincipalFromContext.
+func SetPrincipal(c *gin.Context, principal *Principal) {
+	c.Request = c.Request.WithContext(ContextWithPrincipal(c.Request.Context(), principal))
+}
+
+// SetTenant stores the tenant ID in the request context.
+func SetTenant(c *gin.Context, tenantID string) {
+	c.Request = c.Request.WithContext(ContextWithTenant(c.Request.Context(), tenantID))
+}
+
+// SetLocale stores the locale in the request context.
+func SetLocale(c *gin.Context, locale string) {
+	c.Request = c.Request.WithContext(ContextWithLocale(c.Request.Context(), locale))
+}
+
+// RequestID returns the ID of the request assigned by the server.
+func RequestID(c *gin.Context) string {
+	return RequestIDFromContext(c.Request.Context())
+}
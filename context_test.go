@@ -0,0 +1,57 @@
+package http
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sbreitf1/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestScopedValues(t *testing.T) {
+	ctx := context.Background()
+	assert.Nil(t, PrincipalFromContext(ctx))
+	assert.Empty(t, TenantFromContext(ctx))
+	assert.Empty(t, LocaleFromContext(ctx))
+
+	server, err := NewServer(&ServerConfig{GinMode: gin.TestMode})
+	errors.AssertNil(t, err)
+	server.Engine().Use(func(c *gin.Context) {
+		SetPrincipal(c, &Principal{ID: "user-1", Roles: []string{"admin"}})
+		SetTenant(c, "acme")
+		SetLocale(c, "de-DE")
+	})
+
+	var principal *Principal
+	var tenant, locale, requestID string
+	errors.AssertNil(t, server.RegisterService("values", &routesService{func(e *gin.Engine) {
+		e.GET("/values", RequestHandler(func(ctx context.Context, _ struct{}) (string, errors.Error) {
+			principal, tenant, locale = PrincipalFromContext(ctx), TenantFromContext(ctx), LocaleFromContext(ctx)
+			requestID = RequestIDFromContext(ctx)
+			return "ok", nil
+		}))
+		e.GET("/gin", func(c *gin.Context) {
+			c.String(200, RequestID(c))
+		})
+	}}))
+
+	w := httptest.NewRecorder()
+	server.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/values", nil))
+	assert.Equal(t, 200, w.Code)
+	if assert.NotNil(t, principal) {
+		assert.Equal(t, "user-1", principal.ID)
+		assert.True(t, principal.HasRole("admin"))
+		assert.False(t, principal.HasRole("owner"))
+	}
+	assert.Equal(t, "acme", tenant)
+	assert.Equal(t, "de-DE", locale)
+	assert.Equal(t, w.Header().Get(HeaderRequestID), requestID)
+
+	w = httptest.NewRecorder()
+	server.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/gin", nil))
+	assert.Equal(t, w.Header().Get(HeaderRequestID), w.Body.String())
+
+	assert.False(t, PrincipalFromContext(context.Background()).HasRole("admin"))
+}
@@ -0,0 +1,116 @@
+package http
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sbreitf1/errors"
+)
+
+var (
+	// ErrCookieJar occurs when a persistent cookie jar could not be loaded or saved.
+	ErrCookieJar = errors.New("Cookie jar failed")
+)
+
+// NewCookieJar returns a new in-memory cookie jar that can be assigned to Client.Jar.
+func NewCookieJar() http.CookieJar {
+	jar, _ := cookiejar.New(nil)
+	return jar
+}
+
+// FileCookieJar is a cookie jar that persists cookies to a JSON file. Session cookies without expiration are not persisted.
+type FileCookieJar struct {
+	path string
+	jar  *cookiejar.Jar
+
+	mutex   sync.Mutex
+	entries map[string]storedCookie
+}
+
+type storedCookie struct {
+	URL    string       `json:"url"`
+	Cookie *http.Cookie `json:"cookie"`
+}
+
+// NewFileCookieJar returns a cookie jar backed by the given file. Existing cookies are loaded from the file if it exists.
+func NewFileCookieJar(path string) (*FileCookieJar, errors.Error) {
+	jar, _ := cookiejar.New(nil)
+	fileJar := &FileCookieJar{path: path, jar: jar, entries: make(map[string]storedCookie)}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return fileJar, nil
+	} else if err != nil {
+		return nil, ErrCookieJar.Make().Cause(err)
+	}
+
+	var entries []storedCookie
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, ErrCookieJar.Make().Cause(err)
+	}
+	for _, e := range entries {
+		u, err := url.Parse(e.URL)
+		if err != nil || e.Cookie == nil {
+			continue
+		}
+		fileJar.SetCookies(u, []*http.Cookie{e.Cookie})
+	}
+	return fileJar, nil
+}
+
+// SetCookies stores the cookies received from the given URL.
+func (j *FileCookieJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.jar.SetCookies(u, cookies)
+
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	for _, c := range cookies {
+		key := u.Host + "|" + c.Domain + "|" + c.Path + "|" + c.Name
+		if c.MaxAge < 0 || (!c.Expires.IsZero() && c.Expires.Before(time.Now())) {
+			delete(j.entries, key)
+			continue
+		}
+		if c.MaxAge > 0 {
+			// convert relative lifetime to absolute expiration for persistence
+			cookie := *c
+			cookie.Expires = time.Now().Add(time.Duration(c.MaxAge) * time.Second)
+			cookie.MaxAge = 0
+			c = &cookie
+		}
+		if !c.Expires.IsZero() {
+			j.entries[key] = storedCookie{u.String(), c}
+		}
+	}
+}
+
+// Cookies returns the cookies to send in a request for the given URL.
+func (j *FileCookieJar) Cookies(u *url.URL) []*http.Cookie {
+	return j.jar.Cookies(u)
+}
+
+// Save writes all persistent cookies that did not expire yet to the file.
+func (j *FileCookieJar) Save() errors.Error {
+	j.mutex.Lock()
+	entries := make([]storedCookie, 0, len(j.entries))
+	for _, e := range j.entries {
+		if e.Cookie.Expires.After(time.Now()) {
+			entries = append(entries, e)
+		}
+	}
+	j.mutex.Unlock()
+
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return ErrCookieJar.Make().Cause(err)
+	}
+	if err := ioutil.WriteFile(j.path, data, 0600); err != nil {
+		return ErrCookieJar.Make().Cause(err)
+	}
+	return nil
+}
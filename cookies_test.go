@@ -0,0 +1,58 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/sbreitf1/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCookieJar(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc", Path: "/", MaxAge: 3600})
+			http.SetCookie(w, &http.Cookie{Name: "temp", Value: "xyz", Path: "/"})
+			return
+		}
+		if c, err := r.Cookie("session"); err == nil {
+			w.Write([]byte(c.Value))
+		}
+	}))
+	defer ts.Close()
+
+	path := filepath.Join(t.TempDir(), "cookies.json")
+	jar, err := NewFileCookieJar(path)
+	errors.AssertNil(t, err)
+
+	client := NewClient()
+	client.Jar = jar
+	_, err = client.Do(MethodGet, ts.URL+"/login", nil)
+	errors.AssertNil(t, err)
+	response, err := client.Do(MethodGet, ts.URL+"/me", nil)
+	errors.AssertNil(t, err)
+	assertResponse(t, 200, "abc", response)
+	errors.AssertNil(t, jar.Save())
+
+	t.Run("Restore from file", func(t *testing.T) {
+		restored, err := NewFileCookieJar(path)
+		errors.AssertNil(t, err)
+		client := NewClient()
+		client.Jar = restored
+		response, err := client.Do(MethodGet, ts.URL+"/me", nil)
+		errors.AssertNil(t, err)
+		assertResponse(t, 200, "abc", response)
+
+		// session cookies are not persisted
+		u := response.Request.URL
+		assert.Len(t, restored.Cookies(u), 1)
+	})
+
+	t.Run("Without jar", func(t *testing.T) {
+		response, err := NewClient().Do(MethodGet, ts.URL+"/me", nil)
+		errors.AssertNil(t, err)
+		assertResponse(t, 200, "", response)
+	})
+}
@@ -0,0 +1,132 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sbreitf1/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	// ErrDependencyUnavailable is reported by the readiness of a DependencyProbe when a required dependency does not respond successfully.
+	ErrDependencyUnavailable = errors.New("Dependency unavailable")
+)
+
+// Dependency denotes a remote service that is required to serve traffic.
+type Dependency struct {
+	Name string `json:"name"`
+	// URL of the health endpoint of the dependency, e.g. "http://users:8080/healthz".
+	URL string `json:"url"`
+	// Optional dependencies are checked and logged, but do not affect readiness.
+	Optional bool `json:"optional,omitempty"`
+}
+
+// DependencyProbeConfig contains the parameters of a DependencyProbe.
+type DependencyProbeConfig struct {
+	Dependencies []Dependency `json:"dependencies"`
+	// Timeout limits each health check. Defaults to 2 seconds.
+	Timeout Duration `json:"timeout,omitempty"`
+	// CacheDuration reuses the last result for subsequent readiness checks to avoid amplifying probe traffic along dependency chains. Results are not cached when 0.
+	CacheDuration Duration `json:"cacheDuration,omitempty"`
+}
+
+// DependencyProbe is a service without routes that aggregates the health of remote dependencies into the readiness of this server. Register it using RegisterService.
+type DependencyProbe struct {
+	config DependencyProbeConfig
+	client *http.Client
+
+	mutex     sync.Mutex
+	lastCheck time.Time
+	lastErr   errors.Error
+}
+
+// NewDependencyProbe returns a probe for the configured dependencies.
+func NewDependencyProbe(config *DependencyProbeConfig) (*DependencyProbe, errors.Error) {
+	for _, d := range config.Dependencies {
+		if !strings.HasPrefix(d.URL, "http://") && !strings.HasPrefix(d.URL, "https://") {
+			return nil, ErrInvalidConfig.Msg("Dependency %q requires an absolute URL", d.Name).Make()
+		}
+	}
+
+	p := &DependencyProbe{config: *config}
+	if p.config.Timeout <= 0 {
+		p.config.Timeout = Duration(2 * time.Second)
+	}
+	p.client = &http.Client{Timeout: time.Duration(p.config.Timeout)}
+	return p, nil
+}
+
+// RegisterRoutes does nothing.
+func (p *DependencyProbe) RegisterRoutes(e *gin.Engine) {}
+
+// BeginServing does nothing.
+func (p *DependencyProbe) BeginServing() {}
+
+// StopServing does nothing.
+func (p *DependencyProbe) StopServing() {}
+
+// Healthy always returns nil as unavailable dependencies do not require a restart of this service.
+func (p *DependencyProbe) Healthy() errors.Error {
+	return nil
+}
+
+// Ready checks all dependencies concurrently and returns an error listing the required dependencies that failed.
+func (p *DependencyProbe) Ready() errors.Error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.config.CacheDuration > 0 && time.Since(p.lastCheck) < time.Duration(p.config.CacheDuration) {
+		return p.lastErr
+	}
+	p.lastErr = p.check()
+	p.lastCheck = time.Now()
+	return p.lastErr
+}
+
+func (p *DependencyProbe) check() errors.Error {
+	results := make([]error, len(p.config.Dependencies))
+	var wg sync.WaitGroup
+	for i := range p.config.Dependencies {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = p.checkDependency(p.config.Dependencies[i].URL)
+		}(i)
+	}
+	wg.Wait()
+
+	var failed []string
+	for i, err := range results {
+		if err == nil {
+			continue
+		}
+		d := p.config.Dependencies[i]
+		if d.Optional {
+			log.WithFields(log.Fields{"component": "dependencies", "dependency": d.Name}).Warnf("Optional dependency unavailable: %s", err)
+			continue
+		}
+		failed = append(failed, fmt.Sprintf("%s (%s)", d.Name, err))
+	}
+	if len(failed) > 0 {
+		sort.Strings(failed)
+		return ErrDependencyUnavailable.Msg("Dependencies unavailable: %s", strings.Join(failed, ", ")).Make()
+	}
+	return nil
+}
+
+func (p *DependencyProbe) checkDependency(url string) error {
+	resp, err := p.client.Get(url)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sbreitf1/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDependencyProbe(t *testing.T) {
+	var usersStatus, searchStatus int32 = 200, 200
+	newDependency := func(status *int32, delay time.Duration) *httptest.Server {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(delay)
+			w.WriteHeader(int(atomic.LoadInt32(status)))
+		}))
+		t.Cleanup(ts.Close)
+		return ts
+	}
+	users := newDependency(&usersStatus, 50*time.Millisecond)
+	search := newDependency(&searchStatus, 50*time.Millisecond)
+
+	probe, err := NewDependencyProbe(&DependencyProbeConfig{
+		Dependencies: []Dependency{
+			{Name: "users", URL: users.URL + "/healthz"},
+			{Name: "search", URL: search.URL + "/healthz", Optional: true},
+		},
+		Timeout: Duration(time.Second),
+	})
+	errors.AssertNil(t, err)
+	server, err := NewServer(&ServerConfig{GinMode: gin.TestMode})
+	errors.AssertNil(t, err)
+	errors.AssertNil(t, server.RegisterService("dependencies", probe))
+
+	readiness := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		server.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/readiness", nil))
+		return w
+	}
+
+	start := time.Now()
+	assert.Equal(t, 200, readiness().Code)
+	assert.True(t, time.Since(start) < 90*time.Millisecond, "dependencies are not checked concurrently")
+
+	atomic.StoreInt32(&searchStatus, 500)
+	assert.Equal(t, 200, readiness().Code)
+
+	atomic.StoreInt32(&usersStatus, 503)
+	w := readiness()
+	assert.Equal(t, 503, w.Code)
+	assert.Contains(t, w.Body.String(), "users (status 503)")
+	assert.NotContains(t, w.Body.String(), "search")
+
+	w = httptest.NewRecorder()
+	server.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/healthz", nil))
+	assert.Equal(t, 200, w.Code)
+}
+
+func TestDependencyProbeTimeoutAndCache(t *testing.T) {
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		time.Sleep(100 * time.Millisecond)
+	}))
+	defer ts.Close()
+
+	probe, err := NewDependencyProbe(&DependencyProbeConfig{
+		Dependencies:  []Dependency{{Name: "slow", URL: ts.URL}},
+		Timeout:       Duration(20 * time.Millisecond),
+		CacheDuration: Duration(time.Hour),
+	})
+	errors.AssertNil(t, err)
+	errors.Assert(t, ErrDependencyUnavailable, probe.Ready())
+	errors.Assert(t, ErrDependencyUnavailable, probe.Ready())
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+}
+
+func TestDependencyProbeInvalidConfig(t *testing.T) {
+	_, err := NewDependencyProbe(&DependencyProbeConfig{Dependencies: []Dependency{{Name: "users", URL: "users:8080/healthz"}}})
+	errors.Assert(t, ErrInvalidConfig, err)
+}
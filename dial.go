@@ -0,0 +1,67 @@
+package http
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/sbreitf1/errors"
+)
+
+type unixSocketContextKey struct{}
+
+// dialContext establishes connections for the transport: Unix socket URLs and UnixSocket take precedence over the resolver and DialContext or the default dialer.
+func (client *Client) dialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	if socket, ok := ctx.Value(unixSocketContextKey{}).(string); ok {
+		return client.dialer().DialContext(ctx, "unix", socket)
+	}
+	if len(client.UnixSocket) > 0 {
+		return client.dialer().DialContext(ctx, "unix", client.UnixSocket)
+	}
+	if client.Resolver != nil {
+		return client.dialResolved(ctx, network, addr)
+	}
+	return client.dial(ctx, network, addr)
+}
+
+func (client *Client) dial(ctx context.Context, network, addr string) (net.Conn, error) {
+	if client.DialContext != nil {
+		return client.DialContext(ctx, network, addr)
+	}
+	return client.dialer().DialContext(ctx, network, addr)
+}
+
+func (client *Client) dialer() *net.Dialer {
+	timeout := client.DialTimeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	return &net.Dialer{Timeout: timeout, KeepAlive: 30 * time.Second}
+}
+
+// resolveUnixURL rewrites requests with URLs like unix:///var/run/docker.sock:/containers/json to HTTP requests that are sent through the Unix socket before the colon.
+func resolveUnixURL(req *Request) errors.Error {
+	socket, path := req.URL.Path, "/"
+	if i := strings.Index(req.URL.Path, ":"); i >= 0 {
+		socket, path = req.URL.Path[:i], req.URL.Path[i+1:]
+	}
+	if len(socket) == 0 {
+		return ErrInvalidRequest.Msg("Missing socket path in URL %q", req.URL.String()).Make()
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+
+	// a distinct host per socket keeps pooled connections of different sockets apart
+	sum := sha256.Sum256([]byte(socket))
+	req.URL.Scheme = "http"
+	req.URL.Host = "unix-" + hex.EncodeToString(sum[:8]) + ".localhost"
+	req.URL.Path = path
+	req.URL.RawPath = ""
+	req.Host = "localhost"
+	*req = *req.WithContext(context.WithValue(req.Context(), unixSocketContextKey{}, socket))
+	return nil
+}
@@ -0,0 +1,60 @@
+package http
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/sbreitf1/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnixSocket(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "test.sock")
+	listener, err := net.Listen("unix", socket)
+	if !assert.NoError(t, err) {
+		return
+	}
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Host + " " + r.URL.RequestURI()))
+	})}
+	go server.Serve(listener)
+	defer server.Close()
+
+	t.Run("URL", func(t *testing.T) {
+		response, err := NewClient().Get("unix://"+socket+":/containers/json?all=1", nil)
+		errors.AssertNil(t, err)
+		assertResponse(t, 200, "localhost /containers/json?all=1", response)
+	})
+
+	t.Run("BaseURL", func(t *testing.T) {
+		client := NewClient()
+		client.BaseURL = "unix://" + socket + ":"
+		response, err := client.Get("/version", nil)
+		errors.AssertNil(t, err)
+		assertResponse(t, 200, "localhost /version", response)
+	})
+
+	t.Run("Client", func(t *testing.T) {
+		client := NewClient()
+		client.UnixSocket = socket
+		response, err := client.Get("http://docker/info", nil)
+		errors.AssertNil(t, err)
+		assertResponse(t, 200, "docker /info", response)
+	})
+
+	t.Run("DialContext", func(t *testing.T) {
+		client := NewClient()
+		var dialed string
+		client.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			dialed = addr
+			return net.Dial("unix", socket)
+		}
+		response, err := client.Get("http://example.test/", nil)
+		errors.AssertNil(t, err)
+		assertResponse(t, 200, "example.test /", response)
+		assert.Equal(t, "example.test:80", dialed)
+	})
+}
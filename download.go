@@ -0,0 +1,311 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/sbreitf1/errors"
+)
+
+var (
+	// ErrDownloadFailed is returned when a download could not be written to its destination.
+	ErrDownloadFailed = errors.New("Download failed")
+	// ErrChecksumMismatch is returned when downloaded content does not match the expected checksum.
+	ErrChecksumMismatch = errors.New("Checksum mismatch")
+)
+
+// ProgressFunc is called with the number of transferred bytes and the total size, which is -1 if unknown.
+type ProgressFunc func(transferred, total int64)
+
+// DownloadOptions configures a download.
+type DownloadOptions struct {
+	// Progress is called after every chunk written to the destination.
+	Progress ProgressFunc
+	// SHA256 denotes the expected hex encoded SHA-256 digest of the content. The download fails with ErrChecksumMismatch if it differs.
+	SHA256 string
+	// MD5 denotes the expected hex encoded MD5 digest of the content. The download fails with ErrChecksumMismatch if it differs.
+	MD5 string
+	// VerifyDigestHeader verifies the content against the SHA-256 or MD5 digest announced by the server in a Digest header (RFC 3230) or, if missing, in an ETag consisting of a hex encoded digest as used by many object stores. Downloads without such header are not verified.
+	VerifyDigestHeader bool
+	// Resume keeps partial downloads of DownloadFile and continues them with a range request on the next call. Only downloads with a strong ETag can be resumed, the ETag is verified using If-Range.
+	Resume bool
+	// Prepare can be used to modify the request directly before sending.
+	Prepare func(*Request) errors.Error
+}
+
+// Download requests the given url and writes the response body to w.
+func (client *Client) Download(url string, w io.Writer, options *DownloadOptions) errors.Error {
+	return client.DownloadContext(context.Background(), url, w, options)
+}
+
+// DownloadContext requests the given url and writes the response body to w. The download is aborted when ctx is done.
+func (client *Client) DownloadContext(ctx context.Context, url string, w io.Writer, options *DownloadOptions) errors.Error {
+	if options == nil {
+		options = &DownloadOptions{}
+	}
+
+	response, err := client.do(ctx, MethodGet, url, nil, options.Prepare)
+	if err != nil {
+		return err
+	}
+	defer DrainAndClose(response)
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return unexpectedStatus(response)
+	}
+	checksums, err := options.checksums(response)
+	if err != nil {
+		return err
+	}
+	return options.copy(w, response.Body, 0, response.ContentLength, checksums)
+}
+
+// DownloadFile requests the given url and writes the response body to the file at path. The content is written to a temporary file next to path that replaces the destination only after a successful download.
+func (client *Client) DownloadFile(url, path string, options *DownloadOptions) errors.Error {
+	return client.DownloadFileContext(context.Background(), url, path, options)
+}
+
+// DownloadFileContext requests the given url and writes the response body to the file at path. The download is aborted when ctx is done.
+func (client *Client) DownloadFileContext(ctx context.Context, url, path string, options *DownloadOptions) errors.Error {
+	if options == nil {
+		options = &DownloadOptions{}
+	}
+	partPath := path + ".part"
+	etagPath := partPath + ".etag"
+
+	offset, etag := int64(0), ""
+	if options.Resume {
+		offset, etag = resumeState(partPath, etagPath)
+	}
+
+	response, err := client.do(ctx, MethodGet, url, nil, func(req *Request) errors.Error {
+		if offset > 0 {
+			req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+			req.Header.Set("If-Range", etag)
+		}
+		if options.Prepare != nil {
+			return options.Prepare(req)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	defer DrainAndClose(response)
+
+	resumed := false
+	switch {
+	case offset > 0 && response.StatusCode == http.StatusPartialContent:
+		if response.Header.Get("ETag") != etag || !contentRangeStartsAt(response, offset) {
+			removeDownload(partPath, etagPath)
+			return ErrDownloadFailed.Msg("Resumed content of %q does not match the partial download", url).Make()
+		}
+		resumed = true
+	case offset > 0 && response.StatusCode == http.StatusRequestedRangeNotSatisfiable:
+		removeDownload(partPath, etagPath)
+		return unexpectedStatus(response)
+	case response.StatusCode < 200 || response.StatusCode > 299:
+		return unexpectedStatus(response)
+	}
+
+	checksums, err := options.checksums(response)
+	if err != nil {
+		return err
+	}
+
+	var file *os.File
+	var fileErr error
+	if resumed {
+		file, fileErr = os.OpenFile(partPath, os.O_RDWR|os.O_APPEND, 0)
+		if fileErr == nil && len(checksums) > 0 {
+			_, fileErr = io.Copy(checksumWriter(checksums), io.NewSectionReader(file, 0, offset))
+		}
+	} else {
+		offset = 0
+		file, fileErr = os.Create(partPath)
+		if fileErr == nil {
+			os.Remove(etagPath)
+			if newETag := response.Header.Get("ETag"); options.Resume && len(newETag) > 0 && !strings.HasPrefix(newETag, "W/") {
+				fileErr = ioutil.WriteFile(etagPath, []byte(newETag), 0644)
+			}
+		}
+	}
+	if fileErr != nil {
+		if file != nil {
+			file.Close()
+		}
+		return ErrDownloadFailed.Msg("Could not open file %q", partPath).Make().Cause(fileErr)
+	}
+
+	downloadErr := options.copy(file, response.Body, offset, response.ContentLength, checksums)
+	if err := file.Close(); err != nil && downloadErr == nil {
+		downloadErr = ErrDownloadFailed.Msg("Could not write file %q", partPath).Make().Cause(err)
+	}
+	if downloadErr != nil {
+		// keep the partial download for the next attempt unless the content is corrupt
+		if !options.Resume || downloadErr.Is(ErrChecksumMismatch) {
+			removeDownload(partPath, etagPath)
+		}
+		return downloadErr
+	}
+
+	os.Remove(etagPath)
+	if err := os.Rename(partPath, path); err != nil {
+		os.Remove(partPath)
+		return ErrDownloadFailed.Msg("Could not move download to %q", path).Make().Cause(err)
+	}
+	return nil
+}
+
+// resumeState returns the size and ETag of a partial download. Downloads without strong ETag cannot be resumed safely and are restarted.
+func resumeState(partPath, etagPath string) (int64, string) {
+	info, err := os.Stat(partPath)
+	if err != nil || info.Size() == 0 {
+		return 0, ""
+	}
+	etag, err := ioutil.ReadFile(etagPath)
+	if err != nil || len(etag) == 0 {
+		return 0, ""
+	}
+	return info.Size(), string(etag)
+}
+
+func contentRangeStartsAt(response *Response, offset int64) bool {
+	var start, end int64
+	_, err := fmt.Sscanf(response.Header.Get("Content-Range"), "bytes %d-%d/", &start, &end)
+	return err == nil && start == offset
+}
+
+func removeDownload(partPath, etagPath string) {
+	os.Remove(partPath)
+	os.Remove(etagPath)
+}
+
+// checksum denotes an expected digest of the downloaded content.
+type checksum struct {
+	algorithm string
+	expected  []byte
+	hash      hash.Hash
+}
+
+// checksums returns the digests to verify for the given response.
+func (options *DownloadOptions) checksums(response *Response) ([]*checksum, errors.Error) {
+	var checksums []*checksum
+	add := func(algorithm, expected string, decode func(string) ([]byte, error)) errors.Error {
+		value, err := decode(strings.TrimSpace(expected))
+		if err != nil {
+			return ErrChecksumMismatch.Msg("Invalid %s checksum %q", algorithm, expected).Make().Cause(err)
+		}
+		newHash := md5.New
+		if algorithm == "SHA-256" {
+			newHash = sha256.New
+		}
+		checksums = append(checksums, &checksum{algorithm: algorithm, expected: value, hash: newHash()})
+		return nil
+	}
+
+	if len(options.SHA256) > 0 {
+		if err := add("SHA-256", options.SHA256, hex.DecodeString); err != nil {
+			return nil, err
+		}
+	}
+	if len(options.MD5) > 0 {
+		if err := add("MD5", options.MD5, hex.DecodeString); err != nil {
+			return nil, err
+		}
+	}
+
+	if options.VerifyDigestHeader {
+		found := false
+		for _, value := range response.Header.Values("Digest") {
+			for _, instance := range strings.Split(value, ",") {
+				kv := strings.SplitN(strings.TrimSpace(instance), "=", 2)
+				if len(kv) != 2 {
+					continue
+				}
+				switch strings.ToLower(kv[0]) {
+				case "sha-256":
+					if err := add("SHA-256", kv[1], base64.StdEncoding.DecodeString); err != nil {
+						return nil, err
+					}
+					found = true
+				case "md5":
+					if err := add("MD5", kv[1], base64.StdEncoding.DecodeString); err != nil {
+						return nil, err
+					}
+					found = true
+				}
+			}
+		}
+
+		etag := strings.Trim(response.Header.Get("ETag"), `"`)
+		if _, err := hex.DecodeString(etag); !found && err == nil {
+			switch len(etag) {
+			case 32:
+				add("MD5", etag, hex.DecodeString)
+			case 64:
+				add("SHA-256", etag, hex.DecodeString)
+			}
+		}
+	}
+	return checksums, nil
+}
+
+// checksumWriter returns a writer that updates the hash state of all checksums.
+func checksumWriter(checksums []*checksum) io.Writer {
+	writers := make([]io.Writer, len(checksums))
+	for i, c := range checksums {
+		writers[i] = c.hash
+	}
+	return io.MultiWriter(writers...)
+}
+
+// copy transfers src to dst while reporting progress and verifying the checksums. Offset denotes the number of bytes already transferred, which must already be contained in the hash state of the checksums.
+func (options *DownloadOptions) copy(dst io.Writer, src io.Reader, offset, length int64, checksums []*checksum) errors.Error {
+	total := int64(-1)
+	if length >= 0 {
+		total = offset + length
+	}
+	if len(checksums) > 0 {
+		dst = io.MultiWriter(dst, checksumWriter(checksums))
+	}
+
+	transferred := offset
+	buffer := make([]byte, 32*1024)
+	for {
+		n, readErr := src.Read(buffer)
+		if n > 0 {
+			if _, err := dst.Write(buffer[:n]); err != nil {
+				return ErrDownloadFailed.Msg("Could not write download").Make().Cause(err)
+			}
+			transferred += int64(n)
+			if options.Progress != nil {
+				options.Progress(transferred, total)
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return transportError(readErr)
+		}
+	}
+
+	for _, c := range checksums {
+		if actual := c.hash.Sum(nil); !bytes.Equal(actual, c.expected) {
+			return ErrChecksumMismatch.Msg("Expected %s %s but got %s", c.algorithm, hex.EncodeToString(c.expected), hex.EncodeToString(actual)).Make()
+		}
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/sbreitf1/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDownload(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789"), 10000)
+	sum := sha256.Sum256(content)
+	checksum := hex.EncodeToString(sum[:])
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(404)
+			return
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+		w.Write(content)
+	}))
+	defer server.Close()
+	client := NewClient()
+
+	t.Run("Writer", func(t *testing.T) {
+		var buffer bytes.Buffer
+		var lastTransferred, lastTotal int64
+		err := client.Download(server.URL, &buffer, &DownloadOptions{SHA256: checksum, Progress: func(transferred, total int64) {
+			lastTransferred, lastTotal = transferred, total
+		}})
+		errors.AssertNil(t, err)
+		assert.Equal(t, content, buffer.Bytes())
+		assert.Equal(t, int64(len(content)), lastTransferred)
+		assert.Equal(t, int64(len(content)), lastTotal)
+	})
+
+	t.Run("File", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "artifact.bin")
+		errors.AssertNil(t, client.DownloadFile(server.URL, path, nil))
+		data, _ := ioutil.ReadFile(path)
+		assert.Equal(t, content, data)
+		_, err := os.Stat(path + ".part")
+		assert.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("ChecksumMismatch", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "artifact.bin")
+		err := client.DownloadFile(server.URL, path, &DownloadOptions{SHA256: "00"})
+		errors.Assert(t, ErrChecksumMismatch, err)
+		_, statErr := os.Stat(path)
+		assert.True(t, os.IsNotExist(statErr))
+	})
+
+	t.Run("UnexpectedStatus", func(t *testing.T) {
+		var buffer bytes.Buffer
+		errors.Assert(t, ErrUnexpectedStatus, client.Download(server.URL+"/missing", &buffer, nil))
+	})
+
+	t.Run("Canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		var buffer bytes.Buffer
+		errors.Assert(t, ErrRequestFailed, client.DownloadContext(ctx, server.URL, &buffer, nil))
+	})
+}
+
+func TestResumeDownload(t *testing.T) {
+	content := bytes.Repeat([]byte("abcdefghij"), 1000)
+	sum := sha256.Sum256(content)
+	etag := `"v1"`
+	var ranges []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		w.Header().Set("ETag", etag)
+		if r.URL.Query().Get("abort") == "1" {
+			// announce the full length but only send half of the content
+			w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+			w.Write(content[:len(content)/2])
+			return
+		}
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(content))
+	}))
+	defer server.Close()
+	client := NewClient()
+	options := &DownloadOptions{Resume: true, SHA256: hex.EncodeToString(sum[:])}
+
+	t.Run("Resume", func(t *testing.T) {
+		ranges = nil
+		path := filepath.Join(t.TempDir(), "artifact.bin")
+		errors.Assert(t, ErrRequestFailed, client.DownloadFile(server.URL+"?abort=1", path, options))
+		info, err := os.Stat(path + ".part")
+		if assert.NoError(t, err) {
+			assert.Equal(t, int64(len(content)/2), info.Size())
+		}
+
+		errors.AssertNil(t, client.DownloadFile(server.URL, path, options))
+		data, _ := ioutil.ReadFile(path)
+		assert.Equal(t, content, data)
+		assert.Equal(t, []string{"", fmt.Sprintf("bytes=%d-", len(content)/2)}, ranges)
+		_, err = os.Stat(path + ".part.etag")
+		assert.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("Changed", func(t *testing.T) {
+		ranges = nil
+		path := filepath.Join(t.TempDir(), "artifact.bin")
+		errors.Assert(t, ErrRequestFailed, client.DownloadFile(server.URL+"?abort=1", path, options))
+
+		// If-Range does not match anymore, so the full content is sent again
+		etag = `"v2"`
+		defer func() { etag = `"v1"` }()
+		errors.AssertNil(t, client.DownloadFile(server.URL, path, options))
+		data, _ := ioutil.ReadFile(path)
+		assert.Equal(t, content, data)
+		assert.Len(t, ranges, 2)
+	})
+}
+
+func TestDownloadDigestHeader(t *testing.T) {
+	content := []byte("artifact content")
+	sha := sha256.Sum256(content)
+	md := md5.Sum(content)
+	var digest, etag string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(digest) > 0 {
+			w.Header().Set("Digest", digest)
+		}
+		if len(etag) > 0 {
+			w.Header().Set("ETag", etag)
+		}
+		w.Write(content)
+	}))
+	defer server.Close()
+	client := NewClient()
+	options := &DownloadOptions{VerifyDigestHeader: true}
+
+	digest, etag = "SHA-256="+base64.StdEncoding.EncodeToString(sha[:]), ""
+	errors.AssertNil(t, client.Download(server.URL, ioutil.Discard, options))
+	digest = "md5=" + base64.StdEncoding.EncodeToString(sha[:16])
+	errors.Assert(t, ErrChecksumMismatch, client.Download(server.URL, ioutil.Discard, options))
+
+	digest, etag = "", `"`+hex.EncodeToString(md[:])+`"`
+	errors.AssertNil(t, client.Download(server.URL, ioutil.Discard, options))
+	etag = `"` + hex.EncodeToString(sha[:16]) + `"`
+	path := filepath.Join(t.TempDir(), "artifact.bin")
+	errors.Assert(t, ErrChecksumMismatch, client.DownloadFile(server.URL, path, options))
+	_, err := os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+
+	// opaque ETags are not verified
+	etag = `"v1"`
+	errors.AssertNil(t, client.Download(server.URL, ioutil.Discard, options))
+
+	errors.AssertNil(t, client.Download(server.URL, ioutil.Discard, &DownloadOptions{MD5: hex.EncodeToString(md[:])}))
+	errors.Assert(t, ErrChecksumMismatch, client.Download(server.URL, ioutil.Discard, &DownloadOptions{MD5: hex.EncodeToString(sha[:16])}))
+}
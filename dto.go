@@ -0,0 +1,143 @@
+package http
+
+import (
+	"context"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/sbreitf1/errors"
+)
+
+// RequestHandler adapts a handler receiving a request DTO to a gin handler that answers with the result serialized as JSON and 200 OK. In is decoded from the JSON body, fields tagged with `path:"name"` and `query:"name"` are set from path and query parameters afterwards. The complete DTO is validated using the `binding` tags of gin. Invalid requests are answered with ErrBadRequest, errors returned by handler by the error mapper of the server.
+func RequestHandler[In, Out any](handler func(context.Context, In) (Out, errors.Error)) gin.HandlerFunc {
+	return RequestHandlerWithStatus(200, handler)
+}
+
+// RequestHandlerWithStatus adapts a handler like RequestHandler, but answers successful requests with the given status code.
+func RequestHandlerWithStatus[In, Out any](status int, handler func(context.Context, In) (Out, errors.Error)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var in In
+		if err := BindRequest(c, &in); err != nil {
+			abortWithError(c, err)
+			return
+		}
+		result, err := handler(c.Request.Context(), in)
+		respondJSON(c, status, result, err)
+	}
+}
+
+// BindRequest decodes the JSON body, path and query parameters of the request into the struct pointed to by out and validates it like RequestHandler.
+func BindRequest(c *gin.Context, out interface{}) errors.Error {
+	if c.Request.Body != nil && c.Request.ContentLength != 0 {
+		if err := json.NewDecoder(c.Request.Body).Decode(out); err != nil && err != io.EOF {
+			return ErrBadRequest.Msg("Invalid request body: %s", err.Error()).Make()
+		}
+	}
+
+	v := reflect.ValueOf(out).Elem()
+	for i := 0; v.Kind() == reflect.Struct && i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if len(field.PkgPath) > 0 {
+			// unexported fields cannot be set
+			continue
+		}
+		if name, ok := field.Tag.Lookup("path"); ok {
+			if value, ok := c.Params.Get(name); ok {
+				if err := setField(v.Field(i), []string{value}); err != nil {
+					return ErrBadRequest.Msg("Invalid path parameter %q: %s", name, err.Error()).Make()
+				}
+			}
+		}
+		if name, ok := field.Tag.Lookup("query"); ok {
+			if values, ok := c.GetQueryArray(name); ok {
+				if err := setField(v.Field(i), values); err != nil {
+					return ErrBadRequest.Msg("Invalid query parameter %q: %s", name, err.Error()).Make()
+				}
+			}
+		}
+	}
+
+	if err := binding.Validator.ValidateStruct(out); err != nil {
+		return ErrBadRequest.Msg("Invalid request: %s", strings.Replace(err.Error(), "\n", "; ", -1)).Make()
+	}
+	return nil
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// setField assigns the parameter values to a field of basic kind, a pointer or slice of such kinds or a type implementing encoding.TextUnmarshaler.
+func setField(field reflect.Value, values []string) error {
+	if field.Kind() == reflect.Slice && field.Type().Elem().Kind() != reflect.Uint8 {
+		slice := reflect.MakeSlice(field.Type(), len(values), len(values))
+		for i, value := range values {
+			if err := setValue(slice.Index(i), value); err != nil {
+				return err
+			}
+		}
+		field.Set(slice)
+		return nil
+	}
+	return setValue(field, values[0])
+}
+
+func setValue(field reflect.Value, value string) error {
+	if field.Kind() == reflect.Ptr {
+		ptr := reflect.New(field.Type().Elem())
+		if err := setValue(ptr.Elem(), value); err != nil {
+			return err
+		}
+		field.Set(ptr)
+		return nil
+	}
+	if u, ok := field.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(value))
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if field.Type() == durationType {
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				return err
+			}
+			field.SetInt(int64(d))
+			return nil
+		}
+		n, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Type())
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package http
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sbreitf1/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type updateItemRequest struct {
+	ID      int           `path:"id" json:"-" binding:"min=1"`
+	Tags    []string      `query:"tag" json:"-"`
+	Limit   *uint         `query:"limit" json:"-"`
+	Timeout time.Duration `query:"timeout" json:"-"`
+	Name    string        `json:"name" binding:"required"`
+	Price   float64       `json:"price" binding:"gte=0"`
+}
+
+func TestRequestHandler(t *testing.T) {
+	var received updateItemRequest
+	engine := gin.New()
+	engine.Use(NewErrorMapper().Middleware())
+	engine.PUT("/items/:id", RequestHandler(func(ctx context.Context, req updateItemRequest) (gin.H, errors.Error) {
+		received = req
+		return gin.H{"id": req.ID, "name": req.Name}, nil
+	}))
+
+	serve := func(path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest("PUT", path, strings.NewReader(body)))
+		return w
+	}
+
+	w := serve("/items/7?tag=a&tag=b&limit=10&timeout=5s", `{"name":"chair","price":12.5}`)
+	assert.Equal(t, 200, w.Code)
+	assert.JSONEq(t, `{"id":7,"name":"chair"}`, w.Body.String())
+	assert.Equal(t, 7, received.ID)
+	assert.Equal(t, []string{"a", "b"}, received.Tags)
+	if assert.NotNil(t, received.Limit) {
+		assert.Equal(t, uint(10), *received.Limit)
+	}
+	assert.Equal(t, 5*time.Second, received.Timeout)
+	assert.Equal(t, 12.5, received.Price)
+
+	for _, test := range []struct {
+		path, body, message string
+	}{
+		{"/items/x", `{"name":"chair"}`, `Invalid path parameter \"id\"`},
+		{"/items/0", `{"name":"chair"}`, "Invalid request"},
+		{"/items/1?limit=-1", `{"name":"chair"}`, `Invalid query parameter \"limit\"`},
+		{"/items/1", `{"price":-1}`, "Invalid request"},
+		{"/items/1", `{"name":`, "Invalid request body"},
+		{"/items/1", ``, "Invalid request"},
+	} {
+		w := serve(test.path, test.body)
+		assert.Equal(t, 400, w.Code, test.path)
+		assert.Contains(t, w.Body.String(), test.message, test.path)
+	}
+}
@@ -0,0 +1,58 @@
+package http
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+const responseEnvelopeKey = "responseEnvelope"
+
+// Envelope is the consistent JSON shape of responses written by the typed handler adapters and the error mapper if the response envelope is enabled.
+type Envelope struct {
+	Data  interface{}  `json:"data"`
+	Error interface{}  `json:"error,omitempty"`
+	Meta  EnvelopeMeta `json:"meta"`
+}
+
+// EnvelopeMeta contains information about the request and response.
+type EnvelopeMeta struct {
+	RequestID  string          `json:"requestId,omitempty"`
+	Pagination *PaginationMeta `json:"pagination,omitempty"`
+}
+
+// PaginationMeta describes the page returned as data of an Envelope.
+type PaginationMeta struct {
+	Offset int `json:"offset"`
+	Limit  int `json:"limit"`
+	// Total is the number of all items or -1 if unknown.
+	Total int `json:"total"`
+	// Next is the URL of the following page or empty for the last page.
+	Next string `json:"next,omitempty"`
+}
+
+// Page is a result of the typed handler adapters containing a single page of items. With response envelope, the items are returned as data and the remaining fields as pagination meta.
+type Page struct {
+	Items interface{} `json:"items"`
+	PaginationMeta
+}
+
+// ResponseEnvelopeMiddleware enables the response envelope for subsequent typed handler adapters and the error mapper. The Server adds it to all routes if the response envelope is enabled in the configuration.
+func ResponseEnvelopeMiddleware(c *gin.Context) {
+	c.Set(responseEnvelopeKey, true)
+}
+
+// envelope wraps the result or error body for requests with enabled response envelope.
+func envelope(c *gin.Context, data, err interface{}) interface{} {
+	if !c.GetBool(responseEnvelopeKey) {
+		return data
+	}
+	e := Envelope{Error: err, Meta: EnvelopeMeta{RequestID: RequestID(c)}}
+	if err == nil {
+		e.Data = data
+		if page, ok := data.(Page); ok {
+			e.Data, e.Meta.Pagination = page.Items, &page.PaginationMeta
+		} else if page, ok := data.(*Page); ok && page != nil {
+			e.Data, e.Meta.Pagination = page.Items, &page.PaginationMeta
+		}
+	}
+	return e
+}
@@ -0,0 +1,69 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sbreitf1/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResponseEnvelope(t *testing.T) {
+	server, err := NewServer(&ServerConfig{GinMode: gin.TestMode, ResponseEnvelope: true})
+	errors.AssertNil(t, err)
+	server.ErrorMapper().Map(errTestNotFound, 404)
+
+	errors.AssertNil(t, server.RegisterService("items", &routesService{func(e *gin.Engine) {
+		e.GET("/items/:id", JSONHandler(func(c *gin.Context) (*testItem, errors.Error) {
+			if c.Param("id") != "1" {
+				return nil, errTestNotFound.Make()
+			}
+			return &testItem{ID: "1", Name: "first"}, nil
+		}))
+		e.GET("/items", JSONHandler(func(c *gin.Context) (Page, errors.Error) {
+			return Page{Items: []testItem{{ID: "1", Name: "first"}}, PaginationMeta: PaginationMeta{Offset: 0, Limit: 1, Total: 2, Next: "/items?offset=1"}}, nil
+		}))
+	}}))
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", path, nil)
+		r.Header.Set(HeaderRequestID, "req-1")
+		server.Handler().ServeHTTP(w, r)
+		return w
+	}
+
+	w := serve("/items/1")
+	assert.Equal(t, 200, w.Code)
+	assert.JSONEq(t, `{"data":{"id":"1","name":"first"},"meta":{"requestId":"req-1"}}`, w.Body.String())
+
+	w = serve("/items/2")
+	assert.Equal(t, 404, w.Code)
+	var envelope Envelope
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &envelope))
+	assert.Nil(t, envelope.Data)
+	assert.NotNil(t, envelope.Error)
+	assert.Equal(t, "req-1", envelope.Meta.RequestID)
+
+	w = serve("/items")
+	assert.Equal(t, 200, w.Code)
+	assert.JSONEq(t, `{"data":[{"id":"1","name":"first"}],"meta":{"requestId":"req-1","pagination":{"offset":0,"limit":1,"total":2,"next":"/items?offset=1"}}}`, w.Body.String())
+}
+
+func TestResponseEnvelopeDisabled(t *testing.T) {
+	server, err := NewServer(&ServerConfig{GinMode: gin.TestMode})
+	errors.AssertNil(t, err)
+
+	errors.AssertNil(t, server.RegisterService("items", &routesService{func(e *gin.Engine) {
+		e.GET("/items", JSONHandler(func(c *gin.Context) (*Page, errors.Error) {
+			return &Page{Items: []string{"a"}, PaginationMeta: PaginationMeta{Limit: 1, Total: -1}}, nil
+		}))
+	}}))
+
+	w := httptest.NewRecorder()
+	server.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/items", nil))
+	assert.Equal(t, 200, w.Code)
+	assert.JSONEq(t, `{"items":["a"],"offset":0,"limit":1,"total":-1}`, w.Body.String())
+}
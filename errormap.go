@@ -0,0 +1,120 @@
+package http
+
+import (
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sbreitf1/errors"
+)
+
+// ErrorMapper translates errors attached to a request using c.Error into responses, so handlers can return errors instead of writing error responses themselves. Errors of unmapped classes are answered with the HTTP code and API representation defined by their template.
+type ErrorMapper struct {
+	mutex     sync.RWMutex
+	mappings  []errorMapping
+	localizer func(locale string, err errors.Error) (string, bool)
+}
+
+type errorMapping struct {
+	template errors.Template
+	respond  func(errors.Error) (int, interface{})
+}
+
+// NewErrorMapper returns a mapper without mappings.
+func NewErrorMapper() *ErrorMapper {
+	return &ErrorMapper{}
+}
+
+// Map answers errors of the template class with the given status code and the API representation of the error as body.
+func (m *ErrorMapper) Map(template errors.Template, status int) {
+	m.MapFunc(template, func(err errors.Error) (int, interface{}) {
+		body := err.API()
+		body.ResponseCode = status
+		return status, body
+	})
+}
+
+// MapFunc answers errors of the template class with the status code and JSON body returned by respond. Mapping a class again replaces the previous mapping.
+func (m *ErrorMapper) MapFunc(template errors.Template, respond func(errors.Error) (status int, body interface{})) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	for i := range m.mappings {
+		if m.mappings[i].template.GetType() == template.GetType() {
+			m.mappings[i].respond = respond
+			return
+		}
+	}
+	m.mappings = append(m.mappings, errorMapping{template, respond})
+}
+
+// SetLocalizer defines a function that translates the messages of API error bodies into the locale of the request, see NewLocaleMiddleware. The original message is kept if localize returns false.
+func (m *ErrorMapper) SetLocalizer(localize func(locale string, err errors.Error) (string, bool)) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.localizer = localize
+}
+
+// Respond writes the response for err and aborts the request. Server errors are logged.
+func (m *ErrorMapper) Respond(c *gin.Context, err error) {
+	e := errors.Wrap(err)
+	if e == nil {
+		return
+	}
+	status, body := m.lookup(e)
+	if api, ok := body.(errors.APIError); ok {
+		body = m.localize(LocaleFromContext(c.Request.Context()), e, api)
+	}
+	if status >= 500 {
+		e.ToLog()
+	}
+	if c.GetBool(responseEnvelopeKey) {
+		body = envelope(c, nil, body)
+	}
+	c.Abort()
+	writeJSON(c, status, body)
+}
+
+func (m *ErrorMapper) lookup(err errors.Error) (int, interface{}) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	for _, mapping := range m.mappings {
+		if errors.InstanceOf(err, mapping.template) {
+			return mapping.respond(err)
+		}
+	}
+	body := err.API()
+	return body.ResponseCode, body
+}
+
+func (m *ErrorMapper) localize(locale string, err errors.Error, api errors.APIError) errors.APIError {
+	m.mutex.RLock()
+	localizer := m.localizer
+	m.mutex.RUnlock()
+	if localizer != nil {
+		if message, ok := localizer(locale, err); ok {
+			api.Message = message
+		}
+	}
+	return api
+}
+
+// Middleware returns a middleware that answers requests with the last error attached by subsequent handlers if no response has been written.
+func (m *ErrorMapper) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		if c.Writer.Written() {
+			return
+		}
+		if last := c.Errors.Last(); last != nil {
+			m.Respond(c, last.Err)
+		}
+	}
+}
+
+// ErrorHandler adapts a handler returning an error. Returned errors are attached to the request and answered by the error mapper of the server.
+func ErrorHandler(handler func(*gin.Context) error) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := handler(c); err != nil {
+			abortWithError(c, err)
+		}
+	}
+}
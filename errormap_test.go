@@ -0,0 +1,90 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sbreitf1/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	errTestNotFound  = errors.New("Item not found").Safe()
+	errTestForbidden = errors.New("Access denied").Safe()
+	errTestConflict  = errors.New("Item already exists").Safe().HTTPCode(409)
+)
+
+func TestErrorMapper(t *testing.T) {
+	server, err := NewServer(&ServerConfig{GinMode: gin.TestMode})
+	errors.AssertNil(t, err)
+	server.ErrorMapper().Map(errTestNotFound, 404)
+	server.ErrorMapper().MapFunc(errTestForbidden, func(err errors.Error) (int, interface{}) {
+		return 403, gin.H{"error": "forbidden"}
+	})
+
+	errors.AssertNil(t, server.RegisterService("items", &routesService{func(e *gin.Engine) {
+		e.GET("/items/:id", ErrorHandler(func(c *gin.Context) error {
+			switch c.Param("id") {
+			case "missing":
+				return errTestNotFound.Make()
+			case "secret":
+				return errTestForbidden.Make()
+			case "duplicate":
+				return errTestConflict.Make()
+			case "plain":
+				return fmt.Errorf("database offline")
+			case "written":
+				c.Error(errTestNotFound.Make())
+				c.String(202, "accepted")
+				return nil
+			}
+			c.String(200, "item")
+			return nil
+		}))
+	}}))
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		server.Handler().ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w
+	}
+	decode := func(w *httptest.ResponseRecorder) map[string]interface{} {
+		var body map[string]interface{}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		return body
+	}
+
+	assert.Equal(t, 200, serve("/items/1").Code)
+
+	w := serve("/items/missing")
+	assert.Equal(t, 404, w.Code)
+	assert.Contains(t, decode(w)["message"], "Item not found")
+
+	w = serve("/items/secret")
+	assert.Equal(t, 403, w.Code)
+	assert.Equal(t, "forbidden", decode(w)["error"])
+
+	// unmapped errors use the code of their template
+	assert.Equal(t, 409, serve("/items/duplicate").Code)
+	w = serve("/items/plain")
+	assert.Equal(t, 500, w.Code)
+	assert.NotContains(t, w.Body.String(), "database offline")
+
+	assert.Equal(t, 202, serve("/items/written").Code)
+}
+
+func TestErrorMapperRemap(t *testing.T) {
+	mapper := NewErrorMapper()
+	mapper.Map(errTestNotFound, 404)
+	mapper.Map(errTestNotFound, 410)
+
+	engine := gin.New()
+	engine.Use(mapper.Middleware())
+	engine.GET("/", func(c *gin.Context) { c.Error(errTestNotFound.Make()) })
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, 410, w.Code)
+}
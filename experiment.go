@@ -0,0 +1,133 @@
+package http
+
+import (
+	"hash/fnv"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sbreitf1/errors"
+)
+
+const (
+	// HeaderExperimentPrefix is followed by the experiment name to form the response header containing the assigned bucket, e.g. X-Experiment-Checkout.
+	HeaderExperimentPrefix = "X-Experiment-"
+
+	experimentContextKeyPrefix = "experiment:"
+)
+
+var (
+	experimentMetricsOnce     sync.Once
+	experimentRequestsTotal   *prometheus.CounterVec
+	experimentRequestDuration *prometheus.HistogramVec
+)
+
+// Experiment defines an A/B experiment with weighted buckets.
+type Experiment struct {
+	// Name identifies the experiment in headers, cookies and metrics.
+	Name string `json:"name"`
+	// Buckets contains the variants of the experiment. Requests are distributed according to the bucket weights.
+	Buckets []ExperimentBucket `json:"buckets"`
+	// UserID optionally returns a stable identifier (e.g. of the authenticated user) used for assignment. The cookie identifier is used when nil or empty.
+	UserID func(*gin.Context) string `json:"-"`
+	// Cookie denotes the cookie storing a random identifier for anonymous clients. Defaults to "exp_" followed by the experiment name.
+	Cookie string `json:"cookie,omitempty"`
+}
+
+// ExperimentBucket is a variant of an Experiment.
+type ExperimentBucket struct {
+	Name   string `json:"name"`
+	Weight int    `json:"weight"`
+}
+
+// registerExperimentMetrics creates the request counter and latency histogram labeled by experiment and bucket once, when the first experiment middleware is created.
+func registerExperimentMetrics() {
+	experimentMetricsOnce.Do(func() {
+		experimentRequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+			Subsystem: "experiment",
+			Name:      "requests_total",
+			Help:      "Number of requests by experiment, bucket and status class.",
+		}, []string{"experiment", "bucket", "status"})
+		experimentRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Subsystem: "experiment",
+			Name:      "request_duration_seconds",
+			Help:      "Latency of requests by experiment, bucket and status class.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"experiment", "bucket", "status"})
+		prometheus.MustRegister(experimentRequestsTotal, experimentRequestDuration)
+	})
+}
+
+// NewExperimentMiddleware returns a middleware that deterministically assigns requests to a bucket of the experiment by hashing the user or cookie identifier. The bucket is returned in the X-Experiment-<Name> response header and recorded in prometheus metrics. Use ExperimentBucketFromContext to read it in handlers.
+func NewExperimentMiddleware(experiment *Experiment) (gin.HandlerFunc, errors.Error) {
+	if len(experiment.Name) == 0 {
+		return nil, ErrInvalidConfig.Msg("Experiment requires a name").Make()
+	}
+	total := 0
+	for _, bucket := range experiment.Buckets {
+		if len(bucket.Name) == 0 || bucket.Weight < 0 {
+			return nil, ErrInvalidConfig.Msg("Experiment %q contains a malformed bucket", experiment.Name).Make()
+		}
+		total += bucket.Weight
+	}
+	if total == 0 {
+		return nil, ErrInvalidConfig.Msg("Experiment %q requires at least one bucket with positive weight", experiment.Name).Make()
+	}
+
+	e := *experiment
+	e.Buckets = append([]ExperimentBucket{}, experiment.Buckets...)
+	if len(e.Cookie) == 0 {
+		e.Cookie = "exp_" + e.Name
+	}
+	header := HeaderExperimentPrefix + e.Name
+	registerExperimentMetrics()
+
+	return func(c *gin.Context) {
+		bucket := e.bucket(e.identifier(c), total)
+		c.Set(experimentContextKeyPrefix+e.Name, bucket)
+		c.Header(header, bucket)
+
+		start := time.Now()
+		c.Next()
+
+		status := strconv.Itoa(c.Writer.Status()/100) + "xx"
+		experimentRequestsTotal.WithLabelValues(e.Name, bucket, status).Inc()
+		experimentRequestDuration.WithLabelValues(e.Name, bucket, status).Observe(time.Since(start).Seconds())
+	}, nil
+}
+
+// identifier returns the user identifier or the identifier stored in the experiment cookie. A new identifier is generated and stored in the cookie if neither is available.
+func (e *Experiment) identifier(c *gin.Context) string {
+	if e.UserID != nil {
+		if id := e.UserID(c); len(id) > 0 {
+			return id
+		}
+	}
+	if id, err := c.Cookie(e.Cookie); err == nil && len(id) > 0 {
+		return id
+	}
+	id, _ := newUUID()
+	http.SetCookie(c.Writer, &http.Cookie{Name: e.Cookie, Value: id, Path: "/", MaxAge: 365 * 24 * 60 * 60, HttpOnly: true})
+	return id
+}
+
+func (e *Experiment) bucket(id string, total int) string {
+	h := fnv.New64a()
+	h.Write([]byte(e.Name + ":" + id))
+	point := int(h.Sum64() % uint64(total))
+	for _, bucket := range e.Buckets {
+		if point < bucket.Weight {
+			return bucket.Name
+		}
+		point -= bucket.Weight
+	}
+	return e.Buckets[len(e.Buckets)-1].Name
+}
+
+// ExperimentBucketFromContext returns the bucket assigned to the request for the named experiment or an empty string if the experiment middleware is not installed.
+func ExperimentBucketFromContext(c *gin.Context, experiment string) string {
+	return c.GetString(experimentContextKeyPrefix + experiment)
+}
@@ -0,0 +1,83 @@
+package http
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/sbreitf1/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExperimentMiddleware(t *testing.T) {
+	middleware, err := NewExperimentMiddleware(&Experiment{
+		Name:    "checkout",
+		Buckets: []ExperimentBucket{{"control", 1}, {"variant", 1}},
+		UserID:  func(c *gin.Context) string { return c.GetHeader("X-User") },
+	})
+	errors.AssertNil(t, err)
+
+	engine := gin.New()
+	engine.Use(middleware)
+	engine.GET("/", func(c *gin.Context) { c.String(200, ExperimentBucketFromContext(c, "checkout")) })
+
+	serve := func(user, cookie string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/", nil)
+		if len(user) > 0 {
+			req.Header.Set("X-User", user)
+		}
+		if len(cookie) > 0 {
+			req.Header.Set("Cookie", "exp_checkout="+cookie)
+		}
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("deterministic user assignment", func(t *testing.T) {
+		counts := map[string]int{}
+		for i := 0; i < 1000; i++ {
+			user := "user-" + string(rune('a'+i%26)) + string(rune('a'+i/26))
+			w := serve(user, "")
+			assert.Equal(t, w.Body.String(), serve(user, "").Body.String())
+			assert.Equal(t, w.Body.String(), w.Header().Get("X-Experiment-checkout"))
+			assert.Empty(t, w.Header().Get("Set-Cookie"))
+			counts[w.Body.String()]++
+		}
+		assert.InDelta(t, 500, counts["control"], 80)
+		assert.InDelta(t, 500, counts["variant"], 80)
+	})
+
+	t.Run("anonymous cookie assignment", func(t *testing.T) {
+		w := serve("", "")
+		cookie := w.Result().Cookies()
+		if assert.Len(t, cookie, 1) {
+			assert.Equal(t, "exp_checkout", cookie[0].Name)
+			assert.Equal(t, w.Body.String(), serve("", cookie[0].Value).Body.String())
+		}
+	})
+
+	t.Run("metrics", func(t *testing.T) {
+		metric := &dto.Metric{}
+		assert.NoError(t, experimentRequestsTotal.WithLabelValues("checkout", "control", "2xx").Write(metric))
+		assert.True(t, metric.GetCounter().GetValue() > 0)
+		families, err := prometheus.DefaultGatherer.Gather()
+		assert.NoError(t, err)
+		found := false
+		for _, family := range families {
+			found = found || family.GetName() == "experiment_requests_total"
+		}
+		assert.True(t, found)
+	})
+}
+
+func TestExperimentInvalidConfig(t *testing.T) {
+	_, err := NewExperimentMiddleware(&Experiment{Buckets: []ExperimentBucket{{"a", 1}}})
+	errors.Assert(t, ErrInvalidConfig, err)
+	_, err = NewExperimentMiddleware(&Experiment{Name: "x", Buckets: []ExperimentBucket{{"a", 0}}})
+	errors.Assert(t, ErrInvalidConfig, err)
+	_, err = NewExperimentMiddleware(&Experiment{Name: "x", Buckets: []ExperimentBucket{{"", 1}}})
+	errors.Assert(t, ErrInvalidConfig, err)
+}
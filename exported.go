@@ -1,7 +1,10 @@
 package http
 
 import (
+	"context"
+	"io"
 	"net/http"
+	"net/url"
 
 	"github.com/sbreitf1/errors"
 )
@@ -15,6 +18,8 @@ const (
 	MethodPost RequestMethod = "POST"
 	// MethodPut specifies the HTTP PUT method.
 	MethodPut RequestMethod = "PUT"
+	// MethodPatch specifies the HTTP PATCH method.
+	MethodPatch RequestMethod = "PATCH"
 	// MethodDelete specifies the HTTP DELETE method.
 	MethodDelete RequestMethod = "DELETE"
 	// MethodConnect specifies the HTTP CONNECT method.
@@ -39,6 +44,10 @@ var (
 	ErrInvalidRequest = errors.New("Invalid request")
 	// ErrRequestFailed occurs when sending a request or receiving a response failed.
 	ErrRequestFailed = errors.New("Request failed")
+	// ErrUnexpectedStatus occurs when a response status code does not indicate success.
+	ErrUnexpectedStatus = errors.New("Unexpected status code")
+	// ErrInvalidBody occurs when a request body could not be encoded or a response body could not be decoded.
+	ErrInvalidBody = errors.New("Invalid body")
 )
 
 // Request is an alias for the default 'net/http' Request type.
@@ -58,3 +67,83 @@ func init() {
 func Do(method RequestMethod, url string, f func(*Request) errors.Error) (*Response, errors.Error) {
 	return DefaultClient.Do(method, url, f)
 }
+
+// DoContext performs a cancelable request using the default client.
+func DoContext(ctx context.Context, method RequestMethod, url string, body io.Reader, f func(*Request) errors.Error) (*Response, errors.Error) {
+	return DefaultClient.DoContext(ctx, method, url, body, f)
+}
+
+// DoWithBody performs a request with body using the default client.
+func DoWithBody(method RequestMethod, url, contentType string, body io.Reader, f func(*Request) errors.Error) (*Response, errors.Error) {
+	return DefaultClient.DoWithBody(method, url, contentType, body, f)
+}
+
+// Get sends a GET request using the default client.
+func Get(url string, f func(*Request) errors.Error) (*Response, errors.Error) {
+	return DefaultClient.Get(url, f)
+}
+
+// Head sends a HEAD request using the default client.
+func Head(url string, f func(*Request) errors.Error) (*Response, errors.Error) {
+	return DefaultClient.Head(url, f)
+}
+
+// Delete sends a DELETE request using the default client.
+func Delete(url string, f func(*Request) errors.Error) (*Response, errors.Error) {
+	return DefaultClient.Delete(url, f)
+}
+
+// Post sends a POST request using the default client.
+func Post(url, contentType string, body io.Reader, f func(*Request) errors.Error) (*Response, errors.Error) {
+	return DefaultClient.Post(url, contentType, body, f)
+}
+
+// Put sends a PUT request using the default client.
+func Put(url, contentType string, body io.Reader, f func(*Request) errors.Error) (*Response, errors.Error) {
+	return DefaultClient.Put(url, contentType, body, f)
+}
+
+// Patch sends a PATCH request using the default client.
+func Patch(url, contentType string, body io.Reader, f func(*Request) errors.Error) (*Response, errors.Error) {
+	return DefaultClient.Patch(url, contentType, body, f)
+}
+
+// PostForm sends a form-encoded POST request using the default client.
+func PostForm(url string, data url.Values, f func(*Request) errors.Error) (*Response, errors.Error) {
+	return DefaultClient.PostForm(url, data, f)
+}
+
+// GetJSON sends a GET request and decodes the JSON response using the default client.
+func GetJSON(url string, out interface{}, f func(*Request) errors.Error) errors.Error {
+	return DefaultClient.GetJSON(url, out, f)
+}
+
+// PostJSON sends a JSON encoded POST request and decodes the JSON response using the default client.
+func PostJSON(url string, in, out interface{}, f func(*Request) errors.Error) errors.Error {
+	return DefaultClient.PostJSON(url, in, out, f)
+}
+
+// PutJSON sends a JSON encoded PUT request and decodes the JSON response using the default client.
+func PutJSON(url string, in, out interface{}, f func(*Request) errors.Error) errors.Error {
+	return DefaultClient.PutJSON(url, in, out, f)
+}
+
+// PatchJSON sends a JSON encoded PATCH request and decodes the JSON response using the default client.
+func PatchJSON(url string, in, out interface{}, f func(*Request) errors.Error) errors.Error {
+	return DefaultClient.PatchJSON(url, in, out, f)
+}
+
+// DeleteJSON sends a DELETE request and decodes the JSON response using the default client.
+func DeleteJSON(url string, out interface{}, f func(*Request) errors.Error) errors.Error {
+	return DefaultClient.DeleteJSON(url, out, f)
+}
+
+// Download writes the response body of the given url to w using the default client.
+func Download(url string, w io.Writer, options *DownloadOptions) errors.Error {
+	return DefaultClient.Download(url, w, options)
+}
+
+// DownloadFile writes the response body of the given url to the file at path using the default client.
+func DownloadFile(url, path string, options *DownloadOptions) errors.Error {
+	return DefaultClient.DownloadFile(url, path, options)
+}
@@ -0,0 +1,114 @@
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	stderrors "errors"
+	"net"
+	"syscall"
+
+	"github.com/sbreitf1/errors"
+)
+
+// TransportFailure classifies requests that failed without response. The classification is carried by the ErrRequestFailed error returned for such requests, use TransportFailureOf to obtain it.
+type TransportFailure int
+
+const (
+	// FailureUnknown denotes a request that failed for another reason, e.g. a connection closed by the server.
+	FailureUnknown TransportFailure = iota
+	// FailureDNS denotes that the host name of a request could not be resolved.
+	FailureDNS
+	// FailureConnectionRefused denotes that the server actively refused the connection.
+	FailureConnectionRefused
+	// FailureTLS denotes a failed TLS handshake, e.g. due to an untrusted certificate.
+	FailureTLS
+	// FailureTimeout denotes a request that exceeded a timeout or the deadline of its context.
+	FailureTimeout
+	// FailureCanceled denotes a request whose context has been canceled.
+	FailureCanceled
+)
+
+var (
+	// ErrDNSFailure is attached as cause of ErrRequestFailed when the host name of a request could not be resolved.
+	ErrDNSFailure = errors.New("DNS lookup failed")
+	// ErrConnectionRefused is attached as cause of ErrRequestFailed when the server actively refused the connection.
+	ErrConnectionRefused = errors.New("Connection refused")
+	// ErrTLSFailure is attached as cause of ErrRequestFailed when the TLS handshake failed.
+	ErrTLSFailure = errors.New("TLS handshake failed")
+	// ErrTimeout is attached as cause of ErrRequestFailed when a request exceeded a timeout or the deadline of its context.
+	ErrTimeout = errors.New("Request timed out")
+	// ErrCanceled is attached as cause of ErrRequestFailed when the context of a request has been canceled.
+	ErrCanceled = errors.New("Request canceled")
+)
+
+var transportFailureCauses = map[TransportFailure]errors.Template{
+	FailureDNS:               ErrDNSFailure,
+	FailureConnectionRefused: ErrConnectionRefused,
+	FailureTLS:               ErrTLSFailure,
+	FailureTimeout:           ErrTimeout,
+	FailureCanceled:          ErrCanceled,
+}
+
+// transportError returns ErrRequestFailed for an error of the transport, classified as FailureDNS, FailureConnectionRefused, FailureTLS, FailureTimeout or FailureCanceled if possible.
+func transportError(err error) errors.Error {
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	switch {
+	case stderrors.Is(err, context.Canceled):
+		return requestFailed(FailureCanceled, err)
+	case stderrors.As(err, &dnsErr):
+		return requestFailed(FailureDNS, err)
+	case isTLSError(err):
+		return requestFailed(FailureTLS, err)
+	case stderrors.Is(err, syscall.ECONNREFUSED):
+		return requestFailed(FailureConnectionRefused, err)
+	case stderrors.Is(err, context.DeadlineExceeded), stderrors.As(err, &netErr) && netErr.Timeout():
+		return requestFailed(FailureTimeout, err)
+	}
+	return ErrRequestFailed.Make().Cause(err)
+}
+
+// requestFailed returns ErrRequestFailed carrying a known classification. The error of the matching template is attached as cause with err as its own cause.
+func requestFailed(failure TransportFailure, err error) errors.Error {
+	cause := transportFailureCauses[failure].Make()
+	if err != nil {
+		cause = cause.Cause(err)
+	}
+	return ErrRequestFailed.Make().ErrCode(int(failure)).Cause(cause)
+}
+
+// TransportFailureOf returns the classification of a request that failed with ErrRequestFailed. FailureUnknown is returned for all other errors.
+func TransportFailureOf(err error) TransportFailure {
+	e, ok := err.(errors.Error)
+	if !ok || !errors.InstanceOf(e, ErrRequestFailed) {
+		return FailureUnknown
+	}
+	return TransportFailure(e.API().ErrorCode)
+}
+
+// IsTimeout returns true if err denotes a request that exceeded a timeout or the deadline of its context.
+func IsTimeout(err error) bool {
+	return TransportFailureOf(err) == FailureTimeout
+}
+
+// IsCanceled returns true if err denotes a request whose context has been canceled.
+func IsCanceled(err error) bool {
+	return TransportFailureOf(err) == FailureCanceled
+}
+
+func isTLSError(err error) bool {
+	var recordErr tls.RecordHeaderError
+	var alertErr tls.AlertError
+	var verifyErr *tls.CertificateVerificationError
+	var authorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+	return stderrors.As(err, &recordErr) || stderrors.As(err, &alertErr) || stderrors.As(err, &verifyErr) ||
+		stderrors.As(err, &authorityErr) || stderrors.As(err, &hostnameErr) || stderrors.As(err, &invalidErr)
+}
+
+// IsTransportError returns true if err denotes a request that failed without response, i.e. ErrRequestFailed. Use TransportFailureOf to obtain the reason.
+func IsTransportError(err error) bool {
+	return errors.InstanceOf(err, ErrRequestFailed)
+}
@@ -0,0 +1,43 @@
+package http
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/sbreitf1/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransportError(t *testing.T) {
+	wrap := func(err error) error {
+		return &url.Error{Op: "Get", URL: "http://api.test", Err: err}
+	}
+
+	for failure, err := range map[TransportFailure]error{
+		FailureCanceled:          context.Canceled,
+		FailureTimeout:           context.DeadlineExceeded,
+		FailureDNS:               &net.OpError{Op: "dial", Err: &net.DNSError{Err: "no such host", Name: "api.test"}},
+		FailureConnectionRefused: &net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)},
+		FailureTLS:               x509.UnknownAuthorityError{},
+		FailureUnknown:           fmt.Errorf("unexpected EOF"),
+	} {
+		classified := transportError(wrap(err))
+		errors.Assert(t, ErrRequestFailed, classified)
+		assert.Equal(t, failure, TransportFailureOf(classified))
+	}
+	assert.True(t, IsTimeout(transportError(wrap(&net.OpError{Op: "dial", Err: os.ErrDeadlineExceeded}))))
+	assert.True(t, IsCanceled(transportError(wrap(context.Canceled))))
+	assert.False(t, IsTimeout(ErrUnexpectedStatus.Make()))
+	assert.Equal(t, FailureUnknown, TransportFailureOf(nil))
+	assert.Contains(t, transportError(wrap(context.DeadlineExceeded)).Error(), "Request timed out")
+
+	assert.True(t, IsTransportError(ErrRequestFailed.Make()))
+	assert.False(t, IsTransportError(ErrUnexpectedStatus.Make()))
+	assert.False(t, IsTransportError(nil))
+}
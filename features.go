@@ -0,0 +1,177 @@
+package http
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sbreitf1/errors"
+)
+
+var (
+	// ErrFeatureFlags occurs when feature flags could not be loaded from a file or the environment.
+	ErrFeatureFlags = errors.New("Feature flags could not be loaded")
+)
+
+const (
+	// FeatureFlagsKey denotes the gin context key that contains the *FeatureFlags of a request.
+	FeatureFlagsKey = "featureFlags"
+)
+
+// FeatureFlagsConfig defines the sources of feature flags. Later sources take precedence: defaults, file, environment and finally runtime toggles.
+type FeatureFlagsConfig struct {
+	// Defaults contains the initial state of all known flags.
+	Defaults map[string]bool `json:"defaults,omitempty"`
+	// File denotes a JSON file with an object of flag names and boolean states. It is ignored when missing.
+	File string `json:"file,omitempty"`
+	// EnvPrefix enables flags from environment variables, e.g. FEATURE_NEW_CHECKOUT=true enables "new_checkout" for prefix "FEATURE_".
+	EnvPrefix string `json:"envPrefix,omitempty"`
+}
+
+// FeatureFlags is a registry of named boolean flags that can be toggled at runtime.
+type FeatureFlags struct {
+	config FeatureFlagsConfig
+
+	mutex     sync.RWMutex
+	loaded    map[string]bool
+	overrides map[string]bool
+}
+
+// NewFeatureFlags returns a registry with all flags loaded from the configured sources.
+func NewFeatureFlags(config *FeatureFlagsConfig) (*FeatureFlags, errors.Error) {
+	flags := &FeatureFlags{config: *config, overrides: make(map[string]bool)}
+	if err := flags.Reload(); err != nil {
+		return nil, err
+	}
+	return flags, nil
+}
+
+// Reload re-reads the file and environment sources. Runtime toggles are kept. Register it using Server.OnReload to pick up file changes without redeploy.
+func (flags *FeatureFlags) Reload() errors.Error {
+	loaded := make(map[string]bool, len(flags.config.Defaults))
+	for name, enabled := range flags.config.Defaults {
+		loaded[name] = enabled
+	}
+
+	if len(flags.config.File) > 0 {
+		data, err := ioutil.ReadFile(flags.config.File)
+		if err != nil && !os.IsNotExist(err) {
+			return ErrFeatureFlags.Make().Cause(err)
+		} else if err == nil {
+			var fromFile map[string]bool
+			if err := json.Unmarshal(data, &fromFile); err != nil {
+				return ErrFeatureFlags.Msg("Malformed feature flag file %q", flags.config.File).Make().Cause(err)
+			}
+			for name, enabled := range fromFile {
+				loaded[name] = enabled
+			}
+		}
+	}
+
+	if len(flags.config.EnvPrefix) > 0 {
+		for _, env := range os.Environ() {
+			parts := strings.SplitN(env, "=", 2)
+			if len(parts) != 2 || !strings.HasPrefix(parts[0], flags.config.EnvPrefix) {
+				continue
+			}
+			enabled, err := strconv.ParseBool(parts[1])
+			if err != nil {
+				return ErrFeatureFlags.Msg("Malformed feature flag %s=%q", parts[0], parts[1]).Make().Cause(err)
+			}
+			loaded[strings.ToLower(strings.TrimPrefix(parts[0], flags.config.EnvPrefix))] = enabled
+		}
+	}
+
+	flags.mutex.Lock()
+	defer flags.mutex.Unlock()
+	flags.loaded = loaded
+	return nil
+}
+
+// Enabled returns whether the named flag is enabled. Unknown flags are disabled.
+func (flags *FeatureFlags) Enabled(name string) bool {
+	flags.mutex.RLock()
+	defer flags.mutex.RUnlock()
+	if enabled, ok := flags.overrides[name]; ok {
+		return enabled
+	}
+	return flags.loaded[name]
+}
+
+// Set toggles the named flag at runtime. The toggle takes precedence over all sources until it is reset.
+func (flags *FeatureFlags) Set(name string, enabled bool) {
+	flags.mutex.Lock()
+	defer flags.mutex.Unlock()
+	flags.overrides[name] = enabled
+}
+
+// Reset removes the runtime toggle of the named flag, so the state from the configured sources applies again.
+func (flags *FeatureFlags) Reset(name string) {
+	flags.mutex.Lock()
+	defer flags.mutex.Unlock()
+	delete(flags.overrides, name)
+}
+
+// All returns the current state of all known flags.
+func (flags *FeatureFlags) All() map[string]bool {
+	flags.mutex.RLock()
+	defer flags.mutex.RUnlock()
+	all := make(map[string]bool, len(flags.loaded)+len(flags.overrides))
+	for name, enabled := range flags.loaded {
+		all[name] = enabled
+	}
+	for name, enabled := range flags.overrides {
+		all[name] = enabled
+	}
+	return all
+}
+
+// Middleware returns a middleware that exposes the registry to handlers. Use FeatureEnabled to evaluate flags in a handler.
+func (flags *FeatureFlags) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(FeatureFlagsKey, flags)
+	}
+}
+
+// Require returns a middleware that responds with 404 Not Found while the named flag is disabled.
+func (flags *FeatureFlags) Require(name string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !flags.Enabled(name) {
+			c.AbortWithStatus(404)
+		}
+	}
+}
+
+// RegisterAdminRoutes registers endpoints to list (GET /features), toggle (PUT /features/:name with {"enabled":true}) and reset (DELETE /features/:name) flags. Protect the router group appropriately, e.g. with an authentication middleware.
+func (flags *FeatureFlags) RegisterAdminRoutes(router gin.IRouter) {
+	router.GET("/features", func(c *gin.Context) {
+		c.JSON(200, flags.All())
+	})
+	router.PUT("/features/:name", func(c *gin.Context) {
+		var body struct {
+			Enabled *bool `json:"enabled"`
+		}
+		if err := c.ShouldBindJSON(&body); err != nil || body.Enabled == nil {
+			c.AbortWithStatus(400)
+			return
+		}
+		flags.Set(c.Param("name"), *body.Enabled)
+		c.JSON(200, flags.All())
+	})
+	router.DELETE("/features/:name", func(c *gin.Context) {
+		flags.Reset(c.Param("name"))
+		c.JSON(200, flags.All())
+	})
+}
+
+// FeatureEnabled returns whether the named flag is enabled for the request. It returns false when the feature flag middleware is not installed.
+func FeatureEnabled(c *gin.Context, name string) bool {
+	if flags, ok := c.Value(FeatureFlagsKey).(*FeatureFlags); ok {
+		return flags.Enabled(name)
+	}
+	return false
+}
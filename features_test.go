@@ -0,0 +1,83 @@
+package http
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sbreitf1/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFeatureFlagSources(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "features.json")
+	assert.NoError(t, ioutil.WriteFile(path, []byte(`{"search":true,"export":true}`), 0644))
+	t.Setenv("TESTFEATURE_EXPORT", "false")
+
+	flags, err := NewFeatureFlags(&FeatureFlagsConfig{
+		Defaults:  map[string]bool{"checkout": true, "search": false},
+		File:      path,
+		EnvPrefix: "TESTFEATURE_",
+	})
+	errors.AssertNil(t, err)
+	assert.Equal(t, map[string]bool{"checkout": true, "search": true, "export": false}, flags.All())
+	assert.False(t, flags.Enabled("unknown"))
+
+	flags.Set("search", false)
+	assert.NoError(t, ioutil.WriteFile(path, []byte(`{"search":true,"export":true,"beta":true}`), 0644))
+	errors.AssertNil(t, flags.Reload())
+	assert.False(t, flags.Enabled("search"))
+	assert.True(t, flags.Enabled("beta"))
+	flags.Reset("search")
+	assert.True(t, flags.Enabled("search"))
+
+	t.Setenv("TESTFEATURE_BROKEN", "maybe")
+	errors.Assert(t, ErrFeatureFlags, flags.Reload())
+}
+
+func TestFeatureFlagMiddleware(t *testing.T) {
+	flags, err := NewFeatureFlags(&FeatureFlagsConfig{Defaults: map[string]bool{"beta": false}})
+	errors.AssertNil(t, err)
+
+	engine := gin.New()
+	engine.Use(flags.Middleware())
+	engine.GET("/beta", flags.Require("beta"), func(c *gin.Context) { c.String(200, "beta") })
+	engine.GET("/home", func(c *gin.Context) {
+		if FeatureEnabled(c, "beta") {
+			c.String(200, "new home")
+		} else {
+			c.String(200, "home")
+		}
+	})
+	flags.RegisterAdminRoutes(engine.Group("/admin"))
+
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return w
+	}
+
+	assert.Equal(t, 404, serve("GET", "/beta", "").Code)
+	assert.Equal(t, "home", serve("GET", "/home", "").Body.String())
+
+	assert.Equal(t, 400, serve("PUT", "/admin/features/beta", `{}`).Code)
+	w := serve("PUT", "/admin/features/beta", `{"enabled":true}`)
+	assert.Equal(t, 200, w.Code)
+	assert.JSONEq(t, `{"beta":true}`, w.Body.String())
+	assert.Equal(t, "beta", serve("GET", "/beta", "").Body.String())
+	assert.Equal(t, "new home", serve("GET", "/home", "").Body.String())
+
+	assert.JSONEq(t, `{"beta":false}`, serve("DELETE", "/admin/features/beta", "").Body.String())
+	assert.Equal(t, 404, serve("GET", "/beta", "").Code)
+	assert.JSONEq(t, `{"beta":false}`, serve("GET", "/admin/features", "").Body.String())
+}
+
+func TestFeatureFlagsMissingFile(t *testing.T) {
+	flags, err := NewFeatureFlags(&FeatureFlagsConfig{File: filepath.Join(os.TempDir(), "does-not-exist.json")})
+	errors.AssertNil(t, err)
+	assert.Empty(t, flags.All())
+}
@@ -0,0 +1,577 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sbreitf1/errors"
+)
+
+const (
+	// DefaultGraphQLMaxQuerySize limits the size of GraphQL request bodies that are checked against the query limits if no MaxQuerySize is set.
+	DefaultGraphQLMaxQuerySize = 1 << 20
+)
+
+var (
+	errGraphQLTooLarge = fmt.Errorf("request body too large")
+	errGraphQLTooDeep  = fmt.Errorf("query too deep")
+
+	graphQLMetricsOnce      sync.Once
+	graphQLResolverDuration *prometheus.HistogramVec
+	graphQLResolverErrors   *prometheus.CounterVec
+	graphQLRejectedQueries  *prometheus.CounterVec
+)
+
+// GraphQLConfig contains the parameters of a GraphQL service.
+type GraphQLConfig struct {
+	// Path of the GraphQL endpoint. Defaults to "/graphql".
+	Path string `json:"path,omitempty"`
+	// GraphiQL serves the GraphiQL IDE for browser requests to the endpoint.
+	GraphiQL bool `json:"graphiql,omitempty"`
+	// MaxDepth rejects queries with more nested selection sets, fragments are expanded. Unlimited when 0.
+	MaxDepth int `json:"maxDepth,omitempty"`
+	// MaxComplexity rejects queries selecting more fields in total, fragments are expanded. Unlimited when 0.
+	MaxComplexity int `json:"maxComplexity,omitempty"`
+	// MaxQuerySize limits the size of request bodies that are read to check MaxDepth and MaxComplexity, larger requests are rejected with status 413. Defaults to DefaultGraphQLMaxQuerySize.
+	MaxQuerySize int64 `json:"maxQuerySize,omitempty"`
+	// Handler executes GraphQL requests, e.g. the handler of gqlgen or graph-gophers/graphql-go.
+	Handler http.Handler `json:"-"`
+	// Ready optionally reports the readiness of the service, e.g. whether the schema has been loaded.
+	Ready func() errors.Error `json:"-"`
+}
+
+// GraphQLService serves a GraphQL handler as Service, so it is covered by the probes, metrics and logging of the server. Register it using RegisterService.
+type GraphQLService struct {
+	config GraphQLConfig
+}
+
+type graphQLRequest struct {
+	Query         string `json:"query"`
+	OperationName string `json:"operationName,omitempty"`
+}
+
+// registerGraphQLMetrics creates the resolver metrics. It is called by NewGraphQLService and ObserveGraphQLResolver, since resolvers may be observed without a service.
+func registerGraphQLMetrics() {
+	graphQLMetricsOnce.Do(func() {
+		graphQLResolverDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Subsystem: "graphql",
+			Name:      "resolver_duration_seconds",
+			Help:      "Latency of GraphQL field resolvers.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"type", "field"})
+		graphQLResolverErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+			Subsystem: "graphql",
+			Name:      "resolver_errors_total",
+			Help:      "Number of failed GraphQL field resolutions.",
+		}, []string{"type", "field"})
+		graphQLRejectedQueries = prometheus.NewCounterVec(prometheus.CounterOpts{
+			Subsystem: "graphql",
+			Name:      "rejected_queries_total",
+			Help:      "Number of GraphQL queries rejected before execution by reason.",
+		}, []string{"reason"})
+		prometheus.MustRegister(graphQLResolverDuration, graphQLResolverErrors, graphQLRejectedQueries)
+	})
+}
+
+// NewGraphQLService returns a service mounting the configured GraphQL handler.
+func NewGraphQLService(config *GraphQLConfig) (*GraphQLService, errors.Error) {
+	if config.Handler == nil {
+		return nil, ErrInvalidConfig.Msg("GraphQL service requires a handler").Make()
+	}
+	s := &GraphQLService{config: *config}
+	if len(s.config.Path) == 0 {
+		s.config.Path = "/graphql"
+	}
+	if s.config.MaxQuerySize <= 0 {
+		s.config.MaxQuerySize = DefaultGraphQLMaxQuerySize
+	}
+	registerGraphQLMetrics()
+	return s, nil
+}
+
+// ObserveGraphQLResolver records the duration and failure of a field resolver in the GraphQL metrics. Call it from the resolver middleware of the GraphQL library, e.g. the field interceptor of gqlgen.
+func ObserveGraphQLResolver(typeName, field string, duration time.Duration, err error) {
+	registerGraphQLMetrics()
+	graphQLResolverDuration.WithLabelValues(typeName, field).Observe(duration.Seconds())
+	if err != nil {
+		graphQLResolverErrors.WithLabelValues(typeName, field).Inc()
+	}
+}
+
+// RegisterRoutes registers the GraphQL endpoint for GET and POST requests.
+func (s *GraphQLService) RegisterRoutes(e *gin.Engine) {
+	e.GET(s.config.Path, s.handle)
+	e.POST(s.config.Path, s.handle)
+}
+
+// BeginServing does nothing.
+func (s *GraphQLService) BeginServing() {}
+
+// StopServing does nothing.
+func (s *GraphQLService) StopServing() {}
+
+// Healthy always returns nil.
+func (s *GraphQLService) Healthy() errors.Error {
+	return nil
+}
+
+// Ready returns the result of the configured readiness function.
+func (s *GraphQLService) Ready() errors.Error {
+	if s.config.Ready != nil {
+		return s.config.Ready()
+	}
+	return nil
+}
+
+func (s *GraphQLService) handle(c *gin.Context) {
+	if s.config.GraphiQL && c.Request.Method == "GET" && len(c.Query("query")) == 0 && strings.Contains(c.GetHeader("Accept"), "text/html") {
+		c.Data(200, "text/html; charset=utf-8", []byte(fmt.Sprintf(graphiQLPage, s.config.Path)))
+		return
+	}
+
+	if s.config.MaxDepth > 0 || s.config.MaxComplexity > 0 {
+		requests, err := readGraphQLRequests(c.Request, s.config.MaxQuerySize)
+		if err == errGraphQLTooLarge {
+			s.reject(c, 413, "size", fmt.Sprintf("Request body exceeds the limit of %d bytes", s.config.MaxQuerySize))
+			return
+		} else if err != nil {
+			s.reject(c, 400, "invalid", err.Error())
+			return
+		}
+		for _, r := range requests {
+			if reason, message := s.checkLimits(r); len(reason) > 0 {
+				s.reject(c, 400, reason, message)
+				return
+			}
+		}
+	}
+	s.config.Handler.ServeHTTP(c.Writer, c.Request)
+}
+
+// checkLimits returns the reason and message if the query exceeds the configured limits.
+func (s *GraphQLService) checkLimits(r graphQLRequest) (string, string) {
+	doc, err := parseGraphQLDocument(r.Query, s.config.MaxDepth)
+	if err == errGraphQLTooDeep {
+		return "depth", fmt.Sprintf("Query depth exceeds the limit of %d", s.config.MaxDepth)
+	} else if err != nil {
+		return "invalid", "Invalid GraphQL query: " + err.Error()
+	}
+	depth, complexity := doc.measure(r.OperationName)
+	if s.config.MaxDepth > 0 && depth > s.config.MaxDepth {
+		return "depth", fmt.Sprintf("Query depth %d exceeds the limit of %d", depth, s.config.MaxDepth)
+	}
+	if s.config.MaxComplexity > 0 && complexity > s.config.MaxComplexity {
+		return "complexity", fmt.Sprintf("Query complexity %d exceeds the limit of %d", complexity, s.config.MaxComplexity)
+	}
+	return "", ""
+}
+
+// reject answers the request with a GraphQL error response.
+func (s *GraphQLService) reject(c *gin.Context, status int, reason, message string) {
+	graphQLRejectedQueries.WithLabelValues(reason).Inc()
+	c.AbortWithStatusJSON(status, gin.H{"errors": []gin.H{{"message": message}}})
+}
+
+// readGraphQLRequests returns the requests of a GET, JSON, batched JSON or application/graphql request. The body is restored for the handler. Bodies exceeding maxSize are not read completely and result in errGraphQLTooLarge.
+func readGraphQLRequests(req *http.Request, maxSize int64) ([]graphQLRequest, error) {
+	if req.Method == "GET" {
+		return []graphQLRequest{{Query: req.URL.Query().Get("query"), OperationName: req.URL.Query().Get("operationName")}}, nil
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(req.Body, maxSize+1))
+	req.Body.Close()
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > maxSize {
+		return nil, errGraphQLTooLarge
+	}
+
+	if strings.HasPrefix(req.Header.Get("Content-Type"), "application/graphql") {
+		return []graphQLRequest{{Query: string(body), OperationName: req.URL.Query().Get("operationName")}}, nil
+	}
+	body = bytes.TrimSpace(body)
+	if len(body) > 0 && body[0] == '[' {
+		var requests []graphQLRequest
+		if err := json.Unmarshal(body, &requests); err != nil {
+			return nil, fmt.Errorf("Invalid request body: %s", err.Error())
+		}
+		return requests, nil
+	}
+	var request graphQLRequest
+	if err := json.Unmarshal(body, &request); err != nil {
+		return nil, fmt.Errorf("Invalid request body: %s", err.Error())
+	}
+	return []graphQLRequest{request}, nil
+}
+
+const graphiQLPage = `<!DOCTYPE html>
+<html>
+<head>
+<title>GraphiQL</title>
+<link rel="stylesheet" href="https://unpkg.com/graphiql@3/graphiql.min.css" />
+</head>
+<body style="margin: 0;">
+<div id="graphiql" style="height: 100vh;"></div>
+<script crossorigin src="https://unpkg.com/react@18/umd/react.production.min.js"></script>
+<script crossorigin src="https://unpkg.com/react-dom@18/umd/react-dom.production.min.js"></script>
+<script crossorigin src="https://unpkg.com/graphiql@3/graphiql.min.js"></script>
+<script>
+ReactDOM.createRoot(document.getElementById('graphiql')).render(React.createElement(GraphiQL, {fetcher: GraphiQL.createFetcher({url: '%s'})}));
+</script>
+</body>
+</html>
+`
+
+// graphQLSelection is a field, inline fragment or fragment spread of a selection set.
+type graphQLSelection struct {
+	field    bool
+	spread   string
+	children []graphQLSelection
+}
+
+// graphQLDocument contains the selection sets of the operations and fragments of a query document. Arguments, variables and directives are skipped, as only the shape of the query is measured.
+type graphQLDocument struct {
+	operations map[string][]graphQLSelection
+	fragments  map[string][]graphQLSelection
+
+	depths       map[string]int
+	complexities map[string]int
+	visiting     map[string]bool
+}
+
+// maxGraphQLComplexity caps the computed complexity to avoid overflows for fragment bombs.
+const maxGraphQLComplexity = 1 << 30
+
+// measure returns the maximum depth and complexity of the named operation or all operations if the name is empty or unknown.
+func (doc *graphQLDocument) measure(operationName string) (int, int) {
+	doc.depths, doc.complexities, doc.visiting = make(map[string]int), make(map[string]int), make(map[string]bool)
+	operations := doc.operations
+	if set, ok := doc.operations[operationName]; ok && len(operationName) > 0 {
+		operations = map[string][]graphQLSelection{operationName: set}
+	}
+	depth, complexity := 0, 0
+	for _, set := range operations {
+		if d := doc.depth(set); d > depth {
+			depth = d
+		}
+		if c := doc.complexity(set); c > complexity {
+			complexity = c
+		}
+	}
+	return depth, complexity
+}
+
+func (doc *graphQLDocument) depth(set []graphQLSelection) int {
+	max := 0
+	for _, s := range set {
+		d := 0
+		if s.field {
+			d = 1 + doc.depth(s.children)
+		} else if len(s.spread) > 0 {
+			d = doc.fragmentMeasure(s.spread, doc.depths, doc.depth)
+		} else {
+			d = doc.depth(s.children)
+		}
+		if d > max {
+			max = d
+		}
+	}
+	return max
+}
+
+func (doc *graphQLDocument) complexity(set []graphQLSelection) int {
+	sum := 0
+	for _, s := range set {
+		if s.field {
+			sum += 1 + doc.complexity(s.children)
+		} else if len(s.spread) > 0 {
+			sum += doc.fragmentMeasure(s.spread, doc.complexities, doc.complexity)
+		} else {
+			sum += doc.complexity(s.children)
+		}
+		if sum > maxGraphQLComplexity {
+			return maxGraphQLComplexity
+		}
+	}
+	return sum
+}
+
+// fragmentMeasure returns the memoized measure of a fragment. Unknown and cyclic fragments are measured as 0 and left to the validation of the GraphQL handler.
+func (doc *graphQLDocument) fragmentMeasure(name string, memo map[string]int, measure func([]graphQLSelection) int) int {
+	if value, ok := memo[name]; ok {
+		return value
+	}
+	set, ok := doc.fragments[name]
+	if !ok || doc.visiting[name] {
+		return 0
+	}
+	doc.visiting[name] = true
+	value := measure(set)
+	delete(doc.visiting, name)
+	memo[name] = value
+	return value
+}
+
+type graphQLParser struct {
+	tokens []string
+	pos    int
+
+	// depth denotes the number of fields enclosing the current selection set
+	depth    int
+	maxDepth int
+}
+
+// parseGraphQLDocument parses the operations and fragments of an executable GraphQL document. Parsing stops with errGraphQLTooDeep at the first field nested deeper than maxDepth, as expanded fragments can only increase the depth. Unlimited when 0.
+func parseGraphQLDocument(query string, maxDepth int) (*graphQLDocument, error) {
+	tokens, err := tokenizeGraphQL(query)
+	if err != nil {
+		return nil, err
+	}
+	p := &graphQLParser{tokens: tokens, maxDepth: maxDepth}
+	doc := &graphQLDocument{operations: make(map[string][]graphQLSelection), fragments: make(map[string][]graphQLSelection)}
+	for p.pos < len(p.tokens) {
+		switch p.peek() {
+		case "{":
+			set, err := p.selectionSet()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations[""] = set
+		case "query", "mutation", "subscription":
+			p.pos++
+			name := ""
+			if isGraphQLName(p.peek()) {
+				name = p.next()
+			}
+			if p.peek() == "(" {
+				if err := p.skipArguments(); err != nil {
+					return nil, err
+				}
+			}
+			set, err := p.directivesAndSelectionSet()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations[name] = set
+		case "fragment":
+			p.pos++
+			name := p.next()
+			if !isGraphQLName(name) || p.next() != "on" || !isGraphQLName(p.next()) {
+				return nil, fmt.Errorf("invalid fragment definition")
+			}
+			set, err := p.directivesAndSelectionSet()
+			if err != nil {
+				return nil, err
+			}
+			doc.fragments[name] = set
+		default:
+			return nil, fmt.Errorf("unexpected %q", p.peek())
+		}
+	}
+	if len(doc.operations) == 0 {
+		return nil, fmt.Errorf("no operation defined")
+	}
+	return doc, nil
+}
+
+func (p *graphQLParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *graphQLParser) next() string {
+	t := p.peek()
+	p.pos++
+	return t
+}
+
+func (p *graphQLParser) selectionSet() ([]graphQLSelection, error) {
+	if p.next() != "{" {
+		return nil, fmt.Errorf("expected selection set")
+	}
+	set := make([]graphQLSelection, 0)
+	for p.peek() != "}" {
+		if p.pos >= len(p.tokens) {
+			return nil, fmt.Errorf("unterminated selection set")
+		}
+		s, err := p.selection()
+		if err != nil {
+			return nil, err
+		}
+		set = append(set, s)
+	}
+	p.pos++
+	return set, nil
+}
+
+func (p *graphQLParser) selection() (graphQLSelection, error) {
+	if p.peek() == "..." {
+		p.pos++
+		if p.peek() == "on" {
+			p.pos++
+			if !isGraphQLName(p.next()) {
+				return graphQLSelection{}, fmt.Errorf("expected type condition")
+			}
+		} else if p.peek() != "{" && p.peek() != "@" {
+			name := p.next()
+			if !isGraphQLName(name) {
+				return graphQLSelection{}, fmt.Errorf("expected fragment name")
+			}
+			return graphQLSelection{spread: name}, p.skipDirectives()
+		}
+		children, err := p.directivesAndSelectionSet()
+		return graphQLSelection{children: children}, err
+	}
+
+	if !isGraphQLName(p.next()) {
+		return graphQLSelection{}, fmt.Errorf("expected field")
+	}
+	if p.peek() == ":" {
+		p.pos++
+		if !isGraphQLName(p.next()) {
+			return graphQLSelection{}, fmt.Errorf("expected field after alias")
+		}
+	}
+	if p.peek() == "(" {
+		if err := p.skipArguments(); err != nil {
+			return graphQLSelection{}, err
+		}
+	}
+	if err := p.skipDirectives(); err != nil {
+		return graphQLSelection{}, err
+	}
+	if p.maxDepth > 0 && p.depth >= p.maxDepth {
+		return graphQLSelection{}, errGraphQLTooDeep
+	}
+	s := graphQLSelection{field: true}
+	if p.peek() == "{" {
+		p.depth++
+		children, err := p.selectionSet()
+		p.depth--
+		if err != nil {
+			return graphQLSelection{}, err
+		}
+		s.children = children
+	}
+	return s, nil
+}
+
+func (p *graphQLParser) directivesAndSelectionSet() ([]graphQLSelection, error) {
+	if err := p.skipDirectives(); err != nil {
+		return nil, err
+	}
+	return p.selectionSet()
+}
+
+func (p *graphQLParser) skipDirectives() error {
+	for p.peek() == "@" {
+		p.pos++
+		if !isGraphQLName(p.next()) {
+			return fmt.Errorf("expected directive name")
+		}
+		if p.peek() == "(" {
+			if err := p.skipArguments(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// skipArguments skips balanced parentheses of arguments or variable definitions.
+func (p *graphQLParser) skipArguments() error {
+	depth := 0
+	for p.pos < len(p.tokens) {
+		switch p.next() {
+		case "(":
+			depth++
+		case ")":
+			depth--
+			if depth == 0 {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("unterminated arguments")
+}
+
+func isGraphQLName(token string) bool {
+	if len(token) == 0 {
+		return false
+	}
+	for i, r := range token {
+		if r != '_' && (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') && (i == 0 || r < '0' || r > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// tokenizeGraphQL splits a query into names, punctuators and placeholders for string and number values. Whitespace, commas and comments are dropped.
+func tokenizeGraphQL(query string) ([]string, error) {
+	tokens := make([]string, 0)
+	for i := 0; i < len(query); {
+		ch := query[i]
+		switch {
+		case ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r' || ch == ',':
+			i++
+		case strings.HasPrefix(query[i:], "\uFEFF"):
+			i += len("\uFEFF")
+		case ch == '#':
+			for i < len(query) && query[i] != '\n' && query[i] != '\r' {
+				i++
+			}
+		case strings.HasPrefix(query[i:], "..."):
+			tokens = append(tokens, "...")
+			i += 3
+		case strings.HasPrefix(query[i:], `"""`):
+			end := strings.Index(strings.Replace(query[i+3:], `\"""`, "xxxx", -1), `"""`)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated block string")
+			}
+			tokens = append(tokens, `""`)
+			i += 3 + end + 3
+		case ch == '"':
+			i++
+			for ; i < len(query) && query[i] != '"'; i++ {
+				if query[i] == '\\' {
+					i++
+				} else if query[i] == '\n' {
+					return nil, fmt.Errorf("unterminated string")
+				}
+			}
+			if i >= len(query) {
+				return nil, fmt.Errorf("unterminated string")
+			}
+			tokens = append(tokens, `""`)
+			i++
+		case strings.IndexByte("!$&()=@[]{}|:", ch) >= 0:
+			tokens = append(tokens, string(ch))
+			i++
+		case ch == '-' || (ch >= '0' && ch <= '9'):
+			for i++; i < len(query) && strings.IndexByte("0123456789.eE+-", query[i]) >= 0; i++ {
+			}
+			tokens = append(tokens, "0")
+		case ch == '_' || (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z'):
+			start := i
+			for i++; i < len(query) && (query[i] == '_' || (query[i] >= 'a' && query[i] <= 'z') || (query[i] >= 'A' && query[i] <= 'Z') || (query[i] >= '0' && query[i] <= '9')); i++ {
+			}
+			tokens = append(tokens, query[start:i])
+		default:
+			return nil, fmt.Errorf("unexpected character %q", ch)
+		}
+	}
+	return tokens, nil
+}
@@ -0,0 +1,142 @@
+package http
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sbreitf1/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGraphQLMeasure(t *testing.T) {
+	tests := []struct {
+		query             string
+		depth, complexity int
+	}{
+		{`{ a }`, 1, 1},
+		{`query Q($id: ID = "x") { user(id: $id, filter: {name: "a{", tags: ["(", 1.5e3]}) { name friends { name } } }`, 3, 4},
+		{`query { user { ...F } } fragment F on User { name friends { name } }`, 3, 4},
+		{`{ node { ... on User { name } ... @include(if: true) { id } } }`, 2, 3},
+		{`{ a: user @skip(if: false) { b: name } }`, 2, 2},
+		{"# comment {\n{ a(text: \"\"\"x { \\\"\"\" \"\"\") }", 1, 1},
+		{`{ a { ...F } } fragment F on A { b { ...F } }`, 2, 2},
+		{`{ a { ...Unknown } }`, 1, 1},
+	}
+	for _, test := range tests {
+		doc, err := parseGraphQLDocument(test.query, 0)
+		assert.NoError(t, err, test.query)
+		if err == nil {
+			depth, complexity := doc.measure("")
+			assert.Equal(t, test.depth, depth, test.query)
+			assert.Equal(t, test.complexity, complexity, test.query)
+		}
+	}
+
+	doc, err := parseGraphQLDocument(`query Small { a } query Large { a { b { c } } }`, 0)
+	assert.NoError(t, err)
+	depth, _ := doc.measure("Small")
+	assert.Equal(t, 1, depth)
+	depth, _ = doc.measure("")
+	assert.Equal(t, 3, depth)
+
+	// fragments referencing others repeatedly are measured without expanding them
+	query := "{ ...F40 } fragment F0 on Q { a }"
+	for i := 1; i <= 40; i++ {
+		query += fmt.Sprintf(" fragment F%d on Q { x: a ...F%d y: a ...F%d }", i, i-1, i-1)
+	}
+	doc, err = parseGraphQLDocument(query, 0)
+	assert.NoError(t, err)
+	_, complexity := doc.measure("")
+	assert.Equal(t, maxGraphQLComplexity, complexity)
+
+	// parsing stops at the first field exceeding the depth
+	_, err = parseGraphQLDocument(strings.Repeat("{ a ", 100000)+"{ b", 2)
+	assert.Equal(t, errGraphQLTooDeep, err)
+	_, err = parseGraphQLDocument(`{ a { b } }`, 2)
+	assert.NoError(t, err)
+
+	for _, invalid := range []string{``, `{ a `, `query {`, `{ a(b: 1 }`, `{ a(b: "x) }`, `fragment F { a }`, `{ a % }`, `mutation`} {
+		_, err := parseGraphQLDocument(invalid, 0)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestGraphQLService(t *testing.T) {
+	_, err := NewGraphQLService(&GraphQLConfig{})
+	assert.True(t, errors.InstanceOf(err, ErrInvalidConfig))
+
+	service, err := NewGraphQLService(&GraphQLConfig{GraphiQL: true, MaxDepth: 2, MaxComplexity: 3, Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(fmt.Sprintf(`{"data":{"received":%q}}`, string(body))))
+	})})
+	errors.AssertNil(t, err)
+	errors.AssertNil(t, service.Ready())
+	server, err := NewServer(&ServerConfig{GinMode: gin.TestMode})
+	errors.AssertNil(t, err)
+	errors.AssertNil(t, server.RegisterService("graphql", service))
+
+	serve := func(method, target, contentType, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(method, target, strings.NewReader(body))
+		if len(contentType) > 0 {
+			r.Header.Set("Content-Type", contentType)
+		}
+		server.Handler().ServeHTTP(w, r)
+		return w
+	}
+
+	w := serve("POST", "/graphql", "application/json", `{"query":"{ user { name } }"}`)
+	assert.Equal(t, 200, w.Code)
+	assert.Contains(t, w.Body.String(), `user { name }`)
+
+	rejected := testutil.ToFloat64(graphQLRejectedQueries.WithLabelValues("depth"))
+	w = serve("POST", "/graphql", "application/json", `{"query":"{ user { friends { name } } }"}`)
+	assert.Equal(t, 400, w.Code)
+	assert.JSONEq(t, `{"errors":[{"message":"Query depth exceeds the limit of 2"}]}`, w.Body.String())
+	assert.Equal(t, rejected+1, testutil.ToFloat64(graphQLRejectedQueries.WithLabelValues("depth")))
+
+	// the depth of expanded fragments is only known after parsing
+	w = serve("POST", "/graphql", "application/json", `{"query":"{ user { ...F } } fragment F on User { friends { name } }"}`)
+	assert.Equal(t, 400, w.Code)
+	assert.JSONEq(t, `{"errors":[{"message":"Query depth 3 exceeds the limit of 2"}]}`, w.Body.String())
+
+	w = serve("POST", "/graphql", "application/graphql", "{ a "+strings.Repeat(" ", DefaultGraphQLMaxQuerySize)+"}")
+	assert.Equal(t, 413, w.Code)
+
+	w = serve("POST", "/graphql", "application/json", `[{"query":"{ a }"},{"query":"{ a b c d }"}]`)
+	assert.Equal(t, 400, w.Code)
+	assert.Contains(t, w.Body.String(), "Query complexity 4 exceeds the limit of 3")
+
+	assert.Equal(t, 400, serve("POST", "/graphql", "application/graphql", `{ a { b { c } } }`).Code)
+	assert.Equal(t, 200, serve("POST", "/graphql", "application/graphql", `{ a { b } }`).Code)
+	assert.Equal(t, 400, serve("POST", "/graphql", "application/json", `{"query":"{ a "}`).Code)
+	assert.Equal(t, 400, serve("POST", "/graphql", "application/json", `{`).Code)
+
+	assert.Equal(t, 400, serve("GET", "/graphql?query="+url.QueryEscape("{ a { b { c } } }"), "", "").Code)
+	assert.Equal(t, 200, serve("GET", "/graphql?query="+url.QueryEscape("{ a }"), "", "").Code)
+
+	w = httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/graphql", nil)
+	r.Header.Set("Accept", "text/html,application/xhtml+xml")
+	server.Handler().ServeHTTP(w, r)
+	assert.Equal(t, 200, w.Code)
+	assert.Contains(t, w.Body.String(), "GraphiQL")
+	assert.Contains(t, w.Body.String(), "url: '/graphql'")
+}
+
+func TestObserveGraphQLResolver(t *testing.T) {
+	registerGraphQLMetrics()
+	errs := testutil.ToFloat64(graphQLResolverErrors.WithLabelValues("User", "friends"))
+	ObserveGraphQLResolver("User", "friends", 5*time.Millisecond, nil)
+	ObserveGraphQLResolver("User", "friends", 5*time.Millisecond, fmt.Errorf("failed"))
+	assert.Equal(t, errs+1, testutil.ToFloat64(graphQLResolverErrors.WithLabelValues("User", "friends")))
+}
@@ -0,0 +1,135 @@
+package http
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	grpcHealthCheckPath = "/grpc.health.v1.Health/Check"
+	// grpcMaxHealthCheckSize limits the length of health check requests, which only contain a service name.
+	grpcMaxHealthCheckSize = 4 << 10
+
+	// see grpc.health.v1.HealthCheckResponse.ServingStatus
+	grpcServing        = 1
+	grpcNotServing     = 2
+	grpcServiceUnknown = 3
+)
+
+var (
+	errGRPCMessageTooLarge = fmt.Errorf("message too large")
+	errGRPCCompressed      = fmt.Errorf("compressed message")
+)
+
+// RegisterGRPC serves the given gRPC handler (e.g. a *grpc.Server) on the same listener as the gin engine. Requests are dispatched by content type and the listener accepts unencrypted HTTP/2 (h2c) in addition to HTTP/1. The standard gRPC health check is answered using the readiness of the registered services.
+func (server *Server) RegisterGRPC(handler http.Handler) {
+	server.grpcHandler = handler
+}
+
+func (server *Server) serveMux(w http.ResponseWriter, r *http.Request) {
+	if server.grpcHandler != nil && r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+		if r.URL.Path == grpcHealthCheckPath {
+			server.serveGRPCHealth(w, r)
+		} else {
+			server.grpcHandler.ServeHTTP(w, r)
+		}
+		return
+	}
+	if conn, ok := w.(io.ReaderFrom); ok {
+		r = r.WithContext(context.WithValue(r.Context(), sendfileConnKey{}, conn))
+	}
+	server.engine.ServeHTTP(w, r)
+}
+
+func (server *Server) serveGRPCHealth(w http.ResponseWriter, r *http.Request) {
+	msg, err := readGRPCMessage(r.Body, grpcMaxHealthCheckSize)
+	if err == errGRPCMessageTooLarge {
+		writeGRPCStatus(w, 8, "health check request exceeds the limit of "+strconv.Itoa(grpcMaxHealthCheckSize)+" bytes")
+		return
+	} else if err == errGRPCCompressed {
+		writeGRPCStatus(w, 12, "compressed health check requests are not supported")
+		return
+	} else if err != nil {
+		writeGRPCStatus(w, 13, "malformed health check request")
+		return
+	}
+
+	status := byte(grpcServing)
+	if server.isShuttingDown() {
+		status = grpcNotServing
+	} else if service := decodeHealthCheckService(msg); len(service) > 0 {
+		if s, ok := server.services[service]; !ok {
+			status = grpcServiceUnknown
+		} else if s.Ready() != nil {
+			status = grpcNotServing
+		}
+	} else {
+		for _, s := range server.services {
+			if s.Ready() != nil {
+				status = grpcNotServing
+				break
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/grpc")
+	w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+	w.WriteHeader(200)
+	// HealthCheckResponse{status: <status>} in protobuf wire format
+	w.Write(frameGRPCMessage([]byte{0x08, status}))
+	w.Header().Set("Grpc-Status", "0")
+	w.Header().Set("Grpc-Message", "")
+}
+
+func writeGRPCStatus(w http.ResponseWriter, code int, msg string) {
+	w.Header().Set("Content-Type", "application/grpc")
+	w.Header().Set("Grpc-Status", strconv.Itoa(code))
+	w.Header().Set("Grpc-Message", msg)
+	w.WriteHeader(200)
+}
+
+// readGRPCMessage reads the first length-prefixed message of r. Messages longer than maxSize result in errGRPCMessageTooLarge without being read, compressed messages in errGRPCCompressed.
+func readGRPCMessage(r io.Reader, maxSize uint32) ([]byte, error) {
+	var prefix [5]byte
+	if _, err := io.ReadFull(r, prefix[:]); err != nil {
+		return nil, err
+	}
+	if prefix[0] != 0 {
+		return nil, errGRPCCompressed
+	}
+	length := binary.BigEndian.Uint32(prefix[1:])
+	if length > maxSize {
+		return nil, errGRPCMessageTooLarge
+	}
+	msg := make([]byte, length)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return nil, err
+	}
+	io.Copy(ioutil.Discard, r)
+	return msg, nil
+}
+
+func frameGRPCMessage(msg []byte) []byte {
+	frame := make([]byte, 5+len(msg))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(msg)))
+	copy(frame[5:], msg)
+	return frame
+}
+
+// decodeHealthCheckService extracts field 1 (service) of a HealthCheckRequest.
+func decodeHealthCheckService(msg []byte) string {
+	if len(msg) < 2 || msg[0] != 0x0a {
+		return ""
+	}
+	length, n := binary.Uvarint(msg[1:])
+	if n <= 0 || 1+n+int(length) > len(msg) {
+		return ""
+	}
+	return string(msg[1+n : 1+n+int(length)])
+}
@@ -0,0 +1,109 @@
+package http
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/sbreitf1/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGRPCMultiplexing(t *testing.T) {
+	service := newTestService(t)
+	server, url := newTestServer()
+	server.RegisterService("test-service", service)
+	server.RegisterGRPC(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/grpc")
+		w.Header().Set("Grpc-Status", "12")
+		w.WriteHeader(200)
+	}))
+	if err := server.RunAsync(nil); err != nil {
+		panic(err)
+	}
+	defer server.Shutdown()
+
+	client := &http.Client{Transport: newH2CTransport()}
+
+	t.Run("HTTP/1 request", func(t *testing.T) {
+		resp, err := http.Get(url + "/healthz")
+		errors.AssertNil(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+	})
+
+	t.Run("gRPC request", func(t *testing.T) {
+		resp, err := grpcCall(client, url+"/some.Service/Method", nil)
+		errors.AssertNil(t, err)
+		assert.Equal(t, 2, resp.ProtoMajor)
+		assert.Equal(t, "12", resp.Header.Get("Grpc-Status"))
+	})
+
+	t.Run("gRPC health serving", func(t *testing.T) {
+		resp, err := grpcCall(client, url+grpcHealthCheckPath, []byte{})
+		errors.AssertNil(t, err)
+		msg, err := readGRPCMessage(resp.Body, grpcMaxHealthCheckSize)
+		errors.AssertNil(t, err)
+		assert.Equal(t, []byte{0x08, grpcServing}, msg)
+	})
+
+	t.Run("gRPC health not serving", func(t *testing.T) {
+		service.Readiness = errors.GenericError.Make()
+		defer func() { service.Readiness = nil }()
+		resp, err := grpcCall(client, url+grpcHealthCheckPath, append([]byte{0x0a, 12}, "test-service"...))
+		errors.AssertNil(t, err)
+		msg, err := readGRPCMessage(resp.Body, grpcMaxHealthCheckSize)
+		errors.AssertNil(t, err)
+		assert.Equal(t, []byte{0x08, grpcNotServing}, msg)
+	})
+
+	t.Run("gRPC health unknown service", func(t *testing.T) {
+		resp, err := grpcCall(client, url+grpcHealthCheckPath, append([]byte{0x0a, 3}, "foo"...))
+		errors.AssertNil(t, err)
+		msg, err := readGRPCMessage(resp.Body, grpcMaxHealthCheckSize)
+		errors.AssertNil(t, err)
+		assert.Equal(t, []byte{0x08, grpcServiceUnknown}, msg)
+	})
+
+	t.Run("gRPC health too large", func(t *testing.T) {
+		// only the length prefix is sent, the message is never allocated
+		frame := frameGRPCMessage(nil)
+		binary.BigEndian.PutUint32(frame[1:], 1<<31)
+		req, _ := http.NewRequest("POST", url+grpcHealthCheckPath, bytes.NewReader(frame))
+		req.Header.Set("Content-Type", "application/grpc")
+		resp, err := client.Do(req)
+		errors.AssertNil(t, err)
+		resp.Body.Close()
+		assert.Equal(t, "8", resp.Header.Get("Grpc-Status"))
+	})
+
+	t.Run("gRPC health compressed", func(t *testing.T) {
+		frame := frameGRPCMessage([]byte{0x0a, 3})
+		frame[0] = 1
+		req, _ := http.NewRequest("POST", url+grpcHealthCheckPath, bytes.NewReader(frame))
+		req.Header.Set("Content-Type", "application/grpc")
+		resp, err := client.Do(req)
+		errors.AssertNil(t, err)
+		resp.Body.Close()
+		assert.Equal(t, "12", resp.Header.Get("Grpc-Status"))
+	})
+}
+
+func grpcCall(client *http.Client, url string, msg []byte) (*http.Response, error) {
+	req, _ := http.NewRequest("POST", url, bytes.NewReader(frameGRPCMessage(msg)))
+	req.Header.Set("Content-Type", "application/grpc")
+	resp, err := client.Do(req)
+	if err == nil {
+		data, _ := ioutil.ReadAll(resp.Body)
+		resp.Body = ioutil.NopCloser(bytes.NewReader(data))
+	}
+	return resp, err
+}
+
+func newH2CTransport() *http.Transport {
+	transport := &http.Transport{}
+	transport.Protocols = new(http.Protocols)
+	transport.Protocols.SetUnencryptedHTTP2(true)
+	return transport
+}
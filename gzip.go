@@ -0,0 +1,91 @@
+package http
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/sbreitf1/errors"
+)
+
+// gzipReadCloser decompresses a gzip encoded response body and closes both the reader and the underlying body.
+type gzipReadCloser struct {
+	body   io.ReadCloser
+	reader *gzip.Reader
+}
+
+func (r *gzipReadCloser) Read(p []byte) (int, error) {
+	if r.reader == nil {
+		reader, err := gzip.NewReader(r.body)
+		if err != nil {
+			return 0, err
+		}
+		r.reader = reader
+	}
+	return r.reader.Read(p)
+}
+
+func (r *gzipReadCloser) Close() error {
+	if r.reader != nil {
+		r.reader.Close()
+	}
+	return r.body.Close()
+}
+
+// GzipInterceptor returns an interceptor that requests gzip encoded responses and decompresses them transparently. Requests that already define an Accept-Encoding header or request a byte range are not modified and receive the raw response.
+func GzipInterceptor() Interceptor {
+	return func(next Responder) Responder {
+		return func(req *Request) (*Response, errors.Error) {
+			if len(req.Header.Get("Accept-Encoding")) > 0 || len(req.Header.Get("Range")) > 0 {
+				return next(req)
+			}
+			// the header is set on a copy, so retries of the same request are decompressed as well
+			encodedReq := *req
+			encodedReq.Header = req.Header.Clone()
+			encodedReq.Header.Set("Accept-Encoding", "gzip")
+
+			response, err := next(&encodedReq)
+			if err != nil {
+				return nil, err
+			}
+			if strings.EqualFold(response.Header.Get("Content-Encoding"), "gzip") && response.Body != nil {
+				response.Body = &gzipReadCloser{body: response.Body}
+				response.Header.Del("Content-Encoding")
+				response.Header.Del("Content-Length")
+				response.ContentLength = -1
+				response.Uncompressed = true
+			}
+			return response, nil
+		}
+	}
+}
+
+// compressRequestBody replaces the request body with its gzip compressed form. Bodies that already define a Content-Encoding are sent unchanged.
+func compressRequestBody(req *Request) errors.Error {
+	if req.Body == nil || len(req.Header.Get("Content-Encoding")) > 0 {
+		return nil
+	}
+	data, err := ioutil.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return ErrInvalidBody.Make().Cause(err)
+	}
+
+	var buffer bytes.Buffer
+	writer := gzip.NewWriter(&buffer)
+	writer.Write(data)
+	if err := writer.Close(); err != nil {
+		return ErrInvalidBody.Make().Cause(err)
+	}
+
+	compressed := buffer.Bytes()
+	req.Body = ioutil.NopCloser(bytes.NewReader(compressed))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(compressed)), nil
+	}
+	req.ContentLength = int64(len(compressed))
+	req.Header.Set("Content-Encoding", "gzip")
+	return nil
+}
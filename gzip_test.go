@@ -0,0 +1,96 @@
+package http
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sbreitf1/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGzip(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := r.Body
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			body, _ = gzip.NewReader(r.Body)
+		}
+		data, _ := ioutil.ReadAll(body)
+		if len(data) == 0 {
+			data = []byte("hello world")
+		}
+
+		if r.Header.Get("Accept-Encoding") == "gzip" {
+			w.Header().Set("Content-Encoding", "gzip")
+			writer := gzip.NewWriter(w)
+			writer.Write(data)
+			writer.Close()
+			return
+		}
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	t.Run("Decompress", func(t *testing.T) {
+		response, err := NewClient().Get(server.URL, nil)
+		errors.AssertNil(t, err)
+		assert.Empty(t, response.Header.Get("Content-Encoding"))
+		assert.True(t, response.Uncompressed)
+		str, err := ReadResponseString(response)
+		errors.AssertNil(t, err)
+		assert.Equal(t, "hello world", str)
+	})
+
+	t.Run("Raw", func(t *testing.T) {
+		response, err := NewClient().Get(server.URL, WithHeader("Accept-Encoding", "gzip"))
+		errors.AssertNil(t, err)
+		assert.Equal(t, "gzip", response.Header.Get("Content-Encoding"))
+		DrainAndClose(response)
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		client := NewClient()
+		client.DisableCompression = true
+		response, err := client.Get(server.URL, nil)
+		errors.AssertNil(t, err)
+		assert.Empty(t, response.Header.Get("Content-Encoding"))
+		assert.False(t, response.Uncompressed)
+		DrainAndClose(response)
+	})
+
+	t.Run("CompressRequests", func(t *testing.T) {
+		client := NewClient()
+		client.CompressRequests = true
+		client.DisableCompression = true
+		response, err := client.Post(server.URL, "text/plain", strings.NewReader("compressed request"), nil)
+		errors.AssertNil(t, err)
+		str, _ := ReadResponseString(response)
+		assert.Equal(t, "compressed request", str)
+	})
+}
+
+func TestGzipRetry(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.WriteHeader(503)
+			return
+		}
+		w.Header().Set("Content-Encoding", "gzip")
+		writer := gzip.NewWriter(w)
+		writer.Write([]byte("retried"))
+		writer.Close()
+	}))
+	defer server.Close()
+
+	client := NewClient()
+	client.Retry = &RetryPolicy{MaxAttempts: 2}
+	response, err := client.Get(server.URL, nil)
+	errors.AssertNil(t, err)
+	str, _ := ReadResponseString(response)
+	assert.Equal(t, "retried", str)
+}
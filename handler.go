@@ -0,0 +1,70 @@
+package http
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sbreitf1/errors"
+)
+
+var (
+	// ErrInvalidRoute occurs when a handler should be mounted using a malformed route prefix.
+	ErrInvalidRoute = errors.New("Invalid route")
+)
+
+// Probe contributes a health or readiness check to a service registered via RegisterHandler.
+type Probe struct {
+	health bool
+	check  func() errors.Error
+}
+
+// HealthProbe returns a probe that is evaluated by the /healthz endpoint.
+func HealthProbe(check func() errors.Error) Probe {
+	return Probe{true, check}
+}
+
+// ReadinessProbe returns a probe that is evaluated by the /readiness endpoint.
+func ReadinessProbe(check func() errors.Error) Probe {
+	return Probe{false, check}
+}
+
+// RegisterHandler mounts a plain net/http handler below the given path prefix. The prefix is stripped from the request path before the handler is called. Optional probes are included in the server probe endpoints using the given name.
+func (server *Server) RegisterHandler(name, prefix string, handler http.Handler, probes ...Probe) errors.Error {
+	prefix = "/" + strings.Trim(prefix, "/")
+	if prefix == "/" {
+		return ErrInvalidRoute.Msg("Handler %q requires a non-root prefix", name).Make()
+	}
+	return server.RegisterService(name, &handlerService{prefix, handler, probes})
+}
+
+type handlerService struct {
+	prefix  string
+	handler http.Handler
+	probes  []Probe
+}
+
+func (svc *handlerService) RegisterRoutes(e *gin.Engine) {
+	h := gin.WrapH(http.StripPrefix(svc.prefix, svc.handler))
+	e.Any(svc.prefix, h)
+	e.Any(svc.prefix+"/*path", h)
+}
+func (svc *handlerService) BeginServing() {}
+func (svc *handlerService) StopServing()  {}
+func (svc *handlerService) Healthy() errors.Error {
+	return svc.evaluate(true)
+}
+func (svc *handlerService) Ready() errors.Error {
+	return svc.evaluate(false)
+}
+
+func (svc *handlerService) evaluate(health bool) errors.Error {
+	for _, p := range svc.probes {
+		if p.health == health {
+			if err := p.check(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
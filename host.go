@@ -0,0 +1,30 @@
+package http
+
+import (
+	"context"
+
+	"github.com/sbreitf1/errors"
+)
+
+type serverNameContextKey struct{}
+
+// WithHost returns a request callback that sends the given Host header instead of the host of the request URL.
+func WithHost(host string) func(*Request) errors.Error {
+	return func(req *Request) errors.Error {
+		req.Host = host
+		return nil
+	}
+}
+
+// WithServerName returns a request callback that uses the given name for TLS server name indication and certificate verification instead of the host of the request URL. Requests with server name override use separate connections.
+func WithServerName(serverName string) func(*Request) errors.Error {
+	return func(req *Request) errors.Error {
+		*req = *req.WithContext(context.WithValue(req.Context(), serverNameContextKey{}, serverName))
+		return nil
+	}
+}
+
+func serverNameFromContext(ctx context.Context) string {
+	serverName, _ := ctx.Value(serverNameContextKey{}).(string)
+	return serverName
+}
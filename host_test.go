@@ -0,0 +1,36 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sbreitf1/errors"
+)
+
+func TestHostAndServerName(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Host + "|" + r.TLS.ServerName))
+	}))
+	defer server.Close()
+
+	client := NewClient()
+	client.RootCAs = server.Client().Transport.(*http.Transport).TLSClientConfig.RootCAs
+
+	t.Run("Default", func(t *testing.T) {
+		response, err := client.Get(server.URL, nil)
+		errors.AssertNil(t, err)
+		assertResponse(t, 200, server.Listener.Addr().String()+"|", response)
+	})
+
+	t.Run("Override", func(t *testing.T) {
+		response, err := client.Get(server.URL, Chain(WithHost("api.test"), WithServerName("example.com")))
+		errors.AssertNil(t, err)
+		assertResponse(t, 200, "api.test|example.com", response)
+	})
+
+	t.Run("Verification", func(t *testing.T) {
+		_, err := client.Get(server.URL, WithServerName("other.test"))
+		errors.Assert(t, ErrRequestFailed, err)
+	})
+}
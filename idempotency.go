@@ -0,0 +1,197 @@
+package http
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// HeaderIdempotentReplayed is set on responses that have been replayed for a duplicate idempotency key.
+	HeaderIdempotentReplayed = "Idempotent-Replayed"
+)
+
+// IdempotencyConfig contains the parameters of an idempotency middleware.
+type IdempotencyConfig struct {
+	// Storage keeps the responses of completed requests. Use a shared store like RedisStore to deduplicate requests across replicas. Defaults to an in-memory storage.
+	Storage Store `json:"-"`
+	// Header denotes the request header carrying the idempotency key. Defaults to "Idempotency-Key".
+	Header string `json:"header,omitempty"`
+	// Methods lists the request methods that are deduplicated. Defaults to POST and PATCH.
+	Methods []string `json:"methods,omitempty"`
+	// TTL denotes how long responses are replayed for duplicate keys. Defaults to 24 hours.
+	TTL Duration `json:"ttl,omitempty"`
+	// LockTimeout limits how long a request in progress blocks duplicates, e.g. if a replica crashes while handling it. Defaults to 1 minute.
+	LockTimeout Duration `json:"lockTimeout,omitempty"`
+	// Scope optionally returns the principal of a request (e.g. user ID), so keys of different principals do not collide.
+	Scope func(*gin.Context) string `json:"-"`
+}
+
+type idempotencyRecord struct {
+	Fingerprint string      `json:"fingerprint"`
+	StatusCode  int         `json:"status"`
+	Header      http.Header `json:"header"`
+	Body        []byte      `json:"body"`
+}
+
+type idempotency struct {
+	config IdempotencyConfig
+}
+
+// NewIdempotencyMiddleware returns a middleware that stores the responses of requests carrying an idempotency key and replays them for duplicate submissions within the TTL, e.g. to protect payment endpoints against retried requests. Duplicates arriving while the original request is in progress receive 409 Conflict, reusing a key with a different request body results in 422 Unprocessable Entity. Server errors, 408 Request Timeout and 429 Too Many Requests are not stored, so the request can be retried, e.g. after it has been rejected by a rate limiter or admission queue placed after this middleware.
+func NewIdempotencyMiddleware(config *IdempotencyConfig) gin.HandlerFunc {
+	i := &idempotency{config: *config}
+	if i.config.Storage == nil {
+		i.config.Storage = NewMemoryCacheStorage()
+	}
+	if len(i.config.Header) == 0 {
+		i.config.Header = "Idempotency-Key"
+	}
+	if len(i.config.Methods) == 0 {
+		i.config.Methods = []string{"POST", "PATCH"}
+	}
+	if i.config.TTL <= 0 {
+		i.config.TTL = Duration(24 * time.Hour)
+	}
+	if i.config.LockTimeout <= 0 {
+		i.config.LockTimeout = Duration(time.Minute)
+	}
+	return i.handle
+}
+
+func (i *idempotency) handle(c *gin.Context) {
+	key := c.GetHeader(i.config.Header)
+	if len(key) == 0 || !i.appliesTo(c.Request.Method) {
+		return
+	}
+
+	body, err := ioutil.ReadAll(c.Request.Body)
+	if err != nil {
+		c.AbortWithStatus(400)
+		return
+	}
+	c.Request.Body = readCloser{bytes.NewReader(body), c.Request.Body}
+	fingerprint := i.fingerprint(c.Request, body)
+
+	scope := ""
+	if i.config.Scope != nil {
+		scope = i.config.Scope(c)
+	}
+	storageKey := "idempotency|" + scope + "|" + c.Request.Method + "|" + c.Request.URL.Path + "|" + key
+
+	// the lock holds a unique token, so only this request releases it even if it expired in the meantime
+	owner, uuidErr := newUUID()
+	if uuidErr != nil {
+		c.AbortWithStatus(500)
+		return
+	}
+	locked, lockErr := i.config.Storage.SetIfAbsent(storageKey+"|lock", []byte(owner), time.Duration(i.config.LockTimeout))
+	if lockErr != nil {
+		// duplicates cannot be detected without store
+		c.AbortWithStatus(503)
+		return
+	} else if !locked {
+		c.AbortWithStatus(409)
+		return
+	}
+	defer i.unlock(storageKey+"|lock", []byte(owner))
+
+	if record, ok := i.load(storageKey); ok {
+		if record.Fingerprint != fingerprint {
+			c.AbortWithStatus(422)
+			return
+		}
+		for name, values := range record.Header {
+			c.Writer.Header()[name] = values
+		}
+		c.Header(HeaderIdempotentReplayed, "true")
+		c.Writer.WriteHeader(record.StatusCode)
+		c.Writer.Write(record.Body)
+		c.Abort()
+		return
+	}
+
+	recorder := &responseRecorder{ResponseWriter: c.Writer}
+	c.Writer = recorder
+	c.Next()
+	c.Writer = recorder.ResponseWriter
+
+	if status := recorder.Status(); storableIdempotentStatus(status) {
+		record := idempotencyRecord{
+			Fingerprint: fingerprint,
+			StatusCode:  status,
+			Header:      recorder.Header().Clone(),
+			Body:        recorder.body.Bytes(),
+		}
+		if data, err := json.Marshal(record); err == nil {
+			i.config.Storage.SetWithTTL(storageKey, data, time.Duration(i.config.TTL))
+		}
+	}
+}
+
+// storableIdempotentStatus reports whether a response with the given status is the final result of a request. Server errors and rejections asking the client to retry later are not.
+func storableIdempotentStatus(status int) bool {
+	return status < 500 && status != 408 && status != 429
+}
+
+// unlock releases the lock of key if it is still held by owner.
+func (i *idempotency) unlock(key string, owner []byte) {
+	if store, ok := i.config.Storage.(ConditionalStore); ok {
+		store.DeleteIfEqual(key, owner)
+		return
+	}
+	// not atomic, but a lock acquired by another request is only removed if it expires right in between
+	if data, ok := i.config.Storage.Get(key); ok && bytes.Equal(data, owner) {
+		i.config.Storage.Delete(key)
+	}
+}
+
+func (i *idempotency) appliesTo(method string) bool {
+	for _, m := range i.config.Methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+// fingerprint identifies the request payload to detect reuse of a key for a different request.
+func (i *idempotency) fingerprint(req *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, req.URL.RawQuery+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// load returns the stored record of key.
+func (i *idempotency) load(key string) (idempotencyRecord, bool) {
+	var record idempotencyRecord
+	data, ok := i.config.Storage.Get(key)
+	if !ok || json.Unmarshal(data, &record) != nil {
+		return record, false
+	}
+	return record, true
+}
+
+// responseRecorder passes the response to the underlying writer and keeps a copy of the body.
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
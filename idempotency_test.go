@@ -0,0 +1,176 @@
+package http
+
+import (
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdempotencyMiddleware(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	started := make(chan struct{}, 1)
+	engine := gin.New()
+	engine.Use(NewIdempotencyMiddleware(&IdempotencyConfig{
+		TTL:   Duration(time.Hour),
+		Scope: func(c *gin.Context) string { return c.GetHeader("X-User") },
+	}))
+	engine.POST("/payments", func(c *gin.Context) {
+		n := atomic.AddInt32(&calls, 1)
+		if c.Query("wait") == "1" {
+			started <- struct{}{}
+			<-release
+		}
+		c.Header("Location", "/payments/1")
+		c.JSON(201, gin.H{"call": n})
+	})
+	engine.POST("/failing", func(c *gin.Context) {
+		atomic.AddInt32(&calls, 1)
+		c.Status(500)
+	})
+
+	serve := func(path, key, user, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", path, strings.NewReader(body))
+		if len(key) > 0 {
+			req.Header.Set("Idempotency-Key", key)
+		}
+		req.Header.Set("X-User", user)
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("replay", func(t *testing.T) {
+		first := serve("/payments", "k1", "alice", `{"amount":1}`)
+		assert.Equal(t, 201, first.Code)
+		second := serve("/payments", "k1", "alice", `{"amount":1}`)
+		assert.Equal(t, 201, second.Code)
+		assert.Equal(t, first.Body.String(), second.Body.String())
+		assert.Equal(t, "/payments/1", second.Header().Get("Location"))
+		assert.Equal(t, "true", second.Header().Get(HeaderIdempotentReplayed))
+		assert.Empty(t, first.Header().Get(HeaderIdempotentReplayed))
+	})
+
+	t.Run("different payload", func(t *testing.T) {
+		assert.Equal(t, 422, serve("/payments", "k1", "alice", `{"amount":2}`).Code)
+	})
+
+	t.Run("scoped keys", func(t *testing.T) {
+		before := atomic.LoadInt32(&calls)
+		assert.Equal(t, 201, serve("/payments", "k1", "bob", `{"amount":2}`).Code)
+		assert.Equal(t, before+1, atomic.LoadInt32(&calls))
+	})
+
+	t.Run("without key", func(t *testing.T) {
+		before := atomic.LoadInt32(&calls)
+		serve("/payments", "", "alice", "")
+		serve("/payments", "", "alice", "")
+		assert.Equal(t, before+2, atomic.LoadInt32(&calls))
+	})
+
+	t.Run("server errors are not stored", func(t *testing.T) {
+		before := atomic.LoadInt32(&calls)
+		assert.Equal(t, 500, serve("/failing", "k2", "alice", "").Code)
+		assert.Equal(t, 500, serve("/failing", "k2", "alice", "").Code)
+		assert.Equal(t, before+2, atomic.LoadInt32(&calls))
+	})
+
+	t.Run("in progress", func(t *testing.T) {
+		done := make(chan int)
+		go func() {
+			done <- serve("/payments?wait=1", "k3", "alice", "").Code
+		}()
+		<-started
+		assert.Equal(t, 409, serve("/payments?wait=1", "k3", "alice", "").Code)
+		close(release)
+		assert.Equal(t, 201, <-done)
+		assert.Equal(t, "true", serve("/payments?wait=1", "k3", "alice", "").Header().Get(HeaderIdempotentReplayed))
+	})
+}
+
+func TestIdempotencyExpiry(t *testing.T) {
+	storage := NewMemoryCacheStorage()
+	engine := gin.New()
+	engine.Use(NewIdempotencyMiddleware(&IdempotencyConfig{Storage: storage, TTL: Duration(time.Millisecond)}))
+	engine.PATCH("/items", func(c *gin.Context) { c.Status(204) })
+
+	serve := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("PATCH", "/items", nil)
+		req.Header.Set("Idempotency-Key", "k")
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		return w
+	}
+	serve()
+	time.Sleep(5 * time.Millisecond)
+	assert.Empty(t, serve().Header().Get(HeaderIdempotentReplayed))
+}
+
+func TestIdempotencyLockTimeout(t *testing.T) {
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	engine := gin.New()
+	engine.Use(NewIdempotencyMiddleware(&IdempotencyConfig{LockTimeout: Duration(10 * time.Millisecond)}))
+	engine.POST("/payments", func(c *gin.Context) {
+		if c.Query("wait") == "1" {
+			started <- struct{}{}
+			<-release
+		}
+		c.Status(503)
+	})
+
+	serve := func(path string) int {
+		req := httptest.NewRequest("POST", path, nil)
+		req.Header.Set("Idempotency-Key", "k")
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		return w.Code
+	}
+	done := make(chan int, 2)
+	go func() { done <- serve("/payments?wait=1") }()
+	<-started
+	time.Sleep(20 * time.Millisecond)
+
+	// the expired lock is acquired by a duplicate and must not be released by the first request
+	go func() { done <- serve("/payments?wait=1") }()
+	<-started
+	release <- struct{}{}
+	assert.Equal(t, 503, <-done)
+	assert.Equal(t, 409, serve("/payments"))
+
+	release <- struct{}{}
+	assert.Equal(t, 503, <-done)
+	assert.Equal(t, 503, serve("/payments"))
+}
+
+func TestIdempotencyRetryableRejection(t *testing.T) {
+	var rejected int32
+	engine := gin.New()
+	engine.Use(NewIdempotencyMiddleware(&IdempotencyConfig{}))
+	// a rate limiter behind the idempotency middleware rejects the first attempt
+	engine.Use(func(c *gin.Context) {
+		if atomic.AddInt32(&rejected, 1) == 1 {
+			c.Header("Retry-After", "1")
+			c.AbortWithStatus(429)
+		}
+	})
+	engine.POST("/payments", func(c *gin.Context) { c.Status(201) })
+
+	serve := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/payments", nil)
+		req.Header.Set("Idempotency-Key", "k")
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		return w
+	}
+	assert.Equal(t, 429, serve().Code)
+	retry := serve()
+	assert.Equal(t, 201, retry.Code)
+	assert.Empty(t, retry.Header().Get(HeaderIdempotentReplayed))
+	assert.Equal(t, "true", serve().Header().Get(HeaderIdempotentReplayed))
+}
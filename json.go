@@ -0,0 +1,84 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+
+	"github.com/sbreitf1/errors"
+)
+
+const (
+	// maxErrorBodySnippet limits the number of response body bytes included in status errors.
+	maxErrorBodySnippet = 512
+)
+
+// GetJSON sends a GET request and decodes the JSON response into out.
+func (client *Client) GetJSON(url string, out interface{}, f func(*Request) errors.Error) errors.Error {
+	return client.DoJSON(MethodGet, url, nil, out, f)
+}
+
+// PostJSON sends in as JSON body using a POST request and decodes the JSON response into out.
+func (client *Client) PostJSON(url string, in, out interface{}, f func(*Request) errors.Error) errors.Error {
+	return client.DoJSON(MethodPost, url, in, out, f)
+}
+
+// PutJSON sends in as JSON body using a PUT request and decodes the JSON response into out.
+func (client *Client) PutJSON(url string, in, out interface{}, f func(*Request) errors.Error) errors.Error {
+	return client.DoJSON(MethodPut, url, in, out, f)
+}
+
+// PatchJSON sends in as JSON body using a PATCH request and decodes the JSON response into out.
+func (client *Client) PatchJSON(url string, in, out interface{}, f func(*Request) errors.Error) errors.Error {
+	return client.DoJSON(MethodPatch, url, in, out, f)
+}
+
+// DeleteJSON sends a DELETE request and decodes the JSON response into out.
+func (client *Client) DeleteJSON(url string, out interface{}, f func(*Request) errors.Error) errors.Error {
+	return client.DoJSON(MethodDelete, url, nil, out, f)
+}
+
+// DoJSON sends a request with in encoded as JSON body and decodes the JSON response into out. The body is omitted if in is nil and the response is discarded if out is nil. Responses with non-2xx status codes are returned as ErrUnexpectedStatus.
+func (client *Client) DoJSON(method RequestMethod, url string, in, out interface{}, f func(*Request) errors.Error) errors.Error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return ErrInvalidBody.Make().Cause(err)
+		}
+		body = bytes.NewReader(data)
+	}
+
+	response, err := client.do(context.Background(), method, url, body, func(req *Request) errors.Error {
+		if in != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		req.Header.Set("Accept", "application/json")
+		if f != nil {
+			return f(req)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	defer DrainAndClose(response)
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return unexpectedStatus(response)
+	}
+	if out != nil {
+		return DecodeJSON(response, out)
+	}
+	return nil
+}
+
+func unexpectedStatus(response *Response) errors.Error {
+	snippet, _ := ioutil.ReadAll(io.LimitReader(response.Body, maxErrorBodySnippet))
+	if len(snippet) > 0 {
+		return ErrUnexpectedStatus.Msg("Unexpected status code %d: %s", response.StatusCode, string(snippet)).ErrCode(response.StatusCode).Make()
+	}
+	return ErrUnexpectedStatus.Msg("Unexpected status code %d", response.StatusCode).ErrCode(response.StatusCode).Make()
+}
@@ -0,0 +1,71 @@
+package http
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sbreitf1/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type testUser struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func TestJSON(t *testing.T) {
+	assert.Equal(t, 4, withTestServer(func(url string, f *gin.HandlerFunc) {
+		client := NewClient()
+
+		t.Run("GetJSON", func(t *testing.T) {
+			*f = func(c *gin.Context) {
+				assert.Equal(t, "GET", c.Request.Method)
+				assert.Equal(t, "application/json", c.Request.Header.Get("Accept"))
+				c.JSON(200, testUser{42, "Arthur"})
+			}
+			var user testUser
+			errors.AssertNil(t, client.GetJSON(url+"/users/42", &user, nil))
+			assert.Equal(t, testUser{42, "Arthur"}, user)
+		})
+
+		t.Run("PostJSON", func(t *testing.T) {
+			*f = func(c *gin.Context) {
+				assert.Equal(t, "POST", c.Request.Method)
+				assert.Equal(t, "application/json", c.Request.Header.Get("Content-Type"))
+				var user testUser
+				assert.NoError(t, c.BindJSON(&user))
+				user.ID = 7
+				c.JSON(201, user)
+			}
+			var user testUser
+			errors.AssertNil(t, client.PostJSON(url+"/users", testUser{Name: "Ford"}, &user, nil))
+			assert.Equal(t, testUser{7, "Ford"}, user)
+		})
+
+		t.Run("DeleteJSON without content", func(t *testing.T) {
+			*f = func(c *gin.Context) {
+				assert.Equal(t, "DELETE", c.Request.Method)
+				c.Status(204)
+			}
+			var user testUser
+			errors.AssertNil(t, client.DeleteJSON(url+"/users/7", &user, nil))
+		})
+
+		t.Run("Unexpected status", func(t *testing.T) {
+			*f = func(c *gin.Context) {
+				data, _ := ioutil.ReadAll(c.Request.Body)
+				assert.Equal(t, `{"id":0,"name":""}`, string(data))
+				c.String(409, "already exists")
+			}
+			err := client.PutJSON(url+"/users/1", testUser{}, nil, nil)
+			errors.Assert(t, ErrUnexpectedStatus, err)
+			assert.Contains(t, err.Error(), "409: already exists")
+		})
+
+		t.Run("Invalid input", func(t *testing.T) {
+			*f = nil
+			errors.Assert(t, ErrInvalidBody, client.PostJSON(url+"/users", make(chan int), nil, nil))
+		})
+	}))
+}
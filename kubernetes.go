@@ -0,0 +1,137 @@
+package http
+
+import (
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
+	log "github.com/sirupsen/logrus"
+)
+
+// serviceAccountNamespaceFile is mounted into every pod with a service account token.
+var serviceAccountNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+var (
+	kubernetesLogHookOnce sync.Once
+	kubernetesLogMutex    sync.RWMutex
+	kubernetesLogFields   log.Fields
+)
+
+// KubernetesMetadata identifies the pod running the server. It is added to all log entries and metrics of the server to distinguish replicas.
+type KubernetesMetadata struct {
+	// PodName defaults to the environment variable POD_NAME or the host name, which equals the pod name unless overridden in the pod spec.
+	PodName string `json:"podName,omitempty"`
+	// Namespace defaults to the environment variable POD_NAMESPACE or the namespace of the mounted service account.
+	Namespace string `json:"namespace,omitempty"`
+	// NodeName defaults to the environment variable NODE_NAME.
+	NodeName string `json:"nodeName,omitempty"`
+}
+
+// KubernetesMetadataFromEnv returns the metadata exposed to the pod. Use the downward API to provide the environment variables POD_NAME, POD_NAMESPACE and NODE_NAME from the fields metadata.name, metadata.namespace and spec.nodeName.
+func KubernetesMetadataFromEnv() KubernetesMetadata {
+	return KubernetesMetadata{}.withDefaults()
+}
+
+// withDefaults fills empty fields from the environment.
+func (m KubernetesMetadata) withDefaults() KubernetesMetadata {
+	if len(m.PodName) == 0 {
+		m.PodName = os.Getenv("POD_NAME")
+	}
+	if len(m.PodName) == 0 {
+		m.PodName, _ = os.Hostname()
+	}
+	if len(m.Namespace) == 0 {
+		m.Namespace = os.Getenv("POD_NAMESPACE")
+	}
+	if len(m.Namespace) == 0 {
+		if data, err := ioutil.ReadFile(serviceAccountNamespaceFile); err == nil {
+			m.Namespace = strings.TrimSpace(string(data))
+		}
+	}
+	if len(m.NodeName) == 0 {
+		m.NodeName = os.Getenv("NODE_NAME")
+	}
+	return m
+}
+
+// labels returns the non-empty fields keyed by the label names "pod", "namespace" and "node".
+func (m KubernetesMetadata) labels() map[string]string {
+	labels := make(map[string]string)
+	for name, value := range map[string]string{"pod": m.PodName, "namespace": m.Namespace, "node": m.NodeName} {
+		if len(value) > 0 {
+			labels[name] = value
+		}
+	}
+	return labels
+}
+
+// setKubernetesLogFields adds the metadata to all subsequent log entries of the standard logger and the access log.
+func setKubernetesLogFields(m KubernetesMetadata) {
+	kubernetesLogHookOnce.Do(func() {
+		addLogHook(kubernetesLogHook{})
+	})
+	fields := make(log.Fields)
+	for name, value := range m.labels() {
+		fields[name] = value
+	}
+	kubernetesLogMutex.Lock()
+	defer kubernetesLogMutex.Unlock()
+	kubernetesLogFields = fields
+}
+
+type kubernetesLogHook struct{}
+
+func (kubernetesLogHook) Levels() []log.Level {
+	return log.AllLevels
+}
+
+// Fire adds the metadata fields that are not already set by the entry.
+func (kubernetesLogHook) Fire(entry *log.Entry) error {
+	kubernetesLogMutex.RLock()
+	defer kubernetesLogMutex.RUnlock()
+	for name, value := range kubernetesLogFields {
+		if _, ok := entry.Data[name]; !ok {
+			entry.Data[name] = value
+		}
+	}
+	return nil
+}
+
+// kubernetesGatherer adds labels to all metrics of another gatherer that do not already have a label of the same name.
+type kubernetesGatherer struct {
+	prometheus.Gatherer
+	labels map[string]string
+}
+
+func (g kubernetesGatherer) Gather() ([]*dto.MetricFamily, error) {
+	families, err := g.Gatherer.Gather()
+	for _, family := range families {
+		for _, metric := range family.Metric {
+			existing := make(map[string]bool, len(metric.Label))
+			for _, pair := range metric.Label {
+				existing[pair.GetName()] = true
+			}
+			for name, value := range g.labels {
+				if !existing[name] {
+					name, value := name, value
+					metric.Label = append(metric.Label, &dto.LabelPair{Name: &name, Value: &value})
+				}
+			}
+			sort.Slice(metric.Label, func(i, j int) bool {
+				return metric.Label[i].GetName() < metric.Label[j].GetName()
+			})
+		}
+	}
+	return families, err
+}
+
+// kubernetesMetricsHandler serves the metrics of the default registry labeled with the metadata.
+func kubernetesMetricsHandler(m KubernetesMetadata) gin.HandlerFunc {
+	return gin.WrapH(promhttp.HandlerFor(kubernetesGatherer{Gatherer: prometheus.DefaultGatherer, labels: m.labels()}, promhttp.HandlerOpts{}))
+}
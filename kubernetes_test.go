@@ -0,0 +1,64 @@
+package http
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sbreitf1/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKubernetesMetadataFromEnv(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "namespace")
+	assert.NoError(t, ioutil.WriteFile(file, []byte("prod\n"), 0644))
+	defer func(original string) { serviceAccountNamespaceFile = original }(serviceAccountNamespaceFile)
+	serviceAccountNamespaceFile = file
+
+	t.Setenv("POD_NAME", "api-7d9f-x2x4k")
+	t.Setenv("POD_NAMESPACE", "")
+	t.Setenv("NODE_NAME", "node-1")
+	assert.Equal(t, KubernetesMetadata{PodName: "api-7d9f-x2x4k", Namespace: "prod", NodeName: "node-1"}, KubernetesMetadataFromEnv())
+
+	t.Setenv("POD_NAME", "")
+	t.Setenv("POD_NAMESPACE", "staging")
+	hostname, _ := os.Hostname()
+	metadata := KubernetesMetadata{NodeName: "node-2"}.withDefaults()
+	assert.Equal(t, KubernetesMetadata{PodName: hostname, Namespace: "staging", NodeName: "node-2"}, metadata)
+}
+
+func TestKubernetesMetadata(t *testing.T) {
+	var buffer bytes.Buffer
+	defer log.SetOutput(log.StandardLogger().Out)
+	log.SetOutput(&buffer)
+	defer setKubernetesLogFields(KubernetesMetadata{})
+
+	server, err := NewServer(&ServerConfig{GinMode: gin.TestMode, Kubernetes: &KubernetesMetadata{PodName: "api-0", Namespace: "prod", NodeName: "node-1"}})
+	errors.AssertNil(t, err)
+	server.Engine().GET("/k8s", func(c *gin.Context) {
+		c.String(200, "ok")
+	})
+
+	t.Run("Logs", func(t *testing.T) {
+		server.Handler().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/k8s", nil))
+		assert.Contains(t, buffer.String(), "200 - GET - /k8s")
+		assert.Contains(t, buffer.String(), "namespace=prod node=node-1 pod=api-0")
+
+		buffer.Reset()
+		log.WithField("pod", "other").Warn("explicit")
+		assert.Contains(t, buffer.String(), "pod=other")
+	})
+
+	t.Run("Metrics", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		server.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+		assert.Equal(t, 200, w.Code)
+		assert.Contains(t, w.Body.String(), `namespace="prod",node="node-1",pod="api-0"`)
+		assert.Regexp(t, `build_info\{commit="[^"]*",goversion="[^"]*",namespace="prod",node="node-1",pod="api-0",version="[^"]*"\} 1`, w.Body.String())
+	})
+}
@@ -0,0 +1,137 @@
+package http
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultReadHeaderTimeout is applied when ServerConfig.ReadHeaderTimeout is not set.
+	DefaultReadHeaderTimeout = 10 * time.Second
+	// DefaultIdleTimeout is applied when ServerConfig.IdleTimeout is not set.
+	DefaultIdleTimeout = 2 * time.Minute
+	// DefaultMaxHeaderBytes is applied when ServerConfig.MaxHeaderBytes is not set.
+	DefaultMaxHeaderBytes = 1 << 20
+)
+
+// timeoutOrDefault returns the default for unset timeouts and zero (no limit) for negative timeouts.
+func timeoutOrDefault(timeout Duration, defaultTimeout time.Duration) time.Duration {
+	if timeout < 0 {
+		return 0
+	} else if timeout == 0 {
+		return defaultTimeout
+	}
+	return time.Duration(timeout)
+}
+
+// limitListener bounds the number of concurrently open connections in total and per client IP.
+type limitListener struct {
+	net.Listener
+	slots chan struct{}
+	perIP int
+	done  chan struct{}
+	once  sync.Once
+
+	mutex sync.Mutex
+	conns map[string]int
+}
+
+// newLimitListener wraps l to accept at most maxConns connections at once and at most perIP connections of the same client IP. A zero limit disables the respective check.
+func newLimitListener(l net.Listener, maxConns, perIP int) net.Listener {
+	if maxConns <= 0 && perIP <= 0 {
+		return l
+	}
+	ll := &limitListener{Listener: l, perIP: perIP, done: make(chan struct{}), conns: make(map[string]int)}
+	if maxConns > 0 {
+		ll.slots = make(chan struct{}, maxConns)
+	}
+	return ll
+}
+
+// Accept waits for a free connection slot before accepting the next connection. Connections exceeding the per-IP limit are closed immediately.
+func (l *limitListener) Accept() (net.Conn, error) {
+	for {
+		if l.slots != nil {
+			select {
+			case l.slots <- struct{}{}:
+			case <-l.done:
+				return nil, net.ErrClosed
+			}
+		}
+
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			l.releaseSlot()
+			return nil, err
+		}
+
+		ip := remoteIP(conn)
+		if !l.acquireIP(ip) {
+			conn.Close()
+			l.releaseSlot()
+			continue
+		}
+		return &limitConn{Conn: conn, release: func() {
+			l.releaseIP(ip)
+			l.releaseSlot()
+		}}, nil
+	}
+}
+
+// Close stops accepting connections and unblocks waiting Accept calls.
+func (l *limitListener) Close() error {
+	l.once.Do(func() { close(l.done) })
+	return l.Listener.Close()
+}
+
+func (l *limitListener) releaseSlot() {
+	if l.slots != nil {
+		<-l.slots
+	}
+}
+
+func (l *limitListener) acquireIP(ip string) bool {
+	if l.perIP <= 0 {
+		return true
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.conns[ip] >= l.perIP {
+		return false
+	}
+	l.conns[ip]++
+	return true
+}
+
+func (l *limitListener) releaseIP(ip string) {
+	if l.perIP <= 0 {
+		return
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.conns[ip]--; l.conns[ip] <= 0 {
+		delete(l.conns, ip)
+	}
+}
+
+func remoteIP(conn net.Conn) string {
+	host, _, err := net.SplitHostPort(conn.RemoteAddr().String())
+	if err != nil {
+		return conn.RemoteAddr().String()
+	}
+	return host
+}
+
+// limitConn releases its connection slot when closed.
+type limitConn struct {
+	net.Conn
+	once    sync.Once
+	release func()
+}
+
+func (c *limitConn) Close() error {
+	err := c.Conn.Close()
+	c.once.Do(c.release)
+	return err
+}
@@ -0,0 +1,134 @@
+package http
+
+import (
+	"bufio"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sbreitf1/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServerTimeoutDefaults(t *testing.T) {
+	assert.Equal(t, DefaultReadHeaderTimeout, timeoutOrDefault(0, DefaultReadHeaderTimeout))
+	assert.Equal(t, time.Duration(0), timeoutOrDefault(Duration(-1), DefaultReadHeaderTimeout))
+	assert.Equal(t, time.Second, timeoutOrDefault(Duration(time.Second), DefaultReadHeaderTimeout))
+
+	server, _ := newTestServer()
+	errors.AssertNil(t, server.RunAsync(nil))
+	defer server.Shutdown()
+	assert.Equal(t, DefaultReadHeaderTimeout, server.asyncServer.ReadHeaderTimeout)
+	assert.Equal(t, DefaultIdleTimeout, server.asyncServer.IdleTimeout)
+	assert.Equal(t, DefaultMaxHeaderBytes, server.asyncServer.MaxHeaderBytes)
+	assert.Equal(t, time.Duration(0), server.asyncServer.ReadTimeout)
+}
+
+func TestServerReadHeaderTimeout(t *testing.T) {
+	server, err := NewServer(&ServerConfig{ListenAddress: "127.0.0.1:18087", GinMode: gin.TestMode, ReadHeaderTimeout: Duration(200 * time.Millisecond)})
+	errors.AssertNil(t, err)
+	errors.AssertNil(t, server.RunAsync(nil))
+	defer server.Shutdown()
+
+	conn, dialErr := net.Dial("tcp", "127.0.0.1:18087")
+	if !assert.NoError(t, dialErr) {
+		return
+	}
+	defer conn.Close()
+	// incomplete headers are never finished by a slow client
+	_, writeErr := conn.Write([]byte("GET /healthz HTTP/1.1\r\nHost: localhost\r\n"))
+	assert.NoError(t, writeErr)
+
+	start := time.Now()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	ioutil.ReadAll(conn)
+	assert.True(t, time.Since(start) < 2*time.Second, "connection has not been closed after the header timeout")
+}
+
+func TestServerMaxConnectionsPerIP(t *testing.T) {
+	server, err := NewServer(&ServerConfig{ListenAddress: "127.0.0.1:18088", GinMode: gin.TestMode, MaxConnectionsPerIP: 1})
+	errors.AssertNil(t, err)
+	errors.AssertNil(t, server.RunAsync(nil))
+	defer server.Shutdown()
+
+	first, dialErr := net.Dial("tcp", "127.0.0.1:18088")
+	if !assert.NoError(t, dialErr) {
+		return
+	}
+	second, dialErr := net.Dial("tcp", "127.0.0.1:18088")
+	if !assert.NoError(t, dialErr) {
+		return
+	}
+	defer second.Close()
+
+	// the second connection is closed by the server without a response
+	second.Write([]byte("GET /healthz HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+	second.SetReadDeadline(time.Now().Add(2 * time.Second))
+	data, _ := ioutil.ReadAll(second)
+	assert.Empty(t, data)
+
+	first.Write([]byte("GET /healthz HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+	resp, respErr := http.ReadResponse(bufio.NewReader(first), nil)
+	if assert.NoError(t, respErr) {
+		assert.Equal(t, 200, resp.StatusCode)
+		resp.Body.Close()
+	}
+
+	// the slot is released when the first connection is closed
+	first.Close()
+	time.Sleep(50 * time.Millisecond)
+	resp, getErr := http.Get("http://127.0.0.1:18088/healthz")
+	if assert.NoError(t, getErr) {
+		assert.Equal(t, 200, resp.StatusCode)
+		resp.Body.Close()
+	}
+}
+
+func TestLimitListenerMaxConnections(t *testing.T) {
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.NoError(t, err) {
+		return
+	}
+	l := newLimitListener(inner, 1, 0)
+	defer l.Close()
+
+	accepted := make(chan net.Conn, 2)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				close(accepted)
+				return
+			}
+			accepted <- conn
+		}
+	}()
+
+	for i := 0; i < 2; i++ {
+		conn, err := net.Dial("tcp", inner.Addr().String())
+		if assert.NoError(t, err) {
+			defer conn.Close()
+		}
+	}
+	first := <-accepted
+	select {
+	case <-accepted:
+		assert.Fail(t, "second connection accepted while limit is reached")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	first.Close()
+	select {
+	case conn := <-accepted:
+		conn.Close()
+	case <-time.After(time.Second):
+		assert.Fail(t, "second connection not accepted after release")
+	}
+
+	l.Close()
+	_, open := <-accepted
+	assert.False(t, open)
+}
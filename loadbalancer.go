@@ -0,0 +1,364 @@
+package http
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sbreitf1/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// LoadBalancingStrategy selects the endpoint of a LoadBalancer that receives the next request.
+type LoadBalancingStrategy int
+
+const (
+	// RoundRobin sends requests to all available endpoints in turn.
+	RoundRobin LoadBalancingStrategy = iota
+	// LeastFailures sends requests to the available endpoint with the fewest consecutive failures. Endpoints with equal failures are used in turn.
+	LeastFailures
+)
+
+// LoadBalancer distributes the requests of a client across replicated endpoints. Endpoints that exceeded the failure threshold are excluded for the exclusion duration. When all endpoints are excluded, the healthy endpoint with the earliest end of exclusion is used. Endpoints reported unhealthy by health checks never receive requests, see StartHealthChecks.
+type LoadBalancer struct {
+	// Strategy selects the endpoint of each request attempt.
+	Strategy LoadBalancingStrategy
+	// FailureThreshold denotes the number of consecutive failures that exclude an endpoint. Endpoints are never excluded when zero.
+	FailureThreshold int
+	// ExclusionDuration denotes how long failing endpoints are excluded before they receive requests again.
+	ExclusionDuration time.Duration
+
+	mutex     sync.Mutex
+	endpoints []*lbEndpoint
+	next      int
+	stop      chan struct{}
+}
+
+type lbEndpoint struct {
+	baseURL       string
+	failures      int
+	excludedUntil time.Time
+	unhealthy     bool
+}
+
+// LoadBalancerHealthCheck contains the parameters of the background health checks of a LoadBalancer.
+type LoadBalancerHealthCheck struct {
+	// Path is requested on the host of each endpoint, responses with status 2xx denote a healthy endpoint. Defaults to "/healthz".
+	Path string
+	// Interval denotes the time between two checks of all endpoints. Defaults to 10 seconds.
+	Interval time.Duration
+	// Timeout limits each check. Defaults to 2 seconds.
+	Timeout time.Duration
+	// Client sends the checks. Use a client sharing the Transport of the load balanced client to apply its TLS configuration, but not the load balanced client itself, whose interceptors and load balancer would handle the checks too. A new client is used when nil.
+	Client *Client
+}
+
+type loadBalancerTargetContextKey struct{}
+
+// NewLoadBalancer returns a round-robin load balancer for the given base URLs that excludes endpoints for 30 seconds after 3 consecutive failures.
+func NewLoadBalancer(baseURLs ...string) *LoadBalancer {
+	lb := &LoadBalancer{FailureThreshold: 3, ExclusionDuration: 30 * time.Second}
+	lb.SetEndpoints(baseURLs)
+	return lb
+}
+
+// SetEndpoints replaces the base URLs of the load balancer. The failure state of endpoints that are kept is retained.
+func (lb *LoadBalancer) SetEndpoints(baseURLs []string) {
+	lb.mutex.Lock()
+	defer lb.mutex.Unlock()
+
+	existing := make(map[string]*lbEndpoint)
+	for _, e := range lb.endpoints {
+		existing[e.baseURL] = e
+	}
+	lb.endpoints = make([]*lbEndpoint, 0, len(baseURLs))
+	for _, baseURL := range baseURLs {
+		if e, ok := existing[baseURL]; ok {
+			lb.endpoints = append(lb.endpoints, e)
+		} else {
+			lb.endpoints = append(lb.endpoints, &lbEndpoint{baseURL: baseURL})
+		}
+	}
+}
+
+// Endpoints returns the base URLs of the load balancer.
+func (lb *LoadBalancer) Endpoints() []string {
+	lb.mutex.Lock()
+	defer lb.mutex.Unlock()
+
+	baseURLs := make([]string, len(lb.endpoints))
+	for i, e := range lb.endpoints {
+		baseURLs[i] = e.baseURL
+	}
+	return baseURLs
+}
+
+// Excluded reports whether the endpoint with the given base URL is currently excluded due to failures.
+func (lb *LoadBalancer) Excluded(baseURL string) bool {
+	lb.mutex.Lock()
+	defer lb.mutex.Unlock()
+
+	for _, e := range lb.endpoints {
+		if e.baseURL == baseURL {
+			return lb.excluded(e, time.Now())
+		}
+	}
+	return false
+}
+
+func (lb *LoadBalancer) excluded(e *lbEndpoint, now time.Time) bool {
+	return lb.FailureThreshold > 0 && e.failures >= lb.FailureThreshold && now.Before(e.excludedUntil)
+}
+
+// Healthy reports whether the last health check of the endpoint with the given base URL succeeded. Endpoints are healthy until checked.
+func (lb *LoadBalancer) Healthy(baseURL string) bool {
+	lb.mutex.Lock()
+	defer lb.mutex.Unlock()
+
+	for _, e := range lb.endpoints {
+		if e.baseURL == baseURL {
+			return !e.unhealthy
+		}
+	}
+	return false
+}
+
+// Select returns the base URL that receives the next request.
+func (lb *LoadBalancer) Select() (string, errors.Error) {
+	e, err := lb.selectEndpoint()
+	if err != nil {
+		return "", err
+	}
+	return e.baseURL, nil
+}
+
+func (lb *LoadBalancer) selectEndpoint() (*lbEndpoint, errors.Error) {
+	lb.mutex.Lock()
+	defer lb.mutex.Unlock()
+
+	if len(lb.endpoints) == 0 {
+		return nil, ErrUpstreamUnavailable.Msg("No load balancer endpoints configured").Make()
+	}
+
+	now := time.Now()
+	var selected *lbEndpoint
+	for i := range lb.endpoints {
+		e := lb.endpoints[(lb.next+i)%len(lb.endpoints)]
+		if e.unhealthy || lb.excluded(e, now) {
+			continue
+		}
+		if selected == nil || (lb.Strategy == LeastFailures && e.failures < selected.failures) {
+			selected = e
+		}
+		if lb.Strategy == RoundRobin {
+			break
+		}
+	}
+	if selected == nil {
+		// all healthy endpoints are excluded, so the one recovering first is tried
+		for _, e := range lb.endpoints {
+			if !e.unhealthy && (selected == nil || e.excludedUntil.Before(selected.excludedUntil)) {
+				selected = e
+			}
+		}
+	}
+	if selected == nil {
+		return nil, ErrUpstreamUnavailable.Msg("No healthy load balancer endpoint").Make()
+	}
+
+	for i, e := range lb.endpoints {
+		if e == selected {
+			lb.next = (i + 1) % len(lb.endpoints)
+		}
+	}
+	return selected, nil
+}
+
+// Report records the result of a request to the endpoint with the given base URL.
+func (lb *LoadBalancer) Report(baseURL string, success bool) {
+	lb.mutex.Lock()
+	defer lb.mutex.Unlock()
+
+	for _, e := range lb.endpoints {
+		if e.baseURL == baseURL {
+			lb.report(e, success)
+		}
+	}
+}
+
+func (lb *LoadBalancer) report(e *lbEndpoint, success bool) {
+	if success {
+		e.failures = 0
+		return
+	}
+	e.failures++
+	if lb.FailureThreshold > 0 && e.failures >= lb.FailureThreshold {
+		e.excludedUntil = time.Now().Add(lb.ExclusionDuration)
+	}
+}
+
+// StartHealthChecks checks all endpoints immediately and then periodically in the background until StopHealthChecks is called. Only healthy endpoints are selected for requests. Calling it again restarts the checks with the new configuration.
+func (lb *LoadBalancer) StartHealthChecks(config *LoadBalancerHealthCheck) {
+	check := *config
+	if len(check.Path) == 0 {
+		check.Path = "/healthz"
+	}
+	if check.Interval <= 0 {
+		check.Interval = 10 * time.Second
+	}
+	if check.Timeout <= 0 {
+		check.Timeout = 2 * time.Second
+	}
+	if check.Client == nil {
+		check.Client = NewClient()
+	}
+
+	lb.StopHealthChecks()
+	stop := make(chan struct{})
+	lb.mutex.Lock()
+	lb.stop = stop
+	lb.mutex.Unlock()
+
+	lb.checkHealth(&check, stop)
+	go func() {
+		ticker := time.NewTicker(check.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				lb.checkHealth(&check, stop)
+			}
+		}
+	}()
+}
+
+// StopHealthChecks ends the background health checks. All endpoints are considered healthy afterwards.
+func (lb *LoadBalancer) StopHealthChecks() {
+	lb.mutex.Lock()
+	defer lb.mutex.Unlock()
+
+	if lb.stop != nil {
+		close(lb.stop)
+		lb.stop = nil
+	}
+	for _, e := range lb.endpoints {
+		e.unhealthy = false
+	}
+}
+
+// checkHealth checks all endpoints concurrently and updates their health state.
+func (lb *LoadBalancer) checkHealth(check *LoadBalancerHealthCheck, stop chan struct{}) {
+	baseURLs := lb.Endpoints()
+	results := make([]error, len(baseURLs))
+	var wg sync.WaitGroup
+	for i := range baseURLs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = checkEndpointHealth(check, baseURLs[i])
+		}(i)
+	}
+	wg.Wait()
+
+	lb.mutex.Lock()
+	defer lb.mutex.Unlock()
+	if lb.stop != stop {
+		// checks have been stopped or restarted in the meantime
+		return
+	}
+	for i, baseURL := range baseURLs {
+		for _, e := range lb.endpoints {
+			if e.baseURL != baseURL || e.unhealthy == (results[i] != nil) {
+				continue
+			}
+			e.unhealthy = results[i] != nil
+			if e.unhealthy {
+				log.WithFields(log.Fields{"component": "loadbalancer", "endpoint": baseURL}).Warnf("Endpoint unhealthy: %s", results[i])
+			} else {
+				log.WithFields(log.Fields{"component": "loadbalancer", "endpoint": baseURL}).Info("Endpoint healthy again")
+			}
+		}
+	}
+}
+
+func checkEndpointHealth(check *LoadBalancerHealthCheck, baseURL string) error {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return err
+	}
+	u.Path, u.RawPath, u.RawQuery = check.Path, "", ""
+
+	ctx, cancel := context.WithTimeout(context.Background(), check.Timeout)
+	defer cancel()
+	response, sendErr := check.Client.DoContext(ctx, MethodGet, u.String(), nil, nil)
+	if sendErr != nil {
+		return sendErr
+	}
+	DrainAndClose(response)
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return ErrUpstreamUnavailable.Msg("Health check returned status %d", response.StatusCode).Make()
+	}
+	return nil
+}
+
+// Interceptor returns an interceptor that sends each attempt of requests with relative URL to an endpoint selected by the load balancer and reports the results. Network errors and responses with status 5xx count as failure.
+func (lb *LoadBalancer) Interceptor() Interceptor {
+	return lb.interceptor(nil)
+}
+
+// interceptor sends each attempt as copy of the request with the URL of the selected endpoint. If set, prepare is called with the copy before it is sent, so the request can be signed for the actual URL.
+func (lb *LoadBalancer) interceptor(prepare func(*Request) errors.Error) Interceptor {
+	return func(next Responder) Responder {
+		return func(req *Request) (*Response, errors.Error) {
+			target, ok := req.Context().Value(loadBalancerTargetContextKey{}).(string)
+			if !ok {
+				return next(req)
+			}
+
+			e, err := lb.selectEndpoint()
+			if err != nil {
+				return nil, err
+			}
+			u, parseErr := url.Parse(joinURL(e.baseURL, target))
+			if parseErr != nil {
+				return nil, ErrInvalidRequest.Make().Cause(parseErr)
+			}
+			// retries start again from the unsigned request
+			attempt := req.Clone(req.Context())
+			attempt.URL = u
+			attempt.Host = u.Host
+			if prepare != nil {
+				if err := prepare(attempt); err != nil {
+					return nil, err
+				}
+			}
+
+			response, err := next(attempt)
+			success := err == nil && response.StatusCode < 500
+			lb.mutex.Lock()
+			lb.report(e, success)
+			lb.mutex.Unlock()
+			return response, err
+		}
+	}
+}
+
+// withLoadBalancerTarget stores the relative request URL, so the load balancer can resolve it against the selected endpoint.
+func withLoadBalancerTarget(ctx context.Context, target string) context.Context {
+	return context.WithValue(ctx, loadBalancerTargetContextKey{}, target)
+}
+
+// joinURL appends a relative target URL to base.
+func joinURL(base, target string) string {
+	if len(target) == 0 {
+		return base
+	}
+	if strings.HasPrefix(target, "?") {
+		return strings.TrimRight(base, "/") + target
+	}
+	return strings.TrimRight(base, "/") + "/" + strings.TrimLeft(target, "/")
+}
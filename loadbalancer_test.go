@@ -0,0 +1,219 @@
+package http
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sbreitf1/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func newLoadBalancerTestServer(t *testing.T, name string, failing *int32) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing != nil && atomic.LoadInt32(failing) != 0 {
+			w.WriteHeader(503)
+			return
+		}
+		w.Write([]byte(name + " " + r.URL.RequestURI()))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestLoadBalancer(t *testing.T) {
+	var failing int32
+	a := newLoadBalancerTestServer(t, "a", nil)
+	b := newLoadBalancerTestServer(t, "b", &failing)
+
+	client := NewClient()
+	client.LoadBalancer = NewLoadBalancer(a.URL+"/api", b.URL+"/api/")
+	client.LoadBalancer.FailureThreshold = 2
+	client.LoadBalancer.ExclusionDuration = 50 * time.Millisecond
+
+	get := func() string {
+		response, err := client.Get("/items?id=1", nil)
+		errors.AssertNil(t, err)
+		body, err := ReadResponseString(response)
+		errors.AssertNil(t, err)
+		return body
+	}
+
+	t.Run("RoundRobin", func(t *testing.T) {
+		assert.Equal(t, "a /api/items?id=1", get())
+		assert.Equal(t, "b /api/items?id=1", get())
+		assert.Equal(t, "a /api/items?id=1", get())
+		assert.Equal(t, "b /api/items?id=1", get())
+	})
+
+	t.Run("Exclusion", func(t *testing.T) {
+		atomic.StoreInt32(&failing, 1)
+		get()
+		get()
+		get()
+		get()
+		assert.True(t, client.LoadBalancer.Excluded(b.URL+"/api/"))
+		for i := 0; i < 4; i++ {
+			assert.Equal(t, "a /api/items?id=1", get())
+		}
+
+		atomic.StoreInt32(&failing, 0)
+		time.Sleep(60 * time.Millisecond)
+		assert.False(t, client.LoadBalancer.Excluded(b.URL+"/api/"))
+		assert.Equal(t, "b /api/items?id=1", get())
+	})
+
+	t.Run("Retry", func(t *testing.T) {
+		atomic.StoreInt32(&failing, 1)
+		defer atomic.StoreInt32(&failing, 0)
+		client.Retry = &RetryPolicy{MaxAttempts: 2}
+		defer func() { client.Retry = nil }()
+
+		// the retry of a failed attempt is sent to the next endpoint
+		for i := 0; i < 4; i++ {
+			assert.Equal(t, "a /api/items?id=1", get())
+		}
+	})
+
+	t.Run("AbsoluteURL", func(t *testing.T) {
+		response, err := client.Get(b.URL+"/direct", nil)
+		errors.AssertNil(t, err)
+		assertResponse(t, 200, "b /direct", response)
+	})
+}
+
+func TestLoadBalancerSigning(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Signed") != r.Host+r.URL.RequestURI() {
+			w.WriteHeader(401)
+			return
+		}
+		w.Write([]byte("signed"))
+	}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+	port, _ := strconv.Atoi(u.Port())
+
+	client := NewClient()
+	client.Resolver = &testSRVResolver{records: []*net.SRV{{Target: "127.0.0.1.", Port: uint16(port)}}}
+	client.Retry = &RetryPolicy{MaxAttempts: 3}
+	client.LoadBalancer = NewLoadBalancer("http://127.0.0.1:1/unused", "http+srv://_api._tcp.example.test/api")
+	client.Signer = RequestSignerFunc(func(req *Request) errors.Error {
+		// the signature must cover the URL of the selected and resolved endpoint
+		req.Header.Set("X-Signed", req.Host+req.URL.RequestURI())
+		return nil
+	})
+
+	for i := 0; i < 3; i++ {
+		response, err := client.Get("/items?id=1", nil)
+		errors.AssertNil(t, err)
+		assertResponse(t, 200, "signed", response)
+	}
+}
+
+func TestLoadBalancerLeastFailures(t *testing.T) {
+	lb := NewLoadBalancer("http://a", "http://b", "http://c")
+	lb.Strategy = LeastFailures
+	lb.FailureThreshold = 0
+
+	lb.Report("http://a", false)
+	lb.Report("http://a", false)
+	lb.Report("http://b", false)
+	for i := 0; i < 3; i++ {
+		selected, err := lb.Select()
+		errors.AssertNil(t, err)
+		assert.Equal(t, "http://c", selected)
+	}
+
+	lb.Report("http://c", false)
+	lb.Report("http://c", false)
+	selected, _ := lb.Select()
+	assert.Equal(t, "http://b", selected)
+	assert.False(t, lb.Excluded("http://a"))
+}
+
+func TestLoadBalancerAllExcluded(t *testing.T) {
+	lb := NewLoadBalancer("http://a", "http://b")
+	lb.FailureThreshold = 1
+	lb.ExclusionDuration = time.Minute
+
+	lb.Report("http://b", false)
+	time.Sleep(time.Millisecond)
+	lb.Report("http://a", false)
+	selected, err := lb.Select()
+	errors.AssertNil(t, err)
+	assert.Equal(t, "http://b", selected)
+
+	lb.SetEndpoints([]string{"http://a", "http://c"})
+	assert.Equal(t, []string{"http://a", "http://c"}, lb.Endpoints())
+	assert.True(t, lb.Excluded("http://a"))
+	selected, _ = lb.Select()
+	assert.Equal(t, "http://c", selected)
+
+	_, err = NewLoadBalancer().Select()
+	errors.Assert(t, ErrUpstreamUnavailable, err)
+}
+
+func TestLoadBalancerHealthChecks(t *testing.T) {
+	var unhealthy int32
+	handler := func(name string, unhealthy *int32) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/healthz" {
+				if unhealthy != nil && atomic.LoadInt32(unhealthy) != 0 {
+					w.WriteHeader(503)
+				}
+				return
+			}
+			w.Write([]byte(name))
+		}
+	}
+	a := httptest.NewServer(handler("a", nil))
+	defer a.Close()
+	b := httptest.NewServer(handler("b", &unhealthy))
+	defer b.Close()
+
+	atomic.StoreInt32(&unhealthy, 1)
+	client := NewClient()
+	client.LoadBalancer = NewLoadBalancer(a.URL+"/api", b.URL+"/api")
+	client.LoadBalancer.StartHealthChecks(&LoadBalancerHealthCheck{Interval: 20 * time.Millisecond})
+	defer client.LoadBalancer.StopHealthChecks()
+
+	get := func() string {
+		response, err := client.Get("/items", nil)
+		errors.AssertNil(t, err)
+		body, err := ReadResponseString(response)
+		errors.AssertNil(t, err)
+		return body
+	}
+
+	// the initial check is completed before StartHealthChecks returns
+	assert.False(t, client.LoadBalancer.Healthy(b.URL+"/api"))
+	for i := 0; i < 4; i++ {
+		assert.Equal(t, "a", get())
+	}
+
+	atomic.StoreInt32(&unhealthy, 0)
+	time.Sleep(60 * time.Millisecond)
+	assert.True(t, client.LoadBalancer.Healthy(b.URL+"/api"))
+	assert.Equal(t, "b", get())
+	assert.Equal(t, "a", get())
+
+	t.Run("NoneHealthy", func(t *testing.T) {
+		lb := NewLoadBalancer(b.URL)
+		atomic.StoreInt32(&unhealthy, 1)
+		lb.StartHealthChecks(&LoadBalancerHealthCheck{Interval: time.Minute})
+		_, err := lb.Select()
+		errors.Assert(t, ErrUpstreamUnavailable, err)
+
+		lb.StopHealthChecks()
+		assert.True(t, lb.Healthy(b.URL))
+		selected, err := lb.Select()
+		errors.AssertNil(t, err)
+		assert.Equal(t, b.URL, selected)
+	})
+}
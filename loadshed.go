@@ -0,0 +1,260 @@
+package http
+
+import (
+	"math"
+	"math/rand"
+	"runtime/metrics"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sbreitf1/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// Priority classifies routes for load shedding.
+type Priority int
+
+const (
+	// PriorityCritical routes are never shed.
+	PriorityCritical Priority = iota
+	// PriorityNormal routes are shed at half the current shed fraction.
+	PriorityNormal
+	// PriorityLow routes are shed at the current shed fraction.
+	PriorityLow
+)
+
+// String returns the metric label of the priority.
+func (p Priority) String() string {
+	switch p {
+	case PriorityCritical:
+		return "critical"
+	case PriorityNormal:
+		return "normal"
+	case PriorityLow:
+		return "low"
+	}
+	return strconv.Itoa(int(p))
+}
+
+const maxLatencySamples = 4096
+
+var (
+	loadShedMetricsOnce sync.Once
+	loadShedFraction    prometheus.Gauge
+	loadShedTotal       *prometheus.CounterVec
+)
+
+// LoadShedderConfig contains the thresholds of a LoadShedder. A zero threshold disables the respective check.
+type LoadShedderConfig struct {
+	// CPUThreshold denotes the share of available CPU time (0 to 1) used by the process.
+	CPUThreshold float64 `json:"cpuThreshold,omitempty"`
+	// MemoryThreshold denotes the memory in bytes obtained by the Go runtime and not yet released.
+	MemoryThreshold uint64 `json:"memoryThreshold,omitempty"`
+	// LatencyThreshold denotes the p99 latency of requests passing the shedding middlewares.
+	LatencyThreshold Duration `json:"latencyThreshold,omitempty"`
+	// Interval between two evaluations of the thresholds. Defaults to 1 second.
+	Interval Duration `json:"interval,omitempty"`
+	// Step is added to the shed fraction while a threshold is breached and subtracted otherwise. Defaults to 0.1.
+	Step float64 `json:"step,omitempty"`
+	// MaxFraction limits the share of low priority requests that is shed. Defaults to 0.9.
+	MaxFraction float64 `json:"maxFraction,omitempty"`
+}
+
+// LoadShedder rejects a growing share of low priority requests with 503 Service Unavailable while the process is under pressure.
+type LoadShedder struct {
+	config LoadShedderConfig
+	sample func() loadSample
+	stop   chan struct{}
+	once   sync.Once
+
+	mutex     sync.Mutex
+	fraction  float64
+	latencies []time.Duration
+
+	lastCPU, lastIdle float64
+}
+
+type loadSample struct {
+	cpu    float64
+	memory uint64
+}
+
+// registerLoadShedMetrics creates the gauge of the current shed fraction and the counter of shed requests. Multiple shedders report to the same metrics.
+func registerLoadShedMetrics() {
+	loadShedMetricsOnce.Do(func() {
+		loadShedFraction = prometheus.NewGauge(prometheus.GaugeOpts{
+			Subsystem: "loadshed",
+			Name:      "fraction",
+			Help:      "Share of low priority requests that is currently shed.",
+		})
+		loadShedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+			Subsystem: "loadshed",
+			Name:      "shed_total",
+			Help:      "Number of requests rejected by load shedding by priority.",
+		}, []string{"priority"})
+		prometheus.MustRegister(loadShedFraction, loadShedTotal)
+	})
+}
+
+// NewLoadShedder returns a load shedder that evaluates the configured thresholds in the background until Stop is called. Add its Middleware to routes that may be shed, probes are never affected.
+func NewLoadShedder(config *LoadShedderConfig) (*LoadShedder, errors.Error) {
+	if config.CPUThreshold < 0 || config.CPUThreshold > 1 {
+		return nil, ErrInvalidConfig.Msg("CPU threshold must be between 0 and 1").Make()
+	}
+	if config.MaxFraction < 0 || config.MaxFraction > 1 {
+		return nil, ErrInvalidConfig.Msg("Maximum shed fraction must be between 0 and 1").Make()
+	}
+
+	s := &LoadShedder{config: *config, stop: make(chan struct{})}
+	if s.config.Interval <= 0 {
+		s.config.Interval = Duration(time.Second)
+	}
+	if s.config.Step <= 0 {
+		s.config.Step = 0.1
+	}
+	if s.config.MaxFraction == 0 {
+		s.config.MaxFraction = 0.9
+	}
+	s.sample = s.readRuntimeMetrics
+	s.sample()
+
+	registerLoadShedMetrics()
+	go s.run()
+	return s, nil
+}
+
+// Stop ends the background evaluation. Requests are no longer shed afterwards.
+func (s *LoadShedder) Stop() {
+	s.once.Do(func() {
+		close(s.stop)
+		s.mutex.Lock()
+		s.fraction = 0
+		s.mutex.Unlock()
+		loadShedFraction.Set(0)
+	})
+}
+
+// Fraction returns the share of low priority requests that is currently shed.
+func (s *LoadShedder) Fraction() float64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.fraction
+}
+
+// Middleware returns a middleware that sheds requests of the given priority and records their latency.
+func (s *LoadShedder) Middleware(priority Priority) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if s.shed(priority) {
+			loadShedTotal.WithLabelValues(priority.String()).Inc()
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(time.Duration(s.config.Interval).Seconds()))))
+			c.AbortWithStatus(503)
+			return
+		}
+
+		start := time.Now()
+		c.Next()
+		s.observe(time.Since(start))
+	}
+}
+
+func (s *LoadShedder) shed(priority Priority) bool {
+	fraction := s.Fraction()
+	switch priority {
+	case PriorityCritical:
+		return false
+	case PriorityNormal:
+		fraction /= 2
+	}
+	return fraction > 0 && rand.Float64() < fraction
+}
+
+func (s *LoadShedder) observe(latency time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if len(s.latencies) < maxLatencySamples {
+		s.latencies = append(s.latencies, latency)
+	} else {
+		// replace random samples to keep the distribution of the whole interval
+		s.latencies[rand.Intn(maxLatencySamples)] = latency
+	}
+}
+
+func (s *LoadShedder) run() {
+	ticker := time.NewTicker(time.Duration(s.config.Interval))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			s.update()
+		}
+	}
+}
+
+// update evaluates the thresholds and adjusts the shed fraction.
+func (s *LoadShedder) update() {
+	sample := s.sample()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	p99 := percentile(s.latencies, 0.99)
+	s.latencies = s.latencies[:0]
+
+	var reasons []string
+	if s.config.CPUThreshold > 0 && sample.cpu > s.config.CPUThreshold {
+		reasons = append(reasons, "cpu")
+	}
+	if s.config.MemoryThreshold > 0 && sample.memory > s.config.MemoryThreshold {
+		reasons = append(reasons, "memory")
+	}
+	if s.config.LatencyThreshold > 0 && p99 > time.Duration(s.config.LatencyThreshold) {
+		reasons = append(reasons, "latency")
+	}
+
+	previous := s.fraction
+	if len(reasons) > 0 {
+		s.fraction = math.Min(s.config.MaxFraction, s.fraction+s.config.Step)
+	} else {
+		s.fraction = math.Max(0, s.fraction-s.config.Step)
+	}
+	if previous == 0 && s.fraction > 0 {
+		log.WithFields(log.Fields{"component": "loadshed", "reasons": reasons}).Warn("Begin shedding low priority requests")
+	} else if previous > 0 && s.fraction == 0 {
+		log.WithFields(log.Fields{"component": "loadshed"}).Info("Stopped shedding requests")
+	}
+	loadShedFraction.Set(s.fraction)
+}
+
+// readRuntimeMetrics returns the CPU usage since the last call and the current memory usage of the Go runtime.
+func (s *LoadShedder) readRuntimeMetrics() loadSample {
+	samples := []metrics.Sample{
+		{Name: "/cpu/classes/total:cpu-seconds"},
+		{Name: "/cpu/classes/idle:cpu-seconds"},
+		{Name: "/memory/classes/total:bytes"},
+		{Name: "/memory/classes/heap/released:bytes"},
+	}
+	metrics.Read(samples)
+
+	var sample loadSample
+	total, idle := samples[0].Value.Float64(), samples[1].Value.Float64()
+	if total > s.lastCPU {
+		sample.cpu = 1 - (idle-s.lastIdle)/(total-s.lastCPU)
+	}
+	s.lastCPU, s.lastIdle = total, idle
+	sample.memory = samples[2].Value.Uint64() - samples[3].Value.Uint64()
+	return sample
+}
+
+func percentile(values []time.Duration, p float64) time.Duration {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration{}, values...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted[int(math.Ceil(p*float64(len(sorted))))-1]
+}
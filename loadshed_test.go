@@ -0,0 +1,93 @@
+package http
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sbreitf1/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadShedder(t *testing.T) {
+	shedder, err := NewLoadShedder(&LoadShedderConfig{CPUThreshold: 0.8, MemoryThreshold: 1 << 30, LatencyThreshold: Duration(50 * time.Millisecond), Interval: Duration(time.Hour), Step: 0.5, MaxFraction: 1})
+	errors.AssertNil(t, err)
+	defer shedder.Stop()
+	sample := loadSample{cpu: 0.2, memory: 1 << 20}
+	shedder.sample = func() loadSample { return sample }
+
+	engine := gin.New()
+	engine.GET("/healthz", func(c *gin.Context) { c.Status(200) })
+	engine.GET("/critical", shedder.Middleware(PriorityCritical), func(c *gin.Context) { c.Status(200) })
+	engine.GET("/normal", shedder.Middleware(PriorityNormal), func(c *gin.Context) { c.Status(200) })
+	engine.GET("/low", shedder.Middleware(PriorityLow), func(c *gin.Context) { c.Status(200) })
+	count := func(path string) int {
+		ok := 0
+		for i := 0; i < 200; i++ {
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+			if w.Code == 200 {
+				ok++
+			} else {
+				assert.Equal(t, 503, w.Code)
+				assert.Equal(t, "3600", w.Header().Get("Retry-After"))
+			}
+		}
+		return ok
+	}
+
+	shedder.update()
+	assert.Equal(t, float64(0), shedder.Fraction())
+	assert.Equal(t, 200, count("/low"))
+
+	sample.cpu = 0.9
+	shedder.update()
+	assert.Equal(t, 0.5, shedder.Fraction())
+	assert.InDelta(t, 100, count("/low"), 40)
+	assert.InDelta(t, 150, count("/normal"), 40)
+
+	sample.cpu, sample.memory = 0.2, 2<<30
+	shedder.update()
+	assert.Equal(t, float64(1), shedder.Fraction())
+	assert.Equal(t, 0, count("/low"))
+	assert.Equal(t, 200, count("/critical"))
+	assert.Equal(t, 200, count("/healthz"))
+
+	sample.memory = 1 << 20
+	shedder.update()
+	shedder.update()
+	assert.Equal(t, float64(0), shedder.Fraction())
+
+	// latency threshold
+	shedder.observe(100 * time.Millisecond)
+	shedder.update()
+	assert.Equal(t, 0.5, shedder.Fraction())
+	shedder.Stop()
+	assert.Equal(t, float64(0), shedder.Fraction())
+}
+
+func TestLoadShedderRuntimeMetrics(t *testing.T) {
+	shedder, err := NewLoadShedder(&LoadShedderConfig{Interval: Duration(time.Hour)})
+	errors.AssertNil(t, err)
+	defer shedder.Stop()
+	time.Sleep(10 * time.Millisecond)
+	sample := shedder.readRuntimeMetrics()
+	assert.True(t, sample.cpu >= 0 && sample.cpu <= 1)
+	assert.True(t, sample.memory > 0)
+}
+
+func TestPercentile(t *testing.T) {
+	values := make([]time.Duration, 100)
+	for i := range values {
+		values[i] = time.Duration(100-i) * time.Millisecond
+	}
+	assert.Equal(t, 99*time.Millisecond, percentile(values, 0.99))
+	assert.Equal(t, 50*time.Millisecond, percentile(values, 0.5))
+	assert.Equal(t, time.Duration(0), percentile(nil, 0.99))
+}
+
+func TestLoadShedderInvalidConfig(t *testing.T) {
+	_, err := NewLoadShedder(&LoadShedderConfig{CPUThreshold: 2})
+	errors.Assert(t, ErrInvalidConfig, err)
+}
@@ -0,0 +1,105 @@
+package http
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sbreitf1/errors"
+)
+
+// LocaleConfig contains the parameters of a locale middleware.
+type LocaleConfig struct {
+	// Supported lists the locales offered by the application as BCP 47 language tags, e.g. "en-US" or "de".
+	Supported []string `json:"supported"`
+	// Default is selected if no supported locale is acceptable. Defaults to the first supported locale.
+	Default string `json:"default,omitempty"`
+}
+
+// NewLocaleMiddleware returns a middleware that selects the best supported locale for the Accept-Language header of each request and stores it in the request context, use LocaleFromContext to read it. The selection is returned in the Content-Language header.
+func NewLocaleMiddleware(config *LocaleConfig) (gin.HandlerFunc, errors.Error) {
+	if len(config.Supported) == 0 {
+		return nil, ErrInvalidConfig.Msg("Locale negotiation requires at least one supported locale").Make()
+	}
+	supported := append([]string{}, config.Supported...)
+	defaultLocale := config.Default
+	if len(defaultLocale) == 0 {
+		defaultLocale = supported[0]
+	}
+
+	return func(c *gin.Context) {
+		locale, ok := NegotiateLocale(c.GetHeader("Accept-Language"), supported)
+		if !ok {
+			locale = defaultLocale
+		}
+		SetLocale(c, locale)
+		c.Header("Content-Language", locale)
+		c.Writer.Header().Add("Vary", "Accept-Language")
+	}, nil
+}
+
+type languageRange struct {
+	tag     string
+	quality float64
+}
+
+// NegotiateLocale returns the supported locale matching the Accept-Language header value with the highest preference. Ranges match locales with the same tag or a more specific one ("de" matches "de-CH"), specific ranges fall back to their base language ("de-AT" matches "de"). It returns false if no supported locale is acceptable.
+func NegotiateLocale(acceptLanguage string, supported []string) (string, bool) {
+	ranges := parseAcceptLanguage(acceptLanguage)
+	for _, r := range ranges {
+		if r.tag == "*" {
+			if len(supported) > 0 {
+				return supported[0], true
+			}
+			continue
+		}
+		if locale, ok := matchLocale(r.tag, supported); ok {
+			return locale, true
+		}
+		if i := strings.Index(r.tag, "-"); i > 0 {
+			if locale, ok := matchLocale(r.tag[:i], supported); ok {
+				return locale, true
+			}
+		}
+	}
+	return "", false
+}
+
+func matchLocale(tag string, supported []string) (string, bool) {
+	for _, locale := range supported {
+		if strings.EqualFold(locale, tag) {
+			return locale, true
+		}
+	}
+	for _, locale := range supported {
+		if len(locale) > len(tag) && strings.EqualFold(locale[:len(tag)+1], tag+"-") {
+			return locale, true
+		}
+	}
+	return "", false
+}
+
+// parseAcceptLanguage returns the acceptable language ranges ordered by decreasing quality.
+func parseAcceptLanguage(header string) []languageRange {
+	ranges := make([]languageRange, 0)
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(part, ";")
+		r := languageRange{tag: strings.TrimSpace(params[0]), quality: 1}
+		for _, param := range params[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if q, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					r.quality = q
+				}
+			}
+		}
+		if len(r.tag) > 0 && r.quality > 0 {
+			ranges = append(ranges, r)
+		}
+	}
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].quality > ranges[j].quality
+	})
+	return ranges
+}
@@ -0,0 +1,80 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sbreitf1/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNegotiateLocale(t *testing.T) {
+	supported := []string{"en-US", "de", "fr-CH"}
+	for _, test := range []struct {
+		header, expected string
+		ok               bool
+	}{
+		{"de", "de", true},
+		{"DE", "de", true},
+		{"de-AT, en;q=0.9", "de", true},
+		{"fr", "fr-CH", true},
+		{"en-GB;q=0.5, fr-FR;q=0.8", "fr-CH", true},
+		{"es, *;q=0.1", "en-US", true},
+		{"de;q=0, en", "en-US", true},
+		{"es, it", "", false},
+		{"", "", false},
+	} {
+		locale, ok := NegotiateLocale(test.header, supported)
+		assert.Equal(t, test.expected, locale, test.header)
+		assert.Equal(t, test.ok, ok, test.header)
+	}
+}
+
+func TestLocaleMiddleware(t *testing.T) {
+	middleware, err := NewLocaleMiddleware(&LocaleConfig{Supported: []string{"en", "de"}})
+	errors.AssertNil(t, err)
+	mapper := NewErrorMapper()
+	mapper.Map(errTestNotFound, 404)
+	mapper.SetLocalizer(func(locale string, err errors.Error) (string, bool) {
+		if locale == "de" && errors.InstanceOf(err, errTestNotFound) {
+			return "Eintrag nicht gefunden", true
+		}
+		return "", false
+	})
+
+	engine := gin.New()
+	engine.Use(middleware, mapper.Middleware())
+	engine.GET("/locale", func(c *gin.Context) {
+		c.String(200, LocaleFromContext(c.Request.Context()))
+	})
+	engine.GET("/missing", ErrorHandler(func(c *gin.Context) error {
+		return errTestNotFound.Make()
+	}))
+	serve := func(path, acceptLanguage string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("Accept-Language", acceptLanguage)
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		return w
+	}
+
+	w := serve("/locale", "de-DE,de;q=0.9,en;q=0.8")
+	assert.Equal(t, "de", w.Body.String())
+	assert.Equal(t, "de", w.Header().Get("Content-Language"))
+	assert.Equal(t, "Accept-Language", w.Header().Get("Vary"))
+	assert.Equal(t, "en", serve("/locale", "ja").Body.String())
+
+	var body errors.APIError
+	w = serve("/missing", "de")
+	assert.Equal(t, 404, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "Eintrag nicht gefunden", body.Message)
+	w = serve("/missing", "en")
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Contains(t, body.Message, "Item not found")
+
+	_, err = NewLocaleMiddleware(&LocaleConfig{})
+	errors.Assert(t, ErrInvalidConfig, err)
+}
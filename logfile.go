@@ -0,0 +1,238 @@
+package http
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sbreitf1/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// backupTimeFormat is used in the names of rotated log files like "server-2006-01-02T15-04-05.000.log".
+	backupTimeFormat = "2006-01-02T15-04-05.000"
+)
+
+// LogFileConfig defines a log file with rotation. The fields match those of lumberjack.Logger, so existing configurations can be reused.
+type LogFileConfig struct {
+	// Filename is the path of the current log file. Rotated files are stored next to it with the rotation time appended to the name.
+	Filename string `json:"filename"`
+	// MaxSize rotates the file before it exceeds the given number of megabytes. Defaults to 100.
+	MaxSize int `json:"maxsize,omitempty"`
+	// MaxAge removes rotated files older than the given number of days. Files are kept regardless of their age when 0.
+	MaxAge int `json:"maxage,omitempty"`
+	// MaxBackups limits the number of rotated files to retain. All files are kept when 0.
+	MaxBackups int `json:"maxbackups,omitempty"`
+	// LocalTime uses the local time instead of UTC in the names of rotated files.
+	LocalTime bool `json:"localtime,omitempty"`
+	// RotationInterval additionally rotates the file after the given duration regardless of its size, e.g. 24h for daily files. Disabled when 0.
+	RotationInterval Duration `json:"rotationInterval,omitempty"`
+}
+
+// RotatingFileWriter appends to a log file and rotates it by size and age. It can be used as output of loggers like the server log, see ServerConfig.LogFile, or an audit logger.
+type RotatingFileWriter struct {
+	config   LogFileConfig
+	maxBytes int64
+
+	mutex    sync.Mutex
+	file     *os.File
+	size     int64
+	openedAt time.Time
+}
+
+// NewRotatingFileWriter opens the configured log file for appending and creates missing directories.
+func NewRotatingFileWriter(config *LogFileConfig) (*RotatingFileWriter, errors.Error) {
+	if len(config.Filename) == 0 {
+		return nil, ErrInvalidConfig.Msg("Log file requires a file name").Make()
+	}
+	w := &RotatingFileWriter{config: *config, maxBytes: int64(config.MaxSize) << 20}
+	if w.maxBytes <= 0 {
+		w.maxBytes = 100 << 20
+	}
+	if err := w.open(); err != nil {
+		return nil, ErrInvalidConfig.Msg("Log file %q could not be opened", config.Filename).Make().Cause(err)
+	}
+	return w, nil
+}
+
+// Write appends p to the log file and rotates the file before if p would exceed the maximum size or the rotation interval has elapsed.
+func (w *RotatingFileWriter) Write(p []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.file == nil {
+		return 0, os.ErrClosed
+	}
+	expired := w.config.RotationInterval > 0 && time.Since(w.openedAt) >= time.Duration(w.config.RotationInterval)
+	if w.size > 0 && (w.size+int64(len(p)) > w.maxBytes || expired) {
+		// a failed rotation must not lose the entry as long as the current file is still open
+		if err := w.rotate(); err != nil && w.file == nil {
+			return 0, err
+		}
+	}
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Rotate closes the current log file, renames it with the current time and continues with a new file, e.g. on SIGHUP.
+func (w *RotatingFileWriter) Rotate() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.file == nil {
+		return os.ErrClosed
+	}
+	return w.rotate()
+}
+
+// Close closes the current log file. Subsequent writes fail.
+func (w *RotatingFileWriter) Close() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
+
+func (w *RotatingFileWriter) open() error {
+	if err := os.MkdirAll(filepath.Dir(w.config.Filename), 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(w.config.Filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	w.file, w.size, w.openedAt = file, info.Size(), time.Now()
+	return nil
+}
+
+func (w *RotatingFileWriter) rotate() error {
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+	w.file = nil
+	now := time.Now()
+	if !w.config.LocalTime {
+		now = now.UTC()
+	}
+	prefix, ext := w.backupNameParts()
+	renameErr := os.Rename(w.config.Filename, prefix+now.Format(backupTimeFormat)+ext)
+	// continue logging to the current file if it could not be renamed
+	if err := w.open(); err != nil {
+		return err
+	}
+	if renameErr != nil {
+		// the next attempt is made after another MaxSize bytes or RotationInterval instead of on every write
+		w.size = 0
+		return renameErr
+	}
+	w.removeBackups()
+	return nil
+}
+
+// backupNameParts returns the path of rotated files before and after the rotation time.
+func (w *RotatingFileWriter) backupNameParts() (string, string) {
+	ext := filepath.Ext(w.config.Filename)
+	return strings.TrimSuffix(w.config.Filename, ext) + "-", ext
+}
+
+// removeBackups deletes rotated files exceeding the configured number or age.
+func (w *RotatingFileWriter) removeBackups() {
+	if w.config.MaxBackups <= 0 && w.config.MaxAge <= 0 {
+		return
+	}
+	prefix, ext := w.backupNameParts()
+	files, err := ioutil.ReadDir(filepath.Dir(w.config.Filename))
+	if err != nil {
+		return
+	}
+
+	type backup struct {
+		path string
+		time time.Time
+	}
+	backups := make([]backup, 0)
+	for _, file := range files {
+		path := filepath.Join(filepath.Dir(w.config.Filename), file.Name())
+		if file.IsDir() || !strings.HasPrefix(path, prefix) || !strings.HasSuffix(path, ext) {
+			continue
+		}
+		location := time.UTC
+		if w.config.LocalTime {
+			location = time.Local
+		}
+		t, err := time.ParseInLocation(backupTimeFormat, strings.TrimSuffix(strings.TrimPrefix(path, prefix), ext), location)
+		if err != nil {
+			continue
+		}
+		backups = append(backups, backup{path, t})
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].time.After(backups[j].time)
+	})
+
+	cutoff := time.Now().Add(-time.Duration(w.config.MaxAge) * 24 * time.Hour)
+	for i, b := range backups {
+		if (w.config.MaxBackups > 0 && i >= w.config.MaxBackups) || (w.config.MaxAge > 0 && b.time.Before(cutoff)) {
+			os.Remove(b.path)
+		}
+	}
+}
+
+var (
+	logFilesMutex sync.Mutex
+	serverLogFile *RotatingFileWriter
+	accessLogFile *RotatingFileWriter
+)
+
+// applyLogFiles directs the server and access log output to the configured files. Both files are opened before any output is changed, so a failing configuration keeps the previous outputs. Files of a previous server are closed when replaced.
+func applyLogFiles(config *ServerConfig) errors.Error {
+	var serverLog, accessLog *RotatingFileWriter
+	if config.LogFile != nil {
+		w, err := NewRotatingFileWriter(config.LogFile)
+		if err != nil {
+			return err
+		}
+		serverLog = w
+	}
+	if config.AccessLogFile != nil {
+		w, err := NewRotatingFileWriter(config.AccessLogFile)
+		if err != nil {
+			if serverLog != nil {
+				serverLog.Close()
+			}
+			return err
+		}
+		accessLog = w
+	}
+
+	logFilesMutex.Lock()
+	defer logFilesMutex.Unlock()
+	if serverLog != nil {
+		log.SetOutput(serverLog)
+		if serverLogFile != nil {
+			serverLogFile.Close()
+		}
+		serverLogFile = serverLog
+	}
+	if accessLog != nil {
+		SetAccessLogOutput(accessLog)
+		if accessLogFile != nil {
+			accessLogFile.Close()
+		}
+		accessLogFile = accessLog
+	}
+	return nil
+}
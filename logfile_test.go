@@ -0,0 +1,145 @@
+package http
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sbreitf1/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func listBackups(t *testing.T, dir string) []string {
+	files, err := filepath.Glob(filepath.Join(dir, "app-*.log"))
+	assert.NoError(t, err)
+	sort.Strings(files)
+	return files
+}
+
+func TestRotatingFileWriter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logfile")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "logs", "app.log")
+
+	w, e := NewRotatingFileWriter(&LogFileConfig{Filename: name, MaxBackups: 2})
+	errors.AssertNil(t, e)
+	w.maxBytes = 10
+
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		time.Sleep(2 * time.Millisecond)
+		_, err := w.Write([]byte(line))
+		assert.NoError(t, err)
+	}
+	content, err := ioutil.ReadFile(name)
+	assert.NoError(t, err)
+	assert.Equal(t, "fourth\n", string(content))
+
+	// the oldest rotated file exceeds the number of backups
+	backups := listBackups(t, dir+"/logs")
+	assert.Len(t, backups, 2)
+	content, err = ioutil.ReadFile(backups[0])
+	assert.NoError(t, err)
+	assert.Equal(t, "second\n", string(content))
+
+	assert.NoError(t, w.Rotate())
+	content, err = ioutil.ReadFile(name)
+	assert.NoError(t, err)
+	assert.Empty(t, content)
+
+	assert.NoError(t, w.Close())
+	_, err = w.Write([]byte("closed\n"))
+	assert.Error(t, err)
+}
+
+func TestRotatingFileWriterAge(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logfile")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "app.log")
+
+	old := filepath.Join(dir, "app-"+time.Now().UTC().Add(-72*time.Hour).Format(backupTimeFormat)+".log")
+	assert.NoError(t, ioutil.WriteFile(old, []byte("old\n"), 0644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "app-other.log"), []byte("unrelated\n"), 0644))
+
+	w, e := NewRotatingFileWriter(&LogFileConfig{Filename: name, MaxAge: 1, RotationInterval: Duration(time.Millisecond)})
+	errors.AssertNil(t, e)
+	defer w.Close()
+	w.Write([]byte("first\n"))
+	time.Sleep(2 * time.Millisecond)
+	w.Write([]byte("second\n"))
+
+	backups := listBackups(t, dir)
+	assert.Len(t, backups, 2)
+	assert.NotContains(t, backups, old)
+	assert.Contains(t, backups, filepath.Join(dir, "app-other.log"))
+	content, err := ioutil.ReadFile(name)
+	assert.NoError(t, err)
+	assert.Equal(t, "second\n", string(content))
+
+	_, e = NewRotatingFileWriter(&LogFileConfig{})
+	assert.True(t, errors.InstanceOf(e, ErrInvalidConfig))
+}
+
+func TestServerLogFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logfile")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	defer log.SetOutput(log.StandardLogger().Out)
+	defer SetAccessLogOutput(nil)
+
+	server, e := NewServer(&ServerConfig{GinMode: gin.TestMode, LogFile: &LogFileConfig{Filename: filepath.Join(dir, "server.log")}, AccessLogFile: &LogFileConfig{Filename: filepath.Join(dir, "access.log")}})
+	errors.AssertNil(t, e)
+	server.Engine().GET("/logged", func(c *gin.Context) {
+		log.Info("handler called")
+		c.String(200, "ok")
+	})
+	server.Handler().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/logged", nil))
+
+	serverLog, err := ioutil.ReadFile(filepath.Join(dir, "server.log"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(serverLog), "handler called")
+	assert.False(t, strings.Contains(string(serverLog), "GET - /logged"))
+	accessLog, err := ioutil.ReadFile(filepath.Join(dir, "access.log"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(accessLog), "200 - GET - /logged")
+
+	// an invalid access log file keeps the previous outputs
+	previous := serverLogFile
+	_, e = NewServer(&ServerConfig{GinMode: gin.TestMode, LogFile: &LogFileConfig{Filename: filepath.Join(dir, "other.log")}, AccessLogFile: &LogFileConfig{}})
+	errors.Assert(t, ErrInvalidConfig, e)
+	assert.Equal(t, previous, log.StandardLogger().Out)
+
+	// replaced files are closed
+	errors.AssertNil(t, applyLogFiles(&ServerConfig{LogFile: &LogFileConfig{Filename: filepath.Join(dir, "other.log")}}))
+	_, err = previous.Write([]byte("closed\n"))
+	assert.Equal(t, os.ErrClosed, err)
+}
+
+func TestRotatingFileWriterRenameFailure(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logfile")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "app.log")
+
+	w, e := NewRotatingFileWriter(&LogFileConfig{Filename: name})
+	errors.AssertNil(t, e)
+	defer w.Close()
+	w.maxBytes = 10
+	_, err = w.Write([]byte("first\n"))
+	assert.NoError(t, err)
+
+	// a removed file cannot be renamed, so the entry is written to the reopened file and rotation is retried later
+	assert.NoError(t, os.Remove(name))
+	n, err := w.Write([]byte("second\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, 7, n)
+	assert.Equal(t, int64(7), w.size)
+}
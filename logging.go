@@ -0,0 +1,117 @@
+package http
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sbreitf1/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// RequestLogLevel controls the verbosity of client request logging.
+type RequestLogLevel int
+
+const (
+	// RequestLogNone disables request logging.
+	RequestLogNone RequestLogLevel = iota
+	// RequestLogBasic logs method, URL, status and duration of every request.
+	RequestLogBasic
+	// RequestLogHeaders additionally logs request and response headers with sensitive values redacted.
+	RequestLogHeaders
+	// RequestLogBodies additionally logs truncated request and response bodies.
+	RequestLogBodies
+)
+
+// RequestLogConfig defines how outgoing requests are logged.
+type RequestLogConfig struct {
+	Level RequestLogLevel
+	// BodyLimit denotes the maximum number of body bytes that are logged. Defaults to 1024.
+	BodyLimit int
+	// RedactHeaders lists headers whose values are replaced in the log. Authorization, Proxy-Authorization, Cookie and Set-Cookie are redacted when nil.
+	RedactHeaders []string
+}
+
+var defaultRedactHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+
+// Interceptor returns an interceptor that logs all requests using the package logger.
+func (config *RequestLogConfig) Interceptor() Interceptor {
+	return func(next Responder) Responder {
+		return func(req *Request) (*Response, errors.Error) {
+			if config.Level <= RequestLogNone {
+				return next(req)
+			}
+
+			entry := log.WithFields(log.Fields{"component": "client", "method": req.Method, "url": req.URL.Redacted()})
+			if config.Level >= RequestLogHeaders {
+				entry = entry.WithField("requestHeaders", config.sanitize(req.Header))
+			}
+			if config.Level >= RequestLogBodies && req.Body != nil {
+				body, err := readRequestBody(req)
+				if err != nil {
+					return nil, ErrInvalidRequest.Make().Cause(err)
+				}
+				entry = entry.WithField("requestBody", config.truncate(body))
+			}
+
+			start := time.Now()
+			response, err := next(req)
+			entry = entry.WithField("duration", time.Since(start))
+			if err != nil {
+				entry.WithField("error", err.Error()).Warn("Request failed")
+				return response, err
+			}
+
+			entry = entry.WithFields(log.Fields{"status": response.StatusCode, "proto": response.Proto})
+			if config.Level >= RequestLogHeaders {
+				entry = entry.WithField("responseHeaders", config.sanitize(response.Header))
+			}
+			if config.Level >= RequestLogBodies && response.Body != nil {
+				prefix, _ := ioutil.ReadAll(io.LimitReader(response.Body, int64(config.bodyLimit())))
+				response.Body = readCloser{io.MultiReader(bytes.NewReader(prefix), response.Body), response.Body}
+				entry = entry.WithField("responseBody", config.truncate(prefix))
+			}
+			entry.Info("Request sent")
+			return response, nil
+		}
+	}
+}
+
+func (config *RequestLogConfig) bodyLimit() int {
+	if config.BodyLimit > 0 {
+		return config.BodyLimit
+	}
+	return 1024
+}
+
+func (config *RequestLogConfig) truncate(body []byte) string {
+	if len(body) > config.bodyLimit() {
+		return string(body[:config.bodyLimit()]) + "..."
+	}
+	return string(body)
+}
+
+func (config *RequestLogConfig) sanitize(header http.Header) http.Header {
+	redact := config.RedactHeaders
+	if redact == nil {
+		redact = defaultRedactHeaders
+	}
+	sanitized := header.Clone()
+	for h := range sanitized {
+		for _, r := range redact {
+			if strings.EqualFold(h, r) {
+				sanitized[h] = []string{"[REDACTED]"}
+			}
+		}
+	}
+	return sanitized
+}
+
+// readCloser combines a reader with the closer of the original body.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
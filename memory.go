@@ -0,0 +1,173 @@
+package http
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+// handlerTransport dispatches requests directly to an http.Handler without opening sockets.
+type handlerTransport struct {
+	handler http.Handler
+}
+
+// NewHandlerTransport returns a transport that passes all requests directly to handler, e.g. to connect a Client to a Server in tests without listening on a port. Response bodies are streamed while the handler is running.
+func NewHandlerTransport(handler http.Handler) http.RoundTripper {
+	return &handlerTransport{handler: handler}
+}
+
+// NewInMemoryClient returns a client that sends all requests directly to the handler of the server, see NewHandlerTransport. The server does not need to be running, so BeginServing is not called for its services.
+func (server *Server) NewInMemoryClient() *Client {
+	client := NewClientWithTransport(NewHandlerTransport(server.Handler()))
+	client.BaseURL = "http://localhost"
+	return client
+}
+
+// RoundTrip executes the handler in a separate goroutine and returns as soon as the response headers are committed. Like net/http, small responses are buffered to determine Content-Length and Content-Type.
+func (t *handlerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	serverReq := req.Clone(req.Context())
+	serverReq.RequestURI = req.URL.RequestURI()
+	serverReq.RemoteAddr = "127.0.0.1:0"
+	if serverReq.Body == nil {
+		serverReq.Body = http.NoBody
+	}
+	if len(serverReq.Host) == 0 {
+		serverReq.Host = req.URL.Host
+	}
+
+	pipeReader, pipeWriter := io.Pipe()
+	w := &memoryResponseWriter{
+		header:  make(http.Header),
+		pipe:    pipeWriter,
+		request: req,
+		ready:   make(chan struct{}),
+	}
+	w.response = &http.Response{Proto: "HTTP/1.1", ProtoMajor: 1, ProtoMinor: 1, Body: pipeReader, Request: req}
+
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				// like net/http, the connection is aborted
+				err := fmt.Errorf("handler panic: %v", r)
+				w.abort(err)
+				pipeWriter.CloseWithError(err)
+				return
+			}
+			w.finish()
+			pipeWriter.Close()
+		}()
+		t.handler.ServeHTTP(w, serverReq)
+	}()
+
+	select {
+	case <-w.ready:
+		if w.err != nil {
+			return nil, w.err
+		}
+		return w.response, nil
+	case <-req.Context().Done():
+		pipeReader.CloseWithError(req.Context().Err())
+		return nil, req.Context().Err()
+	}
+}
+
+// memoryResponseWriter streams the response of a handler through a pipe.
+type memoryResponseWriter struct {
+	header   http.Header
+	pipe     *io.PipeWriter
+	request  *http.Request
+	response *http.Response
+	status   int
+	buffer   []byte
+	err      error
+	ready    chan struct{}
+	once     sync.Once
+}
+
+func (w *memoryResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *memoryResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *memoryResponseWriter) Write(data []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	if w.request.Method == http.MethodHead {
+		return len(data), nil
+	}
+	if !w.committed() {
+		w.buffer = append(w.buffer, data...)
+		if len(w.buffer) > memoryBufferSize {
+			return len(data), w.flush()
+		}
+		return len(data), nil
+	}
+	return w.pipe.Write(data)
+}
+
+// Flush commits the response headers and passes all written content to the client.
+func (w *memoryResponseWriter) Flush() {
+	w.WriteHeader(http.StatusOK)
+	w.flush()
+}
+
+// memoryBufferSize denotes the amount of content that is buffered before the response is committed without Content-Length.
+const memoryBufferSize = 2048
+
+func (w *memoryResponseWriter) committed() bool {
+	select {
+	case <-w.ready:
+		return true
+	default:
+		return false
+	}
+}
+
+func (w *memoryResponseWriter) commit() {
+	w.once.Do(func() {
+		if len(w.header.Get("Content-Type")) == 0 && len(w.buffer) > 0 && len(w.header.Get("Content-Encoding")) == 0 {
+			w.header.Set("Content-Type", http.DetectContentType(w.buffer))
+		}
+		w.response.StatusCode = w.status
+		w.response.Status = fmt.Sprintf("%d %s", w.status, http.StatusText(w.status))
+		w.response.Header = w.header.Clone()
+		w.response.ContentLength = -1
+		if length, err := strconv.ParseInt(w.header.Get("Content-Length"), 10, 64); err == nil {
+			w.response.ContentLength = length
+		}
+		close(w.ready)
+	})
+}
+
+func (w *memoryResponseWriter) flush() error {
+	w.commit()
+	buffer := w.buffer
+	w.buffer = nil
+	if len(buffer) > 0 {
+		_, err := w.pipe.Write(buffer)
+		return err
+	}
+	return nil
+}
+
+// finish commits responses that are still buffered when the handler returns with their Content-Length.
+func (w *memoryResponseWriter) finish() {
+	w.WriteHeader(http.StatusOK)
+	if !w.committed() && len(w.header.Get("Content-Length")) == 0 && len(w.header.Get("Transfer-Encoding")) == 0 {
+		w.header.Set("Content-Length", strconv.Itoa(len(w.buffer)))
+	}
+	w.flush()
+}
+
+func (w *memoryResponseWriter) abort(err error) {
+	w.once.Do(func() {
+		w.err = err
+		close(w.ready)
+	})
+}
@@ -0,0 +1,75 @@
+package http
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sbreitf1/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInMemoryClient(t *testing.T) {
+	server, _ := newTestServer()
+	server.RegisterService("test-service", newTestService(t))
+	server.Engine().POST("/echo", func(c *gin.Context) {
+		data, _ := ioutil.ReadAll(c.Request.Body)
+		c.Header("X-Host", c.Request.Host)
+		c.String(201, string(data))
+	})
+	client := server.NewInMemoryClient()
+
+	response, err := client.Get("/healthz", ExpectSuccess())
+	errors.AssertNil(t, err)
+	DrainAndClose(response)
+
+	response, err = client.Post("/echo", "text/plain", strings.NewReader("ping"), nil)
+	errors.AssertNil(t, err)
+	assertResponse(t, 201, "ping", response)
+	assert.Equal(t, "localhost", response.Header.Get("X-Host"))
+
+	response, err = client.Get("/panic", nil)
+	errors.AssertNil(t, err)
+	assert.Equal(t, 500, response.StatusCode)
+	DrainAndClose(response)
+
+	response, err = client.Get("/nonexistent", nil)
+	errors.AssertNil(t, err)
+	assertResponse(t, 404, "404 page not found", response)
+}
+
+func TestHandlerTransportStreaming(t *testing.T) {
+	proceed := make(chan struct{})
+	client := NewClientWithTransport(NewHandlerTransport(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("first"))
+		w.(http.Flusher).Flush()
+		<-proceed
+		w.Write([]byte(" second"))
+	})))
+
+	response, err := client.Get("http://service/", nil)
+	errors.AssertNil(t, err)
+	buffer := make([]byte, 5)
+	_, readErr := response.Body.Read(buffer)
+	assert.NoError(t, readErr)
+	assert.Equal(t, "first", string(buffer))
+	close(proceed)
+	body, err := ReadResponseString(response)
+	errors.AssertNil(t, err)
+	assert.Equal(t, " second", body)
+}
+
+func TestHandlerTransportCanceled(t *testing.T) {
+	client := NewClientWithTransport(NewHandlerTransport(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	})))
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := client.DoContext(ctx, MethodGet, "http://service/", nil, nil)
+	errors.Assert(t, ErrRequestFailed, err)
+	assert.True(t, IsTimeout(err))
+}
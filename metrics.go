@@ -0,0 +1,53 @@
+package http
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sbreitf1/errors"
+)
+
+var (
+	clientMetricsOnce     sync.Once
+	clientRequestsTotal   *prometheus.CounterVec
+	clientRequestDuration *prometheus.HistogramVec
+)
+
+// registerClientMetrics registers the client metrics on the default prometheus registry that is also exposed by the Server on /metrics.
+func registerClientMetrics() {
+	clientMetricsOnce.Do(func() {
+		clientRequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+			Subsystem: "client",
+			Name:      "requests_total",
+			Help:      "Number of outgoing HTTP requests by host, method and status class.",
+		}, []string{"host", "method", "status"})
+		clientRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Subsystem: "client",
+			Name:      "request_duration_seconds",
+			Help:      "Latency of outgoing HTTP requests by host, method and status class.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"host", "method", "status"})
+		prometheus.MustRegister(clientRequestsTotal, clientRequestDuration)
+	})
+}
+
+// MetricsInterceptor returns an interceptor that records prometheus metrics for all requests. Failed requests are labeled with status "error".
+func MetricsInterceptor() Interceptor {
+	registerClientMetrics()
+	return func(next Responder) Responder {
+		return func(req *Request) (*Response, errors.Error) {
+			start := time.Now()
+			response, err := next(req)
+
+			status := "error"
+			if err == nil {
+				status = strconv.Itoa(response.StatusCode/100) + "xx"
+			}
+			clientRequestsTotal.WithLabelValues(req.URL.Host, req.Method, status).Inc()
+			clientRequestDuration.WithLabelValues(req.URL.Host, req.Method, status).Observe(time.Since(start).Seconds())
+			return response, err
+		}
+	}
+}
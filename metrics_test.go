@@ -0,0 +1,34 @@
+package http
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sbreitf1/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientMetrics(t *testing.T) {
+	client := NewClient()
+	client.Metrics = true
+	status := 200
+	client.RequestResponder = func(req *Request) (*Response, errors.Error) {
+		if status == 0 {
+			return nil, errors.GenericError.Make()
+		}
+		return &Response{StatusCode: status, Body: ioutil.NopCloser(strings.NewReader(""))}, nil
+	}
+
+	client.Do(MethodGet, "http://metrics.test/", nil)
+	client.Do(MethodGet, "http://metrics.test/", nil)
+	status = 404
+	client.Do(MethodGet, "http://metrics.test/", nil)
+	status = 0
+	client.Do(MethodPost, "http://metrics.test/", nil)
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(clientRequestsTotal.WithLabelValues("metrics.test", "GET", "2xx")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(clientRequestsTotal.WithLabelValues("metrics.test", "GET", "4xx")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(clientRequestsTotal.WithLabelValues("metrics.test", "POST", "error")))
+}
@@ -0,0 +1,343 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sbreitf1/errors"
+)
+
+var (
+	// ErrUnexpectedRequest is returned by a MockTransport for requests that do not match any expectation.
+	ErrUnexpectedRequest = errors.New("Unexpected request")
+)
+
+// MockTransport answers requests with scripted responses and records all received requests. Assign the result of Responder to Client.RequestResponder to use it.
+type MockTransport struct {
+	mutex        sync.Mutex
+	expectations []*MockExpectation
+	requests     []*RecordedRequest
+	unexpected   []string
+	random       *rand.Rand
+}
+
+// MockExpectation describes a request expected by a MockTransport and the response to return.
+type MockExpectation struct {
+	method    RequestMethod
+	pattern   string
+	header    Header
+	times     int
+	calls     int
+	responder Responder
+	latency   time.Duration
+	faults    []mockFault
+}
+
+// mockFault is injected into the given fraction of matching requests.
+type mockFault struct {
+	rate  float64
+	apply func(req *Request, response *Response, err errors.Error) (*Response, errors.Error)
+}
+
+// RecordedRequest contains a request received by a MockTransport.
+type RecordedRequest struct {
+	Method RequestMethod
+	URL    *url.URL
+	Header Header
+	Body   []byte
+}
+
+// NewMockTransport returns a new mock transport without expectations. Faults are injected with a fixed seed, so test runs are reproducible.
+func NewMockTransport() *MockTransport {
+	return &MockTransport{}
+}
+
+// Seed resets the random source that decides which requests are affected by faults.
+func (m *MockTransport) Seed(seed int64) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.random = rand.New(rand.NewSource(seed))
+}
+
+// On adds an expectation for requests with the given method and URL pattern. Patterns starting with a slash are matched against the URL path, all others against the URL without query. Wildcards are supported as in path.Match. An empty method matches all methods. Expectations are matched in the order they have been added.
+func (m *MockTransport) On(method RequestMethod, pattern string) *MockExpectation {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	expectation := &MockExpectation{method: method, pattern: pattern, header: make(Header)}
+	expectation.Respond(200, "")
+	m.expectations = append(m.expectations, expectation)
+	return expectation
+}
+
+// WithHeader restricts the expectation to requests that contain the given header value.
+func (e *MockExpectation) WithHeader(key, value string) *MockExpectation {
+	e.header.Add(key, value)
+	return e
+}
+
+// Times limits how often the expectation can be matched. Unlimited when zero.
+func (e *MockExpectation) Times(n int) *MockExpectation {
+	e.times = n
+	return e
+}
+
+// Once limits the expectation to a single match.
+func (e *MockExpectation) Once() *MockExpectation {
+	return e.Times(1)
+}
+
+// Respond returns a response with the given status code and body for matching requests.
+func (e *MockExpectation) Respond(status int, body string) *MockExpectation {
+	return e.RespondWith(func(req *Request) (*Response, errors.Error) {
+		return newMockResponse(req, status, make(Header), []byte(body)), nil
+	})
+}
+
+// RespondJSON returns a response with the given status code and the JSON encoded object as body for matching requests.
+func (e *MockExpectation) RespondJSON(status int, obj interface{}) *MockExpectation {
+	data, err := json.Marshal(obj)
+	return e.RespondWith(func(req *Request) (*Response, errors.Error) {
+		if err != nil {
+			return nil, errors.Wrap(err)
+		}
+		return newMockResponse(req, status, Header{"Content-Type": []string{"application/json"}}, data), nil
+	})
+}
+
+// RespondError returns the given error for matching requests.
+func (e *MockExpectation) RespondError(err errors.Error) *MockExpectation {
+	return e.RespondWith(func(req *Request) (*Response, errors.Error) {
+		return nil, err
+	})
+}
+
+// RespondWith answers matching requests with the given responder.
+func (e *MockExpectation) RespondWith(responder Responder) *MockExpectation {
+	e.responder = responder
+	return e
+}
+
+// WithLatency delays the response to matching requests. Requests whose context is done while waiting fail with ErrRequestFailed classified as FailureTimeout or FailureCanceled.
+func (e *MockExpectation) WithLatency(latency time.Duration) *MockExpectation {
+	e.latency = latency
+	return e
+}
+
+// DropConnections lets the given fraction (0 to 1) of matching requests fail like a connection closed by the server before responding.
+func (e *MockExpectation) DropConnections(rate float64) *MockExpectation {
+	return e.addFault(rate, func(req *Request, response *Response, err errors.Error) (*Response, errors.Error) {
+		if err == nil {
+			DrainAndClose(response)
+		}
+		return nil, transportError(&url.Error{Op: req.Method, URL: req.URL.String(), Err: io.EOF})
+	})
+}
+
+// FailWithStatus answers the given fraction (0 to 1) of matching requests with the status code and an empty body instead of the scripted response.
+func (e *MockExpectation) FailWithStatus(rate float64, status int) *MockExpectation {
+	return e.addFault(rate, func(req *Request, response *Response, err errors.Error) (*Response, errors.Error) {
+		if err == nil {
+			DrainAndClose(response)
+		}
+		return newMockResponse(req, status, make(Header), nil), nil
+	})
+}
+
+// TruncateBody cuts the response body of the given fraction (0 to 1) of matching requests after n bytes. Reading beyond fails with io.ErrUnexpectedEOF, while Content-Length still announces the complete body.
+func (e *MockExpectation) TruncateBody(rate float64, n int64) *MockExpectation {
+	return e.addFault(rate, func(req *Request, response *Response, err errors.Error) (*Response, errors.Error) {
+		if err == nil && response.Body != nil {
+			response.Body = &truncatedBody{body: response.Body, remaining: n}
+		}
+		return response, err
+	})
+}
+
+func (e *MockExpectation) addFault(rate float64, apply func(*Request, *Response, errors.Error) (*Response, errors.Error)) *MockExpectation {
+	e.faults = append(e.faults, mockFault{rate: rate, apply: apply})
+	return e
+}
+
+// truncatedBody fails with io.ErrUnexpectedEOF after the remaining bytes have been read.
+type truncatedBody struct {
+	body      io.ReadCloser
+	remaining int64
+}
+
+func (b *truncatedBody) Read(p []byte) (int, error) {
+	if b.remaining <= 0 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	if int64(len(p)) > b.remaining {
+		p = p[:b.remaining]
+	}
+	n, err := b.body.Read(p)
+	b.remaining -= int64(n)
+	return n, err
+}
+
+func (b *truncatedBody) Close() error {
+	return b.body.Close()
+}
+
+func (e *MockExpectation) matches(req *Request) bool {
+	if len(e.method) > 0 && string(e.method) != req.Method {
+		return false
+	}
+	if e.times > 0 && e.calls >= e.times {
+		return false
+	}
+
+	target := req.URL.Path
+	if !strings.HasPrefix(e.pattern, "/") {
+		u := *req.URL
+		u.RawQuery = ""
+		u.Fragment = ""
+		target = u.String()
+	}
+	if ok, _ := path.Match(e.pattern, target); !ok {
+		return false
+	}
+
+	for key, values := range e.header {
+		for _, v := range values {
+			if !containsString(req.Header.Values(key), v) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Responder returns a responder that answers requests according to the expectations.
+func (m *MockTransport) Responder() Responder {
+	return func(req *Request) (*Response, errors.Error) {
+		body, err := readRequestBody(req)
+		if err != nil {
+			return nil, errors.Wrap(err)
+		}
+
+		m.mutex.Lock()
+		m.requests = append(m.requests, &RecordedRequest{Method: RequestMethod(req.Method), URL: req.URL, Header: req.Header.Clone(), Body: body})
+		var expectation *MockExpectation
+		for _, e := range m.expectations {
+			if e.matches(req) {
+				expectation = e
+				expectation.calls++
+				break
+			}
+		}
+		var fault *mockFault
+		if expectation == nil {
+			m.unexpected = append(m.unexpected, fmt.Sprintf("%s %s", req.Method, req.URL))
+		} else {
+			if m.random == nil {
+				m.random = rand.New(rand.NewSource(1))
+			}
+			// every fault draws a number, so the decisions for one fault do not depend on the rates of the others
+			for i := range expectation.faults {
+				if m.random.Float64() < expectation.faults[i].rate && fault == nil {
+					fault = &expectation.faults[i]
+				}
+			}
+		}
+		m.mutex.Unlock()
+
+		if expectation == nil {
+			return nil, ErrUnexpectedRequest.Msg("Unexpected request %s %s", req.Method, req.URL).Make()
+		}
+		if expectation.latency > 0 {
+			if err := sleepContext(req.Context(), expectation.latency); err != nil {
+				return nil, transportError(err)
+			}
+		}
+		response, responseErr := expectation.responder(req)
+		if fault != nil {
+			return fault.apply(req, response, responseErr)
+		}
+		return response, responseErr
+	}
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Requests returns all requests received so far.
+func (m *MockTransport) Requests() []*RecordedRequest {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	requests := make([]*RecordedRequest, len(m.requests))
+	copy(requests, m.requests)
+	return requests
+}
+
+// TestingT is the part of testing.TB used to report failures, so the package does not depend on the testing package.
+type TestingT interface {
+	Errorf(format string, args ...interface{})
+}
+
+// AssertExpectations fails the test for unexpected requests and for expectations with a call limit that have not been fully met.
+func (m *MockTransport) AssertExpectations(t TestingT) bool {
+	if h, ok := t.(interface{ Helper() }); ok {
+		h.Helper()
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	ok := true
+	for _, r := range m.unexpected {
+		t.Errorf("unexpected request %s", r)
+		ok = false
+	}
+	for _, e := range m.expectations {
+		if e.times > 0 && e.calls < e.times {
+			t.Errorf("expected %d calls to %s %s, got %d", e.times, e.method, e.pattern, e.calls)
+			ok = false
+		}
+	}
+	return ok
+}
+
+func newMockResponse(req *Request, status int, header Header, body []byte) *Response {
+	return &Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+func containsString(list []string, str string) bool {
+	for _, s := range list {
+		if s == str {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,108 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sbreitf1/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMockTransport(t *testing.T) {
+	mock := NewMockTransport()
+	mock.On(MethodGet, "/users/*").WithHeader("Accept", "application/json").RespondJSON(200, map[string]string{"name": "alice"})
+	mock.On(MethodPost, "http://api.test/users").Once().Respond(201, "created")
+	mock.On("", "/fail").RespondError(errors.GenericError.Make())
+
+	client := NewClient()
+	client.BaseURL = "http://api.test"
+	client.RequestResponder = mock.Responder()
+
+	var user map[string]string
+	errors.AssertNil(t, client.GetJSON("/users/42", &user, nil))
+	assert.Equal(t, "alice", user["name"])
+
+	response, err := client.DoWithBody(MethodPost, "/users", "text/plain", strings.NewReader("bob"), nil)
+	errors.AssertNil(t, err)
+	assertResponse(t, 201, "created", response)
+
+	_, err = client.Do(MethodDelete, "/fail", nil)
+	errors.Assert(t, ErrRequestFailed, err)
+
+	requests := mock.Requests()
+	assert.Len(t, requests, 3)
+	assert.Equal(t, MethodPost, requests[1].Method)
+	assert.Equal(t, "bob", string(requests[1].Body))
+	assert.True(t, mock.AssertExpectations(t))
+
+	// the POST expectation is exhausted
+	_, err = client.DoWithBody(MethodPost, "/users", "text/plain", strings.NewReader("carol"), nil)
+	errors.Assert(t, ErrRequestFailed, err)
+	_, err = client.Do(MethodGet, "/users/42", nil)
+	errors.Assert(t, ErrRequestFailed, err)
+
+	recorder := &failureRecorder{}
+	assert.False(t, mock.AssertExpectations(recorder))
+	assert.Len(t, recorder.failures, 2)
+}
+
+type failureRecorder struct {
+	failures []string
+}
+
+func (r *failureRecorder) Errorf(format string, args ...interface{}) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+func TestMockTransportFaults(t *testing.T) {
+	mock := NewMockTransport()
+	mock.On(MethodGet, "/slow").WithLatency(100 * time.Millisecond)
+	mock.On(MethodGet, "/flaky").DropConnections(0.5)
+	mock.On(MethodGet, "/unavailable").Respond(200, "ok").FailWithStatus(1, 503)
+	mock.On(MethodGet, "/partial").Respond(200, "0123456789").TruncateBody(1, 4)
+	client := NewClient()
+	client.RequestResponder = mock.Responder()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := client.DoContext(ctx, MethodGet, "http://service/slow", nil, nil)
+	errors.Assert(t, ErrRequestFailed, err)
+	assert.True(t, IsTimeout(err))
+
+	failures := func() int {
+		count := 0
+		for i := 0; i < 100; i++ {
+			if _, err := client.Get("http://service/flaky", nil); err != nil {
+				errors.Assert(t, ErrRequestFailed, err)
+				count++
+			}
+		}
+		return count
+	}
+	mock.Seed(42)
+	first := failures()
+	assert.InDelta(t, 50, first, 15)
+	mock.Seed(42)
+	assert.Equal(t, first, failures())
+
+	response, err := client.Get("http://service/unavailable", nil)
+	errors.AssertNil(t, err)
+	assert.Equal(t, 503, response.StatusCode)
+
+	response, err = client.Get("http://service/partial", nil)
+	errors.AssertNil(t, err)
+	assert.Equal(t, int64(10), response.ContentLength)
+	data, readErr := ioutil.ReadAll(response.Body)
+	assert.Equal(t, io.ErrUnexpectedEOF, readErr)
+	assert.Equal(t, "0123", string(data))
+
+	client.Retry = &RetryPolicy{MaxAttempts: 20}
+	mock.Seed(1)
+	_, err = client.Get("http://service/flaky", ExpectSuccess())
+	errors.AssertNil(t, err)
+}
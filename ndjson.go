@@ -0,0 +1,103 @@
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sbreitf1/errors"
+)
+
+const (
+	// ContentTypeNDJSON denotes newline-delimited JSON streams.
+	ContentTypeNDJSON = "application/x-ndjson"
+)
+
+// NDJSONEncoder writes the items of a newline-delimited JSON stream.
+type NDJSONEncoder struct {
+	w *StreamWriter
+}
+
+// Context returns the context of the underlying stream, see StreamWriter.Context.
+func (e *NDJSONEncoder) Context() context.Context {
+	return e.w.Context()
+}
+
+// Encode writes item as a single line and flushes it to the client.
+func (e *NDJSONEncoder) Encode(item interface{}) error {
+	buf := getBuffer()
+	defer putBuffer(buf)
+	if err := json.NewEncoder(buf).Encode(item); err != nil {
+		return err
+	}
+	_, err := e.w.Write(buf.Bytes())
+	return err
+}
+
+// StreamNDJSON answers the request with a newline-delimited JSON stream of the items encoded by stream, see StreamResponse. The content type defaults to ContentTypeNDJSON, keep-alives are sent as empty lines.
+func (server *Server) StreamNDJSON(c *gin.Context, config *StreamConfig, stream func(*NDJSONEncoder) error) {
+	ndjsonConfig := StreamConfig{ContentType: ContentTypeNDJSON}
+	if config != nil {
+		ndjsonConfig = *config
+		if len(ndjsonConfig.ContentType) == 0 {
+			ndjsonConfig.ContentType = ContentTypeNDJSON
+		}
+	}
+	server.StreamResponse(c, 200, &ndjsonConfig, func(w *StreamWriter) error {
+		return stream(&NDJSONEncoder{w})
+	})
+}
+
+// DecodeNDJSON decodes the newline-delimited JSON body of the given response item by item and passes each item to handle. Empty lines are skipped. Decoding stops at the first error returned by handle, which is returned as is. The body is closed afterwards.
+func DecodeNDJSON[T any](response *Response, handle func(T) error) errors.Error {
+	if response == nil || response.Body == nil {
+		return nil
+	}
+	// streams may be endless, so the remaining body is not drained
+	defer response.Body.Close()
+
+	reader := bufio.NewReader(response.Body)
+	for lineNumber := 1; ; lineNumber++ {
+		line, err := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			var item T
+			if err := json.Unmarshal(line, &item); err != nil {
+				return ErrInvalidBody.Msg("Invalid NDJSON item in line %d", lineNumber).Make().Cause(err)
+			}
+			if err := handle(item); err != nil {
+				return errors.Wrap(err)
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return ErrInvalidBody.Make().Cause(err)
+		}
+	}
+}
+
+// DecodeNDJSONChannel decodes the newline-delimited JSON body of the given response in the background and sends the items to the returned channel, which is closed at the end of the body. A decoding error is sent to the error channel afterwards. Decoding stops and the body is closed when ctx is done.
+func DecodeNDJSONChannel[T any](ctx context.Context, response *Response) (<-chan T, <-chan errors.Error) {
+	items := make(chan T)
+	errs := make(chan errors.Error, 1)
+	go func() {
+		defer close(errs)
+		defer close(items)
+		err := DecodeNDJSON(response, func(item T) error {
+			select {
+			case items <- item:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+		if err != nil {
+			errs <- err
+		}
+	}()
+	return items, errs
+}
@@ -0,0 +1,100 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sbreitf1/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNDJSON(t *testing.T) {
+	server, err := NewServer(&ServerConfig{GinMode: gin.TestMode})
+	errors.AssertNil(t, err)
+	server.Engine().GET("/items", func(c *gin.Context) {
+		server.StreamNDJSON(c, nil, func(e *NDJSONEncoder) error {
+			for i := 1; i <= 3; i++ {
+				if err := e.Encode(testItem{ID: fmt.Sprint(i), Name: "item"}); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+	server.Engine().GET("/endless", func(c *gin.Context) {
+		server.StreamNDJSON(c, &StreamConfig{KeepAlive: 10 * time.Millisecond}, func(e *NDJSONEncoder) error {
+			for i := 1; ; i++ {
+				if err := e.Encode(testItem{ID: fmt.Sprint(i)}); err != nil {
+					return err
+				}
+				time.Sleep(15 * time.Millisecond)
+			}
+		})
+	})
+	httpServer := httptest.NewServer(server.Handler())
+	defer httpServer.Close()
+
+	response, err := NewClient().Get(httpServer.URL+"/items", nil)
+	errors.AssertNil(t, err)
+	assert.Equal(t, ContentTypeNDJSON, response.Header.Get("Content-Type"))
+	ids := make([]string, 0)
+	err = DecodeNDJSON(response, func(item testItem) error {
+		ids = append(ids, item.ID)
+		return nil
+	})
+	errors.AssertNil(t, err)
+	assert.Equal(t, []string{"1", "2", "3"}, ids)
+
+	// handler errors stop decoding
+	response, err = NewClient().Get(httpServer.URL+"/endless", nil)
+	errors.AssertNil(t, err)
+	count := 0
+	err = DecodeNDJSON(response, func(item testItem) error {
+		count++
+		if count == 3 {
+			return fmt.Errorf("enough")
+		}
+		return nil
+	})
+	assert.NotNil(t, err)
+	assert.Equal(t, 3, count)
+
+	response, err = NewClient().Get(httpServer.URL+"/endless", nil)
+	errors.AssertNil(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	items, errs := DecodeNDJSONChannel[testItem](ctx, response)
+	assert.Equal(t, "1", (<-items).ID)
+	assert.Equal(t, "2", (<-items).ID)
+	cancel()
+	for range items {
+	}
+	assert.NotNil(t, <-errs)
+}
+
+func TestDecodeNDJSONInvalid(t *testing.T) {
+	response := &Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader("{\"id\":\"1\"}\n\n{\"id\":\"2\"}\n{invalid\n"))}
+	items, errs := DecodeNDJSONChannel[testItem](context.Background(), response)
+	ids := make([]string, 0)
+	for item := range items {
+		ids = append(ids, item.ID)
+	}
+	assert.Equal(t, []string{"1", "2"}, ids)
+	err := <-errs
+	assert.True(t, errors.InstanceOf(err, ErrInvalidBody))
+	assert.Contains(t, err.Error(), "line 4")
+
+	// the last line does not require a newline
+	response = &Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader("{\"id\":\"1\"}"))}
+	count := 0
+	errors.AssertNil(t, DecodeNDJSON(response, func(item testItem) error {
+		count++
+		return nil
+	}))
+	assert.Equal(t, 1, count)
+}
@@ -0,0 +1,191 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sbreitf1/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// HealthNotifierConfig contains the parameters of a HealthNotifier.
+type HealthNotifierConfig struct {
+	// WebhookURLs receive a POST request for every state change.
+	WebhookURLs []string `json:"webhookUrls"`
+	// Slack sends Slack-compatible payloads ({"text": "..."}) instead of HealthEvent objects.
+	Slack bool `json:"slack,omitempty"`
+	// Interval between two evaluations of the server probes. Defaults to 10 seconds.
+	Interval Duration `json:"interval,omitempty"`
+	// Debounce denotes how long a new state must persist before it is notified to suppress flapping. Defaults to 30 seconds.
+	Debounce Duration `json:"debounce,omitempty"`
+	// Timeout limits each webhook request. Defaults to 5 seconds.
+	Timeout Duration `json:"timeout,omitempty"`
+}
+
+// HealthEvent is sent to the webhooks when the aggregated health or readiness of a server changes.
+type HealthEvent struct {
+	Subsystem string `json:"subsystem,omitempty"`
+	// Probe is either "health" or "readiness".
+	Probe string `json:"probe"`
+	// Status is either "up" or "down".
+	Status         string    `json:"status"`
+	PreviousStatus string    `json:"previousStatus"`
+	Time           time.Time `json:"time"`
+	// Failures maps the names of failed services to their error messages.
+	Failures map[string]string `json:"failures,omitempty"`
+}
+
+// HealthNotifier periodically evaluates the probes of a server and notifies webhooks about state changes.
+type HealthNotifier struct {
+	config HealthNotifierConfig
+	server *Server
+	client *http.Client
+	stop   chan struct{}
+	once   sync.Once
+
+	states [2]notifierState
+}
+
+type notifierState struct {
+	initialized bool
+	reported    bool
+	candidate   bool
+	since       time.Time
+}
+
+// NewHealthNotifier starts evaluating the health and readiness of server in the background until Stop is called. The initial state is not notified.
+func NewHealthNotifier(server *Server, config *HealthNotifierConfig) (*HealthNotifier, errors.Error) {
+	if len(config.WebhookURLs) == 0 {
+		return nil, ErrInvalidConfig.Msg("Health notifier requires at least one webhook URL").Make()
+	}
+	for _, u := range config.WebhookURLs {
+		if !strings.HasPrefix(u, "http://") && !strings.HasPrefix(u, "https://") {
+			return nil, ErrInvalidConfig.Msg("Webhook URL %q must be absolute", u).Make()
+		}
+	}
+
+	n := &HealthNotifier{config: *config, server: server, stop: make(chan struct{})}
+	if n.config.Interval <= 0 {
+		n.config.Interval = Duration(10 * time.Second)
+	}
+	if n.config.Debounce < 0 {
+		n.config.Debounce = 0
+	} else if n.config.Debounce == 0 {
+		n.config.Debounce = Duration(30 * time.Second)
+	}
+	if n.config.Timeout <= 0 {
+		n.config.Timeout = Duration(5 * time.Second)
+	}
+	n.client = &http.Client{Timeout: time.Duration(n.config.Timeout)}
+
+	n.evaluate(time.Now())
+	go n.run()
+	return n, nil
+}
+
+// Stop ends the background evaluation.
+func (n *HealthNotifier) Stop() {
+	n.once.Do(func() {
+		close(n.stop)
+	})
+}
+
+func (n *HealthNotifier) run() {
+	ticker := time.NewTicker(time.Duration(n.config.Interval))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-n.stop:
+			return
+		case now := <-ticker.C:
+			n.evaluate(now)
+		}
+	}
+}
+
+// evaluate updates the state of both probes and notifies changes that persisted for the debounce duration.
+func (n *HealthNotifier) evaluate(now time.Time) {
+	for i, probe := range []string{"health", "readiness"} {
+		errs := n.server.probeErrors(i == 0)
+		up := len(errs) == 0
+
+		st := &n.states[i]
+		if !st.initialized {
+			*st = notifierState{initialized: true, reported: up, candidate: up, since: now}
+			continue
+		}
+		if up != st.candidate {
+			st.candidate, st.since = up, now
+		}
+		if st.candidate != st.reported && now.Sub(st.since) >= time.Duration(n.config.Debounce) {
+			st.reported = st.candidate
+			event := HealthEvent{
+				Subsystem:      n.server.config.SubSystemName,
+				Probe:          probe,
+				Status:         statusName(up),
+				PreviousStatus: statusName(!up),
+				Time:           now,
+			}
+			if len(errs) > 0 {
+				event.Failures = make(map[string]string, len(errs))
+				for _, e := range errs {
+					event.Failures[e.ServiceName] = e.Message
+				}
+			}
+			n.notify(&event)
+		}
+	}
+}
+
+func statusName(up bool) string {
+	if up {
+		return "up"
+	}
+	return "down"
+}
+
+func (n *HealthNotifier) notify(event *HealthEvent) {
+	var payload interface{} = event
+	if n.config.Slack {
+		payload = map[string]string{"text": slackText(event)}
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return
+	}
+
+	for _, u := range n.config.WebhookURLs {
+		resp, err := n.client.Post(u, "application/json", bytes.NewReader(data))
+		if err != nil {
+			log.WithFields(log.Fields{"component": "notifier"}).Warnf("Health webhook %s failed: %s", u, err)
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			log.WithFields(log.Fields{"component": "notifier"}).Warnf("Health webhook %s responded with status %d", u, resp.StatusCode)
+		}
+	}
+}
+
+func slackText(event *HealthEvent) string {
+	name := event.Subsystem
+	if len(name) == 0 {
+		name = "Server"
+	}
+	text := fmt.Sprintf("%s %s changed from %s to %s", name, event.Probe, event.PreviousStatus, event.Status)
+	names := make([]string, 0, len(event.Failures))
+	for service := range event.Failures {
+		names = append(names, service)
+	}
+	sort.Strings(names)
+	for _, service := range names {
+		text += fmt.Sprintf("\n• %s: %s", service, event.Failures[service])
+	}
+	return text
+}
@@ -0,0 +1,113 @@
+package http
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sbreitf1/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type toggleService struct {
+	ready int32
+}
+
+func (svc *toggleService) RegisterRoutes(e *gin.Engine) {}
+func (svc *toggleService) BeginServing()                {}
+func (svc *toggleService) StopServing()                 {}
+func (svc *toggleService) Healthy() errors.Error        { return nil }
+func (svc *toggleService) Ready() errors.Error {
+	if atomic.LoadInt32(&svc.ready) == 0 {
+		return ErrDependencyUnavailable.Msg("database offline").Make()
+	}
+	return nil
+}
+
+func newWebhookReceiver(t *testing.T) (*httptest.Server, chan []byte) {
+	received := make(chan []byte, 10)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		received <- data
+	}))
+	t.Cleanup(ts.Close)
+	return ts, received
+}
+
+func TestHealthNotifier(t *testing.T) {
+	svc := &toggleService{ready: 1}
+	server, err := NewServer(&ServerConfig{GinMode: gin.TestMode, SubSystemName: "orders"})
+	errors.AssertNil(t, err)
+	errors.AssertNil(t, server.RegisterService("db", svc))
+
+	receiver, received := newWebhookReceiver(t)
+	notifier, err := NewHealthNotifier(server, &HealthNotifierConfig{
+		WebhookURLs: []string{receiver.URL},
+		Interval:    Duration(5 * time.Millisecond),
+		Debounce:    Duration(50 * time.Millisecond),
+	})
+	errors.AssertNil(t, err)
+	defer notifier.Stop()
+
+	// flapping within the debounce duration is not notified
+	atomic.StoreInt32(&svc.ready, 0)
+	time.Sleep(20 * time.Millisecond)
+	atomic.StoreInt32(&svc.ready, 1)
+	time.Sleep(80 * time.Millisecond)
+	assert.Len(t, received, 0)
+
+	atomic.StoreInt32(&svc.ready, 0)
+	select {
+	case data := <-received:
+		var event HealthEvent
+		assert.NoError(t, json.Unmarshal(data, &event))
+		assert.Equal(t, "orders", event.Subsystem)
+		assert.Equal(t, "readiness", event.Probe)
+		assert.Equal(t, "down", event.Status)
+		assert.Equal(t, "up", event.PreviousStatus)
+		assert.Equal(t, map[string]string{"db": "database offline"}, event.Failures)
+	case <-time.After(time.Second):
+		t.Fatal("state change was not notified")
+	}
+	time.Sleep(80 * time.Millisecond)
+	assert.Len(t, received, 0)
+}
+
+func TestHealthNotifierSlack(t *testing.T) {
+	svc := &toggleService{ready: 0}
+	server, err := NewServer(&ServerConfig{GinMode: gin.TestMode})
+	errors.AssertNil(t, err)
+	errors.AssertNil(t, server.RegisterService("db", svc))
+
+	receiver, received := newWebhookReceiver(t)
+	notifier, err := NewHealthNotifier(server, &HealthNotifierConfig{
+		WebhookURLs: []string{receiver.URL},
+		Slack:       true,
+		Interval:    Duration(5 * time.Millisecond),
+		Debounce:    -1,
+	})
+	errors.AssertNil(t, err)
+	defer notifier.Stop()
+
+	atomic.StoreInt32(&svc.ready, 1)
+	select {
+	case data := <-received:
+		assert.JSONEq(t, `{"text":"Server readiness changed from down to up"}`, string(data))
+	case <-time.After(time.Second):
+		t.Fatal("state change was not notified")
+	}
+}
+
+func TestHealthNotifierInvalidConfig(t *testing.T) {
+	server, err := NewServer(&ServerConfig{GinMode: gin.TestMode})
+	errors.AssertNil(t, err)
+	_, err = NewHealthNotifier(server, &HealthNotifierConfig{})
+	errors.Assert(t, ErrInvalidConfig, err)
+	_, err = NewHealthNotifier(server, &HealthNotifierConfig{WebhookURLs: []string{"hooks.slack.com/x"}})
+	errors.Assert(t, ErrInvalidConfig, err)
+}
@@ -0,0 +1,154 @@
+package http
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+
+	"github.com/sbreitf1/errors"
+)
+
+// NextPageFunc returns the URL of the page following the given response or an empty string for the last page.
+type NextPageFunc func(response *Response, body []byte) (string, errors.Error)
+
+// Paginator iterates over the pages of a paginated API.
+//
+//	pages := client.Paginate(url, nil, nil)
+//	for pages.Next() {
+//		var items []Item
+//		pages.DecodeJSON(&items)
+//	}
+//	if err := pages.Err(); err != nil {
+//		...
+//	}
+type Paginator struct {
+	// MaxPages limits the number of requested pages. Unlimited when zero.
+	MaxPages int
+
+	client   *Client
+	nextURL  string
+	nextPage NextPageFunc
+	f        func(*Request) errors.Error
+	pages    int
+	response *Response
+	body     []byte
+	err      errors.Error
+}
+
+// Paginate returns a paginator that starts at the given url and follows the URLs returned by next. The RFC 5988 Link header with relation "next" is followed if next is nil. The callback f is applied to every page request.
+func (client *Client) Paginate(url string, next NextPageFunc, f func(*Request) errors.Error) *Paginator {
+	if next == nil {
+		next = LinkHeaderNext
+	}
+	return &Paginator{client: client, nextURL: url, nextPage: next, f: f}
+}
+
+// Next requests the next page and returns true if it has been received successfully. It returns false after the last page or on errors, see Err.
+func (p *Paginator) Next() bool {
+	if p.err != nil || len(p.nextURL) == 0 || (p.MaxPages > 0 && p.pages >= p.MaxPages) {
+		return false
+	}
+
+	response, err := p.client.Get(p.nextURL, p.f)
+	if err != nil {
+		p.err = err
+		return false
+	}
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		p.err = unexpectedStatus(response)
+		DrainAndClose(response)
+		return false
+	}
+	body, err := ReadResponseBody(response)
+	if err != nil {
+		p.err = err
+		return false
+	}
+
+	nextURL, err := p.nextPage(response, body)
+	if err != nil {
+		p.err = err
+		return false
+	}
+	if len(nextURL) > 0 && response.Request != nil {
+		// relative links refer to the current page
+		if u, parseErr := response.Request.URL.Parse(nextURL); parseErr == nil {
+			nextURL = u.String()
+		}
+	}
+
+	p.pages++
+	p.response = response
+	p.body = body
+	p.nextURL = nextURL
+	return true
+}
+
+// Response returns the response of the current page. The body has already been read and is available via Body.
+func (p *Paginator) Response() *Response {
+	return p.response
+}
+
+// Body returns the body of the current page.
+func (p *Paginator) Body() []byte {
+	return p.body
+}
+
+// DecodeJSON decodes the JSON body of the current page into out.
+func (p *Paginator) DecodeJSON(out interface{}) errors.Error {
+	if err := json.Unmarshal(p.body, out); err != nil {
+		return ErrInvalidBody.Make().Cause(err)
+	}
+	return nil
+}
+
+// Err returns the error that stopped the iteration or nil if all pages have been received.
+func (p *Paginator) Err() errors.Error {
+	return p.err
+}
+
+// Each calls f for every page until all pages have been received or f returns an error.
+func (p *Paginator) Each(f func(p *Paginator) errors.Error) errors.Error {
+	for p.Next() {
+		if err := f(p); err != nil {
+			return err
+		}
+	}
+	return p.Err()
+}
+
+// LinkHeaderNext returns the target of the RFC 5988 Link header with relation "next".
+func LinkHeaderNext(response *Response, body []byte) (string, errors.Error) {
+	return ParseLinkHeader(response.Header)["next"], nil
+}
+
+// ParseLinkHeader returns the targets of all RFC 5988 Link headers by relation type.
+func ParseLinkHeader(header Header) map[string]string {
+	links := make(map[string]string)
+	for _, value := range header.Values("Link") {
+		for _, link := range strings.Split(value, ",") {
+			parts := strings.Split(link, ";")
+			target := strings.TrimSpace(parts[0])
+			if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+				continue
+			}
+			target = strings.TrimSuffix(strings.TrimPrefix(target, "<"), ">")
+			if _, err := url.Parse(target); err != nil {
+				continue
+			}
+
+			for _, param := range parts[1:] {
+				kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+				if len(kv) != 2 || !strings.EqualFold(strings.TrimSpace(kv[0]), "rel") {
+					continue
+				}
+				for _, rel := range strings.Fields(strings.Trim(strings.TrimSpace(kv[1]), `"`)) {
+					if _, ok := links[strings.ToLower(rel)]; !ok {
+						links[strings.ToLower(rel)] = target
+					}
+				}
+			}
+		}
+	}
+	return links
+}
@@ -0,0 +1,79 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/sbreitf1/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPaginate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if page == 0 {
+			page = 1
+		}
+		if page < 3 {
+			w.Header().Add("Link", fmt.Sprintf(`</items?page=%d>; rel="next", </items?page=3>; rel="last"`, page+1))
+		}
+		fmt.Fprintf(w, `{"items":[%d,%d],"next":%q}`, 2*page-1, 2*page, map[bool]string{true: fmt.Sprintf("/items?page=%d", page+1)}[page < 3])
+	}))
+	defer server.Close()
+	client := NewClient()
+
+	collect := func(p *Paginator) []int {
+		var all []int
+		for p.Next() {
+			var page struct{ Items []int }
+			errors.AssertNil(t, p.DecodeJSON(&page))
+			all = append(all, page.Items...)
+		}
+		errors.AssertNil(t, p.Err())
+		return all
+	}
+
+	t.Run("LinkHeader", func(t *testing.T) {
+		assert.Equal(t, []int{1, 2, 3, 4, 5, 6}, collect(client.Paginate(server.URL+"/items", nil, nil)))
+	})
+
+	t.Run("Extractor", func(t *testing.T) {
+		next := func(response *Response, body []byte) (string, errors.Error) {
+			var page struct{ Next string }
+			err := errors.Wrap(json.Unmarshal(body, &page))
+			return page.Next, err
+		}
+		assert.Equal(t, []int{1, 2, 3, 4, 5, 6}, collect(client.Paginate(server.URL+"/items", next, nil)))
+	})
+
+	t.Run("MaxPages", func(t *testing.T) {
+		p := client.Paginate(server.URL+"/items", nil, nil)
+		p.MaxPages = 2
+		assert.Equal(t, []int{1, 2, 3, 4}, collect(p))
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		p := client.Paginate(server.URL+"/items", func(*Response, []byte) (string, errors.Error) {
+			return "http://127.0.0.1:1/unreachable", nil
+		}, nil)
+		pages := 0
+		err := p.Each(func(p *Paginator) errors.Error {
+			pages++
+			return nil
+		})
+		errors.Assert(t, ErrRequestFailed, err)
+		assert.Equal(t, 1, pages)
+	})
+}
+
+func TestParseLinkHeader(t *testing.T) {
+	header := Header{"Link": {`<https://api.test/items?page=2>; rel="next prefetch", <https://api.test/items?page=1>;rel=first`}}
+	links := ParseLinkHeader(header)
+	assert.Equal(t, "https://api.test/items?page=2", links["next"])
+	assert.Equal(t, "https://api.test/items?page=2", links["prefetch"])
+	assert.Equal(t, "https://api.test/items?page=1", links["first"])
+}
@@ -0,0 +1,37 @@
+package http
+
+import (
+	"net/http"
+)
+
+// ClientProtocol selects the HTTP protocol versions used by a client.
+type ClientProtocol int
+
+const (
+	// ProtocolAuto uses HTTP/2 for TLS connections if supported by the server and HTTP/1.1 otherwise.
+	ProtocolAuto ClientProtocol = iota
+	// ProtocolHTTP1 forces HTTP/1.1 for all connections.
+	ProtocolHTTP1
+	// ProtocolHTTP2 forces HTTP/2 for TLS connections. Requests fail if the server does not support HTTP/2.
+	ProtocolHTTP2
+	// ProtocolH2C uses unencrypted HTTP/2 with prior knowledge for plain HTTP connections, as usually required by internal gRPC services. TLS connections use HTTP/2.
+	ProtocolH2C
+)
+
+// protocols returns the protocol configuration of the transport for the given protocol selection.
+func (p ClientProtocol) protocols() *http.Protocols {
+	protocols := new(http.Protocols)
+	switch p {
+	case ProtocolHTTP1:
+		protocols.SetHTTP1(true)
+	case ProtocolHTTP2:
+		protocols.SetHTTP2(true)
+	case ProtocolH2C:
+		protocols.SetHTTP2(true)
+		protocols.SetUnencryptedHTTP2(true)
+	default:
+		protocols.SetHTTP1(true)
+		protocols.SetHTTP2(true)
+	}
+	return protocols
+}
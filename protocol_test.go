@@ -0,0 +1,52 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sbreitf1/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProtocol(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Proto))
+	})
+
+	tlsServer := httptest.NewUnstartedServer(handler)
+	tlsServer.EnableHTTP2 = true
+	tlsServer.StartTLS()
+	defer tlsServer.Close()
+
+	plainServer := httptest.NewUnstartedServer(handler)
+	plainServer.Config.Protocols = new(http.Protocols)
+	plainServer.Config.Protocols.SetHTTP1(true)
+	plainServer.Config.Protocols.SetUnencryptedHTTP2(true)
+	plainServer.Start()
+	defer plainServer.Close()
+
+	tests := []struct {
+		name     string
+		protocol ClientProtocol
+		url      string
+		expected string
+	}{
+		{"Auto TLS", ProtocolAuto, tlsServer.URL, "HTTP/2.0"},
+		{"Auto plain", ProtocolAuto, plainServer.URL, "HTTP/1.1"},
+		{"HTTP1", ProtocolHTTP1, tlsServer.URL, "HTTP/1.1"},
+		{"HTTP2", ProtocolHTTP2, tlsServer.URL, "HTTP/2.0"},
+		{"H2C", ProtocolH2C, plainServer.URL, "HTTP/2.0"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := NewClient()
+			client.DisableSSLCheck = true
+			client.Protocol = test.protocol
+			response, err := client.Get(test.url, nil)
+			errors.AssertNil(t, err)
+			assert.Equal(t, test.expected, response.Proto)
+			assertResponse(t, 200, test.expected, response)
+		})
+	}
+}
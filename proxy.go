@@ -0,0 +1,148 @@
+package http
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sbreitf1/errors"
+)
+
+var (
+	// ErrUpstreamUnavailable is reported by the readiness probe of a ReverseProxyService when the upstream does not respond successfully and by load balancers without endpoints.
+	ErrUpstreamUnavailable = errors.New("Upstream unavailable")
+)
+
+// ReverseProxyConfig contains the parameters of a ReverseProxyService.
+type ReverseProxyConfig struct {
+	// Prefix denotes the route prefix that is forwarded to the upstream.
+	Prefix string `json:"prefix"`
+	// Upstream is the base URL all requests are forwarded to.
+	Upstream string `json:"upstream"`
+	// StripPrefix removes the route prefix from the forwarded request path.
+	StripPrefix bool `json:"stripPrefix,omitempty"`
+	// SetHeaders are set on every forwarded request, replacing existing values.
+	SetHeaders map[string]string `json:"setHeaders,omitempty"`
+	// RemoveHeaders are removed from every forwarded request.
+	RemoveHeaders []string `json:"removeHeaders,omitempty"`
+	// Timeout limits the time to wait for the upstream response headers. No limit is applied when zero.
+	Timeout Duration `json:"timeout,omitempty"`
+	// HealthPath is requested relative to the upstream URL to determine readiness. The upstream is not probed when empty.
+	HealthPath string `json:"healthPath,omitempty"`
+}
+
+// ReverseProxyService forwards all requests below a path prefix to an upstream server.
+type ReverseProxyService struct {
+	config   ReverseProxyConfig
+	upstream *url.URL
+	proxy    *httputil.ReverseProxy
+	client   *http.Client
+}
+
+// NewReverseProxyService returns a new service that acts as reverse proxy for the configured upstream.
+func NewReverseProxyService(config *ReverseProxyConfig) (*ReverseProxyService, errors.Error) {
+	upstream, err := url.Parse(config.Upstream)
+	if err != nil {
+		return nil, ErrInvalidConfig.Make().Cause(err)
+	}
+	if len(upstream.Scheme) == 0 || len(upstream.Host) == 0 {
+		return nil, ErrInvalidConfig.Msg("Upstream %q must be an absolute URL", config.Upstream).Make()
+	}
+
+	svc := &ReverseProxyService{config: *config, upstream: upstream}
+	svc.config.Prefix = "/" + strings.Trim(config.Prefix, "/")
+	if svc.config.Prefix == "/" {
+		return nil, ErrInvalidRoute.Msg("Reverse proxy requires a non-root prefix").Make()
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = time.Duration(config.Timeout)
+	svc.proxy = &httputil.ReverseProxy{
+		Director:     svc.direct,
+		Transport:    transport,
+		ErrorHandler: svc.handleError,
+	}
+	svc.client = &http.Client{Transport: transport, Timeout: time.Duration(config.Timeout)}
+	return svc, nil
+}
+
+func (svc *ReverseProxyService) direct(req *http.Request) {
+	path := req.URL.Path
+	if svc.config.StripPrefix {
+		path = strings.TrimPrefix(path, svc.config.Prefix)
+	}
+	req.URL.Scheme = svc.upstream.Scheme
+	req.URL.Host = svc.upstream.Host
+	req.URL.Path = strings.TrimRight(svc.upstream.Path, "/") + "/" + strings.TrimLeft(path, "/")
+	req.URL.RawPath = ""
+	if len(svc.upstream.RawQuery) > 0 {
+		if len(req.URL.RawQuery) > 0 {
+			req.URL.RawQuery = svc.upstream.RawQuery + "&" + req.URL.RawQuery
+		} else {
+			req.URL.RawQuery = svc.upstream.RawQuery
+		}
+	}
+	req.Host = svc.upstream.Host
+
+	for _, h := range svc.config.RemoveHeaders {
+		req.Header.Del(h)
+	}
+	for h, v := range svc.config.SetHeaders {
+		req.Header.Set(h, v)
+	}
+	if _, ok := req.Header["User-Agent"]; !ok {
+		// explicitly disable default User-Agent of the http client
+		req.Header.Set("User-Agent", "")
+	}
+}
+
+func (svc *ReverseProxyService) handleError(w http.ResponseWriter, r *http.Request, err error) {
+	code := 502
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		code = 504
+	}
+	ErrUpstreamUnavailable.Make().Cause(err).ToLog()
+	w.WriteHeader(code)
+}
+
+// RegisterRoutes registers the proxy routes below the configured prefix.
+func (svc *ReverseProxyService) RegisterRoutes(e *gin.Engine) {
+	h := gin.WrapH(svc.proxy)
+	e.Any(svc.config.Prefix, h)
+	e.Any(svc.config.Prefix+"/*path", h)
+}
+
+// BeginServing does nothing.
+func (svc *ReverseProxyService) BeginServing() {}
+
+// StopServing does nothing.
+func (svc *ReverseProxyService) StopServing() {}
+
+// Healthy always returns nil as an unavailable upstream does not require a restart of this service.
+func (svc *ReverseProxyService) Healthy() errors.Error {
+	return nil
+}
+
+// Ready requests the configured health path of the upstream and returns an error for failed requests and non-2xx responses.
+func (svc *ReverseProxyService) Ready() errors.Error {
+	if len(svc.config.HealthPath) == 0 {
+		return nil
+	}
+
+	u := *svc.upstream
+	u.Path = strings.TrimRight(u.Path, "/") + "/" + strings.TrimLeft(svc.config.HealthPath, "/")
+	resp, err := svc.client.Get(u.String())
+	if err != nil {
+		return ErrUpstreamUnavailable.Make().Cause(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return ErrUpstreamUnavailable.Make().Cause(fmt.Errorf("upstream responded with status %d", resp.StatusCode))
+	}
+	return nil
+}
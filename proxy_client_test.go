@@ -0,0 +1,42 @@
+package http
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sbreitf1/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientProxy(t *testing.T) {
+	newProxy := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Proxy", name)
+			w.Header().Set("X-Target", r.URL.String())
+			w.Header().Set("X-Proxy-Auth", r.Header.Get("Proxy-Authorization"))
+		}))
+	}
+	proxy1 := newProxy("one")
+	defer proxy1.Close()
+	proxy2 := newProxy("two")
+	defer proxy2.Close()
+
+	client := NewClient()
+	client.ProxyURL = "http://user:secret@" + proxy1.Listener.Addr().String()
+
+	response, err := client.Do(MethodGet, "http://upstream.example/path", nil)
+	errors.AssertNil(t, err)
+	assert.Equal(t, "one", response.Header.Get("X-Proxy"))
+	assert.Equal(t, "http://upstream.example/path", response.Header.Get("X-Target"))
+	assert.Equal(t, "Basic "+base64.StdEncoding.EncodeToString([]byte("user:secret")), response.Header.Get("X-Proxy-Auth"))
+
+	response, err = client.Do(MethodGet, "http://upstream.example/other", WithProxy(proxy2.URL))
+	errors.AssertNil(t, err)
+	assert.Equal(t, "two", response.Header.Get("X-Proxy"))
+	assert.Equal(t, "", response.Header.Get("X-Proxy-Auth"))
+
+	_, err = client.Do(MethodGet, "http://upstream.example/", WithProxy("://invalid"))
+	errors.Assert(t, ErrInvalidRequest, err)
+}
@@ -0,0 +1,90 @@
+package http
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sbreitf1/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReverseProxyService(t *testing.T) {
+	upstreamHealthy := true
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/health":
+			if !upstreamHealthy {
+				w.WriteHeader(500)
+			}
+		case "/slow":
+			time.Sleep(200 * time.Millisecond)
+		default:
+			w.Header().Set("X-Token", r.Header.Get("X-Token"))
+			w.Header().Set("X-Cookie", r.Header.Get("Cookie"))
+			w.Write([]byte(r.URL.Path + "?" + r.URL.RawQuery))
+		}
+	}))
+	defer upstream.Close()
+
+	svc, err := NewReverseProxyService(&ReverseProxyConfig{
+		Prefix:        "/gateway/",
+		Upstream:      upstream.URL + "/api",
+		StripPrefix:   true,
+		SetHeaders:    map[string]string{"X-Token": "secret"},
+		RemoveHeaders: []string{"Cookie"},
+		Timeout:       Duration(100 * time.Millisecond),
+		HealthPath:    "/health",
+	})
+	errors.AssertNil(t, err)
+
+	server, _ := newTestServer()
+	server.RegisterService("proxy", svc)
+	ts := httptest.NewServer(server.Handler())
+	defer ts.Close()
+
+	t.Run("Forward", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", ts.URL+"/gateway/users?id=1", nil)
+		req.Header.Set("Cookie", "session=1")
+		resp, err := http.DefaultClient.Do(req)
+		errors.AssertNil(t, err)
+		data, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		assert.Equal(t, "/api/users?id=1", string(data))
+		assert.Equal(t, "secret", resp.Header.Get("X-Token"))
+		assert.Equal(t, "", resp.Header.Get("X-Cookie"))
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		slowSvc, svcErr := NewReverseProxyService(&ReverseProxyConfig{Prefix: "/slow", Upstream: upstream.URL, Timeout: Duration(50 * time.Millisecond)})
+		errors.AssertNil(t, svcErr)
+		slowServer, _ := newTestServer()
+		slowServer.RegisterService("slow", slowSvc)
+		slowTS := httptest.NewServer(slowServer.Handler())
+		defer slowTS.Close()
+
+		resp, err := http.Get(slowTS.URL + "/slow")
+		errors.AssertNil(t, err)
+		assert.Equal(t, 504, resp.StatusCode)
+	})
+
+	t.Run("Readiness", func(t *testing.T) {
+		resp, err := http.Get(ts.URL + "/readiness")
+		errors.AssertNil(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+
+		upstreamHealthy = false
+		resp, err = http.Get(ts.URL + "/readiness")
+		errors.AssertNil(t, err)
+		assert.Equal(t, 503, resp.StatusCode)
+	})
+
+	t.Run("Invalid config", func(t *testing.T) {
+		_, err := NewReverseProxyService(&ReverseProxyConfig{Prefix: "/x", Upstream: "no-url"})
+		errors.Assert(t, ErrInvalidConfig, err)
+		_, err = NewReverseProxyService(&ReverseProxyConfig{Prefix: "/", Upstream: upstream.URL})
+		errors.Assert(t, ErrInvalidRoute, err)
+	})
+}
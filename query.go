@@ -0,0 +1,66 @@
+package http
+
+import (
+	"net/url"
+
+	"github.com/sbreitf1/errors"
+)
+
+// Query collects query parameters that are appended to a request URL. Use Apply as request callback.
+type Query struct {
+	values url.Values
+}
+
+// NewQuery returns an empty query parameter builder.
+func NewQuery() *Query {
+	return &Query{make(url.Values)}
+}
+
+// Add appends a value to the given key. Keys can be repeated.
+func (q *Query) Add(key, value string) *Query {
+	q.values.Add(key, value)
+	return q
+}
+
+// Set replaces all values of the given key.
+func (q *Query) Set(key, value string) *Query {
+	q.values.Set(key, value)
+	return q
+}
+
+// Del removes all values of the given key.
+func (q *Query) Del(key string) *Query {
+	q.values.Del(key)
+	return q
+}
+
+// Values returns the collected query parameters.
+func (q *Query) Values() url.Values {
+	return q.values
+}
+
+// Encode returns the escaped query string.
+func (q *Query) Encode() string {
+	return q.values.Encode()
+}
+
+// Apply appends all query parameters to the request URL while retaining existing parameters.
+func (q *Query) Apply(req *Request) errors.Error {
+	values := req.URL.Query()
+	for key, list := range q.values {
+		for _, v := range list {
+			values.Add(key, v)
+		}
+	}
+	req.URL.RawQuery = values.Encode()
+	return nil
+}
+
+// WithQuery returns a request callback that appends the given query parameters.
+func WithQuery(params map[string]string) func(*Request) errors.Error {
+	q := NewQuery()
+	for key, value := range params {
+		q.Add(key, value)
+	}
+	return q.Apply
+}
@@ -0,0 +1,40 @@
+package http
+
+import (
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sbreitf1/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuery(t *testing.T) {
+	assert.Equal(t, 2, withTestServer(func(url string, f *gin.HandlerFunc) {
+		client := NewClient()
+
+		t.Run("Chained", func(t *testing.T) {
+			*f = func(c *gin.Context) {
+				assert.Equal(t, []string{"1"}, c.QueryArray("existing"))
+				assert.Equal(t, []string{"a&b", "c d"}, c.QueryArray("tag"))
+				assert.Equal(t, "x", c.Query("single"))
+				c.Status(200)
+			}
+			q := NewQuery().Add("tag", "a&b").Add("tag", "c d").Set("single", "y").Set("single", "x")
+			_, err := client.Do(MethodGet, url+"/items?existing=1", q.Apply)
+			errors.AssertNil(t, err)
+		})
+
+		t.Run("Map with other callbacks", func(t *testing.T) {
+			*f = func(c *gin.Context) {
+				assert.Equal(t, "1/2", c.Query("page"))
+				assert.Equal(t, "yes", c.GetHeader("X-Test"))
+				c.Status(200)
+			}
+			_, err := client.Do(MethodGet, url+"/items", Chain(WithQuery(map[string]string{"page": "1/2"}), func(r *Request) errors.Error {
+				r.Header.Set("X-Test", "yes")
+				return nil
+			}))
+			errors.AssertNil(t, err)
+		})
+	}))
+}
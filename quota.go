@@ -0,0 +1,255 @@
+package http
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sbreitf1/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// HeaderRateLimitLimit contains the request rate per second that applies to the client.
+	HeaderRateLimitLimit = "X-RateLimit-Limit"
+	// HeaderQuotaRemaining contains the number of requests remaining in the current quota period.
+	HeaderQuotaRemaining = "X-Quota-Remaining"
+
+	limiterIdleTimeout = 10 * time.Minute
+)
+
+// RateLimit defines a token bucket rate and an optional quota for a route or principal tier.
+type RateLimit struct {
+	// Rate denotes the number of requests per second. Unlimited when 0.
+	Rate float64 `json:"rate,omitempty"`
+	// Burst denotes the number of requests that can be sent at once. Defaults to 1.
+	Burst int `json:"burst,omitempty"`
+	// Quota denotes the number of requests per quota period. Unlimited when 0.
+	Quota int `json:"quota,omitempty"`
+	// QuotaPeriod denotes the fixed window of the quota. Defaults to 24 hours.
+	QuotaPeriod Duration `json:"quotaPeriod,omitempty"`
+}
+
+func (limit RateLimit) quotaPeriod() time.Duration {
+	if limit.QuotaPeriod <= 0 {
+		return 24 * time.Hour
+	}
+	return time.Duration(limit.QuotaPeriod)
+}
+
+// RateLimitTable contains the limits that apply to each principal. Both the route and tier limit must admit a request.
+type RateLimitTable struct {
+	// Default applies to principals without tier or with an unknown tier.
+	Default RateLimit `json:"default"`
+	// Tiers maps principal tiers (e.g. "free" or "premium") to their limits.
+	Tiers map[string]RateLimit `json:"tiers,omitempty"`
+	// Routes maps routes in the form "METHOD /path" (e.g. "POST /orders") to limits that apply to each principal in addition to the tier limit.
+	Routes map[string]RateLimit `json:"routes,omitempty"`
+}
+
+// PrincipalFunc returns the principal of a request (e.g. API key or user ID) and its tier. Return an empty principal for anonymous requests to limit them by client IP.
+type PrincipalFunc func(*gin.Context) (principal, tier string)
+
+// RateLimitBackend stores the state of the limits enforced by a rate limit middleware. Use a shared backend like the one returned by NewRedisRateLimitBackend to enforce limits fleet-wide instead of per replica.
+type RateLimitBackend interface {
+	// Admit applies the limit to the bucket with given key. It returns the remaining quota (-1 without quota) if the request is admitted, otherwise the time to wait before the limit admits requests again.
+	Admit(key string, limit RateLimit) (admitted bool, remaining int, wait time.Duration, err errors.Error)
+	// Refund returns a request previously admitted for key, e.g. because another limit rejected it.
+	Refund(key string, limit RateLimit)
+}
+
+type rateLimitMiddleware struct {
+	table     RateLimitTable
+	principal PrincipalFunc
+	backend   RateLimitBackend
+}
+
+// NewRateLimitMiddleware returns a middleware that limits the request rate and quota of each principal according to the limits table. Rejected requests receive 429 Too Many Requests with a Retry-After header. Without principal function, all requests are limited by client IP using the default tier. Limits are enforced per replica, use NewRateLimitMiddlewareWithBackend to share them.
+func NewRateLimitMiddleware(table *RateLimitTable, principal PrincipalFunc) (gin.HandlerFunc, errors.Error) {
+	return NewRateLimitMiddlewareWithBackend(table, principal, NewMemoryRateLimitBackend())
+}
+
+// NewRateLimitMiddlewareWithBackend returns a rate limit middleware like NewRateLimitMiddleware that keeps its state in the given backend.
+func NewRateLimitMiddlewareWithBackend(table *RateLimitTable, principal PrincipalFunc, backend RateLimitBackend) (gin.HandlerFunc, errors.Error) {
+	limits := []RateLimit{table.Default}
+	for _, limit := range table.Tiers {
+		limits = append(limits, limit)
+	}
+	for route, limit := range table.Routes {
+		if len(strings.Fields(route)) != 2 {
+			return nil, ErrInvalidConfig.Msg("Malformed rate limit route %q", route).Make()
+		}
+		limits = append(limits, limit)
+	}
+	for _, limit := range limits {
+		if limit.Rate < 0 || limit.Burst < 0 || limit.Quota < 0 || limit.QuotaPeriod < 0 {
+			return nil, ErrInvalidConfig.Msg("Rate limits must not be negative").Make()
+		}
+	}
+
+	m := &rateLimitMiddleware{table: *table, principal: principal, backend: backend}
+	return m.handle, nil
+}
+
+func (m *rateLimitMiddleware) handle(c *gin.Context) {
+	principal, tier := "", ""
+	if m.principal != nil {
+		principal, tier = m.principal(c)
+	}
+	if len(principal) == 0 {
+		principal, tier = "ip:"+c.ClientIP(), ""
+	}
+
+	limit, ok := m.table.Tiers[tier]
+	if !ok {
+		limit = m.table.Default
+	}
+	keys, limits := []string{tier + "|" + principal}, []RateLimit{limit}
+	if route, routeLimit, ok := m.route(c.Request.Method, c.Request.URL.Path); ok {
+		keys, limits = append(keys, route+"|"+principal), append(limits, routeLimit)
+	}
+
+	// requests are only counted if all limits admit them
+	for i := range keys {
+		if !m.admit(c, keys[i], limits[i]) {
+			for j := 0; j < i; j++ {
+				m.backend.Refund(keys[j], limits[j])
+			}
+			return
+		}
+	}
+}
+
+// route returns the most specific route of the table matching the request.
+func (m *rateLimitMiddleware) route(method, path string) (string, RateLimit, bool) {
+	var bestRoute string
+	var bestLimit RateLimit
+	specificity := -1
+	for route, limit := range m.table.Routes {
+		parts := strings.Fields(route)
+		if !strings.EqualFold(parts[0], method) {
+			continue
+		}
+		if s, ok := matchRoute(parts[1], path); ok && s > specificity {
+			bestRoute, bestLimit, specificity = route, limit, s
+		}
+	}
+	return bestRoute, bestLimit, specificity >= 0
+}
+
+// admit applies the limit to the bucket with given key and aborts the request if it is exceeded.
+func (m *rateLimitMiddleware) admit(c *gin.Context, key string, limit RateLimit) bool {
+	if limit.Rate <= 0 && limit.Quota <= 0 {
+		return true
+	}
+
+	admitted, remaining, wait, err := m.backend.Admit(key, limit)
+	if err != nil {
+		// an unavailable backend must not take down the service
+		log.WithFields(log.Fields{"component": "ratelimit"}).Warnf("Rate limit backend failed: %s", err)
+		return true
+	}
+	if limit.Rate > 0 {
+		c.Header(HeaderRateLimitLimit, strconv.FormatFloat(limit.Rate, 'f', -1, 64))
+	}
+	if limit.Quota > 0 && remaining >= 0 {
+		c.Header(HeaderQuotaRemaining, strconv.Itoa(remaining))
+	}
+	if !admitted {
+		rejectRateLimited(c, wait)
+	}
+	return admitted
+}
+
+type principalLimiter struct {
+	limiter     *RateLimiter
+	quotaEnd    time.Time
+	quotaCount  int
+	lastRequest time.Time
+}
+
+type memoryRateLimitBackend struct {
+	mutex     sync.Mutex
+	limiters  map[string]*principalLimiter
+	lastSweep time.Time
+}
+
+// NewMemoryRateLimitBackend returns a backend that enforces rate limits using token buckets within the current process.
+func NewMemoryRateLimitBackend() RateLimitBackend {
+	return &memoryRateLimitBackend{limiters: make(map[string]*principalLimiter), lastSweep: time.Now()}
+}
+
+func (b *memoryRateLimitBackend) Admit(key string, limit RateLimit) (bool, int, time.Duration, errors.Error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	now := time.Now()
+	b.sweep(now)
+
+	l, ok := b.limiters[key]
+	if !ok {
+		l = &principalLimiter{}
+		if limit.Rate > 0 {
+			l.limiter = NewRateLimiter(limit.Rate, limit.Burst)
+		}
+		b.limiters[key] = l
+	}
+	l.lastRequest = now
+
+	remaining := -1
+	if limit.Quota > 0 {
+		if !now.Before(l.quotaEnd) {
+			l.quotaEnd, l.quotaCount = now.Add(limit.quotaPeriod()), 0
+		}
+		if l.quotaCount >= limit.Quota {
+			return false, 0, l.quotaEnd.Sub(now), nil
+		}
+		remaining = limit.Quota - l.quotaCount
+	}
+
+	if l.limiter != nil {
+		if wait := l.limiter.reserve(); wait > 0 {
+			l.limiter.cancel()
+			return false, remaining, wait, nil
+		}
+	}
+
+	if limit.Quota > 0 {
+		l.quotaCount++
+		remaining--
+	}
+	return true, remaining, 0, nil
+}
+
+func (b *memoryRateLimitBackend) Refund(key string, limit RateLimit) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if l, ok := b.limiters[key]; ok {
+		if l.limiter != nil {
+			l.limiter.cancel()
+		}
+		if limit.Quota > 0 && l.quotaCount > 0 {
+			l.quotaCount--
+		}
+	}
+}
+
+// sweep removes limiters of principals that have been idle for a while and whose quota period has ended to bound memory usage.
+func (b *memoryRateLimitBackend) sweep(now time.Time) {
+	if now.Sub(b.lastSweep) < time.Minute {
+		return
+	}
+	b.lastSweep = now
+	for key, l := range b.limiters {
+		if now.Sub(l.lastRequest) > limiterIdleTimeout && now.After(l.quotaEnd) {
+			delete(b.limiters, key)
+		}
+	}
+}
+
+func rejectRateLimited(c *gin.Context, wait time.Duration) {
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	c.AbortWithStatus(429)
+}
@@ -0,0 +1,88 @@
+package http
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sbreitf1/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimitMiddleware(t *testing.T) {
+	middleware, err := NewRateLimitMiddleware(&RateLimitTable{
+		Default: RateLimit{Rate: 0.1, Burst: 2},
+		Tiers: map[string]RateLimit{
+			"premium": {Rate: 0.1, Burst: 5, Quota: 4, QuotaPeriod: Duration(time.Hour)},
+		},
+		Routes: map[string]RateLimit{
+			"POST /orders/:id": {Rate: 0.1, Burst: 1},
+		},
+	}, func(c *gin.Context) (string, string) {
+		key := c.GetHeader("X-API-Key")
+		if key == "premium-key" {
+			return key, "premium"
+		}
+		return key, "free"
+	})
+	errors.AssertNil(t, err)
+
+	engine := gin.New()
+	engine.Use(middleware)
+	engine.GET("/items", func(c *gin.Context) { c.Status(200) })
+	engine.POST("/orders/:id", func(c *gin.Context) { c.Status(201) })
+	serve := func(method, path, key, remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.RemoteAddr = remoteAddr
+		if len(key) > 0 {
+			req.Header.Set("X-API-Key", key)
+		}
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("default tier by principal", func(t *testing.T) {
+		assert.Equal(t, 200, serve("GET", "/items", "free-key", "10.0.0.1:1").Code)
+		assert.Equal(t, 200, serve("GET", "/items", "free-key", "10.0.0.1:1").Code)
+		w := serve("GET", "/items", "free-key", "10.0.0.1:1")
+		assert.Equal(t, 429, w.Code)
+		assert.Equal(t, "10", w.Header().Get("Retry-After"))
+		assert.Equal(t, "0.1", w.Header().Get(HeaderRateLimitLimit))
+		assert.Equal(t, 200, serve("GET", "/items", "other-key", "10.0.0.1:1").Code)
+	})
+
+	t.Run("anonymous by client ip", func(t *testing.T) {
+		assert.Equal(t, 200, serve("GET", "/items", "", "10.0.0.2:1").Code)
+		assert.Equal(t, 200, serve("GET", "/items", "", "10.0.0.2:2").Code)
+		assert.Equal(t, 429, serve("GET", "/items", "", "10.0.0.2:3").Code)
+		assert.Equal(t, 200, serve("GET", "/items", "", "10.0.0.3:1").Code)
+	})
+
+	t.Run("premium quota", func(t *testing.T) {
+		for i := 3; i >= 0; i-- {
+			w := serve("GET", "/items", "premium-key", "10.0.0.4:1")
+			assert.Equal(t, 200, w.Code)
+			assert.Equal(t, string(rune('0'+i)), w.Header().Get(HeaderQuotaRemaining))
+		}
+		w := serve("GET", "/items", "premium-key", "10.0.0.4:1")
+		assert.Equal(t, 429, w.Code)
+		assert.Equal(t, "0", w.Header().Get(HeaderQuotaRemaining))
+		assert.Equal(t, "3600", w.Header().Get("Retry-After"))
+	})
+
+	t.Run("route limit", func(t *testing.T) {
+		assert.Equal(t, 201, serve("POST", "/orders/1", "route-key", "10.0.0.5:1").Code)
+		assert.Equal(t, 429, serve("POST", "/orders/1", "route-key", "10.0.0.5:1").Code)
+		assert.Equal(t, 200, serve("GET", "/items", "route-key", "10.0.0.5:1").Code)
+		assert.Equal(t, 201, serve("POST", "/orders/2", "route-key-2", "10.0.0.5:1").Code)
+	})
+}
+
+func TestRateLimitMiddlewareInvalidConfig(t *testing.T) {
+	_, err := NewRateLimitMiddleware(&RateLimitTable{Default: RateLimit{Rate: -1}}, nil)
+	errors.Assert(t, ErrInvalidConfig, err)
+	_, err = NewRateLimitMiddleware(&RateLimitTable{Routes: map[string]RateLimit{"/orders": {Rate: 1}}}, nil)
+	errors.Assert(t, ErrInvalidConfig, err)
+}
@@ -0,0 +1,116 @@
+package http
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sbreitf1/errors"
+)
+
+var (
+	// ErrRateLimited is returned when a request could not be admitted by a rate limiter.
+	ErrRateLimited = errors.New("Rate limit exceeded")
+)
+
+// RateLimiter is a token bucket limiting the number of events per second while allowing short bursts.
+type RateLimiter struct {
+	rate  float64
+	burst float64
+
+	mutex  sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter returns a new rate limiter allowing rate events per second with the given burst size. The bucket is initially full. Events are not limited when rate is 0 or negative.
+func NewRateLimiter(rate float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{rate: rate, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+}
+
+// reserve takes a token from the bucket and returns the time to wait until the token is available.
+func (limiter *RateLimiter) reserve() time.Duration {
+	if limiter.rate <= 0 {
+		return 0
+	}
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+
+	now := time.Now()
+	limiter.tokens += now.Sub(limiter.last).Seconds() * limiter.rate
+	if limiter.tokens > limiter.burst {
+		limiter.tokens = limiter.burst
+	}
+	limiter.last = now
+
+	limiter.tokens--
+	if limiter.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-limiter.tokens / limiter.rate * float64(time.Second))
+}
+
+func (limiter *RateLimiter) cancel() {
+	if limiter.rate <= 0 {
+		return
+	}
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+	limiter.tokens++
+}
+
+// Allow takes a token and returns true if it is available immediately. No token is consumed otherwise.
+func (limiter *RateLimiter) Allow() bool {
+	if limiter.reserve() > 0 {
+		limiter.cancel()
+		return false
+	}
+	return true
+}
+
+// Wait blocks until a token is available or the context is done.
+func (limiter *RateLimiter) Wait(ctx context.Context) errors.Error {
+	wait := limiter.reserve()
+	if wait <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		limiter.cancel()
+		return ErrRateLimited.Make().Cause(ctx.Err())
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Interceptor returns an interceptor that delays requests until the limiter admits them.
+func (limiter *RateLimiter) Interceptor() Interceptor {
+	return func(next Responder) Responder {
+		return func(req *Request) (*Response, errors.Error) {
+			if err := limiter.Wait(req.Context()); err != nil {
+				return nil, err
+			}
+			return next(req)
+		}
+	}
+}
+
+// HostRateLimitInterceptor returns an interceptor that delays requests until the limiter of the target host name admits them. Requests to hosts without limiter are not delayed.
+func HostRateLimitInterceptor(limiters map[string]*RateLimiter) Interceptor {
+	return func(next Responder) Responder {
+		return func(req *Request) (*Response, errors.Error) {
+			if limiter, ok := limiters[req.URL.Hostname()]; ok {
+				if err := limiter.Wait(req.Context()); err != nil {
+					return nil, err
+				}
+			}
+			return next(req)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package http
+
+import (
+	"context"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sbreitf1/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiter(t *testing.T) {
+	limiter := NewRateLimiter(100, 2)
+	assert.True(t, limiter.Allow())
+	assert.True(t, limiter.Allow())
+	assert.False(t, limiter.Allow())
+
+	start := time.Now()
+	errors.AssertNil(t, limiter.Wait(context.Background()))
+	assert.True(t, time.Since(start) >= 5*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	slow := NewRateLimiter(0.1, 1)
+	slow.Allow()
+	errors.Assert(t, ErrRateLimited, slow.Wait(ctx))
+
+	unlimited := NewRateLimiter(0, 1)
+	for i := 0; i < 3; i++ {
+		assert.True(t, unlimited.Allow())
+		errors.AssertNil(t, unlimited.Wait(ctx))
+	}
+}
+
+func TestClientRateLimit(t *testing.T) {
+	client := NewClient()
+	client.RateLimiter = NewRateLimiter(1000, 10)
+	client.HostRateLimiters = map[string]*RateLimiter{"quota": NewRateLimiter(20, 1)}
+	client.RequestResponder = func(req *Request) (*Response, errors.Error) {
+		return &Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader(""))}, nil
+	}
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		_, err := client.Do(MethodGet, "http://other/", nil)
+		errors.AssertNil(t, err)
+	}
+	assert.True(t, time.Since(start) < 40*time.Millisecond, "Unlimited host should not be throttled")
+
+	start = time.Now()
+	for i := 0; i < 3; i++ {
+		_, err := client.Do(MethodGet, "http://quota/", nil)
+		errors.AssertNil(t, err)
+	}
+	assert.True(t, time.Since(start) >= 90*time.Millisecond, "Requests to limited host should be throttled")
+}
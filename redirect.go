@@ -0,0 +1,38 @@
+package http
+
+import (
+	"net/http"
+	"net/url"
+)
+
+// checkRedirect applies the redirect policy of the client.
+func (client *Client) checkRedirect(req *http.Request, via []*http.Request) error {
+	if client.MaxRedirects < 0 {
+		return http.ErrUseLastResponse
+	}
+	max := client.MaxRedirects
+	if max == 0 {
+		max = 10
+	}
+	if len(via) > max {
+		return http.ErrUseLastResponse
+	}
+
+	if client.StripAuthOnRedirect && req.URL.Host != via[0].URL.Host {
+		req.Header.Del("Authorization")
+	}
+	return nil
+}
+
+// RedirectChain returns the URLs of all requests that led to the given response in order, starting with the initial request and ending with the URL of the final response.
+func RedirectChain(response *Response) []*url.URL {
+	chain := make([]*url.URL, 0)
+	for req := response.Request; req != nil; {
+		chain = append([]*url.URL{req.URL}, chain...)
+		if req.Response == nil {
+			break
+		}
+		req = req.Response.Request
+	}
+	return chain
+}
@@ -0,0 +1,77 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/sbreitf1/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedirects(t *testing.T) {
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("auth=" + r.Header.Get("Authorization")))
+	}))
+	defer other.Close()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/external" {
+			http.Redirect(w, r, other.URL+"/target", 302)
+			return
+		}
+		n, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/"))
+		if n > 0 {
+			http.Redirect(w, r, "/"+strconv.Itoa(n-1), 302)
+			return
+		}
+		w.Write([]byte("auth=" + r.Header.Get("Authorization")))
+	}))
+	defer ts.Close()
+
+	t.Run("Follow and chain", func(t *testing.T) {
+		response, err := NewClient().Do(MethodGet, ts.URL+"/2", nil)
+		errors.AssertNil(t, err)
+		assert.Equal(t, 200, response.StatusCode)
+		chain := RedirectChain(response)
+		if assert.Len(t, chain, 3) {
+			assert.Equal(t, "/2", chain[0].Path)
+			assert.Equal(t, "/1", chain[1].Path)
+			assert.Equal(t, "/0", chain[2].Path)
+		}
+	})
+
+	t.Run("Limit", func(t *testing.T) {
+		client := NewClient()
+		client.MaxRedirects = 1
+		response, err := client.Do(MethodGet, ts.URL+"/3", nil)
+		errors.AssertNil(t, err)
+		assert.Equal(t, 302, response.StatusCode)
+		assert.Len(t, RedirectChain(response), 2)
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		client := NewClient()
+		client.MaxRedirects = -1
+		response, err := client.Do(MethodGet, ts.URL+"/1", nil)
+		errors.AssertNil(t, err)
+		assert.Equal(t, 302, response.StatusCode)
+		assert.Len(t, RedirectChain(response), 1)
+	})
+
+	t.Run("Strip authorization", func(t *testing.T) {
+		client := NewClient()
+		client.StripAuthOnRedirect = true
+		client.DefaultHeader.Set("Authorization", "Bearer secret")
+
+		response, err := client.Do(MethodGet, ts.URL+"/1", nil)
+		errors.AssertNil(t, err)
+		assertResponse(t, 200, "auth=Bearer secret", response)
+
+		response, err = client.Do(MethodGet, ts.URL+"/external", nil)
+		errors.AssertNil(t, err)
+		assertResponse(t, 200, "auth=", response)
+	})
+}
@@ -0,0 +1,371 @@
+package http
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/sbreitf1/errors"
+)
+
+var (
+	// ErrStore occurs when a store operation failed, e.g. because the Redis server is unreachable.
+	ErrStore = errors.New("Store operation failed")
+)
+
+// RedisConfig contains the connection parameters of a RedisStore.
+type RedisConfig struct {
+	// Address of the Redis server in the form host:port.
+	Address  string `json:"address"`
+	Password string `json:"password,omitempty"`
+	DB       int    `json:"db,omitempty"`
+	// Prefix is prepended to all keys, so multiple applications can share a Redis database.
+	Prefix string `json:"prefix,omitempty"`
+	// Timeout limits dialing and each command. Defaults to 3 seconds.
+	Timeout Duration `json:"timeout,omitempty"`
+	// PoolSize limits the number of idle connections kept for reuse. Defaults to 10.
+	PoolSize int `json:"poolSize,omitempty"`
+}
+
+// RedisStore is a Store backed by a Redis server, so multiple replicas share cached responses, idempotency records and rate limits. Failing reads are treated as cache misses and failing writes are ignored, use Ping to monitor availability.
+type RedisStore struct {
+	config RedisConfig
+	pool   chan *redisConn
+}
+
+type redisConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// redisError is an error reply of the Redis server.
+type redisError string
+
+func (err redisError) Error() string {
+	return string(err)
+}
+
+// NewRedisStore returns a store for the given Redis server. The connection is verified using PING.
+func NewRedisStore(config *RedisConfig) (*RedisStore, errors.Error) {
+	if len(config.Address) == 0 {
+		return nil, ErrStore.Msg("Redis address must not be empty").Make()
+	}
+	s := &RedisStore{config: *config}
+	if s.config.Timeout <= 0 {
+		s.config.Timeout = Duration(3 * time.Second)
+	}
+	if s.config.PoolSize <= 0 {
+		s.config.PoolSize = 10
+	}
+	s.pool = make(chan *redisConn, s.config.PoolSize)
+	if err := s.Ping(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Ping checks the connection to the Redis server.
+func (s *RedisStore) Ping() errors.Error {
+	_, err := s.do("PING")
+	return err
+}
+
+// Get returns the entry stored for key.
+func (s *RedisStore) Get(key string) ([]byte, bool) {
+	reply, err := s.do("GET", s.config.Prefix+key)
+	data, ok := reply.([]byte)
+	return data, err == nil && ok
+}
+
+// Set stores an entry for key.
+func (s *RedisStore) Set(key string, data []byte) {
+	s.do("SET", s.config.Prefix+key, data)
+}
+
+// SetWithTTL stores an entry for key that expires after ttl.
+func (s *RedisStore) SetWithTTL(key string, data []byte, ttl time.Duration) {
+	if ttl <= 0 {
+		s.Set(key, data)
+		return
+	}
+	s.do("SET", s.config.Prefix+key, data, "PX", strconv.FormatInt(ttlMillis(ttl), 10))
+}
+
+// SetIfAbsent stores an entry for key that expires after ttl only if no entry exists and reports whether it has been stored.
+func (s *RedisStore) SetIfAbsent(key string, data []byte, ttl time.Duration) (bool, errors.Error) {
+	args := []interface{}{s.config.Prefix + key, data, "NX"}
+	if ttl > 0 {
+		args = append(args, "PX", strconv.FormatInt(ttlMillis(ttl), 10))
+	}
+	reply, err := s.do("SET", args...)
+	if err != nil {
+		return false, err
+	}
+	// a nil reply indicates that the key already exists
+	return reply != nil, nil
+}
+
+// Delete removes the entry stored for key.
+func (s *RedisStore) Delete(key string) {
+	s.do("DEL", s.config.Prefix+key)
+}
+
+// deleteIfEqualScript atomically deletes a key holding the expected value.
+const deleteIfEqualScript = `if redis.call("GET", KEYS[1]) == ARGV[1] then return redis.call("DEL", KEYS[1]) else return 0 end`
+
+// DeleteIfEqual removes the entry stored for key if it equals data and reports whether it has been removed.
+func (s *RedisStore) DeleteIfEqual(key string, data []byte) (bool, errors.Error) {
+	reply, err := s.do("EVAL", deleteIfEqualScript, "1", s.config.Prefix+key, data)
+	if err != nil {
+		return false, err
+	}
+	deleted, _ := reply.(int64)
+	return deleted == 1, nil
+}
+
+// Close closes all idle connections.
+func (s *RedisStore) Close() {
+	for {
+		select {
+		case c := <-s.pool:
+			c.conn.Close()
+		default:
+			return
+		}
+	}
+}
+
+func ttlMillis(ttl time.Duration) int64 {
+	if ms := ttl.Milliseconds(); ms > 0 {
+		return ms
+	}
+	return 1
+}
+
+// do sends a command and returns its reply, which is nil, int64, string, []byte or []interface{}.
+func (s *RedisStore) do(command string, args ...interface{}) (interface{}, errors.Error) {
+	c, err := s.conn()
+	if err != nil {
+		return nil, ErrStore.Make().Cause(err)
+	}
+
+	c.conn.SetDeadline(time.Now().Add(time.Duration(s.config.Timeout)))
+	reply, err := c.command(append([]interface{}{command}, args...)...)
+	if err != nil {
+		if _, ok := err.(redisError); ok {
+			// the connection is still usable after error replies
+			s.release(c)
+		} else {
+			c.conn.Close()
+		}
+		return nil, ErrStore.Msg("Redis command %s failed", command).Make().Cause(err)
+	}
+	s.release(c)
+	return reply, nil
+}
+
+// conn returns an idle connection or dials a new one.
+func (s *RedisStore) conn() (*redisConn, error) {
+	select {
+	case c := <-s.pool:
+		return c, nil
+	default:
+	}
+
+	conn, err := net.DialTimeout("tcp", s.config.Address, time.Duration(s.config.Timeout))
+	if err != nil {
+		return nil, err
+	}
+	c := &redisConn{conn: conn, reader: bufio.NewReader(conn)}
+	conn.SetDeadline(time.Now().Add(time.Duration(s.config.Timeout)))
+	if len(s.config.Password) > 0 {
+		if _, err := c.command("AUTH", s.config.Password); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	if s.config.DB != 0 {
+		if _, err := c.command("SELECT", strconv.Itoa(s.config.DB)); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+func (s *RedisStore) release(c *redisConn) {
+	select {
+	case s.pool <- c:
+	default:
+		c.conn.Close()
+	}
+}
+
+// command writes a command in the RESP protocol and reads the reply.
+func (c *redisConn) command(args ...interface{}) (interface{}, error) {
+	buf := []byte("*" + strconv.Itoa(len(args)) + "\r\n")
+	for _, arg := range args {
+		var data []byte
+		switch v := arg.(type) {
+		case []byte:
+			data = v
+		case string:
+			data = []byte(v)
+		default:
+			data = []byte(fmt.Sprint(v))
+		}
+		buf = append(buf, "$"+strconv.Itoa(len(data))+"\r\n"...)
+		buf = append(buf, data...)
+		buf = append(buf, "\r\n"...)
+	}
+	if _, err := c.conn.Write(buf); err != nil {
+		return nil, err
+	}
+	return readRESP(c.reader)
+}
+
+// readRESP reads a single reply in the RESP protocol.
+func readRESP(r *bufio.Reader) (interface{}, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("malformed RESP line %q", line)
+	}
+	prefix, value := line[0], line[1:len(line)-2]
+
+	switch prefix {
+	case '+':
+		return value, nil
+	case '-':
+		return nil, redisError(value)
+	case ':':
+		return strconv.ParseInt(value, 10, 64)
+	case '$':
+		length, err := strconv.Atoi(value)
+		if err != nil || length < 0 {
+			return nil, err
+		}
+		data := make([]byte, length+2)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+		return data[:length], nil
+	case '*':
+		count, err := strconv.Atoi(value)
+		if err != nil || count < 0 {
+			return nil, err
+		}
+		values := make([]interface{}, count)
+		for i := range values {
+			if values[i], err = readRESP(r); err != nil {
+				if _, ok := err.(redisError); !ok {
+					return nil, err
+				}
+				values[i] = err
+			}
+		}
+		return values, nil
+	}
+	return nil, fmt.Errorf("unknown RESP type %q", prefix)
+}
+
+type redisRateLimitBackend struct {
+	store *RedisStore
+}
+
+// NewRedisRateLimitBackend returns a backend that enforces rate limits across all replicas sharing the Redis store. Rates are approximated by fixed windows of Burst/Rate seconds admitting Burst requests each. Quota windows begin with the first request of a principal.
+func NewRedisRateLimitBackend(store *RedisStore) RateLimitBackend {
+	return &redisRateLimitBackend{store}
+}
+
+func (b *redisRateLimitBackend) Admit(key string, limit RateLimit) (bool, int, time.Duration, errors.Error) {
+	remaining := -1
+	if limit.Quota > 0 {
+		count, admitted, wait, err := b.increment("ratelimit|quota|"+key, int64(limit.Quota), limit.quotaPeriod())
+		if err != nil {
+			return false, 0, 0, err
+		} else if !admitted {
+			return false, 0, wait, nil
+		}
+		remaining = limit.Quota - int(count)
+	}
+
+	if limit.Rate > 0 {
+		burst, window := limit.rateWindow()
+		_, admitted, wait, err := b.increment("ratelimit|rate|"+key, burst, window)
+		if err == nil && admitted {
+			return true, remaining, 0, nil
+		}
+		if limit.Quota > 0 {
+			b.decrement("ratelimit|quota|" + key)
+			remaining++
+		}
+		return false, remaining, wait, err
+	}
+	return true, remaining, 0, nil
+}
+
+func (b *redisRateLimitBackend) Refund(key string, limit RateLimit) {
+	if limit.Quota > 0 {
+		b.decrement("ratelimit|quota|" + key)
+	}
+	if limit.Rate > 0 {
+		b.decrement("ratelimit|rate|" + key)
+	}
+}
+
+// rateWindow returns the fixed window that approximates the token bucket of the limit.
+func (limit RateLimit) rateWindow() (int64, time.Duration) {
+	burst := limit.Burst
+	if burst < 1 {
+		burst = 1
+	}
+	return int64(burst), time.Duration(float64(burst) / limit.Rate * float64(time.Second))
+}
+
+// increment counts a request in the window of key. If the limit is exceeded, the request is not counted and the remaining time of the window is returned.
+func (b *redisRateLimitBackend) increment(key string, limit int64, window time.Duration) (int64, bool, time.Duration, errors.Error) {
+	key = b.store.config.Prefix + key
+	reply, err := b.store.do("INCR", key)
+	if err != nil {
+		return 0, false, 0, err
+	}
+	count, _ := reply.(int64)
+	if count == 1 {
+		// the first request begins a new window
+		if _, err := b.store.do("PEXPIRE", key, strconv.FormatInt(ttlMillis(window), 10)); err != nil {
+			return 0, false, 0, err
+		}
+	}
+	if count <= limit {
+		return count, true, 0, nil
+	}
+
+	b.store.do("DECR", key)
+	reply, err = b.store.do("PTTL", key)
+	if err != nil {
+		return 0, false, 0, err
+	}
+	ttl, _ := reply.(int64)
+	if ttl < 0 {
+		// repair windows without expiration, e.g. if the previous expiration could not be set
+		b.store.do("PEXPIRE", key, strconv.FormatInt(ttlMillis(window), 10))
+		ttl = ttlMillis(window)
+	}
+	return count, false, time.Duration(ttl) * time.Millisecond, nil
+}
+
+func (b *redisRateLimitBackend) decrement(key string) {
+	key = b.store.config.Prefix + key
+	if reply, err := b.store.do("DECR", key); err == nil {
+		if count, _ := reply.(int64); count < 0 {
+			// the window expired in the meantime
+			b.store.do("DEL", key)
+		}
+	}
+}
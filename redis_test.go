@@ -0,0 +1,313 @@
+package http
+
+import (
+	"bufio"
+	"net"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sbreitf1/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeRedis is a minimal in-memory Redis server supporting the commands used by RedisStore.
+type fakeRedis struct {
+	listener net.Listener
+	password string
+
+	mutex   sync.Mutex
+	values  map[string]string
+	expires map[string]time.Time
+}
+
+func newFakeRedis(t *testing.T, password string) *fakeRedis {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &fakeRedis{listener: listener, password: password, values: make(map[string]string), expires: make(map[string]time.Time)}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go r.serve(conn)
+		}
+	}()
+	t.Cleanup(func() { listener.Close() })
+	return r
+}
+
+func (r *fakeRedis) Addr() string {
+	return r.listener.Addr().String()
+}
+
+func (r *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	authenticated := len(r.password) == 0
+	for {
+		reply, err := readRESP(reader)
+		if err != nil {
+			return
+		}
+		var args []string
+		for _, arg := range reply.([]interface{}) {
+			args = append(args, string(arg.([]byte)))
+		}
+		if strings.ToUpper(args[0]) == "AUTH" {
+			authenticated = args[1] == r.password
+			if !authenticated {
+				conn.Write([]byte("-WRONGPASS invalid password\r\n"))
+				continue
+			}
+			conn.Write([]byte("+OK\r\n"))
+			continue
+		}
+		if !authenticated {
+			conn.Write([]byte("-NOAUTH Authentication required\r\n"))
+			continue
+		}
+		conn.Write([]byte(r.execute(args)))
+	}
+}
+
+func (r *fakeRedis) execute(args []string) string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	now := time.Now()
+	for key, expires := range r.expires {
+		if !now.Before(expires) {
+			delete(r.values, key)
+			delete(r.expires, key)
+		}
+	}
+
+	switch strings.ToUpper(args[0]) {
+	case "PING":
+		return "+PONG\r\n"
+	case "SELECT":
+		return "+OK\r\n"
+	case "GET":
+		value, ok := r.values[args[1]]
+		if !ok {
+			return "$-1\r\n"
+		}
+		return "$" + strconv.Itoa(len(value)) + "\r\n" + value + "\r\n"
+	case "SET":
+		nx, ttl := false, time.Duration(0)
+		for i := 3; i < len(args); i++ {
+			switch strings.ToUpper(args[i]) {
+			case "NX":
+				nx = true
+			case "PX":
+				ms, _ := strconv.Atoi(args[i+1])
+				ttl = time.Duration(ms) * time.Millisecond
+				i++
+			}
+		}
+		if _, exists := r.values[args[1]]; exists && nx {
+			return "$-1\r\n"
+		}
+		r.values[args[1]] = args[2]
+		delete(r.expires, args[1])
+		if ttl > 0 {
+			r.expires[args[1]] = now.Add(ttl)
+		}
+		return "+OK\r\n"
+	case "DEL":
+		_, exists := r.values[args[1]]
+		delete(r.values, args[1])
+		delete(r.expires, args[1])
+		if exists {
+			return ":1\r\n"
+		}
+		return ":0\r\n"
+	case "INCR", "DECR":
+		value, _ := strconv.ParseInt(r.values[args[1]], 10, 64)
+		if strings.ToUpper(args[0]) == "INCR" {
+			value++
+		} else {
+			value--
+		}
+		r.values[args[1]] = strconv.FormatInt(value, 10)
+		return ":" + strconv.FormatInt(value, 10) + "\r\n"
+	case "EVAL":
+		// only the script of DeleteIfEqual is supported
+		if args[1] != deleteIfEqualScript {
+			return "-ERR unknown script\r\n"
+		}
+		if value, exists := r.values[args[3]]; !exists || value != args[4] {
+			return ":0\r\n"
+		}
+		delete(r.values, args[3])
+		delete(r.expires, args[3])
+		return ":1\r\n"
+	case "PEXPIRE":
+		if _, exists := r.values[args[1]]; !exists {
+			return ":0\r\n"
+		}
+		ms, _ := strconv.Atoi(args[2])
+		r.expires[args[1]] = now.Add(time.Duration(ms) * time.Millisecond)
+		return ":1\r\n"
+	case "PTTL":
+		if _, exists := r.values[args[1]]; !exists {
+			return ":-2\r\n"
+		}
+		expires, ok := r.expires[args[1]]
+		if !ok {
+			return ":-1\r\n"
+		}
+		return ":" + strconv.FormatInt(expires.Sub(now).Milliseconds(), 10) + "\r\n"
+	}
+	return "-ERR unknown command\r\n"
+}
+
+func TestRedisStore(t *testing.T) {
+	server := newFakeRedis(t, "secret")
+	store, err := NewRedisStore(&RedisConfig{Address: server.Addr(), Password: "secret", DB: 1, Prefix: "app:"})
+	errors.AssertNil(t, err)
+	defer store.Close()
+
+	_, ok := store.Get("key")
+	assert.False(t, ok)
+	store.Set("key", []byte("value\r\nwith binary \x00"))
+	data, ok := store.Get("key")
+	assert.True(t, ok)
+	assert.Equal(t, "value\r\nwith binary \x00", string(data))
+	server.mutex.Lock()
+	assert.Contains(t, server.values, "app:key")
+	server.mutex.Unlock()
+
+	stored, err := store.SetIfAbsent("key", []byte("other"), time.Second)
+	errors.AssertNil(t, err)
+	assert.False(t, stored)
+	store.Delete("key")
+	stored, err = store.SetIfAbsent("key", []byte("other"), 20*time.Millisecond)
+	errors.AssertNil(t, err)
+	assert.True(t, stored)
+
+	time.Sleep(30 * time.Millisecond)
+	_, ok = store.Get("key")
+	assert.False(t, ok)
+
+	store.SetWithTTL("ttl", []byte("x"), time.Hour)
+	_, ok = store.Get("ttl")
+	assert.True(t, ok)
+
+	deleted, err := store.DeleteIfEqual("ttl", []byte("y"))
+	errors.AssertNil(t, err)
+	assert.False(t, deleted)
+	deleted, err = store.DeleteIfEqual("ttl", []byte("x"))
+	errors.AssertNil(t, err)
+	assert.True(t, deleted)
+	_, ok = store.Get("ttl")
+	assert.False(t, ok)
+}
+
+func TestRedisStoreErrors(t *testing.T) {
+	server := newFakeRedis(t, "secret")
+	_, err := NewRedisStore(&RedisConfig{Address: server.Addr(), Password: "wrong"})
+	errors.Assert(t, ErrStore, err)
+
+	_, err = NewRedisStore(&RedisConfig{})
+	errors.Assert(t, ErrStore, err)
+
+	store, err := NewRedisStore(&RedisConfig{Address: server.Addr(), Password: "secret", Timeout: Duration(100 * time.Millisecond)})
+	errors.AssertNil(t, err)
+	server.listener.Close()
+	store.Close()
+	_, setErr := store.SetIfAbsent("key", nil, time.Second)
+	errors.Assert(t, ErrStore, setErr)
+	_, ok := store.Get("key")
+	assert.False(t, ok)
+}
+
+func TestIdempotencyWithRedisStore(t *testing.T) {
+	server := newFakeRedis(t, "")
+	store, err := NewRedisStore(&RedisConfig{Address: server.Addr()})
+	errors.AssertNil(t, err)
+	defer store.Close()
+
+	var _ ConditionalStore = store
+	var _ ConditionalStore = NewMemoryCacheStorage()
+	locked, err := store.SetIfAbsent("idempotency|lock", []byte{1}, time.Minute)
+	errors.AssertNil(t, err)
+	assert.True(t, locked)
+}
+
+func TestRedisRateLimitBackend(t *testing.T) {
+	server := newFakeRedis(t, "")
+	store, err := NewRedisStore(&RedisConfig{Address: server.Addr(), Prefix: "app:"})
+	errors.AssertNil(t, err)
+	defer store.Close()
+
+	table := &RateLimitTable{
+		Default: RateLimit{Rate: 0.1, Burst: 2},
+		Tiers: map[string]RateLimit{
+			"premium": {Rate: 100, Burst: 100, Quota: 3, QuotaPeriod: Duration(time.Hour)},
+		},
+	}
+	principal := func(c *gin.Context) (string, string) {
+		key := c.GetHeader("X-API-Key")
+		if key == "premium-key" {
+			return key, "premium"
+		}
+		return key, "free"
+	}
+	// two replicas sharing the same backend
+	var engines []*gin.Engine
+	for i := 0; i < 2; i++ {
+		middleware, err := NewRateLimitMiddlewareWithBackend(table, principal, NewRedisRateLimitBackend(store))
+		errors.AssertNil(t, err)
+		engine := gin.New()
+		engine.Use(middleware)
+		engine.GET("/items", func(c *gin.Context) { c.Status(200) })
+		engines = append(engines, engine)
+	}
+	serve := func(replica int, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/items", nil)
+		req.Header.Set("X-API-Key", key)
+		w := httptest.NewRecorder()
+		engines[replica].ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("rate", func(t *testing.T) {
+		assert.Equal(t, 200, serve(0, "free-key").Code)
+		assert.Equal(t, 200, serve(1, "free-key").Code)
+		w := serve(0, "free-key")
+		assert.Equal(t, 429, w.Code)
+		assert.Equal(t, "20", w.Header().Get("Retry-After"))
+		assert.Equal(t, 429, serve(1, "free-key").Code)
+		assert.Equal(t, 200, serve(1, "other-key").Code)
+	})
+
+	t.Run("quota", func(t *testing.T) {
+		for i := 2; i >= 0; i-- {
+			w := serve(i%2, "premium-key")
+			assert.Equal(t, 200, w.Code)
+			assert.Equal(t, strconv.Itoa(i), w.Header().Get(HeaderQuotaRemaining))
+		}
+		w := serve(1, "premium-key")
+		assert.Equal(t, 429, w.Code)
+		assert.Equal(t, "0", w.Header().Get(HeaderQuotaRemaining))
+		assert.Equal(t, "3600", w.Header().Get("Retry-After"))
+
+		server.mutex.Lock()
+		assert.Equal(t, "3", server.values["app:ratelimit|quota|premium|premium-key"])
+		server.mutex.Unlock()
+	})
+
+	t.Run("unavailable backend", func(t *testing.T) {
+		server.listener.Close()
+		store.Close()
+		assert.Equal(t, 200, serve(0, "free-key").Code)
+	})
+}
@@ -0,0 +1,343 @@
+package http
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sbreitf1/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	// ErrRegistry is returned when a service instance could not be registered or deregistered in a service registry.
+	ErrRegistry = errors.New("Service registry operation failed")
+)
+
+// ServiceInstance describes an instance of a service in a service registry.
+type ServiceInstance struct {
+	// ID identifies the instance. Defaults to the name and address.
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// Address is the advertised host and port of the instance, e.g. "10.0.0.5:8080".
+	Address string            `json:"address"`
+	Tags    []string          `json:"tags,omitempty"`
+	Meta    map[string]string `json:"meta,omitempty"`
+	// HealthCheckURL is polled by registries supporting health checks, e.g. "http://10.0.0.5:8080/readiness".
+	HealthCheckURL string `json:"healthCheckUrl,omitempty"`
+}
+
+// ServiceRegistry registers service instances for service discovery.
+type ServiceRegistry interface {
+	Register(instance *ServiceInstance) errors.Error
+	Deregister(instance *ServiceInstance) errors.Error
+}
+
+// ServiceRegistration is a service without routes that registers an instance in a service registry on BeginServing and deregisters it on StopServing, so instances of the server appear in service discovery automatically. Register it using RegisterService.
+type ServiceRegistration struct {
+	registry ServiceRegistry
+	instance ServiceInstance
+}
+
+// NewServiceRegistration returns a service registering instance in registry.
+func NewServiceRegistration(registry ServiceRegistry, instance *ServiceInstance) (*ServiceRegistration, errors.Error) {
+	if len(instance.Name) == 0 {
+		return nil, ErrInvalidConfig.Msg("Service registration requires a service name").Make()
+	}
+	if _, _, err := net.SplitHostPort(instance.Address); err != nil {
+		return nil, ErrInvalidConfig.Msg("Service registration requires an address with host and port").Make().Cause(err)
+	}
+	r := &ServiceRegistration{registry: registry, instance: *instance}
+	if len(r.instance.ID) == 0 {
+		r.instance.ID = r.instance.Name + "-" + strings.NewReplacer(":", "-", "[", "", "]", "").Replace(r.instance.Address)
+	}
+	return r, nil
+}
+
+// Instance returns the registered instance.
+func (r *ServiceRegistration) Instance() ServiceInstance {
+	return r.instance
+}
+
+// RegisterRoutes does nothing.
+func (r *ServiceRegistration) RegisterRoutes(e *gin.Engine) {}
+
+// BeginServing registers the instance. Failures are logged, the server keeps serving.
+func (r *ServiceRegistration) BeginServing() {
+	if err := r.registry.Register(&r.instance); err != nil {
+		log.WithField("serviceId", r.instance.ID).WithError(err).Error("Service instance could not be registered")
+		return
+	}
+	log.WithField("serviceId", r.instance.ID).Info("Service instance registered")
+}
+
+// StopServing deregisters the instance.
+func (r *ServiceRegistration) StopServing() {
+	if err := r.registry.Deregister(&r.instance); err != nil {
+		log.WithField("serviceId", r.instance.ID).WithError(err).Warn("Service instance could not be deregistered")
+	}
+}
+
+// Healthy always returns nil.
+func (r *ServiceRegistration) Healthy() errors.Error {
+	return nil
+}
+
+// Ready always returns nil as registration failures do not affect serving requests.
+func (r *ServiceRegistration) Ready() errors.Error {
+	return nil
+}
+
+// ConsulRegistryConfig contains the parameters of a ConsulRegistry.
+type ConsulRegistryConfig struct {
+	// URL of the Consul agent. Defaults to "http://127.0.0.1:8500".
+	URL string `json:"url,omitempty"`
+	// Token is sent as ACL token.
+	Token string `json:"token,omitempty"`
+	// CheckInterval of the HTTP health check. Defaults to 10 seconds.
+	CheckInterval Duration `json:"checkInterval,omitempty"`
+	// DeregisterAfter removes instances whose health check has been critical for the given duration, e.g. after a crash. Defaults to 1 minute.
+	DeregisterAfter Duration `json:"deregisterAfter,omitempty"`
+	// Timeout limits each request to the agent, so an unreachable agent does not block the shutdown of the server. Defaults to 5 seconds.
+	Timeout Duration `json:"timeout,omitempty"`
+}
+
+// ConsulRegistry registers service instances at the local Consul agent.
+type ConsulRegistry struct {
+	config ConsulRegistryConfig
+	client *Client
+}
+
+// NewConsulRegistry returns a registry using the HTTP API of a Consul agent.
+func NewConsulRegistry(config *ConsulRegistryConfig) *ConsulRegistry {
+	r := &ConsulRegistry{config: *config, client: NewClient()}
+	if len(r.config.URL) == 0 {
+		r.config.URL = "http://127.0.0.1:8500"
+	}
+	if r.config.CheckInterval <= 0 {
+		r.config.CheckInterval = Duration(10 * time.Second)
+	}
+	if r.config.DeregisterAfter <= 0 {
+		r.config.DeregisterAfter = Duration(time.Minute)
+	}
+	if r.config.Timeout <= 0 {
+		r.config.Timeout = Duration(5 * time.Second)
+	}
+	r.client.BaseURL = r.config.URL
+	r.client.Timeout = time.Duration(r.config.Timeout)
+	if len(r.config.Token) > 0 {
+		r.client.DefaultHeader.Set("X-Consul-Token", r.config.Token)
+	}
+	return r
+}
+
+type consulRegistration struct {
+	ID      string            `json:"ID"`
+	Name    string            `json:"Name"`
+	Address string            `json:"Address"`
+	Port    int               `json:"Port"`
+	Tags    []string          `json:"Tags,omitempty"`
+	Meta    map[string]string `json:"Meta,omitempty"`
+	Check   *consulCheck      `json:"Check,omitempty"`
+}
+
+type consulCheck struct {
+	HTTP                           string `json:"HTTP"`
+	Interval                       string `json:"Interval"`
+	DeregisterCriticalServiceAfter string `json:"DeregisterCriticalServiceAfter"`
+}
+
+// Register registers the instance with an HTTP health check if the instance defines a health check URL.
+func (r *ConsulRegistry) Register(instance *ServiceInstance) errors.Error {
+	host, portStr, err := net.SplitHostPort(instance.Address)
+	if err != nil {
+		return ErrRegistry.Msg("Invalid instance address %q", instance.Address).Make().Cause(err)
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return ErrRegistry.Msg("Invalid instance port %q", portStr).Make().Cause(err)
+	}
+
+	registration := consulRegistration{ID: instance.ID, Name: instance.Name, Address: host, Port: port, Tags: instance.Tags, Meta: instance.Meta}
+	if len(instance.HealthCheckURL) > 0 {
+		registration.Check = &consulCheck{HTTP: instance.HealthCheckURL, Interval: time.Duration(r.config.CheckInterval).String(), DeregisterCriticalServiceAfter: time.Duration(r.config.DeregisterAfter).String()}
+	}
+	if err := r.client.PutJSON("/v1/agent/service/register", registration, nil, nil); err != nil {
+		return ErrRegistry.Msg("Instance %q could not be registered in Consul", instance.ID).Make().Cause(err)
+	}
+	return nil
+}
+
+// Deregister removes the instance from the agent.
+func (r *ConsulRegistry) Deregister(instance *ServiceInstance) errors.Error {
+	if err := r.client.PutJSON("/v1/agent/service/deregister/"+url.PathEscape(instance.ID), nil, nil, nil); err != nil {
+		return ErrRegistry.Msg("Instance %q could not be deregistered in Consul", instance.ID).Make().Cause(err)
+	}
+	return nil
+}
+
+// EtcdRegistryConfig contains the parameters of an EtcdRegistry.
+type EtcdRegistryConfig struct {
+	// URL of the etcd JSON gateway. Defaults to "http://127.0.0.1:2379".
+	URL string `json:"url,omitempty"`
+	// Prefix of the instance keys, which are named like "<prefix><name>/<id>". Defaults to "/services/".
+	Prefix string `json:"prefix,omitempty"`
+	// TTL of the lease that keeps the instance key. The lease is refreshed periodically, so keys of crashed instances expire after the TTL. Defaults to 30 seconds.
+	TTL Duration `json:"ttl,omitempty"`
+	// Timeout limits each request to the gateway, so an unreachable etcd does not block lease refreshes and the shutdown of the server. Defaults to 5 seconds.
+	Timeout Duration `json:"timeout,omitempty"`
+}
+
+// EtcdRegistry stores service instances as JSON values of keys bound to a lease in etcd.
+type EtcdRegistry struct {
+	config EtcdRegistryConfig
+	client *Client
+
+	mutex  sync.Mutex
+	leases map[string]*etcdLease
+}
+
+type etcdLease struct {
+	id   string
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewEtcdRegistry returns a registry using the JSON gateway of the etcd v3 API.
+func NewEtcdRegistry(config *EtcdRegistryConfig) *EtcdRegistry {
+	r := &EtcdRegistry{config: *config, client: NewClient(), leases: make(map[string]*etcdLease)}
+	if len(r.config.URL) == 0 {
+		r.config.URL = "http://127.0.0.1:2379"
+	}
+	if len(r.config.Prefix) == 0 {
+		r.config.Prefix = "/services/"
+	}
+	if r.config.TTL < Duration(time.Second) {
+		r.config.TTL = Duration(30 * time.Second)
+	}
+	if r.config.Timeout <= 0 {
+		r.config.Timeout = Duration(5 * time.Second)
+	}
+	r.client.BaseURL = r.config.URL
+	r.client.Timeout = time.Duration(r.config.Timeout)
+	return r
+}
+
+// Register grants a lease, stores the instance under its key and refreshes the lease until the instance is deregistered. The instance is registered again if the lease expired nevertheless, e.g. because etcd was unavailable for longer than the TTL.
+func (r *EtcdRegistry) Register(instance *ServiceInstance) errors.Error {
+	leaseID, err := r.put(instance)
+	if err != nil {
+		return err
+	}
+
+	lease := &etcdLease{id: leaseID, stop: make(chan struct{}), done: make(chan struct{})}
+	r.mutex.Lock()
+	previous := r.leases[instance.ID]
+	r.leases[instance.ID] = lease
+	r.mutex.Unlock()
+	if previous != nil {
+		r.stopLease(previous)
+	}
+	go r.keepAlive(*instance, lease)
+	return nil
+}
+
+// put grants a new lease and stores the instance bound to it. It returns the ID of the lease.
+func (r *EtcdRegistry) put(instance *ServiceInstance) (string, errors.Error) {
+	var grant struct {
+		// the gateway encodes 64 bit integers as strings
+		ID json.Number `json:"ID"`
+	}
+	if err := r.client.PostJSON("/v3/lease/grant", map[string]interface{}{"TTL": int64(time.Duration(r.config.TTL) / time.Second)}, &grant, nil); err != nil {
+		return "", ErrRegistry.Msg("Lease for instance %q could not be granted by etcd", instance.ID).Make().Cause(err)
+	}
+	if len(grant.ID) == 0 {
+		return "", ErrRegistry.Msg("Lease for instance %q has not been granted by etcd", instance.ID).Make()
+	}
+
+	value, err := json.Marshal(instance)
+	if err != nil {
+		return "", ErrRegistry.Make().Cause(err)
+	}
+	put := map[string]string{
+		"key":   base64.StdEncoding.EncodeToString([]byte(r.key(instance))),
+		"value": base64.StdEncoding.EncodeToString(value),
+		"lease": grant.ID.String(),
+	}
+	if err := r.client.PostJSON("/v3/kv/put", put, nil, nil); err != nil {
+		r.revoke(grant.ID.String())
+		return "", ErrRegistry.Msg("Instance %q could not be stored in etcd", instance.ID).Make().Cause(err)
+	}
+	return grant.ID.String(), nil
+}
+
+// Deregister stops refreshing the lease and revokes it, which deletes the instance key.
+func (r *EtcdRegistry) Deregister(instance *ServiceInstance) errors.Error {
+	r.mutex.Lock()
+	lease := r.leases[instance.ID]
+	delete(r.leases, instance.ID)
+	r.mutex.Unlock()
+	if lease == nil {
+		return nil
+	}
+	r.stopLease(lease)
+	if err := r.revoke(lease.id); err != nil {
+		return ErrRegistry.Msg("Lease of instance %q could not be revoked in etcd", instance.ID).Make().Cause(err)
+	}
+	return nil
+}
+
+func (r *EtcdRegistry) key(instance *ServiceInstance) string {
+	return r.config.Prefix + instance.Name + "/" + instance.ID
+}
+
+// keepAlive refreshes the lease until it is stopped. The lease ID is replaced when the instance is registered again, it must only be read after the lease has been stopped.
+func (r *EtcdRegistry) keepAlive(instance ServiceInstance, lease *etcdLease) {
+	defer close(lease.done)
+	ticker := time.NewTicker(time.Duration(r.config.TTL) / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-lease.stop:
+			return
+		case <-ticker.C:
+			var response struct {
+				Result struct {
+					// omitted by the gateway for expired leases
+					TTL json.Number `json:"TTL"`
+				} `json:"result"`
+			}
+			if err := r.client.PostJSON("/v3/lease/keepalive", map[string]string{"ID": lease.id}, &response, nil); err != nil {
+				log.WithField("serviceId", instance.ID).WithError(err).Warn("Lease could not be refreshed in etcd")
+				continue
+			}
+			if ttl, _ := response.Result.TTL.Int64(); ttl > 0 {
+				continue
+			}
+
+			// the instance key has been deleted together with the expired lease
+			log.WithField("serviceId", instance.ID).Warn("Lease expired in etcd, registering instance again")
+			leaseID, err := r.put(&instance)
+			if err != nil {
+				log.WithField("serviceId", instance.ID).WithError(err).Warn("Instance could not be registered again in etcd")
+				continue
+			}
+			lease.id = leaseID
+		}
+	}
+}
+
+func (r *EtcdRegistry) stopLease(lease *etcdLease) {
+	close(lease.stop)
+	<-lease.done
+}
+
+func (r *EtcdRegistry) revoke(leaseID string) errors.Error {
+	return r.client.PostJSON("/v3/lease/revoke", map[string]string{"ID": leaseID}, nil, nil)
+}
@@ -0,0 +1,193 @@
+package http
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sbreitf1/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// registryRecorder records the requests of a fake registry API.
+type registryRecorder struct {
+	mutex    sync.Mutex
+	requests []string
+	bodies   []map[string]interface{}
+	tokens   []string
+}
+
+func (r *registryRecorder) handler(respond func(path string) string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(req.Body).Decode(&body)
+		r.mutex.Lock()
+		r.requests = append(r.requests, req.Method+" "+req.URL.Path)
+		r.bodies = append(r.bodies, body)
+		r.tokens = append(r.tokens, req.Header.Get("X-Consul-Token"))
+		r.mutex.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(respond(req.URL.Path)))
+	})
+}
+
+func (r *registryRecorder) body(i int) map[string]interface{} {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.bodies[i]
+}
+
+func (r *registryRecorder) count(request string) int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	count := 0
+	for _, req := range r.requests {
+		if req == request {
+			count++
+		}
+	}
+	return count
+}
+
+func TestServiceRegistration(t *testing.T) {
+	_, err := NewServiceRegistration(NewConsulRegistry(&ConsulRegistryConfig{}), &ServiceInstance{Address: "10.0.0.5:8080"})
+	assert.True(t, errors.InstanceOf(err, ErrInvalidConfig))
+	_, err = NewServiceRegistration(NewConsulRegistry(&ConsulRegistryConfig{}), &ServiceInstance{Name: "users", Address: "10.0.0.5"})
+	assert.True(t, errors.InstanceOf(err, ErrInvalidConfig))
+
+	recorder := &registryRecorder{}
+	consul := httptest.NewServer(recorder.handler(func(string) string { return "" }))
+	defer consul.Close()
+
+	registration, err := NewServiceRegistration(NewConsulRegistry(&ConsulRegistryConfig{URL: consul.URL, Token: "secret"}), &ServiceInstance{Name: "users", Address: "10.0.0.5:8080", Tags: []string{"v1"}, HealthCheckURL: "http://10.0.0.5:8080/readiness"})
+	errors.AssertNil(t, err)
+	assert.Equal(t, "users-10.0.0.5-8080", registration.Instance().ID)
+
+	registration.BeginServing()
+	registration.StopServing()
+	assert.Equal(t, []string{"PUT /v1/agent/service/register", "PUT /v1/agent/service/deregister/users-10.0.0.5-8080"}, recorder.requests)
+	assert.Equal(t, []string{"secret", "secret"}, recorder.tokens)
+	body := recorder.bodies[0]
+	assert.Equal(t, "users-10.0.0.5-8080", body["ID"])
+	assert.Equal(t, "10.0.0.5", body["Address"])
+	assert.Equal(t, float64(8080), body["Port"])
+	assert.Equal(t, []interface{}{"v1"}, body["Tags"])
+	assert.Equal(t, map[string]interface{}{"HTTP": "http://10.0.0.5:8080/readiness", "Interval": "10s", "DeregisterCriticalServiceAfter": "1m0s"}, body["Check"])
+}
+
+func TestConsulRegistryUnavailable(t *testing.T) {
+	consul := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(403)
+	}))
+	defer consul.Close()
+
+	registry := NewConsulRegistry(&ConsulRegistryConfig{URL: consul.URL})
+	err := registry.Register(&ServiceInstance{ID: "users-1", Name: "users", Address: "10.0.0.5:8080"})
+	assert.True(t, errors.InstanceOf(err, ErrRegistry))
+	err = registry.Deregister(&ServiceInstance{ID: "users-1"})
+	assert.True(t, errors.InstanceOf(err, ErrRegistry))
+}
+
+func TestEtcdRegistry(t *testing.T) {
+	recorder := &registryRecorder{}
+	etcd := httptest.NewServer(recorder.handler(func(path string) string {
+		switch path {
+		case "/v3/lease/grant":
+			return `{"ID":"7587863612460466952","TTL":"1"}`
+		case "/v3/lease/keepalive":
+			return `{"result":{"ID":"7587863612460466952","TTL":"1"}}`
+		}
+		return "{}"
+	}))
+	defer etcd.Close()
+
+	registry := NewEtcdRegistry(&EtcdRegistryConfig{URL: etcd.URL, TTL: Duration(time.Second)})
+	instance := &ServiceInstance{ID: "users-1", Name: "users", Address: "10.0.0.5:8080"}
+	errors.AssertNil(t, registry.Register(instance))
+
+	put := recorder.body(1)
+	key, _ := base64.StdEncoding.DecodeString(put["key"].(string))
+	assert.Equal(t, "/services/users/users-1", string(key))
+	value, _ := base64.StdEncoding.DecodeString(put["value"].(string))
+	assert.JSONEq(t, `{"id":"users-1","name":"users","address":"10.0.0.5:8080"}`, string(value))
+	assert.Equal(t, "7587863612460466952", put["lease"])
+	assert.Equal(t, float64(1), recorder.body(0)["TTL"])
+
+	// the lease is refreshed every third of the TTL
+	time.Sleep(800 * time.Millisecond)
+	assert.True(t, recorder.count("POST /v3/lease/keepalive") >= 1)
+
+	errors.AssertNil(t, registry.Deregister(instance))
+	assert.Equal(t, 1, recorder.count("POST /v3/lease/revoke"))
+	keepAlives := recorder.count("POST /v3/lease/keepalive")
+	time.Sleep(500 * time.Millisecond)
+	assert.Equal(t, keepAlives, recorder.count("POST /v3/lease/keepalive"))
+	errors.AssertNil(t, registry.Deregister(instance))
+	assert.Equal(t, 1, recorder.count("POST /v3/lease/grant"))
+}
+
+func TestEtcdRegistryExpiredLease(t *testing.T) {
+	recorder := &registryRecorder{}
+	etcd := httptest.NewServer(recorder.handler(func(path string) string {
+		switch path {
+		case "/v3/lease/grant":
+			return `{"ID":"7587863612460466952","TTL":"1"}`
+		case "/v3/lease/keepalive":
+			// the gateway omits the TTL of expired leases
+			return `{"result":{"ID":"7587863612460466952"}}`
+		}
+		return "{}"
+	}))
+	defer etcd.Close()
+
+	registry := NewEtcdRegistry(&EtcdRegistryConfig{URL: etcd.URL, TTL: Duration(time.Second)})
+	instance := &ServiceInstance{ID: "users-1", Name: "users", Address: "10.0.0.5:8080"}
+	errors.AssertNil(t, registry.Register(instance))
+	time.Sleep(500 * time.Millisecond)
+	errors.AssertNil(t, registry.Deregister(instance))
+
+	assert.True(t, recorder.count("POST /v3/lease/grant") >= 2)
+	assert.Equal(t, recorder.count("POST /v3/lease/grant"), recorder.count("POST /v3/kv/put"))
+}
+
+func TestRegistryTimeout(t *testing.T) {
+	blocked := make(chan struct{})
+	blackhole := func(w http.ResponseWriter, r *http.Request) {
+		<-blocked
+	}
+	consul := httptest.NewServer(http.HandlerFunc(blackhole))
+	defer consul.Close()
+	etcd := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v3/lease/grant":
+			w.Write([]byte(`{"ID":"7587863612460466952","TTL":"1"}`))
+		case "/v3/kv/put":
+			w.Write([]byte("{}"))
+		default:
+			blackhole(w, r)
+		}
+	}))
+	defer etcd.Close()
+	// release the pending requests before the servers are closed
+	defer close(blocked)
+
+	consulRegistry := NewConsulRegistry(&ConsulRegistryConfig{URL: consul.URL, Timeout: Duration(100 * time.Millisecond)})
+	start := time.Now()
+	err := consulRegistry.Deregister(&ServiceInstance{ID: "users-1"})
+	assert.True(t, errors.InstanceOf(err, ErrRegistry))
+	assert.True(t, time.Since(start) < time.Second)
+
+	etcdRegistry := NewEtcdRegistry(&EtcdRegistryConfig{URL: etcd.URL, TTL: Duration(time.Second), Timeout: Duration(100 * time.Millisecond)})
+	instance := &ServiceInstance{ID: "users-1", Name: "users", Address: "10.0.0.5:8080"}
+	errors.AssertNil(t, etcdRegistry.Register(instance))
+	// a keepalive is pending when the instance is deregistered
+	time.Sleep(400 * time.Millisecond)
+	start = time.Now()
+	err = etcdRegistry.Deregister(instance)
+	assert.True(t, errors.InstanceOf(err, ErrRegistry))
+	assert.True(t, time.Since(start) < time.Second)
+}
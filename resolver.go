@@ -0,0 +1,102 @@
+package http
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+)
+
+// Resolver looks up the addresses of a host name. It is implemented by *net.Resolver.
+type Resolver interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+// CachingResolver caches the results of another resolver for a fixed duration and answers static host overrides without lookup.
+type CachingResolver struct {
+	// Resolver performs the actual lookups. The default resolver is used when nil.
+	Resolver Resolver
+	// TTL denotes how long lookup results are cached. Results are not cached when zero.
+	TTL time.Duration
+	// Hosts maps host names to static addresses, similar to /etc/hosts.
+	Hosts map[string][]string
+
+	mutex sync.Mutex
+	cache map[string]dnsCacheEntry
+}
+
+type dnsCacheEntry struct {
+	addrs   []string
+	expires time.Time
+}
+
+// NewCachingResolver returns a resolver that caches lookups of the default resolver for the given duration.
+func NewCachingResolver(ttl time.Duration) *CachingResolver {
+	return &CachingResolver{TTL: ttl, Hosts: make(map[string][]string)}
+}
+
+// LookupHost returns the static addresses or the cached lookup result of host.
+func (r *CachingResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	if addrs, ok := r.Hosts[host]; ok {
+		return addrs, nil
+	}
+
+	r.mutex.Lock()
+	entry, ok := r.cache[host]
+	r.mutex.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		return entry.addrs, nil
+	}
+
+	resolver := r.Resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	addrs, err := resolver.LookupHost(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.TTL > 0 {
+		r.mutex.Lock()
+		if r.cache == nil {
+			r.cache = make(map[string]dnsCacheEntry)
+		}
+		r.cache[host] = dnsCacheEntry{addrs: addrs, expires: time.Now().Add(r.TTL)}
+		r.mutex.Unlock()
+	}
+	return addrs, nil
+}
+
+// Flush removes all cached lookup results.
+func (r *CachingResolver) Flush() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.cache = nil
+}
+
+// dialResolved resolves the host of addr using the client resolver and dials the resulting addresses in order until a connection is established.
+func (client *Client) dialResolved(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || net.ParseIP(host) != nil {
+		return client.dial(ctx, network, addr)
+	}
+
+	addrs, err := client.Resolver.LookupHost(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	if len(addrs) == 0 {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+
+	var lastErr error
+	for _, a := range addrs {
+		conn, err := client.dial(ctx, network, net.JoinHostPort(a, port))
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
@@ -0,0 +1,71 @@
+package http
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/sbreitf1/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type countingResolver struct {
+	lookups int
+	addrs   []string
+}
+
+func (r *countingResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	r.lookups++
+	return r.addrs, nil
+}
+
+func TestCachingResolver(t *testing.T) {
+	backend := &countingResolver{addrs: []string{"10.0.0.1"}}
+	resolver := NewCachingResolver(50 * time.Millisecond)
+	resolver.Resolver = backend
+	resolver.Hosts["static.test"] = []string{"127.0.0.2"}
+
+	addrs, err := resolver.LookupHost(context.Background(), "static.test")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"127.0.0.2"}, addrs)
+	assert.Equal(t, 0, backend.lookups)
+
+	resolver.LookupHost(context.Background(), "api.test")
+	addrs, _ = resolver.LookupHost(context.Background(), "api.test")
+	assert.Equal(t, []string{"10.0.0.1"}, addrs)
+	assert.Equal(t, 1, backend.lookups, "Second lookup should be cached")
+
+	time.Sleep(60 * time.Millisecond)
+	resolver.LookupHost(context.Background(), "api.test")
+	assert.Equal(t, 2, backend.lookups, "Expired entries should be looked up again")
+
+	resolver.Flush()
+	resolver.LookupHost(context.Background(), "api.test")
+	assert.Equal(t, 3, backend.lookups)
+}
+
+func TestClientResolver(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Host))
+	}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+	_, port, _ := net.SplitHostPort(u.Host)
+
+	resolver := NewCachingResolver(time.Minute)
+	resolver.Hosts["api.test"] = []string{"127.0.0.1"}
+	client := NewClient()
+	client.Resolver = resolver
+
+	response, err := client.Get("http://api.test:"+port+"/", nil)
+	errors.AssertNil(t, err)
+	assertResponse(t, 200, "api.test:"+port, response)
+
+	resolver.Hosts["unknown.invalid"] = []string{}
+	_, err = client.Get("http://unknown.invalid:"+port+"/", nil)
+	errors.Assert(t, ErrRequestFailed, err)
+}
@@ -0,0 +1,165 @@
+package http
+
+import (
+	crand "crypto/rand"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sbreitf1/errors"
+)
+
+// RetryPolicy defines how failed requests are retried. Requests are retried on network errors and on responses with status 429 or 5xx (except 501).
+type RetryPolicy struct {
+	// MaxAttempts denotes the total number of attempts including the initial request.
+	MaxAttempts int `json:"maxAttempts"`
+	// InitialBackoff is the wait time before the first retry. It is doubled for every subsequent retry.
+	InitialBackoff time.Duration `json:"initialBackoff"`
+	// MaxBackoff limits the wait time between two attempts. A Retry-After header exceeding this limit stops retrying.
+	MaxBackoff time.Duration `json:"maxBackoff"`
+	// Jitter randomizes each backoff by the given fraction (0 to 1) to avoid synchronized retries of many clients.
+	Jitter float64 `json:"jitter"`
+	// RetryNonIdempotent enables retries for POST and PATCH requests.
+	RetryNonIdempotent bool `json:"retryNonIdempotent,omitempty"`
+	// IdempotencyKeyHeader denotes the header that carries a generated idempotency key for retried POST and PATCH requests. The key is reused for all attempts of a request, so upstreams can deduplicate them. Defaults to "Idempotency-Key".
+	IdempotencyKeyHeader string `json:"idempotencyKeyHeader,omitempty"`
+	// DisableIdempotencyKey disables the generation of idempotency keys.
+	DisableIdempotencyKey bool `json:"disableIdempotencyKey,omitempty"`
+	// Budget optionally limits the total number of retries. Failed attempts are returned as-is when the budget is exhausted.
+	Budget *RetryBudget `json:"-"`
+}
+
+// DefaultRetryPolicy returns a retry policy with 3 attempts and exponential backoff starting at 100ms.
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     5 * time.Second,
+		Jitter:         0.2,
+	}
+}
+
+func (policy *RetryPolicy) allowsMethod(method string) bool {
+	switch method {
+	case "GET", "HEAD", "OPTIONS", "TRACE", "PUT", "DELETE":
+		return true
+	default:
+		return policy.RetryNonIdempotent
+	}
+}
+
+func (policy *RetryPolicy) backoff(attempt int) time.Duration {
+	backoff := policy.InitialBackoff
+	for i := 1; i < attempt && (policy.MaxBackoff <= 0 || backoff < policy.MaxBackoff); i++ {
+		backoff *= 2
+	}
+	if policy.MaxBackoff > 0 && backoff > policy.MaxBackoff {
+		backoff = policy.MaxBackoff
+	}
+	if policy.Jitter > 0 {
+		backoff += time.Duration((rand.Float64()*2 - 1) * policy.Jitter * float64(backoff))
+	}
+	return backoff
+}
+
+func isRetryableStatus(code int) bool {
+	return code == 429 || (code >= 500 && code != 501)
+}
+
+// retryAfter parses the Retry-After header given in seconds or as HTTP date.
+func retryAfter(response *Response) (time.Duration, bool) {
+	value := response.Header.Get("Retry-After")
+	if len(value) == 0 {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return time.Until(t), true
+	}
+	return 0, false
+}
+
+// Interceptor returns an interceptor that retries failed requests according to the policy.
+func (policy *RetryPolicy) Interceptor() Interceptor {
+	return func(next Responder) Responder {
+		return func(req *Request) (*Response, errors.Error) {
+			return policy.send(next, req)
+		}
+	}
+}
+
+func (policy *RetryPolicy) send(next Responder, req *Request) (*Response, errors.Error) {
+	if policy.MaxAttempts <= 1 || !policy.allowsMethod(req.Method) || (req.Body != nil && req.GetBody == nil) {
+		return next(req)
+	}
+
+	if policy.Budget != nil {
+		policy.Budget.RecordRequest()
+	}
+	if (req.Method == "POST" || req.Method == "PATCH") && !policy.DisableIdempotencyKey {
+		header := policy.IdempotencyKeyHeader
+		if len(header) == 0 {
+			header = "Idempotency-Key"
+		}
+		if len(req.Header.Get(header)) == 0 {
+			key, err := newUUID()
+			if err != nil {
+				return nil, ErrInvalidRequest.Make().Cause(err)
+			}
+			req.Header.Set(header, key)
+		}
+	}
+
+	for attempt := 1; ; attempt++ {
+		response, err := next(req)
+		if attempt >= policy.MaxAttempts || errors.InstanceOf(err, ErrCircuitOpen) || IsCanceled(err) || (err == nil && !isRetryableStatus(response.StatusCode)) {
+			return response, err
+		}
+		if policy.Budget != nil && !policy.Budget.TryRetry() {
+			return response, err
+		}
+
+		wait := policy.backoff(attempt)
+		if err == nil {
+			if d, ok := retryAfter(response); ok {
+				if policy.MaxBackoff > 0 && d > policy.MaxBackoff {
+					return response, nil
+				}
+				wait = d
+			}
+			io.Copy(ioutil.Discard, response.Body)
+			response.Body.Close()
+		}
+
+		select {
+		case <-req.Context().Done():
+			return nil, ErrRequestFailed.Make().Cause(req.Context().Err())
+		case <-time.After(wait):
+		}
+
+		if req.GetBody != nil {
+			body, bodyErr := req.GetBody()
+			if bodyErr != nil {
+				return nil, ErrInvalidRequest.Make().Cause(bodyErr)
+			}
+			req.Body = body
+		}
+	}
+}
+
+// newUUID returns a random (version 4) UUID.
+func newUUID() (string, error) {
+	var b [16]byte
+	if _, err := crand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
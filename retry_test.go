@@ -0,0 +1,133 @@
+package http
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sbreitf1/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetry(t *testing.T) {
+	newClient := func(responses ...int) (*Client, *int) {
+		client := NewClient()
+		client.Retry = &RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: 10 * time.Millisecond}
+		calls := 0
+		client.RequestResponder = func(req *Request) (*Response, errors.Error) {
+			code := responses[calls]
+			calls++
+			if req.Body != nil {
+				data, _ := ioutil.ReadAll(req.Body)
+				assert.Equal(t, "payload", string(data))
+			}
+			if code == 0 {
+				return nil, errors.GenericError.Msg("connection reset").Make()
+			}
+			return &Response{StatusCode: code, Header: make(Header), Body: ioutil.NopCloser(strings.NewReader(""))}, nil
+		}
+		return client, &calls
+	}
+
+	t.Run("Success after failures", func(t *testing.T) {
+		client, calls := newClient(0, 503, 200)
+		response, err := client.Do(MethodGet, "http://localhost/", nil)
+		errors.AssertNil(t, err)
+		assert.Equal(t, 200, response.StatusCode)
+		assert.Equal(t, 3, *calls)
+	})
+
+	t.Run("Attempts exhausted", func(t *testing.T) {
+		client, calls := newClient(500, 502, 429, 200)
+		response, err := client.Do(MethodGet, "http://localhost/", nil)
+		errors.AssertNil(t, err)
+		assert.Equal(t, 429, response.StatusCode)
+		assert.Equal(t, 3, *calls)
+	})
+
+	t.Run("Non-retryable status", func(t *testing.T) {
+		client, calls := newClient(404, 200)
+		response, err := client.Do(MethodGet, "http://localhost/", nil)
+		errors.AssertNil(t, err)
+		assert.Equal(t, 404, response.StatusCode)
+		assert.Equal(t, 1, *calls)
+	})
+
+	t.Run("POST not retried by default", func(t *testing.T) {
+		client, calls := newClient(503, 200)
+		response, err := client.Post("http://localhost/", "text/plain", strings.NewReader("payload"), nil)
+		errors.AssertNil(t, err)
+		assert.Equal(t, 503, response.StatusCode)
+		assert.Equal(t, 1, *calls)
+	})
+
+	t.Run("POST retried with body", func(t *testing.T) {
+		client, calls := newClient(503, 200)
+		client.Retry.RetryNonIdempotent = true
+		response, err := client.Post("http://localhost/", "text/plain", strings.NewReader("payload"), nil)
+		errors.AssertNil(t, err)
+		assert.Equal(t, 200, response.StatusCode)
+		assert.Equal(t, 2, *calls)
+	})
+}
+
+func TestIdempotencyKey(t *testing.T) {
+	client := NewClient()
+	client.Retry = &RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, RetryNonIdempotent: true}
+	keys := make([]string, 0)
+	client.RequestResponder = func(req *Request) (*Response, errors.Error) {
+		keys = append(keys, req.Header.Get("Idempotency-Key"))
+		return &Response{StatusCode: 503, Body: ioutil.NopCloser(strings.NewReader(""))}, nil
+	}
+
+	_, err := client.Post("http://localhost/", "text/plain", strings.NewReader("payload"), nil)
+	errors.AssertNil(t, err)
+	if assert.Len(t, keys, 3) {
+		assert.Len(t, keys[0], 36)
+		assert.Equal(t, keys[0], keys[1])
+		assert.Equal(t, keys[0], keys[2])
+	}
+
+	_, err = client.Post("http://localhost/", "text/plain", strings.NewReader("payload"), nil)
+	errors.AssertNil(t, err)
+	assert.NotEqual(t, keys[0], keys[3], "Every request should receive a new key")
+
+	// existing keys and custom header names are respected
+	client.Retry.IdempotencyKeyHeader = "X-Request-Key"
+	client.RequestResponder = func(req *Request) (*Response, errors.Error) {
+		assert.Equal(t, "my-key", req.Header.Get("X-Request-Key"))
+		assert.Equal(t, "", req.Header.Get("Idempotency-Key"))
+		return &Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader(""))}, nil
+	}
+	_, err = client.Post("http://localhost/", "text/plain", strings.NewReader("payload"), WithHeader("X-Request-Key", "my-key"))
+	errors.AssertNil(t, err)
+}
+
+func TestRetryAfter(t *testing.T) {
+	d, ok := retryAfter(&Response{Header: Header{"Retry-After": {"2"}}})
+	assert.True(t, ok)
+	assert.Equal(t, 2*time.Second, d)
+
+	d, ok = retryAfter(&Response{Header: Header{"Retry-After": {time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)}}})
+	assert.True(t, ok)
+	assert.True(t, d > 50*time.Second)
+
+	_, ok = retryAfter(&Response{Header: Header{}})
+	assert.False(t, ok)
+}
+
+func TestBackoff(t *testing.T) {
+	policy := &RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+	assert.Equal(t, 100*time.Millisecond, policy.backoff(1))
+	assert.Equal(t, 200*time.Millisecond, policy.backoff(2))
+	assert.Equal(t, 800*time.Millisecond, policy.backoff(4))
+	assert.Equal(t, time.Second, policy.backoff(10))
+
+	policy.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		b := policy.backoff(1)
+		assert.True(t, b >= 50*time.Millisecond && b <= 150*time.Millisecond)
+	}
+}
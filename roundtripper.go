@@ -0,0 +1,48 @@
+package http
+
+import (
+	"net/http"
+)
+
+// NewClientWithTransport returns a new HTTP client that sends requests through the given transport instead of its own connection pool, see Client.Transport.
+func NewClientWithTransport(transport http.RoundTripper) *Client {
+	client := NewClient()
+	client.Transport = transport
+	return client
+}
+
+// clientRoundTripper sends requests of a net/http client through the request pipeline of a Client.
+type clientRoundTripper struct {
+	client *Client
+}
+
+// AsRoundTripper returns a http.RoundTripper that sends requests like this client, including default headers, authentication, signing, retries, interceptors and metrics. Redirects are followed according to the client configuration.
+func (client *Client) AsRoundTripper() http.RoundTripper {
+	return &clientRoundTripper{client: client}
+}
+
+// AsHTTPClient returns a *http.Client that sends all requests through AsRoundTripper, for libraries that only accept a net/http client. Redirects and cookies are handled by this client, so the returned client does not follow redirects itself.
+func (client *Client) AsHTTPClient() *http.Client {
+	return &http.Client{
+		Transport: client.AsRoundTripper(),
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// RoundTrip sends req through the client. The request is cloned, because round trippers must not modify it.
+func (rt *clientRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	r := req.Clone(req.Context())
+	if err := rt.client.prepareRequest(r, nil); err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+	response, err := rt.client.responder()(r)
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
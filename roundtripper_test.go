@@ -0,0 +1,80 @@
+package http
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/sbreitf1/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type countingRoundTripper struct {
+	count int32
+}
+
+func (rt *countingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	atomic.AddInt32(&rt.count, 1)
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestNewClientWithTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	transport := &countingRoundTripper{}
+	client := NewClientWithTransport(transport)
+	_, err := client.Get(server.URL, ExpectSuccess())
+	errors.AssertNil(t, err)
+	_, err = client.Get(server.URL, WithServerName("example.com"))
+	errors.AssertNil(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&transport.count))
+}
+
+func TestAsHTTPClient(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "/target", http.StatusFound)
+			return
+		}
+		w.Write([]byte(r.URL.Path + " " + r.Header.Get("Authorization") + " " + r.Header.Get("X-Client")))
+	}))
+	defer server.Close()
+
+	client := NewClient()
+	client.DefaultHeader.Set("X-Client", "sbreitf1")
+	client.TokenSource = TokenSourceFunc(func() (*Token, errors.Error) { return &Token{AccessToken: "secret"}, nil })
+	client.Retry = DefaultRetryPolicy()
+	client.Retry.InitialBackoff = 0
+
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/redirect", nil)
+	assert.NoError(t, err)
+	response, err := client.AsHTTPClient().Do(req)
+	assert.NoError(t, err)
+	defer response.Body.Close()
+	body, err := ioutil.ReadAll(response.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, "/target Bearer secret sbreitf1", string(body))
+	assert.Empty(t, req.Header.Get("Authorization"))
+	assert.Equal(t, int32(3), atomic.LoadInt32(&requests))
+}
+
+func TestAsRoundTripperError(t *testing.T) {
+	client := NewClient()
+	client.RequestResponder = func(req *Request) (*Response, errors.Error) {
+		return nil, requestFailed(FailureConnectionRefused, nil)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "http://localhost/", nil)
+	_, err := client.AsRoundTripper().RoundTrip(req)
+	assert.Equal(t, FailureConnectionRefused, TransportFailureOf(err))
+}
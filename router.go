@@ -0,0 +1,179 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"reflect"
+	"runtime"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sbreitf1/errors"
+)
+
+var (
+	// ErrRouteConflict occurs when a service registers a route that conflicts with a route of another service or the server.
+	ErrRouteConflict = errors.New("Route conflict")
+)
+
+// mountedRoute describes a route of the server engine that is served by the router of a service.
+type mountedRoute struct {
+	service string
+	handler string
+}
+
+type serviceRouterKey struct{}
+
+type fallbackProbeKey struct{}
+
+// noFallback is the NoRoute and NoMethod handler of service routers until a service sets its own fallback handlers. Like an empty handler chain, it lets gin answer with the default status and body.
+func noFallback(*gin.Context) {}
+
+var noFallbackName = runtime.FuncForPC(reflect.ValueOf(noFallback).Pointer()).Name()
+
+// newServiceRouter returns the isolated engine a service registers its routes on. Middlewares and fallback handlers of a service only apply to its own routes.
+func newServiceRouter() *gin.Engine {
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		if probe, ok := c.Request.Context().Value(fallbackProbeKey{}).(*string); ok {
+			// report the last handler of the chain without executing it, see hasFallbackHandlers
+			*probe = c.HandlerName()
+			c.Abort()
+			return
+		}
+		// share keys and errors with the context of the server engine, so global middlewares and handlers can exchange values
+		if outer, ok := c.Request.Context().Value(serviceRouterKey{}).(*gin.Context); ok {
+			if outer.Keys == nil {
+				outer.Keys = make(map[string]interface{})
+			}
+			c.Keys = outer.Keys
+			// file content is passed on unless a middleware of the server engine replaced the writer
+			if to, ok := outer.Writer.(io.ReaderFrom); ok {
+				c.Writer = &sendfileWriter{ResponseWriter: c.Writer, to: to}
+			}
+			c.Next()
+			outer.Errors = append(outer.Errors, c.Errors...)
+		}
+	})
+	router.NoRoute(noFallback)
+	router.NoMethod(noFallback)
+	return router
+}
+
+// registerServiceRoutes lets the service register its routes on a new router and mounts them on the server engine if they do not conflict with existing routes.
+func (server *Server) registerServiceRoutes(name string, s Service) (router *gin.Engine, err errors.Error) {
+	router = newServiceRouter()
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				err = ErrRouteConflict.Msg("Service %q registers conflicting routes: %v", name, r).Make()
+			}
+		}()
+		s.RegisterRoutes(router)
+	}()
+	if err != nil {
+		return nil, err
+	}
+
+	routes := router.Routes()
+	if err := server.checkRouteConflicts(name, routes); err != nil {
+		return nil, err
+	}
+	noRoute, noMethod := hasFallbackHandlers(router)
+	if (noRoute || noMethod) && len(server.fallbackService) > 0 {
+		return nil, ErrRouteConflict.Msg("Service %q and service %q both register fallback handlers", name, server.fallbackService).Make()
+	}
+	return router, nil
+}
+
+// mountServiceRouter forwards all routes and fallback handlers of the service router from the server engine.
+func (server *Server) mountServiceRouter(name string, router *gin.Engine) {
+	handler := func(c *gin.Context) {
+		router.ServeHTTP(c.Writer, c.Request.WithContext(context.WithValue(c.Request.Context(), serviceRouterKey{}, c)))
+	}
+	for _, r := range router.Routes() {
+		server.engine.Handle(r.Method, r.Path, handler)
+		server.mountedRoutes[r.Method+" "+r.Path] = mountedRoute{name, r.Handler}
+	}
+
+	noRoute, noMethod := hasFallbackHandlers(router)
+	if noRoute {
+		server.engine.NoRoute(handler)
+	}
+	if noMethod {
+		server.engine.NoMethod(handler)
+	}
+	if noRoute || noMethod {
+		server.fallbackService = name
+	}
+}
+
+// checkRouteConflicts returns a descriptive error if one of routes cannot be registered in addition to the routes of the server engine.
+func (server *Server) checkRouteConflicts(name string, routes gin.RoutesInfo) errors.Error {
+	existing := server.engine.Routes()
+	for _, r := range routes {
+		if reason, conflict := routeConflict(existing, r); conflict {
+			for _, e := range existing {
+				if _, ok := routeConflict(gin.RoutesInfo{e}, r); ok {
+					return ErrRouteConflict.Msg("Route %s %s of service %q conflicts with route %s %s of %s: %s", r.Method, r.Path, name, e.Method, e.Path, server.routeOwner(e), reason).Make()
+				}
+			}
+			return ErrRouteConflict.Msg("Route %s %s of service %q conflicts with existing routes: %s", r.Method, r.Path, name, reason).Make()
+		}
+		existing = append(existing, r)
+	}
+	return nil
+}
+
+func (server *Server) routeOwner(r gin.RouteInfo) string {
+	if mounted, ok := server.mountedRoutes[r.Method+" "+r.Path]; ok {
+		return fmt.Sprintf("service %q", mounted.service)
+	}
+	return "the server"
+}
+
+// routeConflict reports whether gin refuses to register route in addition to the existing routes and returns the reason.
+func routeConflict(existing gin.RoutesInfo, route gin.RouteInfo) (reason string, conflict bool) {
+	engine := gin.New()
+	noop := func(*gin.Context) {}
+	for _, e := range existing {
+		if e.Method == route.Method {
+			engine.Handle(e.Method, e.Path, noop)
+		}
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			reason, conflict = fmt.Sprint(r), true
+		}
+	}()
+	engine.Handle(route.Method, route.Path, noop)
+	return "", false
+}
+
+// hasFallbackHandlers reports whether the service replaced the NoRoute or NoMethod handlers of its router. Gin does not expose them, so the router is asked which handler would answer requests without route.
+func hasFallbackHandlers(router *gin.Engine) (noRoute, noMethod bool) {
+	handleMethodNotAllowed := router.HandleMethodNotAllowed
+	defer func() {
+		router.HandleMethodNotAllowed = handleMethodNotAllowed
+	}()
+
+	// no routes exist for an unknown method, so the NoRoute handlers answer
+	router.HandleMethodNotAllowed = false
+	noRoute = fallbackHandlerName(router, "/") != noFallbackName
+	// an existing path requested with an unknown method is answered by the NoMethod handlers
+	if routes := router.Routes(); len(routes) > 0 {
+		router.HandleMethodNotAllowed = true
+		noMethod = fallbackHandlerName(router, routes[0].Path) != noFallbackName
+	}
+	return noRoute, noMethod
+}
+
+// fallbackHandlerName returns the name of the handler the router selects for path requested with an unknown method.
+func fallbackHandlerName(router *gin.Engine, path string) string {
+	var name string
+	req := httptest.NewRequest("FALLBACK", "/", nil)
+	req.URL.Path = path
+	router.ServeHTTP(httptest.NewRecorder(), req.WithContext(context.WithValue(req.Context(), fallbackProbeKey{}, &name)))
+	return name
+}
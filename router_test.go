@@ -0,0 +1,129 @@
+package http
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sbreitf1/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type routesService struct {
+	register func(*gin.Engine)
+}
+
+func (svc *routesService) RegisterRoutes(e *gin.Engine) { svc.register(e) }
+func (svc *routesService) BeginServing()                {}
+func (svc *routesService) StopServing()                 {}
+func (svc *routesService) Healthy() errors.Error        { return nil }
+func (svc *routesService) Ready() errors.Error          { return nil }
+
+func TestServiceRouterIsolation(t *testing.T) {
+	server, err := NewServer(&ServerConfig{GinMode: gin.TestMode})
+	errors.AssertNil(t, err)
+	server.Engine().Use(func(c *gin.Context) {
+		c.Set("global", "value")
+		c.Next()
+		c.Header("X-Handler-Key", c.GetString("handler"))
+	})
+
+	errors.AssertNil(t, server.RegisterService("items", &routesService{func(e *gin.Engine) {
+		e.Use(func(c *gin.Context) { c.Header("X-Service", "items") })
+		e.GET("/items/:id", func(c *gin.Context) {
+			c.Set("handler", "items")
+			c.String(200, c.Param("id")+" "+c.GetString("global"))
+		})
+	}}))
+	errors.AssertNil(t, server.RegisterService("users", &routesService{func(e *gin.Engine) {
+		e.GET("/users", func(c *gin.Context) { c.Status(204) })
+	}}))
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		server.Handler().ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w
+	}
+
+	w := serve("/items/42")
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "42 value", w.Body.String())
+	assert.Equal(t, "items", w.Header().Get("X-Service"))
+	assert.Equal(t, "items", w.Header().Get("X-Handler-Key"))
+
+	w = serve("/users")
+	assert.Equal(t, 204, w.Code)
+	assert.Empty(t, w.Header().Get("X-Service"))
+	assert.Equal(t, 404, serve("/unknown").Code)
+	assert.Equal(t, 1, server.StartupSummary().Services["items"])
+}
+
+func TestServiceRouteConflicts(t *testing.T) {
+	server, err := NewServer(&ServerConfig{GinMode: gin.TestMode})
+	errors.AssertNil(t, err)
+	errors.AssertNil(t, server.RegisterService("items", &routesService{func(e *gin.Engine) {
+		e.GET("/items/:id", func(c *gin.Context) {})
+	}}))
+
+	err = server.RegisterService("orders", &routesService{func(e *gin.Engine) {
+		e.GET("/orders", func(c *gin.Context) {})
+		e.GET("/items/:name", func(c *gin.Context) {})
+	}})
+	errors.Assert(t, ErrRouteConflict, err)
+	assert.Contains(t, err.Error(), `Route GET /items/:name of service "orders" conflicts with route GET /items/:id of service "items"`)
+	// no routes of the rejected service are mounted
+	w := httptest.NewRecorder()
+	server.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/orders", nil))
+	assert.Equal(t, 404, w.Code)
+
+	err = server.RegisterService("probes", &routesService{func(e *gin.Engine) {
+		e.GET("/healthz", func(c *gin.Context) {})
+	}})
+	errors.Assert(t, ErrRouteConflict, err)
+	assert.Contains(t, err.Error(), "of the server")
+
+	err = server.RegisterService("duplicate", &routesService{func(e *gin.Engine) {
+		e.GET("/duplicate", func(c *gin.Context) {})
+		e.GET("/duplicate", func(c *gin.Context) {})
+	}})
+	errors.Assert(t, ErrRouteConflict, err)
+
+	errors.AssertNil(t, server.RegisterService("other-method", &routesService{func(e *gin.Engine) {
+		e.POST("/items/:name", func(c *gin.Context) {})
+	}}))
+}
+
+func TestServiceFallbackHandlers(t *testing.T) {
+	server, err := NewServer(&ServerConfig{GinMode: gin.TestMode})
+	errors.AssertNil(t, err)
+	errors.AssertNil(t, server.RegisterService("mock", &routesService{func(e *gin.Engine) {
+		e.NoRoute(func(c *gin.Context) { c.String(200, "fallback") })
+	}}))
+
+	w := httptest.NewRecorder()
+	server.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/anything", nil))
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "fallback", w.Body.String())
+
+	err = server.RegisterService("mock2", &routesService{func(e *gin.Engine) {
+		e.NoRoute(func(c *gin.Context) {})
+	}})
+	errors.Assert(t, ErrRouteConflict, err)
+
+	// middlewares of services without fallback handlers are not mistaken for them
+	errors.AssertNil(t, server.RegisterService("auth", &routesService{func(e *gin.Engine) {
+		e.Use(func(c *gin.Context) { c.AbortWithStatus(401) })
+		e.GET("/secured", func(c *gin.Context) {})
+	}}))
+
+	noRoute, noMethod := hasFallbackHandlers(newServiceRouter())
+	assert.False(t, noRoute)
+	assert.False(t, noMethod)
+	router := newServiceRouter()
+	router.GET("/items", func(c *gin.Context) {})
+	router.NoMethod(func(c *gin.Context) { c.Status(405) })
+	noRoute, noMethod = hasFallbackHandlers(router)
+	assert.False(t, noRoute)
+	assert.True(t, noMethod)
+	assert.False(t, router.HandleMethodNotAllowed)
+}
@@ -2,11 +2,14 @@ package http
 
 import (
 	"context"
-	"fmt"
+	"crypto/tls"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -20,6 +23,12 @@ var (
 	ErrGraceShutdown = errors.New("Server gracefully shut down")
 	// ErrServeFailed occurs when an error occurs during http serving.
 	ErrServeFailed = errors.New("Serving failed")
+	// ErrInvalidConfig occurs when the server configuration contains malformed values.
+	ErrInvalidConfig = errors.New("Invalid server configuration")
+	// ErrReloadFailed is returned when the server configuration could not be reloaded.
+	ErrReloadFailed = errors.New("Configuration reload failed")
+	// ErrDrainTimeout is returned when in-flight requests did not complete while draining the server.
+	ErrDrainTimeout = errors.New("Drain timeout exceeded")
 )
 
 // Service defines functionality for web services that can be served.
@@ -35,6 +44,47 @@ type Service interface {
 type ServerConfig struct {
 	ListenAddress string `json:"listenAddress"`
 	SubSystemName string `json:"subsystemName,omitempty"`
+	// LogLevel sets the logrus log level (e.g. "debug", "info", "warn"). The current level is kept when empty.
+	LogLevel string `json:"logLevel,omitempty"`
+	// GinMode sets the gin mode ("debug", "release" or "test"). The current mode is kept when empty.
+	GinMode string `json:"ginMode,omitempty"`
+	// TLSCertFile and TLSKeyFile enable HTTPS when both are set. The files are re-read on reload.
+	TLSCertFile string `json:"tlsCertFile,omitempty"`
+	TLSKeyFile  string `json:"tlsKeyFile,omitempty"`
+	// ReadHeaderTimeout bounds the time to read the request headers to protect against slow clients. Defaults to 10 seconds, negative values disable the limit.
+	ReadHeaderTimeout Duration `json:"readHeaderTimeout,omitempty"`
+	// ReadTimeout bounds the time to read a complete request including its body. Unlimited by default to support large uploads.
+	ReadTimeout Duration `json:"readTimeout,omitempty"`
+	// WriteTimeout bounds the time to write a response. Unlimited by default to support streaming responses.
+	WriteTimeout Duration `json:"writeTimeout,omitempty"`
+	// IdleTimeout closes keep-alive connections without further requests. Defaults to 2 minutes, negative values disable the limit.
+	IdleTimeout Duration `json:"idleTimeout,omitempty"`
+	// MaxHeaderBytes limits the size of request headers. Defaults to 1 MiB.
+	MaxHeaderBytes int `json:"maxHeaderBytes,omitempty"`
+	// MaxConnections limits the number of concurrently open connections. Further connections wait in the accept backlog. Unlimited when 0.
+	MaxConnections int `json:"maxConnections,omitempty"`
+	// MaxConnectionsPerIP limits the number of concurrently open connections of a single client IP. Further connections are closed immediately. Unlimited when 0.
+	MaxConnectionsPerIP int `json:"maxConnectionsPerIP,omitempty"`
+	// AdminListenAddress enables a separate listener for administrative endpoints that must not be exposed publicly.
+	AdminListenAddress string `json:"adminListenAddress,omitempty"`
+	// EnableDebugEndpoints serves /debug/routes and /debug/config on the admin listener.
+	EnableDebugEndpoints bool `json:"enableDebugEndpoints,omitempty"`
+	// RequestTimeout denotes the default deadline of request contexts. Routes can declare different timeouts, see RouteTimeoutProvider. Unlimited when 0.
+	RequestTimeout Duration `json:"requestTimeout,omitempty"`
+	// ShutdownDelay keeps serving requests after readiness reports the shutdown, so load balancers and kube-proxy stop routing new connections before the listener is closed. No delay when 0.
+	ShutdownDelay Duration `json:"shutdownDelay,omitempty"`
+	// ResponseEnvelope wraps the responses of typed handler adapters and the error mapper in an Envelope for all routes.
+	ResponseEnvelope bool `json:"responseEnvelope,omitempty"`
+	// AccessLogBufferSize enables asynchronous access logging, so slow log output does not add latency to requests. Up to the given number of entries are buffered, further entries are dropped and counted in the log_dropped_entries_total metric. The buffer is shared by the access logs of all servers and kept until changed by SetAccessLogBufferSize or CloseAccessLog. The current setting is kept when 0.
+	AccessLogBufferSize int `json:"accessLogBufferSize,omitempty"`
+	// LogFile directs the log output to a file with rotation instead of stderr.
+	LogFile *LogFileConfig `json:"logFile,omitempty"`
+	// AccessLogFile directs the access log entries to a separate file with rotation. Use SetAccessLogOutput for other writers.
+	AccessLogFile *LogFileConfig `json:"accessLogFile,omitempty"`
+	// Kubernetes adds pod name, namespace and node to all log entries and to the labels of all metrics served on /metrics. Empty fields are read from the environment, see KubernetesMetadataFromEnv. Disabled when nil.
+	Kubernetes *KubernetesMetadata `json:"kubernetes,omitempty"`
+	// BuildInfo is exported as build_info metric. Empty fields are filled from the information recorded in the binary.
+	BuildInfo BuildInfo `json:"-"`
 }
 
 // Server contains http web server functionality with kubernetes probes and prometheus metrics. It can serve an arbitrary collection of services.
@@ -43,17 +93,67 @@ type Server struct {
 
 	engine      *gin.Engine
 	asyncServer *http.Server
+	adminEngine *gin.Engine
+	adminServer *http.Server
 
-	services map[string]Service
+	services        map[string]Service
+	serviceRoutes   map[string]int
+	mountedRoutes   map[string]mountedRoute
+	fallbackService string
+	grpcHandler     http.Handler
+	errorMapper     *ErrorMapper
+
+	timeoutMutex  sync.RWMutex
+	routeTimeouts map[string]time.Duration
+
+	shuttingDown    int32
+	streamsStopped  chan struct{}
+	stopStreamsOnce sync.Once
+
+	startupLogger    func(*StartupSummary)
+	startupLoggerSet bool
+
+	configLoader    func() (*ServerConfig, errors.Error)
+	reloadCallbacks []func(*ServerConfig) errors.Error
+
+	certMutex   sync.RWMutex
+	certificate *tls.Certificate
 }
 
 // NewServer returns a new instance of Server to handle web requests.
 func NewServer(config *ServerConfig) (*Server, errors.Error) {
+	if err := applyGinMode(config.GinMode); err != nil {
+		return nil, err
+	}
+	redirectGinOutput()
+
 	engine := gin.New()
-	server := &Server{*config, engine, nil, make(map[string]Service, 0)}
+	server := &Server{config: *config, engine: engine, services: make(map[string]Service, 0), serviceRoutes: make(map[string]int), mountedRoutes: make(map[string]mountedRoute), routeTimeouts: make(map[string]time.Duration), errorMapper: NewErrorMapper(), streamsStopped: make(chan struct{})}
+
+	if err := applyLogLevel(config.LogLevel); err != nil {
+		return nil, err
+	}
+	if err := server.loadCertificate(config); err != nil {
+		return nil, err
+	}
+
+	if err := applyLogFiles(config); err != nil {
+		return nil, err
+	}
+	if config.AccessLogBufferSize > 0 {
+		SetAccessLogBufferSize(config.AccessLogBufferSize)
+	}
+	if config.Kubernetes != nil {
+		metadata := config.Kubernetes.withDefaults()
+		server.config.Kubernetes = &metadata
+		setKubernetesLogFields(metadata)
+	}
 
 	// global middlewares
-	engine.Use(ginLogger)
+	engine.Use(sendfileMiddleware, requestIDMiddleware, ginLogger, server.timeoutMiddleware, server.errorMapper.Middleware())
+	if config.ResponseEnvelope {
+		engine.Use(ResponseEnvelopeMiddleware)
+	}
 
 	// metrics
 	p := ginprometheus.NewPrometheus(config.SubSystemName)
@@ -67,27 +167,71 @@ func NewServer(config *ServerConfig) (*Server, errors.Error) {
 		}
 		return url
 	}
-	p.Use(engine)
+	if server.config.Kubernetes != nil {
+		engine.Use(p.HandlerFunc())
+		engine.GET(p.MetricsPath, kubernetesMetricsHandler(*server.config.Kubernetes))
+	} else {
+		p.Use(engine)
+	}
+	setBuildInfo(config.BuildInfo)
 
 	// server specific routes
 	engine.GET("/healthz", server.handleGetHealthz)
 	engine.GET("/readiness", server.handleGetReadiness)
 
+	if len(config.AdminListenAddress) > 0 {
+		server.adminEngine = server.newAdminEngine()
+	}
+
 	return server, nil
 }
 
-// RegisterService registers a new named service in the server. The name is used to identify the server in probes.
+// Engine returns the underlying gin engine to register additional middlewares or routes.
+func (server *Server) Engine() *gin.Engine {
+	return server.engine
+}
+
+// Handler returns the complete route tree including probes, metrics and all registered services. Use it to embed the server into another http.Server or an httptest.Server.
+func (server *Server) Handler() http.Handler {
+	return http.HandlerFunc(server.serveMux)
+}
+
+// ErrorMapper returns the mapper that answers errors attached to requests of all routes, e.g. by handlers adapted with ErrorHandler.
+func (server *Server) ErrorMapper() *ErrorMapper {
+	return server.errorMapper
+}
+
+// RegisterService registers a new named service in the server. The name is used to identify the server in probes. Each service registers its routes on a separate engine, so its middlewares only apply to its own routes. ErrRouteConflict is returned if a route conflicts with the routes of the server or another service.
 func (server *Server) RegisterService(name string, s Service) errors.Error {
-	s.RegisterRoutes(server.engine)
+	var timeouts map[[2]string]time.Duration
+	if provider, ok := s.(RouteTimeoutProvider); ok {
+		var err errors.Error
+		if timeouts, err = serviceRouteTimeouts(name, provider); err != nil {
+			return err
+		}
+	}
+	router, err := server.registerServiceRoutes(name, s)
+	if err != nil {
+		return err
+	}
+	// timeouts are only applied once the routes have been accepted
+	for route, timeout := range timeouts {
+		server.SetRouteTimeout(route[0], route[1], timeout)
+	}
+	server.mountServiceRouter(name, router)
 	server.services[name] = s
+	server.serviceRoutes[name] = len(router.Routes())
 	return nil
 }
 
-// Run executes the server and gracefully shuts it down when a system signal is received.
+// Run executes the server and gracefully shuts it down when a system signal is received. SIGHUP triggers a configuration reload instead.
 func (server *Server) Run() errors.Error {
 	// graceful shutdown: https://github.com/gin-gonic/examples/blob/master/graceful-shutdown/graceful-shutdown/server.go
-	quit := make(chan os.Signal)
+	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, os.Interrupt, os.Kill)
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
 
 	var returnErr errors.Error
 	callback := func(err errors.Error) {
@@ -102,21 +246,50 @@ func (server *Server) Run() errors.Error {
 		return err
 	}
 
-	sig := <-quit
-	log.Infof("Signal %v received -> Shutdown server", sig)
-	if err := server.Shutdown(); err != nil {
-		return err
+	for {
+		select {
+		case <-hup:
+			log.Info("Signal SIGHUP received -> Reload configuration")
+			if err := server.Reload(); err != nil {
+				log.Errorf("Reload error: %s", err)
+			}
+
+		case sig := <-quit:
+			log.Infof("Signal %v received -> Shutdown server", sig)
+			if err := server.Shutdown(); err != nil {
+				return err
+			}
+			return returnErr
+		}
 	}
-	return returnErr
 }
 
 // RunAsync begins asynchronuous handling of incoming http requests. Use Shutdown() to gracefully shut down the sever.
 func (server *Server) RunAsync(callback func(errors.Error)) errors.Error {
-	server.asyncServer = &http.Server{Addr: server.config.ListenAddress, Handler: server.engine}
+	server.asyncServer = &http.Server{
+		Addr:              server.config.ListenAddress,
+		Handler:           server.Handler(),
+		ReadHeaderTimeout: timeoutOrDefault(server.config.ReadHeaderTimeout, DefaultReadHeaderTimeout),
+		ReadTimeout:       timeoutOrDefault(server.config.ReadTimeout, 0),
+		WriteTimeout:      timeoutOrDefault(server.config.WriteTimeout, 0),
+		IdleTimeout:       timeoutOrDefault(server.config.IdleTimeout, DefaultIdleTimeout),
+		MaxHeaderBytes:    server.config.MaxHeaderBytes,
+	}
+	if server.asyncServer.MaxHeaderBytes <= 0 {
+		server.asyncServer.MaxHeaderBytes = DefaultMaxHeaderBytes
+	}
+	if server.grpcHandler != nil {
+		server.asyncServer.Protocols = new(http.Protocols)
+		server.asyncServer.Protocols.SetHTTP1(true)
+		server.asyncServer.Protocols.SetHTTP2(true)
+		server.asyncServer.Protocols.SetUnencryptedHTTP2(true)
+	}
+	server.logStartupSummary()
+	server.runAdmin()
 	var returnErr errors.Error
 	go func() {
 		server.notifyBeginServing()
-		err := server.asyncServer.ListenAndServe()
+		err := server.listenAndServe()
 		if err != nil {
 			if err == http.ErrServerClosed {
 				returnErr = ErrGraceShutdown.Make()
@@ -133,6 +306,129 @@ func (server *Server) RunAsync(callback func(errors.Error)) errors.Error {
 	return returnErr
 }
 
+// listenAndServe opens the listener with the configured connection limits and serves requests until the server is shut down.
+func (server *Server) listenAndServe() error {
+	addr := server.asyncServer.Addr
+	if len(addr) == 0 {
+		addr = ":http"
+		if server.isTLS() {
+			addr = ":https"
+		}
+	}
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	l = newLimitListener(l, server.config.MaxConnections, server.config.MaxConnectionsPerIP)
+
+	if server.isTLS() {
+		server.asyncServer.TLSConfig = &tls.Config{GetCertificate: server.getCertificate}
+		return server.asyncServer.ServeTLS(l, "", "")
+	}
+	return server.asyncServer.Serve(l)
+}
+
+// SetConfigLoader defines the function used to re-read the server configuration on reload. Without a loader, reload only re-applies the current configuration (e.g. re-reads TLS certificates).
+func (server *Server) SetConfigLoader(loader func() (*ServerConfig, errors.Error)) {
+	server.configLoader = loader
+}
+
+// OnReload registers a callback that is executed with the new configuration after the server applied a reload. Use it to re-read application specific settings.
+func (server *Server) OnReload(callback func(*ServerConfig) errors.Error) {
+	server.reloadCallbacks = append(server.reloadCallbacks, callback)
+}
+
+// Reload re-reads the configuration using the config loader and applies log level and TLS certificates. All registered reload callbacks are executed afterwards. The listen address cannot be changed without restarting the server.
+func (server *Server) Reload() errors.Error {
+	config := server.config
+	if server.configLoader != nil {
+		newConfig, err := server.configLoader()
+		if err != nil {
+			return ErrReloadFailed.Make().Cause(err)
+		}
+		config = *newConfig
+	}
+
+	if err := applyLogLevel(config.LogLevel); err != nil {
+		return ErrReloadFailed.Make().Cause(err)
+	}
+	if err := server.loadCertificate(&config); err != nil {
+		return ErrReloadFailed.Make().Cause(err)
+	}
+	server.config.LogLevel = config.LogLevel
+	server.config.TLSCertFile = config.TLSCertFile
+	server.config.TLSKeyFile = config.TLSKeyFile
+
+	for _, callback := range server.reloadCallbacks {
+		if err := callback(&config); err != nil {
+			return ErrReloadFailed.Make().Cause(err)
+		}
+	}
+	return nil
+}
+
+func applyLogLevel(level string) errors.Error {
+	if len(level) == 0 {
+		return nil
+	}
+	lvl, err := log.ParseLevel(level)
+	if err != nil {
+		return ErrInvalidConfig.Make().Cause(err)
+	}
+	log.SetLevel(lvl)
+	return nil
+}
+
+func applyGinMode(mode string) errors.Error {
+	switch mode {
+	case "":
+		return nil
+	case gin.DebugMode, gin.ReleaseMode, gin.TestMode:
+		gin.SetMode(mode)
+		return nil
+	default:
+		return ErrInvalidConfig.Msg("Unknown gin mode %q", mode).Make()
+	}
+}
+
+var redirectGinOutputOnce sync.Once
+
+// redirectGinOutput forwards gin's debug and error output to the logger.
+func redirectGinOutput() {
+	redirectGinOutputOnce.Do(func() {
+		logger := log.WithField("component", "gin")
+		gin.DefaultWriter = logger.WriterLevel(log.DebugLevel)
+		gin.DefaultErrorWriter = logger.WriterLevel(log.ErrorLevel)
+	})
+}
+
+func (server *Server) isTLS() bool {
+	return len(server.config.TLSCertFile) > 0 && len(server.config.TLSKeyFile) > 0
+}
+
+func (server *Server) loadCertificate(config *ServerConfig) errors.Error {
+	if len(config.TLSCertFile) == 0 && len(config.TLSKeyFile) == 0 {
+		return nil
+	}
+	if len(config.TLSCertFile) == 0 || len(config.TLSKeyFile) == 0 {
+		return ErrInvalidConfig.Msg("TLS requires both certificate and key file").Make()
+	}
+	cert, err := tls.LoadX509KeyPair(config.TLSCertFile, config.TLSKeyFile)
+	if err != nil {
+		return ErrInvalidConfig.Make().Cause(err)
+	}
+	server.certMutex.Lock()
+	defer server.certMutex.Unlock()
+	server.certificate = &cert
+	return nil
+}
+
+func (server *Server) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	server.certMutex.RLock()
+	defer server.certMutex.RUnlock()
+	return server.certificate, nil
+}
+
 func (server *Server) notifyBeginServing() {
 	for _, service := range server.services {
 		service.BeginServing()
@@ -145,12 +441,48 @@ func (server *Server) notifyStopServing() {
 	}
 }
 
-// Shutdown gracefully stops the http server.
+// Shutdown gracefully stops the http server. Readiness fails for the configured shutdown delay before in-flight requests are drained for up to 5 seconds and the remaining connections are force-closed.
 func (server *Server) Shutdown() errors.Error {
+	server.delayShutdown()
+	if err := server.Drain(5 * time.Second); err != nil {
+		server.Close()
+		return err
+	}
+	return nil
+}
+
+// Drain stops accepting new connections, cancels streamed responses and waits until all in-flight requests are completed or the timeout elapses. ErrDrainTimeout is returned if requests are still active, use Close to abort them.
+func (server *Server) Drain(timeout time.Duration) errors.Error {
+	server.stopStreams()
+	if server.asyncServer == nil {
+		return nil
+	}
 	// graceful shutdown: https://github.com/gin-gonic/examples/blob/master/graceful-shutdown/graceful-shutdown/server.go
-	context, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
-	return errors.Wrap(server.asyncServer.Shutdown(context))
+	if server.adminServer != nil {
+		defer server.adminServer.Close()
+	}
+	defer flushAccessLog()
+	if err := server.asyncServer.Shutdown(ctx); err != nil {
+		if err == context.DeadlineExceeded {
+			return ErrDrainTimeout.Msg("In-flight requests did not complete within %s", timeout).Make()
+		}
+		return errors.Wrap(err)
+	}
+	return nil
+}
+
+// Close immediately closes the listener and all connections, including connections with in-flight requests.
+func (server *Server) Close() errors.Error {
+	server.stopStreams()
+	if server.asyncServer == nil {
+		return nil
+	}
+	if server.adminServer != nil {
+		server.adminServer.Close()
+	}
+	return errors.Wrap(server.asyncServer.Close())
 }
 
 func ginLogger(c *gin.Context) {
@@ -170,8 +502,7 @@ func ginLogger(c *gin.Context) {
 
 	url := c.Request.RequestURI
 	if !strings.HasPrefix(url, "/healthz") && !strings.HasPrefix(url, "/readiness") && !strings.HasPrefix(url, "/metrics") {
-		str := fmt.Sprintf("%s - %d - %s - %s (%s)", c.Request.RemoteAddr, c.Writer.Status(), c.Request.Method, c.Request.RequestURI, time.Since(t))
-		log.WithField("component", "gin").Info(str)
+		getAccessLogger().WithFields(log.Fields{"component": "gin", "requestId": RequestIDFromContext(c.Request.Context())}).Info(accessLogMessage(c, time.Since(t)))
 	}
 }
 
@@ -182,13 +513,7 @@ type serviceError struct {
 
 // HandleGetHealthz returns 200 OK if all registered services alive, otherwise 500.
 func (server *Server) handleGetHealthz(c *gin.Context) {
-	errs := make([]serviceError, 0)
-	for name, service := range server.services {
-		if err := service.Healthy(); err != nil {
-			errs = append(errs, serviceError{name, err.Error()})
-		}
-	}
-	if len(errs) > 0 {
+	if errs := server.probeErrors(true); len(errs) > 0 {
 		c.JSON(500, errs)
 	} else {
 		c.JSON(200, errs)
@@ -197,15 +522,29 @@ func (server *Server) handleGetHealthz(c *gin.Context) {
 
 // HandleGetReadiness returns 200 OK if all services are ready to serve traffic, otherwise 503.
 func (server *Server) handleGetReadiness(c *gin.Context) {
+	if errs := server.probeErrors(false); len(errs) > 0 {
+		c.JSON(503, errs)
+	} else {
+		c.JSON(200, errs)
+	}
+}
+
+// probeErrors evaluates the health or readiness of all registered services and returns the failed ones.
+func (server *Server) probeErrors(health bool) []serviceError {
 	errs := make([]serviceError, 0)
+	if !health && server.isShuttingDown() {
+		errs = append(errs, serviceError{"server", "Server is shutting down"})
+	}
 	for name, service := range server.services {
-		if err := service.Ready(); err != nil {
+		var err errors.Error
+		if health {
+			err = service.Healthy()
+		} else {
+			err = service.Ready()
+		}
+		if err != nil {
 			errs = append(errs, serviceError{name, err.Error()})
 		}
 	}
-	if len(errs) > 0 {
-		c.JSON(503, errs)
-	} else {
-		c.JSON(200, errs)
-	}
+	return errs
 }
@@ -2,24 +2,17 @@ package http
 
 import (
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"testing"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sbreitf1/errors"
+	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 )
 
-func init() {
-	//TODO just to silence gin:
-	/*errors.Logger = log.Errorf
-	gin.SetMode(gin.ReleaseMode)
-	gin.DefaultWriter = ioutil.Discard
-	gin.DefaultErrorWriter = ioutil.Discard
-	log.SetOutput(ioutil.Discard)*/
-}
-
 func TestDefaultEndpoints(t *testing.T) {
 	server, url := newTestServer()
 	if err := server.RunAsync(nil); err != nil {
@@ -103,6 +96,60 @@ func TestRun(t *testing.T) {
 	awaitTrue(t, func() bool { return errors.InstanceOf(returnErr, ErrGraceShutdown) }, "Expected graceful server shutdown error, but got %v instead", returnErr)
 }
 
+func TestDrainAndClose(t *testing.T) {
+	server, url := newTestServer()
+	release := make(chan struct{})
+	started := make(chan struct{}, 2)
+	server.Engine().GET("/slow", func(c *gin.Context) {
+		started <- struct{}{}
+		select {
+		case <-release:
+		case <-time.After(2 * time.Second):
+		}
+		c.Status(204)
+	})
+	if err := server.RunAsync(nil); err != nil {
+		panic(err)
+	}
+
+	results := make(chan error, 2)
+	request := func() {
+		resp, err := http.Get(url + "/slow")
+		if err == nil {
+			resp.Body.Close()
+		}
+		results <- err
+	}
+
+	// completed within the drain timeout
+	go request()
+	<-started
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+	}()
+	errors.AssertNil(t, server.Drain(time.Second))
+	assert.NoError(t, <-results)
+	_, err := http.Get(url + "/healthz")
+	assert.Error(t, err)
+
+	// aborted by Close after the drain timeout
+	server, url = newTestServer()
+	server.Engine().GET("/slow", func(c *gin.Context) {
+		started <- struct{}{}
+		time.Sleep(2 * time.Second)
+		c.Status(204)
+	})
+	if err := server.RunAsync(nil); err != nil {
+		panic(err)
+	}
+	go request()
+	<-started
+	errors.Assert(t, ErrDrainTimeout, server.Drain(50*time.Millisecond))
+	errors.AssertNil(t, server.Close())
+	assert.Error(t, <-results)
+}
+
 func TestCallbacks(t *testing.T) {
 	service := newTestService(t)
 	assert.False(t, service.RoutesRegistered, "Routes should not be registered yet")
@@ -148,6 +195,91 @@ func TestRecover(t *testing.T) {
 	}
 }
 
+func TestEngine(t *testing.T) {
+	server, url := newTestServer()
+	assert.Equal(t, gin.TestMode, gin.Mode())
+	server.Engine().GET("/custom", func(c *gin.Context) { c.Status(204) })
+	if err := server.RunAsync(nil); err != nil {
+		panic(err)
+	}
+	defer server.Shutdown()
+
+	resp, err := http.Get(url + "/custom")
+	errors.AssertNil(t, err)
+	assert.Equal(t, 204, resp.StatusCode)
+
+	_, err = NewServer(&ServerConfig{GinMode: "loud"})
+	errors.Assert(t, ErrInvalidConfig, err)
+}
+
+func TestRegisterHandler(t *testing.T) {
+	server, url := newTestServer()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/users", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(202)
+	})
+	ready := errors.GenericError.Msg("not yet").Make()
+	errors.AssertNil(t, server.RegisterHandler("legacy", "/legacy", mux, ReadinessProbe(func() errors.Error { return ready })))
+	errors.Assert(t, ErrInvalidRoute, server.RegisterHandler("root", "/", mux))
+	if err := server.RunAsync(nil); err != nil {
+		panic(err)
+	}
+	defer server.Shutdown()
+
+	resp, err := http.Get(url + "/legacy/users")
+	errors.AssertNil(t, err)
+	assert.Equal(t, 202, resp.StatusCode)
+
+	resp, err = http.Get(url + "/readiness")
+	errors.AssertNil(t, err)
+	assert.Equal(t, 503, resp.StatusCode)
+
+	ready = nil
+	resp, err = http.Get(url + "/readiness")
+	errors.AssertNil(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+}
+
+func TestHandler(t *testing.T) {
+	server, _ := newTestServer()
+	server.RegisterService("test-service", newTestService(t))
+	ts := httptest.NewServer(server.Handler())
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/healthz")
+	errors.AssertNil(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+
+	resp, err = http.Get(ts.URL + "/panic")
+	errors.AssertNil(t, err)
+	assert.Equal(t, 500, resp.StatusCode)
+}
+
+func TestReload(t *testing.T) {
+	server, _ := newTestServer()
+	defer log.SetLevel(log.GetLevel())
+
+	server.SetConfigLoader(func() (*ServerConfig, errors.Error) {
+		return &ServerConfig{ListenAddress: ":1234", LogLevel: "debug"}, nil
+	})
+	var reloadedConfig *ServerConfig
+	server.OnReload(func(config *ServerConfig) errors.Error {
+		reloadedConfig = config
+		return nil
+	})
+
+	errors.AssertNil(t, server.Reload())
+	assert.Equal(t, log.DebugLevel, log.GetLevel())
+	if assert.NotNil(t, reloadedConfig) {
+		assert.Equal(t, "debug", reloadedConfig.LogLevel)
+	}
+
+	server.SetConfigLoader(func() (*ServerConfig, errors.Error) {
+		return &ServerConfig{LogLevel: "verbose-ish"}, nil
+	})
+	errors.Assert(t, ErrReloadFailed, server.Reload())
+}
+
 /* ############################################# */
 /* ###                Helper                 ### */
 /* ############################################# */
@@ -157,7 +289,7 @@ func newTestServer() (*Server, string) {
 	if len(port) == 0 {
 		port = "8080"
 	}
-	config := ServerConfig{ListenAddress: ":" + port}
+	config := ServerConfig{ListenAddress: ":" + port, GinMode: gin.TestMode}
 	server, err := NewServer(&config)
 	if err != nil {
 		panic(err)
@@ -0,0 +1,150 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sbreitf1/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// HeaderShadowRequest is set on requests duplicated by a shadow middleware, so the shadow upstream can tell them apart.
+	HeaderShadowRequest = "X-Shadow-Request"
+)
+
+// ShadowConfig contains the parameters of a shadow middleware.
+type ShadowConfig struct {
+	// Upstream is the base URL duplicated requests are sent to. Path and query of the original request are appended.
+	Upstream string `json:"upstream"`
+	// Percentage denotes the share of requests (0 to 100) that is duplicated.
+	Percentage float64 `json:"percentage"`
+	// Timeout limits the duration of shadow requests. Defaults to 10 seconds.
+	Timeout Duration `json:"timeout,omitempty"`
+	// MaxBodySize limits the size of request bodies that are buffered for duplication. Larger requests are not shadowed. Defaults to 1 MiB.
+	MaxBodySize int64 `json:"maxBodySize,omitempty"`
+	// MaxConcurrent limits the number of concurrent shadow requests. Requests are not shadowed while the limit is reached. Defaults to 100.
+	MaxConcurrent int `json:"maxConcurrent,omitempty"`
+}
+
+type shadow struct {
+	config   ShadowConfig
+	upstream *url.URL
+	client   *http.Client
+	slots    chan struct{}
+}
+
+// NewShadowMiddleware returns a middleware that asynchronously sends a copy of a configurable share of requests to a shadow upstream, e.g. to validate a new service version against production traffic. Responses of the shadow upstream are discarded and never affect the original request.
+func NewShadowMiddleware(config *ShadowConfig) (gin.HandlerFunc, errors.Error) {
+	upstream, err := url.Parse(config.Upstream)
+	if err != nil {
+		return nil, ErrInvalidConfig.Make().Cause(err)
+	}
+	if len(upstream.Scheme) == 0 || len(upstream.Host) == 0 {
+		return nil, ErrInvalidConfig.Msg("Shadow upstream %q must be an absolute URL", config.Upstream).Make()
+	}
+	if config.Percentage < 0 || config.Percentage > 100 {
+		return nil, ErrInvalidConfig.Msg("Shadow percentage must be between 0 and 100").Make()
+	}
+
+	s := &shadow{config: *config, upstream: upstream}
+	if s.config.Timeout <= 0 {
+		s.config.Timeout = Duration(10 * time.Second)
+	}
+	if s.config.MaxBodySize <= 0 {
+		s.config.MaxBodySize = 1 << 20
+	}
+	if s.config.MaxConcurrent <= 0 {
+		s.config.MaxConcurrent = 100
+	}
+	s.client = &http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	s.slots = make(chan struct{}, s.config.MaxConcurrent)
+	return s.handle, nil
+}
+
+func (s *shadow) handle(c *gin.Context) {
+	if s.config.Percentage <= 0 || rand.Float64()*100 >= s.config.Percentage {
+		return
+	}
+
+	body, ok := s.bufferBody(c.Request)
+	if !ok {
+		return
+	}
+	select {
+	case s.slots <- struct{}{}:
+	default:
+		return
+	}
+
+	req, cancel, err := s.newRequest(c.Request, body)
+	if err != nil {
+		<-s.slots
+		log.WithFields(log.Fields{"component": "shadow"}).Debugf("Could not create shadow request: %s", err)
+		return
+	}
+	go func() {
+		defer func() { <-s.slots }()
+		defer cancel()
+		response, err := s.client.Do(req)
+		if err != nil {
+			log.WithFields(log.Fields{"component": "shadow"}).Debugf("Shadow request %s %s failed: %s", req.Method, req.URL, err)
+			return
+		}
+		io.Copy(ioutil.Discard, response.Body)
+		response.Body.Close()
+	}()
+}
+
+// bufferBody reads the request body for duplication and restores it for the handler. Bodies exceeding the size limit are restored without buffering and not shadowed.
+func (s *shadow) bufferBody(req *http.Request) ([]byte, bool) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, true
+	}
+	if req.ContentLength > s.config.MaxBodySize {
+		return nil, false
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(req.Body, s.config.MaxBodySize+1))
+	if err != nil || int64(len(data)) > s.config.MaxBodySize {
+		req.Body = readCloser{io.MultiReader(bytes.NewReader(data), req.Body), req.Body}
+		return nil, false
+	}
+	req.Body = readCloser{bytes.NewReader(data), req.Body}
+	return data, true
+}
+
+func (s *shadow) newRequest(original *http.Request, body []byte) (*http.Request, context.CancelFunc, error) {
+	target := *s.upstream
+	target.Path = strings.TrimRight(s.upstream.Path, "/") + original.URL.Path
+	target.RawPath = ""
+	target.RawQuery = original.URL.RawQuery
+
+	// the shadow request must not be canceled when the original request completes
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(s.config.Timeout))
+	req, err := http.NewRequestWithContext(ctx, original.Method, target.String(), bytes.NewReader(body))
+	if err != nil {
+		cancel()
+		return nil, nil, err
+	}
+	req.Header = original.Header.Clone()
+	for _, h := range []string{"Connection", "Keep-Alive", "Proxy-Connection", "Te", "Trailer", "Transfer-Encoding", "Upgrade"} {
+		req.Header.Del(h)
+	}
+	req.Header.Set(HeaderShadowRequest, "true")
+	if id := RequestIDFromContext(original.Context()); len(id) > 0 {
+		req.Header.Set(HeaderRequestID, id)
+	}
+	return req, cancel, nil
+}
@@ -0,0 +1,79 @@
+package http
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sbreitf1/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShadowMiddleware(t *testing.T) {
+	shadowed := make(chan *http.Request, 10)
+	shadowBodies := make(chan string, 10)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		shadowed <- r
+		shadowBodies <- string(data)
+		w.WriteHeader(500)
+	}))
+	defer upstream.Close()
+
+	newEngine := func(percentage float64) *gin.Engine {
+		middleware, err := NewShadowMiddleware(&ShadowConfig{Upstream: upstream.URL + "/v2", Percentage: percentage, MaxBodySize: 16})
+		errors.AssertNil(t, err)
+		engine := gin.New()
+		engine.Use(requestIDMiddleware, middleware)
+		engine.POST("/items", func(c *gin.Context) {
+			data, _ := ioutil.ReadAll(c.Request.Body)
+			c.String(201, string(data))
+		})
+		return engine
+	}
+
+	engine := newEngine(100)
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/items?dry=1", strings.NewReader("payload"))
+	req.Header.Set("X-Custom", "yes")
+	engine.ServeHTTP(w, req)
+	assert.Equal(t, 201, w.Code)
+	assert.Equal(t, "payload", w.Body.String())
+
+	select {
+	case r := <-shadowed:
+		assert.Equal(t, "POST", r.Method)
+		assert.Equal(t, "/v2/items?dry=1", r.URL.RequestURI())
+		assert.Equal(t, "yes", r.Header.Get("X-Custom"))
+		assert.Equal(t, "true", r.Header.Get(HeaderShadowRequest))
+		assert.Equal(t, w.Header().Get(HeaderRequestID), r.Header.Get(HeaderRequestID))
+		assert.Equal(t, "payload", <-shadowBodies)
+	case <-time.After(time.Second):
+		assert.Fail(t, "request has not been shadowed")
+	}
+
+	// bodies exceeding the limit are passed to the handler but not shadowed
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("POST", "/items", strings.NewReader(strings.Repeat("x", 32))))
+	assert.Equal(t, strings.Repeat("x", 32), w.Body.String())
+
+	engine = newEngine(0)
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("POST", "/items", strings.NewReader("payload")))
+	assert.Equal(t, 201, w.Code)
+
+	select {
+	case r := <-shadowed:
+		assert.Fail(t, "unexpected shadow request", r.URL.String())
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	_, err := NewShadowMiddleware(&ShadowConfig{Upstream: "/relative", Percentage: 10})
+	errors.Assert(t, ErrInvalidConfig, err)
+	_, err = NewShadowMiddleware(&ShadowConfig{Upstream: upstream.URL, Percentage: 120})
+	errors.Assert(t, ErrInvalidConfig, err)
+}
@@ -0,0 +1,21 @@
+package http
+
+import (
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// delayShutdown lets readiness fail and keeps serving for the configured shutdown delay, so endpoint removal can propagate before the listener closes.
+func (server *Server) delayShutdown() {
+	if !atomic.CompareAndSwapInt32(&server.shuttingDown, 0, 1) || server.asyncServer == nil || server.config.ShutdownDelay <= 0 {
+		return
+	}
+	log.Infof("Delaying shutdown by %s to propagate endpoint removal", time.Duration(server.config.ShutdownDelay))
+	time.Sleep(time.Duration(server.config.ShutdownDelay))
+}
+
+func (server *Server) isShuttingDown() bool {
+	return atomic.LoadInt32(&server.shuttingDown) != 0
+}
@@ -0,0 +1,49 @@
+package http
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sbreitf1/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShutdownDelay(t *testing.T) {
+	server, err := NewServer(&ServerConfig{ListenAddress: "127.0.0.1:18094", GinMode: gin.TestMode, ShutdownDelay: Duration(300 * time.Millisecond)})
+	errors.AssertNil(t, err)
+	errors.AssertNil(t, server.RegisterService("test", newTestService(t)))
+	errors.AssertNil(t, server.RunAsync(nil))
+
+	resp, getErr := http.Get("http://127.0.0.1:18094/readiness")
+	if assert.NoError(t, getErr) {
+		resp.Body.Close()
+		assert.Equal(t, 200, resp.StatusCode)
+	}
+
+	done := make(chan struct{})
+	start := time.Now()
+	go func() {
+		server.Shutdown()
+		close(done)
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	// requests are still served while readiness reports the shutdown
+	resp, getErr = http.Get("http://127.0.0.1:18094/readiness")
+	if assert.NoError(t, getErr) {
+		resp.Body.Close()
+		assert.Equal(t, 503, resp.StatusCode)
+	}
+	resp, getErr = http.Get("http://127.0.0.1:18094/healthz")
+	if assert.NoError(t, getErr) {
+		resp.Body.Close()
+		assert.Equal(t, 200, resp.StatusCode)
+	}
+
+	<-done
+	assert.True(t, time.Since(start) >= 300*time.Millisecond)
+	_, getErr = http.Get("http://127.0.0.1:18094/healthz")
+	assert.Error(t, getErr)
+}
@@ -0,0 +1,196 @@
+package http
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sbreitf1/errors"
+)
+
+var (
+	// ErrSigningFailed occurs when a request could not be signed.
+	ErrSigningFailed = errors.New("Request signing failed")
+)
+
+// RequestSigner signs outgoing requests. It is executed after the request callback, so it sees the final headers and body.
+type RequestSigner interface {
+	Sign(req *Request) errors.Error
+}
+
+// RequestSignerFunc is a function that implements RequestSigner.
+type RequestSignerFunc func(req *Request) errors.Error
+
+// Sign calls the underlying function.
+func (f RequestSignerFunc) Sign(req *Request) errors.Error {
+	return f(req)
+}
+
+// HMACSigner signs requests using HMAC-SHA256 over method, path with query, date and body hash. The signature is sent as "HMAC-SHA256 keyId=<KeyID>,signature=<base64>" in the configured header.
+type HMACSigner struct {
+	KeyID  string
+	Secret []byte
+	// Header denotes the header that receives the signature. Defaults to "Authorization".
+	Header string
+
+	now func() time.Time
+}
+
+// Sign adds Date, X-Content-SHA256 and the signature header to the request.
+func (signer *HMACSigner) Sign(req *Request) errors.Error {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return ErrSigningFailed.Make().Cause(err)
+	}
+
+	if len(req.Header.Get("Date")) == 0 {
+		req.Header.Set("Date", signerNow(signer.now).UTC().Format(http.TimeFormat))
+	}
+	bodyHash := sha256Hex(body)
+	req.Header.Set("X-Content-SHA256", bodyHash)
+
+	stringToSign := strings.Join([]string{req.Method, req.URL.RequestURI(), req.Header.Get("Date"), bodyHash}, "\n")
+	mac := hmac.New(sha256.New, signer.Secret)
+	mac.Write([]byte(stringToSign))
+
+	header := signer.Header
+	if len(header) == 0 {
+		header = "Authorization"
+	}
+	req.Header.Set(header, "HMAC-SHA256 keyId="+signer.KeyID+",signature="+base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+	return nil
+}
+
+// AWSSigner signs requests using AWS Signature Version 4.
+type AWSSigner struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	// SessionToken is sent as X-Amz-Security-Token for temporary credentials.
+	SessionToken string
+	Region       string
+	Service      string
+	// SetContentSHA256 adds the X-Amz-Content-Sha256 header as required by S3.
+	SetContentSHA256 bool
+
+	now func() time.Time
+}
+
+// Sign adds the X-Amz-Date and Authorization headers to the request.
+func (signer *AWSSigner) Sign(req *Request) errors.Error {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return ErrSigningFailed.Make().Cause(err)
+	}
+
+	now := signerNow(signer.now).UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	if len(signer.SessionToken) > 0 {
+		req.Header.Set("X-Amz-Security-Token", signer.SessionToken)
+	}
+	if signer.SetContentSHA256 {
+		req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	}
+
+	host := req.Host
+	if len(host) == 0 {
+		host = req.URL.Host
+	}
+	headers := map[string]string{"host": host}
+	for h, values := range req.Header {
+		lh := strings.ToLower(h)
+		if lh == "content-type" || strings.HasPrefix(lh, "x-amz-") {
+			headers[lh] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for h := range headers {
+		names = append(names, h)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, h := range names {
+		canonicalHeaders.WriteString(h + ":" + headers[h] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := req.URL.EscapedPath()
+	if len(path) == 0 {
+		path = "/"
+	}
+	canonicalRequest := strings.Join([]string{req.Method, path, awsCanonicalQuery(req.URL.Query()), canonicalHeaders.String(), signedHeaders, payloadHash}, "\n")
+
+	scope := date + "/" + signer.Region + "/" + signer.Service + "/aws4_request"
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, sha256Hex([]byte(canonicalRequest))}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+signer.SecretAccessKey), date)
+	key = hmacSHA256(key, signer.Region)
+	key = hmacSHA256(key, signer.Service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+signer.AccessKeyID+"/"+scope+", SignedHeaders="+signedHeaders+", Signature="+signature)
+	return nil
+}
+
+func awsCanonicalQuery(values url.Values) string {
+	params := make([]string, 0)
+	for key, list := range values {
+		for _, v := range list {
+			params = append(params, awsEscape(key)+"="+awsEscape(v))
+		}
+	}
+	sort.Strings(params)
+	return strings.Join(params, "&")
+}
+
+func awsEscape(str string) string {
+	return strings.Replace(url.QueryEscape(str), "+", "%20", -1)
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func signerNow(now func() time.Time) time.Time {
+	if now != nil {
+		return now()
+	}
+	return time.Now()
+}
+
+// readRequestBody reads the complete request body and replaces it, so it can still be sent.
+func readRequestBody(req *Request) ([]byte, error) {
+	if req.Body == nil {
+		return nil, nil
+	}
+	data, err := ioutil.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(data))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(data)), nil
+	}
+	return data, nil
+}
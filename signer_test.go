@@ -0,0 +1,50 @@
+package http
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sbreitf1/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAWSSigner(t *testing.T) {
+	// example from the AWS Signature Version 4 documentation
+	signer := &AWSSigner{
+		AccessKeyID:     "AKIDEXAMPLE",
+		SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+		Region:          "us-east-1",
+		Service:         "iam",
+		now:             func() time.Time { return time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC) },
+	}
+	req, _ := http.NewRequest("GET", "https://iam.amazonaws.com/?Action=ListUsers&Version=2010-05-08", nil)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+	errors.AssertNil(t, signer.Sign(req))
+	assert.Equal(t, "20150830T123600Z", req.Header.Get("X-Amz-Date"))
+	assert.Equal(t, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/iam/aws4_request, SignedHeaders=content-type;host;x-amz-date, Signature=5d672d79c15b13162d9279b0855cfba6789a8edb4c82c400e06b5924a6f2b5d7", req.Header.Get("Authorization"))
+}
+
+func TestHMACSigner(t *testing.T) {
+	client := NewClient()
+	client.Signer = &HMACSigner{KeyID: "key1", Secret: []byte("secret"), Header: "X-Signature"}
+	client.RequestResponder = func(req *Request) (*Response, errors.Error) {
+		body, _ := ioutil.ReadAll(req.Body)
+		assert.Equal(t, "payload", string(body))
+		assert.Equal(t, "added-by-callback", req.Header.Get("X-Custom"))
+		assert.True(t, strings.HasSuffix(req.Header.Get("Date"), " GMT"))
+
+		mac := hmac.New(sha256.New, []byte("secret"))
+		mac.Write([]byte("POST\n/items?a=1\n" + req.Header.Get("Date") + "\n" + sha256Hex(body)))
+		assert.Equal(t, "HMAC-SHA256 keyId=key1,signature="+base64.StdEncoding.EncodeToString(mac.Sum(nil)), req.Header.Get("X-Signature"))
+		return &Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader(""))}, nil
+	}
+
+	_, err := client.Post("http://localhost/items?a=1", "text/plain", strings.NewReader("payload"), WithHeader("X-Custom", "added-by-callback"))
+	errors.AssertNil(t, err)
+}
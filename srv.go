@@ -0,0 +1,125 @@
+package http
+
+import (
+	"context"
+	"math/rand"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sbreitf1/errors"
+)
+
+const (
+	// DefaultSRVRefreshInterval denotes how long SRV records are used before they are looked up again.
+	DefaultSRVRefreshInterval = 30 * time.Second
+)
+
+// SRVResolver looks up SRV records. It is implemented by *net.Resolver and used for URLs with "http+srv" and "https+srv" scheme when set as Client.Resolver.
+type SRVResolver interface {
+	LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)
+}
+
+type srvCacheEntry struct {
+	records []*net.SRV
+	expires time.Time
+}
+
+// isSRVScheme reports whether scheme denotes a URL with SRV name instead of host, e.g. "http+srv://_api._tcp.example.com/v1".
+func isSRVScheme(scheme string) bool {
+	return scheme == "http+srv" || scheme == "https+srv"
+}
+
+// resolveSRVURL replaces the SRV name of the request URL with host and port of a target selected by priority and weight.
+func (client *Client) resolveSRVURL(req *Request) errors.Error {
+	name := req.URL.Hostname()
+	if len(name) == 0 {
+		return ErrInvalidRequest.Msg("Missing SRV name in URL %q", req.URL.String()).Make()
+	}
+
+	records, err := client.lookupSRV(req.Context(), name)
+	if err != nil {
+		return err
+	}
+	target := selectSRV(records)
+	if target == nil {
+		return requestFailed(FailureDNS, &net.DNSError{Err: "no SRV target available", Name: name, IsNotFound: true})
+	}
+
+	req.URL.Scheme = strings.TrimSuffix(req.URL.Scheme, "+srv")
+	req.URL.Host = net.JoinHostPort(strings.TrimSuffix(target.Target, "."), strconv.Itoa(int(target.Port)))
+	req.Host = req.URL.Host
+	return nil
+}
+
+// lookupSRV returns the cached SRV records of name and refreshes them after SRVRefreshInterval. Stale records are kept when the refresh fails, so a DNS outage does not interrupt requests to known targets.
+func (client *Client) lookupSRV(ctx context.Context, name string) ([]*net.SRV, errors.Error) {
+	client.srvMutex.Lock()
+	entry, ok := client.srvCache[name]
+	client.srvMutex.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		return entry.records, nil
+	}
+
+	var resolver SRVResolver = net.DefaultResolver
+	if r, ok := client.Resolver.(SRVResolver); ok {
+		resolver = r
+	}
+	_, records, err := resolver.LookupSRV(ctx, "", "", name)
+	if err != nil || len(records) == 0 {
+		if ok {
+			return entry.records, nil
+		}
+		if err == nil {
+			err = &net.DNSError{Err: "no SRV records", Name: name, IsNotFound: true}
+		}
+		return nil, requestFailed(FailureDNS, err)
+	}
+
+	interval := client.SRVRefreshInterval
+	if interval <= 0 {
+		interval = DefaultSRVRefreshInterval
+	}
+	client.srvMutex.Lock()
+	if client.srvCache == nil {
+		client.srvCache = make(map[string]srvCacheEntry)
+	}
+	client.srvCache[name] = srvCacheEntry{records: records, expires: time.Now().Add(interval)}
+	client.srvMutex.Unlock()
+	return records, nil
+}
+
+// selectSRV returns a target of the lowest priority chosen randomly by weight as described in RFC 2782. Targets with zero weight are only chosen if all targets of that priority have zero weight. It returns nil if no target is available, including the "." target denoting that the service is not provided.
+func selectSRV(records []*net.SRV) *net.SRV {
+	var candidates []*net.SRV
+	for _, r := range records {
+		if r.Target == "." || len(r.Target) == 0 {
+			continue
+		}
+		if len(candidates) == 0 || r.Priority < candidates[0].Priority {
+			candidates = []*net.SRV{r}
+		} else if r.Priority == candidates[0].Priority {
+			candidates = append(candidates, r)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	total := 0
+	for _, r := range candidates {
+		total += int(r.Weight)
+	}
+	if total == 0 {
+		return candidates[rand.Intn(len(candidates))]
+	}
+	n := rand.Intn(total)
+	for _, r := range candidates {
+		n -= int(r.Weight)
+		if n < 0 {
+			return r
+		}
+	}
+	return candidates[len(candidates)-1]
+}
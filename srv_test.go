@@ -0,0 +1,128 @@
+package http
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sbreitf1/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type testSRVResolver struct {
+	mutex   sync.Mutex
+	records []*net.SRV
+	err     error
+	lookups int
+}
+
+func (r *testSRVResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	return net.DefaultResolver.LookupHost(ctx, host)
+}
+
+func (r *testSRVResolver) LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.lookups++
+	if name != "_api._tcp.example.test" {
+		return "", nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+	}
+	return name, r.records, r.err
+}
+
+func (r *testSRVResolver) set(records []*net.SRV, err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.records, r.err = records, err
+}
+
+func newSRVTestServer(t *testing.T, name string) (*httptest.Server, uint16) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(name + " " + r.URL.RequestURI()))
+	}))
+	t.Cleanup(server.Close)
+	u, _ := url.Parse(server.URL)
+	port, _ := strconv.Atoi(u.Port())
+	return server, uint16(port)
+}
+
+func TestSRVDiscovery(t *testing.T) {
+	_, primaryPort := newSRVTestServer(t, "primary")
+	_, backupPort := newSRVTestServer(t, "backup")
+
+	resolver := &testSRVResolver{records: []*net.SRV{
+		{Target: "127.0.0.1.", Port: backupPort, Priority: 20, Weight: 10},
+		{Target: "127.0.0.1.", Port: primaryPort, Priority: 10, Weight: 10},
+	}}
+	client := NewClient()
+	client.Resolver = resolver
+	client.BaseURL = "http+srv://_api._tcp.example.test/api"
+	client.SRVRefreshInterval = 50 * time.Millisecond
+
+	for i := 0; i < 5; i++ {
+		response, err := client.Get("/items?limit=1", nil)
+		errors.AssertNil(t, err)
+		assertResponse(t, 200, "primary /api/items?limit=1", response)
+	}
+	assert.Equal(t, 1, resolver.lookups)
+
+	// refreshed records are used after the interval
+	resolver.set([]*net.SRV{{Target: "127.0.0.1.", Port: backupPort, Priority: 20, Weight: 10}}, nil)
+	time.Sleep(60 * time.Millisecond)
+	response, err := client.Get("/items", nil)
+	errors.AssertNil(t, err)
+	assertResponse(t, 200, "backup /api/items", response)
+	assert.Equal(t, 2, resolver.lookups)
+
+	// stale records are kept when the lookup fails
+	resolver.set(nil, &net.DNSError{Err: "server misbehaving", Name: "_api._tcp.example.test"})
+	time.Sleep(60 * time.Millisecond)
+	response, err = client.Get("/items", nil)
+	errors.AssertNil(t, err)
+	assertResponse(t, 200, "backup /api/items", response)
+
+	t.Run("UnknownName", func(t *testing.T) {
+		_, err := client.Get("https+srv://_other._tcp.example.test/", nil)
+		assert.Equal(t, FailureDNS, TransportFailureOf(err))
+	})
+
+	t.Run("NotProvided", func(t *testing.T) {
+		client := NewClient()
+		client.Resolver = &testSRVResolver{records: []*net.SRV{{Target: "."}}}
+		_, err := client.Get("http+srv://_api._tcp.example.test/", nil)
+		assert.Equal(t, FailureDNS, TransportFailureOf(err))
+	})
+}
+
+func TestSelectSRV(t *testing.T) {
+	heavy := &net.SRV{Target: "heavy.", Priority: 1, Weight: 3}
+	light := &net.SRV{Target: "light.", Priority: 1, Weight: 1}
+	unused := &net.SRV{Target: "unused.", Priority: 1}
+	backup := &net.SRV{Target: "backup.", Priority: 2, Weight: 100}
+
+	counts := make(map[string]int)
+	for i := 0; i < 4000; i++ {
+		counts[selectSRV([]*net.SRV{backup, heavy, light, unused}).Target]++
+	}
+	assert.Equal(t, 0, counts["backup."])
+	assert.Equal(t, 0, counts["unused."])
+	assert.InDelta(t, 3000, counts["heavy."], 250)
+	assert.InDelta(t, 1000, counts["light."], 250)
+
+	// zero weights are chosen uniformly
+	counts = make(map[string]int)
+	other := &net.SRV{Target: "other.", Priority: 1}
+	for i := 0; i < 2000; i++ {
+		counts[selectSRV([]*net.SRV{unused, other}).Target]++
+	}
+	assert.InDelta(t, 1000, counts["unused."], 200)
+
+	assert.Nil(t, selectSRV(nil))
+	assert.Nil(t, selectSRV([]*net.SRV{{Target: "."}}))
+}
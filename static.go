@@ -0,0 +1,148 @@
+package http
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sbreitf1/errors"
+)
+
+var (
+	// ErrNotFound is answered with 404 Not Found, e.g. for static files that do not exist.
+	ErrNotFound = errors.New("Not found").Safe().HTTPCode(404)
+)
+
+// precompressedEncodings lists the content encodings of pre-compressed files in order of preference with their file suffix.
+var precompressedEncodings = []struct {
+	encoding string
+	suffix   string
+}{
+	{"br", ".br"},
+	{"gzip", ".gz"},
+}
+
+// StaticHandler returns a handler serving the files below root for the path parameter "filepath", e.g. for the route "/assets/*filepath". Pre-compressed variants next to a file with suffix .br or .gz are served instead if the client accepts the encoding, so large assets do not need to be compressed per request. Missing files and directories are answered with ErrNotFound by the error mapper of the server.
+func StaticHandler(root string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		name := filepath.Join(root, filepath.FromSlash(path.Clean("/"+c.Param("filepath"))))
+		info, err := os.Stat(name)
+		if err != nil || info.IsDir() {
+			abortWithError(c, ErrNotFound.Msg("File %q not found", c.Param("filepath")).Make())
+			return
+		}
+
+		// the content type must not be sniffed from compressed content
+		contentType := mime.TypeByExtension(filepath.Ext(name))
+		if len(contentType) == 0 {
+			contentType = "application/octet-stream"
+		}
+		c.Header("Content-Type", contentType)
+
+		file := name
+		acceptEncoding := c.GetHeader("Accept-Encoding")
+		for _, e := range precompressedEncodings {
+			if variant, err := os.Stat(name + e.suffix); err == nil && !variant.IsDir() {
+				// responses differ by encoding as soon as any variant exists
+				c.Writer.Header().Add("Vary", "Accept-Encoding")
+				if file == name && acceptsEncoding(acceptEncoding, e.encoding) {
+					file, info = name+e.suffix, variant
+					c.Header("Content-Encoding", e.encoding)
+				}
+			}
+		}
+
+		serveFile(c, name, file, info)
+	}
+}
+
+// SendFile answers the request with the content of the file at name, e.g. for media or artifact downloads. For requests of the Server on plain TCP connections, the content is passed to the socket using sendfile without copying it through user space, unless a middleware replaced the response writer. Range requests are answered with 206 Partial Content and a Content-Range header, If-Range is validated against the Last-Modified date and a strong ETag derived from size and modification time of the file. Missing files and directories are answered with ErrNotFound by the error mapper of the server.
+func SendFile(c *gin.Context, name string) {
+	info, err := os.Stat(name)
+	if err != nil || info.IsDir() {
+		abortWithError(c, ErrNotFound.Msg("File %q not found", filepath.Base(name)).Make())
+		return
+	}
+	serveFile(c, name, name, info)
+}
+
+// serveFile writes the content of file with validators and range support. The content type is derived from name if not already set.
+func serveFile(c *gin.Context, name, file string, info os.FileInfo) {
+	f, err := os.Open(file)
+	if err != nil {
+		abortWithError(c, ErrNotFound.Msg("File %q not found", filepath.Base(name)).Make())
+		return
+	}
+	defer f.Close()
+	if len(c.Writer.Header().Get("ETag")) == 0 {
+		c.Header("ETag", fileETag(info))
+	}
+	http.ServeContent(c.Writer, c.Request, name, info.ModTime(), f)
+}
+
+// sendfileConnKey stores the response writer of the connection in the request context, see sendfileMiddleware.
+type sendfileConnKey struct{}
+
+// sendfileWriter passes file content to the writer below the gin writer, so the connection of the server can use sendfile instead of copying the content through user space. The passed bytes are added to the size of the response for access log and metrics.
+type sendfileWriter struct {
+	gin.ResponseWriter
+	to   io.ReaderFrom
+	sent int
+}
+
+// ReadFrom writes the pending status code and copies r to the underlying writer. It is used by io.Copy in http.ServeContent.
+func (w *sendfileWriter) ReadFrom(r io.Reader) (int64, error) {
+	w.WriteHeaderNow()
+	n, err := w.to.ReadFrom(r)
+	w.sent += int(n)
+	return n, err
+}
+
+func (w *sendfileWriter) Size() int {
+	return w.ResponseWriter.Size() + w.sent
+}
+
+// sendfileMiddleware lets the gin writer pass file content to the connection if the connection supports it. It must be the first middleware, so the writer has not been replaced yet.
+func sendfileMiddleware(c *gin.Context) {
+	if conn, ok := c.Request.Context().Value(sendfileConnKey{}).(io.ReaderFrom); ok {
+		c.Writer = &sendfileWriter{ResponseWriter: c.Writer, to: conn}
+	}
+}
+
+// fileETag returns a strong entity tag for the file version described by info.
+func fileETag(info os.FileInfo) string {
+	return `"` + strconv.FormatInt(info.Size(), 16) + "-" + strconv.FormatInt(info.ModTime().UnixNano(), 16) + `"`
+}
+
+// acceptsEncoding reports whether the Accept-Encoding header value allows the given content encoding.
+func acceptsEncoding(acceptEncoding, encoding string) bool {
+	accepted := false
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		params := strings.Split(part, ";")
+		coding := strings.TrimSpace(params[0])
+		if !strings.EqualFold(coding, encoding) && coding != "*" {
+			continue
+		}
+		quality := 1.0
+		for _, param := range params[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if q, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					quality = q
+				}
+			}
+		}
+		if strings.EqualFold(coding, encoding) {
+			// an explicit entry overrides the wildcard
+			return quality > 0
+		}
+		accepted = quality > 0
+	}
+	return accepted
+}
@@ -0,0 +1,245 @@
+package http
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sbreitf1/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStaticHandler(t *testing.T) {
+	root, err := ioutil.TempDir("", "static")
+	assert.NoError(t, err)
+	defer os.RemoveAll(root)
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(root, "app.js"), []byte("plain"), 0644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(root, "app.js.gz"), []byte("gzip"), 0644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(root, "app.js.br"), []byte("brotli"), 0644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(root, "style.css"), []byte("body{}"), 0644))
+	assert.NoError(t, os.Mkdir(filepath.Join(root, "dir"), 0755))
+
+	server, e := NewServer(&ServerConfig{GinMode: gin.TestMode})
+	errors.AssertNil(t, e)
+	errors.AssertNil(t, server.RegisterService("assets", &routesService{func(e *gin.Engine) {
+		e.GET("/assets/*filepath", StaticHandler(root))
+	}}))
+
+	serve := func(path, acceptEncoding string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", path, nil)
+		if len(acceptEncoding) > 0 {
+			r.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		server.Handler().ServeHTTP(w, r)
+		return w
+	}
+
+	w := serve("/assets/app.js", "gzip, deflate, br")
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "brotli", w.Body.String())
+	assert.Equal(t, "br", w.Header().Get("Content-Encoding"))
+	assert.Contains(t, w.Header().Get("Content-Type"), "javascript")
+	assert.Equal(t, "Accept-Encoding", w.Header().Get("Vary"))
+
+	w = serve("/assets/app.js", "gzip, br;q=0")
+	assert.Equal(t, "gzip", w.Body.String())
+	assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+
+	w = serve("/assets/app.js", "")
+	assert.Equal(t, "plain", w.Body.String())
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+	assert.Equal(t, "Accept-Encoding", w.Header().Get("Vary"))
+
+	w = serve("/assets/style.css", "gzip")
+	assert.Equal(t, "body{}", w.Body.String())
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+	assert.Empty(t, w.Header().Get("Vary"))
+
+	assert.Equal(t, 404, serve("/assets/missing.js", "").Code)
+	assert.Equal(t, 404, serve("/assets/dir", "").Code)
+	assert.Equal(t, 404, serve("/assets/../static.go", "").Code)
+}
+
+func TestAcceptsEncoding(t *testing.T) {
+	assert.True(t, acceptsEncoding("gzip, br", "br"))
+	assert.True(t, acceptsEncoding("GZIP;q=0.5", "gzip"))
+	assert.True(t, acceptsEncoding("*", "br"))
+	assert.False(t, acceptsEncoding("*, br;q=0", "br"))
+	assert.False(t, acceptsEncoding("gzip;q=0", "gzip"))
+	assert.False(t, acceptsEncoding("deflate", "gzip"))
+	assert.False(t, acceptsEncoding("", "gzip"))
+}
+
+func TestSendFile(t *testing.T) {
+	root, err := ioutil.TempDir("", "sendfile")
+	assert.NoError(t, err)
+	defer os.RemoveAll(root)
+	name := filepath.Join(root, "artifact.bin")
+	assert.NoError(t, ioutil.WriteFile(name, []byte("0123456789"), 0644))
+
+	server, e := NewServer(&ServerConfig{GinMode: gin.TestMode})
+	errors.AssertNil(t, e)
+	errors.AssertNil(t, server.RegisterService("artifacts", &routesService{func(e *gin.Engine) {
+		e.GET("/artifact", func(c *gin.Context) {
+			SendFile(c, name)
+		})
+		e.GET("/missing", func(c *gin.Context) {
+			SendFile(c, filepath.Join(root, "missing.bin"))
+		})
+	}}))
+
+	serve := func(path string, headers map[string]string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", path, nil)
+		for k, v := range headers {
+			r.Header.Set(k, v)
+		}
+		server.Handler().ServeHTTP(w, r)
+		return w
+	}
+
+	w := serve("/artifact", nil)
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "0123456789", w.Body.String())
+	assert.Equal(t, "bytes", w.Header().Get("Accept-Ranges"))
+	etag := w.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+	lastModified := w.Header().Get("Last-Modified")
+	assert.NotEmpty(t, lastModified)
+
+	w = serve("/artifact", map[string]string{"Range": "bytes=2-4"})
+	assert.Equal(t, 206, w.Code)
+	assert.Equal(t, "234", w.Body.String())
+	assert.Equal(t, "bytes 2-4/10", w.Header().Get("Content-Range"))
+
+	w = serve("/artifact", map[string]string{"Range": "bytes=-3"})
+	assert.Equal(t, 206, w.Code)
+	assert.Equal(t, "789", w.Body.String())
+	assert.Equal(t, "bytes 7-9/10", w.Header().Get("Content-Range"))
+
+	w = serve("/artifact", map[string]string{"Range": "bytes=0-1,5-6"})
+	assert.Equal(t, 206, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "multipart/byteranges")
+
+	w = serve("/artifact", map[string]string{"Range": "bytes=8-", "If-Range": etag})
+	assert.Equal(t, 206, w.Code)
+	assert.Equal(t, "89", w.Body.String())
+	w = serve("/artifact", map[string]string{"Range": "bytes=8-", "If-Range": `"outdated"`})
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "0123456789", w.Body.String())
+	w = serve("/artifact", map[string]string{"Range": "bytes=8-", "If-Range": lastModified})
+	assert.Equal(t, 206, w.Code)
+
+	w = serve("/artifact", map[string]string{"Range": "bytes=20-"})
+	assert.Equal(t, 416, w.Code)
+	assert.Equal(t, "bytes */10", w.Header().Get("Content-Range"))
+
+	assert.Equal(t, 304, serve("/artifact", map[string]string{"If-None-Match": etag}).Code)
+	assert.Equal(t, 404, serve("/missing", nil).Code)
+}
+
+func TestSendFileConnection(t *testing.T) {
+	root, err := ioutil.TempDir("", "sendfile")
+	assert.NoError(t, err)
+	defer os.RemoveAll(root)
+	name := filepath.Join(root, "artifact.bin")
+	content := bytes.Repeat([]byte("0123456789"), 10000)
+	assert.NoError(t, ioutil.WriteFile(name, content, 0644))
+
+	server, e := NewServer(&ServerConfig{GinMode: gin.TestMode})
+	errors.AssertNil(t, e)
+	sizes := make(chan int, 3)
+	handler := func(c *gin.Context) {
+		_, zeroCopy := c.Writer.(io.ReaderFrom)
+		assert.True(t, zeroCopy)
+		SendFile(c, name)
+		sizes <- c.Writer.Size()
+	}
+	errors.AssertNil(t, server.RegisterService("artifacts", &routesService{func(e *gin.Engine) {
+		e.GET("/artifact", handler)
+	}}))
+	server.Engine().GET("/engine/artifact", handler)
+	httpServer := httptest.NewServer(server.Handler())
+	defer httpServer.Close()
+
+	response, err := http.Get(httpServer.URL + "/artifact")
+	assert.NoError(t, err)
+	assert.Equal(t, 200, response.StatusCode)
+	body, err := ioutil.ReadAll(response.Body)
+	response.Body.Close()
+	assert.NoError(t, err)
+	assert.Equal(t, content, body)
+	// the written bytes are counted for access log and metrics
+	assert.Equal(t, len(content), <-sizes)
+
+	request, _ := http.NewRequest("GET", httpServer.URL+"/artifact", nil)
+	request.Header.Set("Range", "bytes=10-19")
+	response, err = http.DefaultClient.Do(request)
+	assert.NoError(t, err)
+	assert.Equal(t, 206, response.StatusCode)
+	assert.Equal(t, "bytes 10-19/100000", response.Header.Get("Content-Range"))
+	body, err = ioutil.ReadAll(response.Body)
+	response.Body.Close()
+	assert.NoError(t, err)
+	assert.Equal(t, "0123456789", string(body))
+	assert.Equal(t, 10, <-sizes)
+
+	// routes of the server engine pass the content to the connection too
+	response, err = http.Get(httpServer.URL + "/engine/artifact")
+	assert.NoError(t, err)
+	body, err = ioutil.ReadAll(response.Body)
+	response.Body.Close()
+	assert.NoError(t, err)
+	assert.Equal(t, content, body)
+	assert.Equal(t, len(content), <-sizes)
+}
+
+// BenchmarkSendFile compares SendFile, which passes the content to the socket using sendfile, with the buffered copy of c.File on a plain gin engine.
+func BenchmarkSendFile(b *testing.B) {
+	root, err := ioutil.TempDir("", "sendfile")
+	assert.NoError(b, err)
+	defer os.RemoveAll(root)
+	name := filepath.Join(root, "artifact.bin")
+	assert.NoError(b, ioutil.WriteFile(name, bytes.Repeat([]byte{42}, 8<<20), 0644))
+
+	server, e := NewServer(&ServerConfig{GinMode: gin.TestMode})
+	if e != nil {
+		b.Fatal(e)
+	}
+	server.Engine().GET("/sendfile", func(c *gin.Context) {
+		SendFile(c, name)
+	})
+	sendfileServer := httptest.NewServer(server.Handler())
+	defer sendfileServer.Close()
+
+	engine := gin.New()
+	engine.GET("/copy", func(c *gin.Context) {
+		// gin copies the file through user space buffers
+		c.File(name)
+	})
+	copyServer := httptest.NewServer(engine)
+	defer copyServer.Close()
+
+	for _, url := range []string{sendfileServer.URL + "/sendfile", copyServer.URL + "/copy"} {
+		b.Run(url[strings.LastIndex(url, "/")+1:], func(b *testing.B) {
+			b.SetBytes(8 << 20)
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				response, err := http.Get(url)
+				if err != nil {
+					b.Fatal(err)
+				}
+				io.Copy(ioutil.Discard, response.Body)
+				response.Body.Close()
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package http
+
+import (
+	"context"
+
+	"github.com/sbreitf1/errors"
+)
+
+type expectStatusContextKey struct{}
+
+// ExpectStatus returns a request callback that turns responses with other status codes into ErrUnexpectedStatus. The error contains a snippet of the response body and the status code, see StatusCodeOf. The closed response is still returned for inspection.
+func ExpectStatus(codes ...int) func(*Request) errors.Error {
+	return expectStatus(func(status int) bool {
+		for _, code := range codes {
+			if status == code {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// ExpectSuccess returns a request callback that turns responses with non-2xx status codes into ErrUnexpectedStatus.
+func ExpectSuccess() func(*Request) errors.Error {
+	return expectStatus(func(status int) bool {
+		return status >= 200 && status <= 299
+	})
+}
+
+func expectStatus(check func(int) bool) func(*Request) errors.Error {
+	return func(req *Request) errors.Error {
+		*req = *req.WithContext(context.WithValue(req.Context(), expectStatusContextKey{}, check))
+		return nil
+	}
+}
+
+// checkStatus returns ErrUnexpectedStatus if the status code of the response has not been expected by the request.
+func checkStatus(req *Request, response *Response) errors.Error {
+	check, ok := req.Context().Value(expectStatusContextKey{}).(func(int) bool)
+	if !ok || check(response.StatusCode) {
+		return nil
+	}
+	err := unexpectedStatus(response)
+	DrainAndClose(response)
+	return err
+}
+
+// StatusCodeOf returns the status code carried by an ErrUnexpectedStatus error.
+func StatusCodeOf(err errors.Error) (int, bool) {
+	if !errors.InstanceOf(err, ErrUnexpectedStatus) {
+		return 0, false
+	}
+	return err.API().ErrorCode, true
+}
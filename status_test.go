@@ -0,0 +1,41 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/sbreitf1/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpectStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status, _ := strconv.Atoi(r.URL.Query().Get("status"))
+		w.WriteHeader(status)
+		w.Write([]byte("status body"))
+	}))
+	defer server.Close()
+	client := NewClient()
+
+	response, err := client.Get(server.URL+"?status=204", ExpectStatus(200, 204))
+	errors.AssertNil(t, err)
+	assert.Equal(t, 204, response.StatusCode)
+
+	response, err = client.Get(server.URL+"?status=404", ExpectStatus(200, 204))
+	errors.Assert(t, ErrUnexpectedStatus, err)
+	assert.Equal(t, 404, response.StatusCode)
+	assert.Contains(t, err.Error(), "status body")
+	status, ok := StatusCodeOf(err)
+	assert.True(t, ok)
+	assert.Equal(t, 404, status)
+
+	_, err = client.Get(server.URL+"?status=201", ExpectSuccess())
+	errors.AssertNil(t, err)
+	_, err = client.Get(server.URL+"?status=500", ExpectSuccess())
+	errors.Assert(t, ErrUnexpectedStatus, err)
+
+	_, ok = StatusCodeOf(ErrRequestFailed.Make())
+	assert.False(t, ok)
+}
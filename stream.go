@@ -0,0 +1,122 @@
+package http
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sbreitf1/errors"
+)
+
+// StreamConfig contains the parameters of a streamed response.
+type StreamConfig struct {
+	// ContentType of the response. Defaults to "application/octet-stream".
+	ContentType string
+	// KeepAlive writes KeepAliveData if nothing has been written for the given duration, so proxies and load balancers do not close idle streams. Disabled when 0.
+	KeepAlive time.Duration
+	// KeepAliveData is written as keep-alive. Defaults to a newline.
+	KeepAliveData []byte
+}
+
+// StreamWriter writes the body of a streamed response. Every write is flushed to the client immediately.
+type StreamWriter struct {
+	ctx       context.Context
+	mutex     sync.Mutex
+	w         gin.ResponseWriter
+	lastWrite time.Time
+}
+
+// Context returns a context that is canceled when the client disconnects, the request deadline is exceeded or the server shuts down.
+func (w *StreamWriter) Context() context.Context {
+	return w.ctx
+}
+
+// Write writes and flushes p. It fails with the error of the context once the stream has been canceled.
+func (w *StreamWriter) Write(p []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if err := w.ctx.Err(); err != nil {
+		return 0, err
+	}
+	n, err := w.w.Write(p)
+	w.w.Flush()
+	w.lastWrite = time.Now()
+	return n, err
+}
+
+// keepAlive writes data if the stream has been idle for the given interval until ctx is done.
+func (w *StreamWriter) keepAlive(ctx context.Context, interval time.Duration, data []byte) {
+	ticker := time.NewTicker(interval / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.mutex.Lock()
+			idle := time.Since(w.lastWrite) >= interval
+			w.mutex.Unlock()
+			if idle {
+				w.Write(data)
+			}
+		}
+	}
+}
+
+// StreamResponse answers the request with status and a body written by stream, e.g. for long-running exports. The context of the writer is canceled when the client disconnects or the server shuts down, so Shutdown does not wait for the stream to finish. Errors returned by stream are logged, because the status has already been sent.
+func (server *Server) StreamResponse(c *gin.Context, status int, config *StreamConfig, stream func(*StreamWriter) error) {
+	if config == nil {
+		config = &StreamConfig{}
+	}
+	contentType := config.ContentType
+	if len(contentType) == 0 {
+		contentType = "application/octet-stream"
+	}
+
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+	go func() {
+		select {
+		case <-server.streamsStopped:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	c.Header("Content-Type", contentType)
+	// proxies like nginx must not buffer the stream
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(status)
+	c.Writer.WriteHeaderNow()
+	c.Writer.Flush()
+
+	w := &StreamWriter{ctx: ctx, w: c.Writer, lastWrite: time.Now()}
+	var keepAlive sync.WaitGroup
+	if config.KeepAlive > 0 {
+		data := config.KeepAliveData
+		if len(data) == 0 {
+			data = []byte("\n")
+		}
+		keepAliveCtx, stopKeepAlive := context.WithCancel(ctx)
+		defer stopKeepAlive()
+		keepAlive.Add(1)
+		go func() {
+			defer keepAlive.Done()
+			w.keepAlive(keepAliveCtx, config.KeepAlive, data)
+		}()
+		// the response writer must not be used after the handler returned
+		defer keepAlive.Wait()
+	}
+
+	if err := stream(w); err != nil && ctx.Err() == nil {
+		errors.Wrap(err).ToLog()
+	}
+}
+
+// stopStreams cancels the contexts of all streamed responses.
+func (server *Server) stopStreams() {
+	server.stopStreamsOnce.Do(func() {
+		close(server.streamsStopped)
+	})
+}
@@ -0,0 +1,93 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sbreitf1/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreamResponse(t *testing.T) {
+	server, err := NewServer(&ServerConfig{GinMode: gin.TestMode})
+	errors.AssertNil(t, err)
+	finished := make(chan error, 1)
+	server.Engine().GET("/export", func(c *gin.Context) {
+		server.StreamResponse(c, 200, &StreamConfig{ContentType: "text/plain", KeepAlive: 20 * time.Millisecond}, func(w *StreamWriter) error {
+			w.Write([]byte("first\n"))
+			<-w.Context().Done()
+			_, err := w.Write([]byte("canceled\n"))
+			finished <- err
+			return err
+		})
+	})
+	httpServer := httptest.NewServer(server.Handler())
+	defer httpServer.Close()
+
+	response, e := http.Get(httpServer.URL + "/export")
+	assert.NoError(t, e)
+	defer response.Body.Close()
+	assert.Equal(t, 200, response.StatusCode)
+	assert.Equal(t, "text/plain", response.Header.Get("Content-Type"))
+
+	reader := bufio.NewReader(response.Body)
+	line, e := reader.ReadString('\n')
+	assert.NoError(t, e)
+	assert.Equal(t, "first\n", line)
+	// keep-alive while the stream is idle
+	line, e = reader.ReadString('\n')
+	assert.NoError(t, e)
+	assert.Equal(t, "\n", line)
+
+	// shutdown terminates the stream
+	server.Drain(time.Second)
+	select {
+	case err := <-finished:
+		assert.Equal(t, context.Canceled, err)
+	case <-time.After(time.Second):
+		t.Fatal("stream has not been canceled")
+	}
+	rest, _ := ioutil.ReadAll(reader)
+	for _, b := range rest {
+		assert.Equal(t, byte('\n'), b)
+	}
+}
+
+func TestStreamResponseClientDisconnect(t *testing.T) {
+	server, err := NewServer(&ServerConfig{GinMode: gin.TestMode})
+	errors.AssertNil(t, err)
+	finished := make(chan struct{})
+	server.Engine().GET("/export", func(c *gin.Context) {
+		server.StreamResponse(c, 200, nil, func(w *StreamWriter) error {
+			defer close(finished)
+			w.Write([]byte("first\n"))
+			<-w.Context().Done()
+			return nil
+		})
+	})
+	httpServer := httptest.NewServer(server.Handler())
+	defer httpServer.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	request, _ := http.NewRequestWithContext(ctx, "GET", httpServer.URL+"/export", nil)
+	response, e := http.DefaultClient.Do(request)
+	assert.NoError(t, e)
+	assert.Equal(t, "application/octet-stream", response.Header.Get("Content-Type"))
+	line, e := bufio.NewReader(response.Body).ReadString('\n')
+	assert.NoError(t, e)
+	assert.Equal(t, "first\n", line)
+	cancel()
+	response.Body.Close()
+
+	select {
+	case <-finished:
+	case <-time.After(time.Second):
+		t.Fatal("stream has not been canceled")
+	}
+}
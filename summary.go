@@ -0,0 +1,82 @@
+package http
+
+import (
+	"reflect"
+	"runtime"
+	"sort"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// StartupSummary describes the server configuration when it begins serving.
+type StartupSummary struct {
+	ListenAddress      string
+	AdminListenAddress string
+	TLS                bool
+	// Services maps the names of registered services to the number of routes they registered.
+	Services map[string]int
+	// Routes denotes the total number of routes including probes and metrics.
+	Routes int
+	// Middlewares contains the names of the global middlewares in execution order.
+	Middlewares []string
+}
+
+// SetStartupLogger replaces the function that logs the startup summary when the server begins serving. Pass nil to suppress the summary.
+func (server *Server) SetStartupLogger(logger func(*StartupSummary)) {
+	server.startupLogger = logger
+	server.startupLoggerSet = true
+}
+
+// StartupSummary returns a summary of the current server configuration.
+func (server *Server) StartupSummary() *StartupSummary {
+	summary := &StartupSummary{
+		ListenAddress:      server.config.ListenAddress,
+		AdminListenAddress: server.config.AdminListenAddress,
+		TLS:                server.isTLS(),
+		Services:           make(map[string]int, len(server.services)),
+		Routes:             len(server.engine.Routes()),
+	}
+	for name := range server.services {
+		summary.Services[name] = server.serviceRoutes[name]
+	}
+	for _, handler := range server.engine.Handlers {
+		summary.Middlewares = append(summary.Middlewares, runtime.FuncForPC(reflect.ValueOf(handler).Pointer()).Name())
+	}
+	return summary
+}
+
+// logStartupSummary passes the startup summary to the configured logger.
+func (server *Server) logStartupSummary() {
+	logger := defaultStartupLogger
+	if server.startupLoggerSet {
+		logger = server.startupLogger
+	}
+	if logger != nil {
+		logger(server.StartupSummary())
+	}
+}
+
+// defaultStartupLogger writes the summary as structured log entry.
+func defaultStartupLogger(summary *StartupSummary) {
+	services := make([]string, 0, len(summary.Services))
+	for name := range summary.Services {
+		services = append(services, name)
+	}
+	sort.Strings(services)
+
+	fields := log.Fields{
+		"component":     "server",
+		"listenAddress": summary.ListenAddress,
+		"tls":           summary.TLS,
+		"services":      services,
+		"routes":        summary.Routes,
+		"middlewares":   summary.Middlewares,
+	}
+	if len(summary.AdminListenAddress) > 0 {
+		fields["adminListenAddress"] = summary.AdminListenAddress
+	}
+	for _, name := range services {
+		fields["routes."+name] = summary.Services[name]
+	}
+	log.WithFields(fields).Info("Server starting")
+}
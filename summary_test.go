@@ -0,0 +1,50 @@
+package http
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/sbreitf1/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStartupSummary(t *testing.T) {
+	server, _ := newTestServer()
+	server.RegisterService("test-service", newTestService(t))
+
+	var summary *StartupSummary
+	server.SetStartupLogger(func(s *StartupSummary) { summary = s })
+	errors.AssertNil(t, server.RunAsync(nil))
+	defer server.Shutdown()
+
+	if assert.NotNil(t, summary) {
+		assert.Equal(t, server.config.ListenAddress, summary.ListenAddress)
+		assert.False(t, summary.TLS)
+		assert.Equal(t, map[string]int{"test-service": 1}, summary.Services)
+		assert.Equal(t, len(server.Engine().Routes()), summary.Routes)
+		if assert.True(t, len(summary.Middlewares) >= 3) {
+			assert.True(t, strings.HasSuffix(summary.Middlewares[0], "sendfileMiddleware"))
+			assert.True(t, strings.HasSuffix(summary.Middlewares[1], "requestIDMiddleware"))
+			assert.True(t, strings.HasSuffix(summary.Middlewares[2], "ginLogger"))
+		}
+	}
+}
+
+func TestStartupSummaryLogging(t *testing.T) {
+	var buffer bytes.Buffer
+	defer log.SetOutput(log.StandardLogger().Out)
+	log.SetOutput(&buffer)
+
+	server, _ := newTestServer()
+	server.RegisterService("test-service", newTestService(t))
+	defaultStartupLogger(server.StartupSummary())
+	assert.Contains(t, buffer.String(), "Server starting")
+	assert.Contains(t, buffer.String(), "routes.test-service=1")
+
+	buffer.Reset()
+	server.SetStartupLogger(nil)
+	server.logStartupSummary()
+	assert.Empty(t, buffer.String())
+}
@@ -0,0 +1,169 @@
+package http
+
+import (
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sbreitf1/errors"
+)
+
+const (
+	// TenantOther is the metric label of tenants exceeding the cardinality limit and of requests without tenant.
+	TenantOther = "other"
+)
+
+var (
+	tenantMetricsOnce     sync.Once
+	tenantRequestsTotal   *prometheus.CounterVec
+	tenantRequestDuration *prometheus.HistogramVec
+)
+
+// TenantConfig contains the parameters of a tenant middleware. The tenant is resolved from the token claim, the header and the subdomain in this order.
+type TenantConfig struct {
+	// Claim denotes the claim of the principal stored using SetPrincipal that contains the tenant ID.
+	Claim string `json:"claim,omitempty"`
+	// Header denotes the request header carrying the tenant ID, e.g. "X-Tenant-ID".
+	Header string `json:"header,omitempty"`
+	// BaseDomain enables tenant resolution from subdomains, e.g. "acme" for requests to "acme.example.com" with base domain "example.com".
+	BaseDomain string `json:"baseDomain,omitempty"`
+	// Required rejects requests without tenant with 400 Bad Request.
+	Required bool `json:"required,omitempty"`
+	// MaxMetricTenants limits the number of distinct tenant labels in metrics. Further tenants are recorded as "other". Defaults to 100.
+	MaxMetricTenants int `json:"maxMetricTenants,omitempty"`
+	// RateLimits optionally limits requests per tenant. Tiers are keyed by tenant ID, the default limit applies to all other tenants.
+	RateLimits *RateLimitTable `json:"rateLimits,omitempty"`
+	// RateLimitBackend keeps the rate limit state. Defaults to an in-memory backend.
+	RateLimitBackend RateLimitBackend `json:"-"`
+}
+
+type tenantMiddleware struct {
+	config  TenantConfig
+	limiter gin.HandlerFunc
+
+	mutex        sync.Mutex
+	metricLabels map[string]bool
+}
+
+// registerTenantMetrics creates the per-tenant request metrics once, so multiple tenant middlewares can be created without duplicate registration.
+func registerTenantMetrics() {
+	tenantMetricsOnce.Do(func() {
+		tenantRequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+			Subsystem: "tenant",
+			Name:      "requests_total",
+			Help:      "Number of requests by tenant and status class.",
+		}, []string{"tenant", "status"})
+		tenantRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Subsystem: "tenant",
+			Name:      "request_duration_seconds",
+			Help:      "Latency of requests by tenant.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"tenant"})
+		prometheus.MustRegister(tenantRequestsTotal, tenantRequestDuration)
+	})
+}
+
+// NewTenantMiddleware returns a middleware that resolves the tenant of each request and stores it in the request context, use TenantFromContext to read it. Requests are recorded in prometheus metrics by tenant and optionally rate limited per tenant.
+func NewTenantMiddleware(config *TenantConfig) (gin.HandlerFunc, errors.Error) {
+	if len(config.Claim) == 0 && len(config.Header) == 0 && len(config.BaseDomain) == 0 {
+		return nil, ErrInvalidConfig.Msg("Tenant resolution requires a claim, header or base domain").Make()
+	}
+
+	m := &tenantMiddleware{config: *config, metricLabels: make(map[string]bool)}
+	m.config.BaseDomain = strings.ToLower(strings.Trim(config.BaseDomain, "."))
+	if m.config.MaxMetricTenants <= 0 {
+		m.config.MaxMetricTenants = 100
+	}
+	if config.RateLimits != nil {
+		backend := config.RateLimitBackend
+		if backend == nil {
+			backend = NewMemoryRateLimitBackend()
+		}
+		limiter, err := NewRateLimitMiddlewareWithBackend(config.RateLimits, func(c *gin.Context) (string, string) {
+			tenant := TenantFromContext(c.Request.Context())
+			if len(tenant) == 0 {
+				// requests without tenant are limited by client IP
+				return "", ""
+			}
+			return "tenant:" + tenant, tenant
+		}, backend)
+		if err != nil {
+			return nil, err
+		}
+		m.limiter = limiter
+	}
+
+	registerTenantMetrics()
+	return m.handle, nil
+}
+
+func (m *tenantMiddleware) handle(c *gin.Context) {
+	tenant := m.resolve(c)
+	if len(tenant) == 0 && m.config.Required {
+		c.AbortWithStatusJSON(400, ErrBadRequest.Msg("Tenant could not be determined").Make().API())
+		return
+	}
+	if len(tenant) > 0 {
+		SetTenant(c, tenant)
+	}
+
+	start := time.Now()
+	if m.limiter != nil {
+		m.limiter(c)
+	}
+	if !c.IsAborted() {
+		c.Next()
+	}
+
+	label := m.metricLabel(tenant)
+	tenantRequestsTotal.WithLabelValues(label, strconv.Itoa(c.Writer.Status()/100)+"xx").Inc()
+	tenantRequestDuration.WithLabelValues(label).Observe(time.Since(start).Seconds())
+}
+
+func (m *tenantMiddleware) resolve(c *gin.Context) string {
+	if len(m.config.Claim) > 0 {
+		if principal := PrincipalFromContext(c.Request.Context()); principal != nil {
+			if tenant, ok := principal.Claims[m.config.Claim].(string); ok && len(tenant) > 0 {
+				return tenant
+			}
+		}
+	}
+	if len(m.config.Header) > 0 {
+		if tenant := c.GetHeader(m.config.Header); len(tenant) > 0 {
+			return tenant
+		}
+	}
+	if len(m.config.BaseDomain) > 0 {
+		host := strings.ToLower(c.Request.Host)
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if strings.HasSuffix(host, "."+m.config.BaseDomain) {
+			labels := strings.Split(strings.TrimSuffix(host, "."+m.config.BaseDomain), ".")
+			// the label directly below the base domain denotes the tenant, e.g. "acme" for "api.acme.example.com"
+			return labels[len(labels)-1]
+		}
+	}
+	return ""
+}
+
+// metricLabel returns the label of tenant and caps the number of distinct labels.
+func (m *tenantMiddleware) metricLabel(tenant string) string {
+	if len(tenant) == 0 {
+		return TenantOther
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.metricLabels[tenant] {
+		return tenant
+	}
+	if len(m.metricLabels) < m.config.MaxMetricTenants {
+		m.metricLabels[tenant] = true
+		return tenant
+	}
+	return TenantOther
+}
@@ -0,0 +1,94 @@
+package http
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sbreitf1/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTenantMiddleware(t *testing.T) {
+	middleware, err := NewTenantMiddleware(&TenantConfig{
+		Claim:            "tenant",
+		Header:           "X-Tenant-ID",
+		BaseDomain:       "example.com",
+		Required:         true,
+		MaxMetricTenants: 3,
+	})
+	errors.AssertNil(t, err)
+
+	engine := gin.New()
+	engine.Use(func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "token" {
+			SetPrincipal(c, &Principal{ID: "user", Claims: map[string]interface{}{"tenant": "claimed"}})
+		}
+	}, middleware)
+	engine.GET("/tenant", func(c *gin.Context) {
+		c.String(200, TenantFromContext(c.Request.Context()))
+	})
+	serve := func(host, header, auth string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "http://"+host+"/tenant", nil)
+		req.Header.Set("X-Tenant-ID", header)
+		req.Header.Set("Authorization", auth)
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		return w
+	}
+
+	acme := testutil.ToFloat64(tenantRequestsTotal.WithLabelValues("acme", "2xx"))
+	assert.Equal(t, "claimed", serve("acme.example.com", "header", "token").Body.String())
+	assert.Equal(t, "header", serve("acme.example.com", "header", "").Body.String())
+	assert.Equal(t, "acme", serve("api.acme.example.com:8080", "", "").Body.String())
+	assert.Equal(t, 400, serve("example.com", "", "").Code)
+	assert.Equal(t, 400, serve("acme.example.org", "", "").Code)
+
+	before := testutil.ToFloat64(tenantRequestsTotal.WithLabelValues(TenantOther, "2xx"))
+	assert.Equal(t, 200, serve("beta.example.com", "", "").Code)
+	// claimed, header and acme already occupy the tenant labels
+	assert.Equal(t, before+1, testutil.ToFloat64(tenantRequestsTotal.WithLabelValues(TenantOther, "2xx")))
+	assert.Equal(t, acme+1, testutil.ToFloat64(tenantRequestsTotal.WithLabelValues("acme", "2xx")))
+}
+
+func TestTenantRateLimits(t *testing.T) {
+	middleware, err := NewTenantMiddleware(&TenantConfig{
+		Header: "X-Tenant-ID",
+		RateLimits: &RateLimitTable{
+			Default: RateLimit{Rate: 0.1, Burst: 1},
+			Tiers:   map[string]RateLimit{"premium": {Rate: 0.1, Burst: 3}},
+		},
+	})
+	errors.AssertNil(t, err)
+
+	engine := gin.New()
+	engine.Use(middleware)
+	engine.GET("/", func(c *gin.Context) { c.Status(204) })
+	serve := func(tenant string) int {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("X-Tenant-ID", tenant)
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, 204, serve("small"))
+	assert.Equal(t, 429, serve("small"))
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, 204, serve("premium"))
+	}
+	assert.Equal(t, 429, serve("premium"))
+	assert.Equal(t, 204, serve("other-small"))
+
+	before := testutil.ToFloat64(tenantRequestsTotal.WithLabelValues("small", "4xx"))
+	serve("small")
+	assert.Equal(t, before+1, testutil.ToFloat64(tenantRequestsTotal.WithLabelValues("small", "4xx")))
+}
+
+func TestTenantMiddlewareInvalidConfig(t *testing.T) {
+	_, err := NewTenantMiddleware(&TenantConfig{})
+	errors.Assert(t, ErrInvalidConfig, err)
+	_, err = NewTenantMiddleware(&TenantConfig{Header: "X-Tenant-ID", RateLimits: &RateLimitTable{Default: RateLimit{Rate: -1}}})
+	errors.Assert(t, ErrInvalidConfig, err)
+}
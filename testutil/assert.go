@@ -0,0 +1,82 @@
+package testutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// ResponseBody reads the complete response body and replaces it with an in-memory copy, so the body can be asserted multiple times.
+func ResponseBody(t testing.TB, response *http.Response) []byte {
+	t.Helper()
+	if response == nil || response.Body == nil {
+		return nil
+	}
+	data, err := ioutil.ReadAll(response.Body)
+	response.Body.Close()
+	assert.NoError(t, err, "could not read response body")
+	response.Body = ioutil.NopCloser(bytes.NewReader(data))
+	return data
+}
+
+// AssertStatus asserts the status code of the response and reports the response body on mismatch.
+func AssertStatus(t testing.TB, response *http.Response, expected int) bool {
+	t.Helper()
+	if !assert.NotNil(t, response, "response") {
+		return false
+	}
+	if response.StatusCode != expected {
+		return assert.Fail(t, "unexpected status code", "expected %d but got %d with body %q", expected, response.StatusCode, string(ResponseBody(t, response)))
+	}
+	return true
+}
+
+// AssertHeader asserts that the response header key has the given value.
+func AssertHeader(t testing.TB, response *http.Response, key, expected string) bool {
+	t.Helper()
+	if !assert.NotNil(t, response, "response") {
+		return false
+	}
+	return assert.Equal(t, expected, response.Header.Get(key), "header %s", key)
+}
+
+// AssertBody asserts the complete response body.
+func AssertBody(t testing.TB, response *http.Response, expected string) bool {
+	t.Helper()
+	if !assert.NotNil(t, response, "response") {
+		return false
+	}
+	return assert.Equal(t, expected, string(ResponseBody(t, response)), "response body")
+}
+
+// AssertJSONBody asserts that the response body is JSON equal to expected, which is either a JSON string, a byte slice or a value that is marshaled to JSON. Formatting and key order are ignored.
+func AssertJSONBody(t testing.TB, response *http.Response, expected interface{}) bool {
+	t.Helper()
+	if !assert.NotNil(t, response, "response") {
+		return false
+	}
+	var expectedJSON string
+	switch v := expected.(type) {
+	case string:
+		expectedJSON = v
+	case []byte:
+		expectedJSON = string(v)
+	default:
+		data, err := json.Marshal(expected)
+		if !assert.NoError(t, err, "could not marshal expected body") {
+			return false
+		}
+		expectedJSON = string(data)
+	}
+	return assert.JSONEq(t, expectedJSON, string(ResponseBody(t, response)), "response body")
+}
+
+// AssertResponse asserts status code and body of the response.
+func AssertResponse(t testing.TB, response *http.Response, expectedStatus int, expectedBody string) bool {
+	t.Helper()
+	return AssertStatus(t, response, expectedStatus) && AssertBody(t, response, expectedBody)
+}
@@ -0,0 +1,33 @@
+package testutil
+
+import (
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sbreitf1/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAssertions(t *testing.T) {
+	server := NewServer(t)
+	server.Handle(func(c *gin.Context) {
+		c.Header("X-Version", "2")
+		c.JSON(201, gin.H{"id": 42, "name": "item"})
+	})
+	response, err := server.Client().Get("/items", nil)
+	errors.AssertNil(t, err)
+
+	assert.True(t, AssertStatus(t, response, 201))
+	assert.True(t, AssertHeader(t, response, "X-Version", "2"))
+	assert.True(t, AssertJSONBody(t, response, `{"name": "item", "id": 42}`))
+	assert.True(t, AssertJSONBody(t, response, map[string]interface{}{"id": 42, "name": "item"}))
+	assert.True(t, AssertResponse(t, response, 201, `{"id":42,"name":"item"}`))
+
+	recorder := &failureRecorder{TB: t}
+	assert.False(t, AssertStatus(recorder, response, 200))
+	assert.False(t, AssertHeader(recorder, response, "X-Version", "1"))
+	assert.False(t, AssertJSONBody(recorder, response, `{"id": 43, "name": "item"}`))
+	assert.False(t, AssertBody(recorder, nil, ""))
+	assert.Len(t, recorder.failures, 4)
+	assert.Contains(t, recorder.failures[0], "expected 200 but got 201")
+}
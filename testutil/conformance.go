@@ -0,0 +1,165 @@
+package testutil
+
+import (
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sbreitf1/errors"
+	shttp "github.com/sbreitf1/http"
+	"github.com/stretchr/testify/assert"
+)
+
+// ConformanceOptions configures RunServiceConformance.
+type ConformanceOptions struct {
+	// Name denotes the name the service is registered with. Defaults to "service".
+	Name string
+	// ReadyTimeout denotes how long the service may take to become ready after BeginServing. Defaults to 5 seconds.
+	ReadyTimeout time.Duration
+	// Routes lists routes like "GET /items/:id" the service must register.
+	Routes []string
+	// Serving is called while the service is ready with a client for the running server to perform additional checks.
+	Serving func(t testing.TB, client *shttp.Client)
+}
+
+// lifecycleRecorder records the lifecycle calls of a service.
+type lifecycleRecorder struct {
+	shttp.Service
+
+	mutex sync.Mutex
+	calls []string
+}
+
+func (r *lifecycleRecorder) record(call string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.calls = append(r.calls, call)
+}
+
+func (r *lifecycleRecorder) recorded() []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]string{}, r.calls...)
+}
+
+func (r *lifecycleRecorder) RegisterRoutes(engine *gin.Engine) {
+	r.record("RegisterRoutes")
+	r.Service.RegisterRoutes(engine)
+}
+
+func (r *lifecycleRecorder) BeginServing() {
+	r.record("BeginServing")
+	r.Service.BeginServing()
+}
+
+func (r *lifecycleRecorder) StopServing() {
+	r.record("StopServing")
+	r.Service.StopServing()
+}
+
+// RunServiceConformance runs service in a Server and verifies its complete lifecycle: routes are registered without conflicting with the server routes, the liveness and readiness probes succeed while serving and BeginServing and StopServing are called exactly once. It returns false if any check failed.
+func RunServiceConformance(t testing.TB, service shttp.Service, options *ConformanceOptions) bool {
+	t.Helper()
+	if options == nil {
+		options = &ConformanceOptions{}
+	}
+	name := options.Name
+	if len(name) == 0 {
+		name = "service"
+	}
+	readyTimeout := options.ReadyTimeout
+	if readyTimeout <= 0 {
+		readyTimeout = 5 * time.Second
+	}
+
+	address, ok := freeAddress(t)
+	if !ok {
+		return false
+	}
+	server, err := shttp.NewServer(&shttp.ServerConfig{ListenAddress: address, GinMode: gin.TestMode})
+	if !assert.Nil(t, err, "NewServer") {
+		return false
+	}
+
+	recorder := &lifecycleRecorder{Service: service}
+	if !assert.NotPanics(t, func() { err = server.RegisterService(name, recorder) }, "RegisterRoutes must not conflict with server routes") || !assert.Nil(t, err, "RegisterService") {
+		return false
+	}
+	success := assertRoutes(t, server.Engine(), options.Routes)
+
+	stopped := make(chan errors.Error, 1)
+	if err := server.RunAsync(func(err errors.Error) { stopped <- err }); !assert.Nil(t, err, "RunAsync") {
+		return false
+	}
+	client := shttp.NewClient()
+	client.BaseURL = "http://" + address
+
+	success = assertProbe(t, client, "/healthz", time.Second) && success
+	if assertProbe(t, client, "/readiness", readyTimeout) {
+		if options.Serving != nil {
+			options.Serving(t, client)
+		}
+	} else {
+		success = false
+	}
+
+	if err := server.Shutdown(); !assert.Nil(t, err, "Shutdown") {
+		return false
+	}
+	select {
+	case err := <-stopped:
+		success = assert.True(t, errors.InstanceOf(err, shttp.ErrGraceShutdown), "server must shut down gracefully, got %v", err) && success
+	case <-time.After(10 * time.Second):
+		return assert.Fail(t, "server did not stop within 10 seconds")
+	}
+
+	success = assert.Equal(t, []string{"RegisterRoutes", "BeginServing", "StopServing"}, recorder.recorded(), "lifecycle calls") && success
+	return success
+}
+
+func freeAddress(t testing.TB) (string, bool) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.NoError(t, err, "no free port available") {
+		return "", false
+	}
+	defer listener.Close()
+	return listener.Addr().String(), true
+}
+
+func assertRoutes(t testing.TB, engine *gin.Engine, routes []string) bool {
+	registered := make(map[string]bool)
+	for _, route := range engine.Routes() {
+		registered[route.Method+" "+route.Path] = true
+	}
+	success := true
+	for _, route := range routes {
+		fields := strings.Fields(route)
+		success = assert.True(t, registered[strings.Join(fields, " ")], "route %q must be registered", route) && success
+	}
+	return success
+}
+
+// assertProbe polls a probe endpoint until it returns 200 OK or the timeout elapses.
+func assertProbe(t testing.TB, client *shttp.Client, path string, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for {
+		response, err := client.Get(path, nil)
+		var body string
+		if err == nil {
+			body, err = shttp.ReadResponseString(response)
+			if err == nil && response.StatusCode == 200 {
+				return true
+			}
+		}
+		if time.Now().After(deadline) {
+			if err != nil {
+				return assert.Fail(t, "probe "+path+" failed", "%v", err)
+			}
+			return assert.Fail(t, "probe "+path+" failed", "status %d: %s", response.StatusCode, body)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
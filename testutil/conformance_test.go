@@ -0,0 +1,84 @@
+package testutil
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sbreitf1/errors"
+	shttp "github.com/sbreitf1/http"
+	"github.com/stretchr/testify/assert"
+)
+
+type exampleService struct {
+	serving      int32
+	neverReady   bool
+	extraRoute   string
+	healthyCalls int32
+}
+
+func (s *exampleService) RegisterRoutes(engine *gin.Engine) {
+	engine.GET("/items/:id", func(c *gin.Context) { c.String(200, c.Param("id")) })
+	if len(s.extraRoute) > 0 {
+		engine.GET(s.extraRoute, func(c *gin.Context) {})
+	}
+}
+
+func (s *exampleService) BeginServing() { atomic.StoreInt32(&s.serving, 1) }
+func (s *exampleService) StopServing()  { atomic.StoreInt32(&s.serving, 0) }
+
+func (s *exampleService) Healthy() errors.Error {
+	atomic.AddInt32(&s.healthyCalls, 1)
+	return nil
+}
+
+func (s *exampleService) Ready() errors.Error {
+	if s.neverReady || atomic.LoadInt32(&s.serving) == 0 {
+		return errors.New("not serving").Make()
+	}
+	return nil
+}
+
+func TestRunServiceConformance(t *testing.T) {
+	service := &exampleService{}
+	called := false
+	assert.True(t, RunServiceConformance(t, service, &ConformanceOptions{
+		Routes: []string{"GET /items/:id"},
+		Serving: func(t testing.TB, client *shttp.Client) {
+			called = true
+			response, err := client.Get("/items/42", nil)
+			if assert.Nil(t, err) {
+				body, _ := shttp.ReadResponseString(response)
+				assert.Equal(t, "42", body)
+			}
+		},
+	}))
+	assert.True(t, called)
+	assert.True(t, atomic.LoadInt32(&service.healthyCalls) > 0)
+}
+
+func TestRunServiceConformanceFailures(t *testing.T) {
+	recorder := &failureRecorder{TB: t}
+	assert.False(t, RunServiceConformance(recorder, &exampleService{neverReady: true}, &ConformanceOptions{
+		ReadyTimeout: 100 * time.Millisecond,
+		Routes:       []string{"POST /items"},
+	}))
+	assert.Len(t, recorder.failures, 2)
+
+	recorder = &failureRecorder{TB: t}
+	assert.False(t, RunServiceConformance(recorder, &exampleService{extraRoute: "/healthz"}, nil))
+	assert.Len(t, recorder.failures, 1)
+}
+
+type failureRecorder struct {
+	testing.TB
+	failures []string
+}
+
+func (r *failureRecorder) Helper() {}
+
+func (r *failureRecorder) Errorf(format string, args ...interface{}) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
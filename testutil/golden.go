@@ -0,0 +1,144 @@
+package testutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/sbreitf1/errors"
+	shttp "github.com/sbreitf1/http"
+	"github.com/stretchr/testify/assert"
+)
+
+// DefaultGoldenIgnoreHeaders contains headers that differ between test runs and are not recorded by default.
+var DefaultGoldenIgnoreHeaders = []string{"X-Request-ID", "Traceparent", "Idempotency-Key", "Date"}
+
+// GoldenRecorder records outgoing requests and compares them with a golden file when the test completes. Set the environment variable UPDATE_GOLDEN=1 to (re-)write golden files instead.
+type GoldenRecorder struct {
+	// IgnoreHeaders are not recorded. Defaults to DefaultGoldenIgnoreHeaders.
+	IgnoreHeaders []string
+	// Update writes the recorded requests to the golden file instead of comparing them.
+	Update bool
+
+	t        testing.TB
+	path     string
+	mutex    sync.Mutex
+	requests []goldenRequest
+}
+
+type goldenRequest struct {
+	Method     string              `json:"method"`
+	URI        string              `json:"uri"`
+	Header     map[string][]string `json:"header,omitempty"`
+	Body       string              `json:"body,omitempty"`
+	BodyBase64 []byte              `json:"bodyBase64,omitempty"`
+}
+
+// NewGoldenRecorder returns a recorder for the golden file at path, which is verified automatically when the test completes.
+func NewGoldenRecorder(t testing.TB, path string) *GoldenRecorder {
+	g := &GoldenRecorder{
+		IgnoreHeaders: DefaultGoldenIgnoreHeaders,
+		Update:        os.Getenv("UPDATE_GOLDEN") == "1",
+		t:             t,
+		path:          path,
+	}
+	t.Cleanup(func() { g.Verify() })
+	return g
+}
+
+// Interceptor returns an interceptor that records every request passing it. Add it to a client with Client.Use, retries are recorded as separate requests.
+func (g *GoldenRecorder) Interceptor() shttp.Interceptor {
+	return func(next shttp.Responder) shttp.Responder {
+		return func(req *shttp.Request) (*shttp.Response, errors.Error) {
+			body, err := g.readBody(req)
+			if err != nil {
+				return nil, shttp.ErrInvalidBody.Make().Cause(err)
+			}
+			g.record(req, body)
+			return next(req)
+		}
+	}
+}
+
+// readBody reads the request body and replaces it, so it can still be sent.
+func (g *GoldenRecorder) readBody(req *shttp.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	body, err := ioutil.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(body)), nil
+	}
+	return body, nil
+}
+
+func (g *GoldenRecorder) record(req *shttp.Request, body []byte) {
+	header := make(map[string][]string)
+	for key, values := range req.Header {
+		if !g.ignored(key) {
+			header[key] = values
+		}
+	}
+	// scheme and host are omitted, because test servers listen on random ports
+	recorded := goldenRequest{Method: req.Method, URI: req.URL.RequestURI(), Header: header}
+	if utf8.Valid(body) {
+		recorded.Body = string(body)
+	} else {
+		recorded.BodyBase64 = body
+	}
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.requests = append(g.requests, recorded)
+}
+
+func (g *GoldenRecorder) ignored(header string) bool {
+	for _, ignored := range g.IgnoreHeaders {
+		if http.CanonicalHeaderKey(ignored) == http.CanonicalHeaderKey(header) {
+			return true
+		}
+	}
+	return false
+}
+
+// Verify compares the recorded requests with the golden file and reports differences as test failure. In update mode, the golden file is written instead. It is called automatically when the test completes.
+func (g *GoldenRecorder) Verify() bool {
+	g.t.Helper()
+	g.mutex.Lock()
+	requests := append([]goldenRequest{}, g.requests...)
+	g.mutex.Unlock()
+
+	actual, err := json.MarshalIndent(requests, "", "  ")
+	if !assert.NoError(g.t, err) {
+		return false
+	}
+	actual = append(actual, '\n')
+
+	if g.Update {
+		if err := os.MkdirAll(filepath.Dir(g.path), os.ModePerm); !assert.NoError(g.t, err) {
+			return false
+		}
+		return assert.NoError(g.t, ioutil.WriteFile(g.path, actual, 0644), "could not write golden file")
+	}
+
+	expected, err := ioutil.ReadFile(g.path)
+	if os.IsNotExist(err) {
+		return assert.Fail(g.t, "golden file "+g.path+" does not exist", "run the test with UPDATE_GOLDEN=1 to create it")
+	}
+	if !assert.NoError(g.t, err, "could not read golden file") {
+		return false
+	}
+	return assert.Equal(g.t, string(expected), string(actual), "requests differ from golden file %s, run the test with UPDATE_GOLDEN=1 to accept the changes", g.path)
+}
@@ -0,0 +1,52 @@
+package testutil
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sbreitf1/errors"
+	shttp "github.com/sbreitf1/http"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGoldenRecorder(t *testing.T) {
+	server := NewServer(t)
+	send := func(recorder *GoldenRecorder, name string) {
+		client := server.Client()
+		client.PropagateTrace = true
+		client.Use(recorder.Interceptor())
+		_, err := client.Get("/items?limit=10", shttp.WithHeader("Accept", "application/json"))
+		errors.AssertNil(t, err)
+		_, err = client.Post("/items", "application/json", strings.NewReader(`{"name":"`+name+`"}`), nil)
+		errors.AssertNil(t, err)
+	}
+	path := filepath.Join(t.TempDir(), "golden", "requests.json")
+
+	recorder := &GoldenRecorder{IgnoreHeaders: DefaultGoldenIgnoreHeaders, Update: true, t: t, path: path}
+	send(recorder, "first")
+	assert.True(t, recorder.Verify())
+	data, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"body": "{\"name\":\"first\"}"`)
+	assert.NotContains(t, string(data), "Traceparent")
+	assert.Contains(t, string(data), `"uri": "/items?limit=10"`)
+
+	recorder = &GoldenRecorder{IgnoreHeaders: DefaultGoldenIgnoreHeaders, t: t, path: path}
+	send(recorder, "first")
+	assert.True(t, recorder.Verify())
+
+	failures := &failureRecorder{TB: t}
+	recorder = &GoldenRecorder{IgnoreHeaders: DefaultGoldenIgnoreHeaders, t: failures, path: path}
+	send(recorder, "second")
+	assert.False(t, recorder.Verify())
+	if assert.Len(t, failures.failures, 1) {
+		assert.Contains(t, failures.failures[0], `+    "body": "{\"name\":\"second\"}"`)
+	}
+
+	failures = &failureRecorder{TB: t}
+	recorder = &GoldenRecorder{t: failures, path: path + ".missing"}
+	assert.False(t, recorder.Verify())
+	assert.Len(t, failures.failures, 1)
+}
@@ -0,0 +1,605 @@
+package testutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sbreitf1/errors"
+)
+
+var (
+	// ErrInvalidOpenAPI is returned when an OpenAPI document cannot be loaded.
+	ErrInvalidOpenAPI = errors.New("Invalid OpenAPI document")
+)
+
+// OpenAPIMock answers requests with the example responses of an OpenAPI 3 document in JSON format. Requests are validated against the parameters and request body schemas of the matching operation and rejected with 400 Bad Request if invalid.
+//
+// The response is selected by the lowest 2xx status code of the operation. Clients can request another response with a header like "Prefer: code=404" and a named example with "Prefer: example=name". Responses without example are generated from their schema.
+type OpenAPIMock struct {
+	document   openAPIDocument
+	operations []*openAPIOperation
+}
+
+type openAPIDocument struct {
+	Paths      map[string]map[string]json.RawMessage `json:"paths"`
+	Components struct {
+		Schemas map[string]*openAPISchema `json:"schemas"`
+	} `json:"components"`
+}
+
+type openAPIOperation struct {
+	Method      string
+	Path        string
+	segments    []string
+	Parameters  []*openAPIParameter         `json:"parameters"`
+	RequestBody *openAPIRequestBody         `json:"requestBody"`
+	Responses   map[string]*openAPIResponse `json:"responses"`
+}
+
+type openAPIParameter struct {
+	Name     string         `json:"name"`
+	In       string         `json:"in"`
+	Required bool           `json:"required"`
+	Schema   *openAPISchema `json:"schema"`
+}
+
+type openAPIRequestBody struct {
+	Required bool                         `json:"required"`
+	Content  map[string]*openAPIMediaType `json:"content"`
+}
+
+type openAPIResponse struct {
+	Headers map[string]struct {
+		Schema  *openAPISchema `json:"schema"`
+		Example interface{}    `json:"example"`
+	} `json:"headers"`
+	Content map[string]*openAPIMediaType `json:"content"`
+}
+
+type openAPIMediaType struct {
+	Schema   *openAPISchema `json:"schema"`
+	Example  interface{}    `json:"example"`
+	Examples map[string]struct {
+		Value interface{} `json:"value"`
+	} `json:"examples"`
+}
+
+type openAPISchema struct {
+	Ref        string                    `json:"$ref"`
+	Type       string                    `json:"type"`
+	Format     string                    `json:"format"`
+	Nullable   bool                      `json:"nullable"`
+	Required   []string                  `json:"required"`
+	Properties map[string]*openAPISchema `json:"properties"`
+	Items      *openAPISchema            `json:"items"`
+	Enum       []interface{}             `json:"enum"`
+	Minimum    *float64                  `json:"minimum"`
+	Maximum    *float64                  `json:"maximum"`
+	MinLength  *int                      `json:"minLength"`
+	MaxLength  *int                      `json:"maxLength"`
+	MinItems   *int                      `json:"minItems"`
+	MaxItems   *int                      `json:"maxItems"`
+	Pattern    string                    `json:"pattern"`
+	AllOf      []*openAPISchema          `json:"allOf"`
+	OneOf      []*openAPISchema          `json:"oneOf"`
+	AnyOf      []*openAPISchema          `json:"anyOf"`
+	Example    interface{}               `json:"example"`
+	Default    interface{}               `json:"default"`
+}
+
+var openAPIMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// LoadOpenAPIMock reads an OpenAPI 3 document in JSON format from a file.
+func LoadOpenAPIMock(path string) (*OpenAPIMock, errors.Error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, ErrInvalidOpenAPI.Msg("Could not read OpenAPI document %q", path).Make().Cause(err)
+	}
+	return NewOpenAPIMock(data)
+}
+
+// NewOpenAPIMock parses an OpenAPI 3 document in JSON format. Schema references are supported for components/schemas.
+func NewOpenAPIMock(document []byte) (*OpenAPIMock, errors.Error) {
+	mock := &OpenAPIMock{}
+	if err := json.Unmarshal(document, &mock.document); err != nil {
+		return nil, ErrInvalidOpenAPI.Make().Cause(err)
+	}
+
+	for path, item := range mock.document.Paths {
+		var shared []*openAPIParameter
+		if raw, ok := item["parameters"]; ok {
+			if err := json.Unmarshal(raw, &shared); err != nil {
+				return nil, ErrInvalidOpenAPI.Msg("Invalid parameters of path %q", path).Make().Cause(err)
+			}
+		}
+		for _, method := range openAPIMethods {
+			raw, ok := item[method]
+			if !ok {
+				continue
+			}
+			operation := &openAPIOperation{Method: strings.ToUpper(method), Path: path, segments: strings.Split(strings.Trim(path, "/"), "/")}
+			if err := json.Unmarshal(raw, operation); err != nil {
+				return nil, ErrInvalidOpenAPI.Msg("Invalid operation %s %s", operation.Method, path).Make().Cause(err)
+			}
+			operation.Parameters = append(append([]*openAPIParameter{}, shared...), operation.Parameters...)
+			mock.operations = append(mock.operations, operation)
+		}
+	}
+	// prefer static segments over templates for overlapping paths
+	sort.Slice(mock.operations, func(i, j int) bool {
+		return strings.Count(mock.operations[i].Path, "{") < strings.Count(mock.operations[j].Path, "{")
+	})
+	return mock, nil
+}
+
+// NewOpenAPIServer starts a test server that is answered by an OpenAPIMock for the given OpenAPI document in JSON format.
+func NewOpenAPIServer(t testing.TB, document []byte) *Server {
+	t.Helper()
+	mock, err := NewOpenAPIMock(document)
+	if err != nil {
+		t.Fatalf("could not load OpenAPI document: %v", err)
+	}
+	server := NewServer(t)
+	server.Handle(mock.Handle)
+	return server
+}
+
+// RegisterRoutes answers all requests that are not handled by other routes of the engine, so the mock can be registered as service in a Server.
+func (mock *OpenAPIMock) RegisterRoutes(engine *gin.Engine) {
+	engine.NoRoute(mock.Handle)
+	engine.NoMethod(mock.Handle)
+}
+
+// BeginServing does nothing.
+func (mock *OpenAPIMock) BeginServing() {}
+
+// StopServing does nothing.
+func (mock *OpenAPIMock) StopServing() {}
+
+// Healthy always returns nil.
+func (mock *OpenAPIMock) Healthy() errors.Error { return nil }
+
+// Ready always returns nil.
+func (mock *OpenAPIMock) Ready() errors.Error { return nil }
+
+// Handle validates the request and answers with the example response of the matching operation.
+func (mock *OpenAPIMock) Handle(c *gin.Context) {
+	operation, pathParams, pathMatched := mock.match(c.Request.Method, c.Request.URL.Path)
+	if operation == nil {
+		if pathMatched {
+			c.JSON(http.StatusMethodNotAllowed, gin.H{"errors": []string{"method not allowed"}})
+		} else {
+			c.JSON(http.StatusNotFound, gin.H{"errors": []string{"no operation for path " + c.Request.URL.Path}})
+		}
+		return
+	}
+
+	if errs := mock.validateRequest(c, operation, pathParams); len(errs) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"errors": errs})
+		return
+	}
+
+	prefer := parsePrefer(c.Request.Header.Get("Prefer"))
+	status, response := selectOpenAPIResponse(operation, prefer["code"])
+	if response == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"errors": []string{"no response defined for " + operation.Method + " " + operation.Path}})
+		return
+	}
+
+	for name, header := range response.Headers {
+		if header.Example != nil {
+			c.Header(name, fmt.Sprint(header.Example))
+		} else if header.Schema != nil {
+			c.Header(name, fmt.Sprint(mock.generate(header.Schema, 0)))
+		}
+	}
+
+	contentType, media := selectOpenAPIMediaType(response.Content)
+	if media == nil {
+		c.Status(status)
+		return
+	}
+	body := mock.example(media, prefer["example"])
+	if text, ok := body.(string); ok && !strings.Contains(contentType, "json") {
+		c.Data(status, contentType, []byte(text))
+		return
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"errors": []string{err.Error()}})
+		return
+	}
+	c.Data(status, contentType, data)
+}
+
+// match returns the operation for method and path and whether any operation matches the path.
+func (mock *OpenAPIMock) match(method, path string) (*openAPIOperation, map[string]string, bool) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	pathMatched := false
+	for _, operation := range mock.operations {
+		params, ok := operation.matchPath(segments)
+		if !ok {
+			continue
+		}
+		pathMatched = true
+		if operation.Method == method {
+			return operation, params, true
+		}
+	}
+	return nil, nil, pathMatched
+}
+
+func (operation *openAPIOperation) matchPath(segments []string) (map[string]string, bool) {
+	if len(segments) != len(operation.segments) {
+		return nil, false
+	}
+	params := make(map[string]string)
+	for i, segment := range operation.segments {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			if len(segments[i]) == 0 {
+				return nil, false
+			}
+			params[segment[1:len(segment)-1]] = segments[i]
+		} else if segment != segments[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+func (mock *OpenAPIMock) validateRequest(c *gin.Context, operation *openAPIOperation, pathParams map[string]string) []string {
+	var errs []string
+	for _, param := range operation.Parameters {
+		var value string
+		var present bool
+		switch param.In {
+		case "path":
+			value, present = pathParams[param.Name]
+		case "query":
+			value, present = c.GetQuery(param.Name)
+		case "header":
+			value = c.GetHeader(param.Name)
+			present = len(value) > 0
+		case "cookie":
+			cookie, err := c.Cookie(param.Name)
+			value, present = cookie, err == nil
+		}
+		if !present {
+			if param.Required {
+				errs = append(errs, fmt.Sprintf("missing required %s parameter %q", param.In, param.Name))
+			}
+			continue
+		}
+		if param.Schema != nil {
+			errs = append(errs, mock.validate(param.Schema, parseParameter(value, mock.resolve(param.Schema)), param.In+" parameter "+param.Name)...)
+		}
+	}
+
+	if operation.RequestBody == nil {
+		return errs
+	}
+	data, _ := ioutil.ReadAll(c.Request.Body)
+	if len(data) == 0 {
+		if operation.RequestBody.Required {
+			errs = append(errs, "missing required request body")
+		}
+		return errs
+	}
+	contentType := strings.TrimSpace(strings.Split(c.ContentType(), ";")[0])
+	media, ok := operation.RequestBody.Content[contentType]
+	if !ok {
+		return append(errs, fmt.Sprintf("unsupported content type %q", contentType))
+	}
+	if media.Schema != nil && strings.Contains(contentType, "json") {
+		var body interface{}
+		if err := json.Unmarshal(data, &body); err != nil {
+			return append(errs, "invalid JSON body: "+err.Error())
+		}
+		errs = append(errs, mock.validate(media.Schema, body, "body")...)
+	}
+	return errs
+}
+
+// parseParameter converts a parameter string to the type of its schema, invalid values are kept as string to fail validation.
+func parseParameter(value string, schema *openAPISchema) interface{} {
+	switch schema.Type {
+	case "integer", "number":
+		if number, err := strconv.ParseFloat(value, 64); err == nil {
+			return number
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return value
+}
+
+func (mock *OpenAPIMock) resolve(schema *openAPISchema) *openAPISchema {
+	for i := 0; i < 32 && schema != nil && len(schema.Ref) > 0; i++ {
+		schema = mock.document.Components.Schemas[strings.TrimPrefix(schema.Ref, "#/components/schemas/")]
+	}
+	if schema == nil {
+		return &openAPISchema{}
+	}
+	return schema
+}
+
+// validate checks value against schema and returns all violations, prefixed with the location of the value.
+func (mock *OpenAPIMock) validate(schema *openAPISchema, value interface{}, location string) []string {
+	schema = mock.resolve(schema)
+	if value == nil {
+		if schema.Nullable || len(schema.Type) == 0 {
+			return nil
+		}
+		return []string{location + " must not be null"}
+	}
+
+	var errs []string
+	for _, sub := range schema.AllOf {
+		errs = append(errs, mock.validate(sub, value, location)...)
+	}
+	for _, alternatives := range [][]*openAPISchema{schema.OneOf, schema.AnyOf} {
+		if len(alternatives) == 0 {
+			continue
+		}
+		valid := false
+		for _, sub := range alternatives {
+			if len(mock.validate(sub, value, location)) == 0 {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			errs = append(errs, location+" does not match any allowed schema")
+		}
+	}
+
+	if len(schema.Enum) > 0 {
+		found := false
+		for _, allowed := range schema.Enum {
+			if fmt.Sprint(allowed) == fmt.Sprint(value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			errs = append(errs, fmt.Sprintf("%s must be one of %v", location, schema.Enum))
+		}
+	}
+
+	switch schema.Type {
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return append(errs, location+" must be an object")
+		}
+		for _, name := range schema.Required {
+			if _, ok := object[name]; !ok {
+				errs = append(errs, fmt.Sprintf("%s is missing required property %q", location, name))
+			}
+		}
+		names := make([]string, 0, len(schema.Properties))
+		for name := range schema.Properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if propertyValue, ok := object[name]; ok {
+				errs = append(errs, mock.validate(schema.Properties[name], propertyValue, location+"."+name)...)
+			}
+		}
+	case "array":
+		array, ok := value.([]interface{})
+		if !ok {
+			return append(errs, location+" must be an array")
+		}
+		if schema.MinItems != nil && len(array) < *schema.MinItems {
+			errs = append(errs, fmt.Sprintf("%s must contain at least %d items", location, *schema.MinItems))
+		}
+		if schema.MaxItems != nil && len(array) > *schema.MaxItems {
+			errs = append(errs, fmt.Sprintf("%s must contain at most %d items", location, *schema.MaxItems))
+		}
+		if schema.Items != nil {
+			for i, item := range array {
+				errs = append(errs, mock.validate(schema.Items, item, fmt.Sprintf("%s[%d]", location, i))...)
+			}
+		}
+	case "string":
+		text, ok := value.(string)
+		if !ok {
+			return append(errs, location+" must be a string")
+		}
+		if schema.MinLength != nil && len([]rune(text)) < *schema.MinLength {
+			errs = append(errs, fmt.Sprintf("%s must be at least %d characters long", location, *schema.MinLength))
+		}
+		if schema.MaxLength != nil && len([]rune(text)) > *schema.MaxLength {
+			errs = append(errs, fmt.Sprintf("%s must be at most %d characters long", location, *schema.MaxLength))
+		}
+		if len(schema.Pattern) > 0 {
+			if pattern, err := regexp.Compile(schema.Pattern); err == nil && !pattern.MatchString(text) {
+				errs = append(errs, fmt.Sprintf("%s must match pattern %s", location, schema.Pattern))
+			}
+		}
+	case "integer", "number":
+		number, ok := value.(float64)
+		if !ok {
+			return append(errs, location+" must be a "+schema.Type)
+		}
+		if schema.Type == "integer" && number != math.Trunc(number) {
+			return append(errs, location+" must be an integer")
+		}
+		if schema.Minimum != nil && number < *schema.Minimum {
+			errs = append(errs, fmt.Sprintf("%s must be at least %v", location, *schema.Minimum))
+		}
+		if schema.Maximum != nil && number > *schema.Maximum {
+			errs = append(errs, fmt.Sprintf("%s must be at most %v", location, *schema.Maximum))
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			errs = append(errs, location+" must be a boolean")
+		}
+	}
+	return errs
+}
+
+// selectOpenAPIResponse returns the response with the preferred status code or the lowest success status code. Preferred codes that are no status code like "default" are ignored.
+func selectOpenAPIResponse(operation *openAPIOperation, preferredCode string) (int, *openAPIResponse) {
+	if response, ok := operation.Responses[preferredCode]; ok {
+		if status, err := strconv.Atoi(preferredCode); err == nil && status >= 100 && status <= 999 {
+			return status, response
+		}
+	}
+	codes := make([]string, 0, len(operation.Responses))
+	for code := range operation.Responses {
+		if _, err := strconv.Atoi(code); err == nil {
+			codes = append(codes, code)
+		}
+	}
+	sort.Slice(codes, func(i, j int) bool {
+		// success codes first, then ascending
+		iSuccess, jSuccess := strings.HasPrefix(codes[i], "2"), strings.HasPrefix(codes[j], "2")
+		if iSuccess != jSuccess {
+			return iSuccess
+		}
+		return codes[i] < codes[j]
+	})
+	if len(codes) > 0 {
+		status, _ := strconv.Atoi(codes[0])
+		return status, operation.Responses[codes[0]]
+	}
+	if response, ok := operation.Responses["default"]; ok {
+		return http.StatusOK, response
+	}
+	return 0, nil
+}
+
+func selectOpenAPIMediaType(content map[string]*openAPIMediaType) (string, *openAPIMediaType) {
+	if media, ok := content["application/json"]; ok {
+		return "application/json", media
+	}
+	contentTypes := make([]string, 0, len(content))
+	for contentType := range content {
+		contentTypes = append(contentTypes, contentType)
+	}
+	if len(contentTypes) == 0 {
+		return "", nil
+	}
+	sort.Strings(contentTypes)
+	return contentTypes[0], content[contentTypes[0]]
+}
+
+// example returns the named or first example of the media type and generates one from the schema if none is defined.
+func (mock *OpenAPIMock) example(media *openAPIMediaType, name string) interface{} {
+	if example, ok := media.Examples[name]; ok {
+		return example.Value
+	}
+	if media.Example != nil {
+		return media.Example
+	}
+	if len(media.Examples) > 0 {
+		names := make([]string, 0, len(media.Examples))
+		for name := range media.Examples {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return media.Examples[names[0]].Value
+	}
+	if media.Schema != nil {
+		return mock.generate(media.Schema, 0)
+	}
+	return nil
+}
+
+// generate returns an example value that satisfies the type structure of schema.
+func (mock *OpenAPIMock) generate(schema *openAPISchema, depth int) interface{} {
+	schema = mock.resolve(schema)
+	if schema.Example != nil {
+		return schema.Example
+	}
+	if schema.Default != nil {
+		return schema.Default
+	}
+	if len(schema.Enum) > 0 {
+		return schema.Enum[0]
+	}
+	if depth > 16 {
+		return nil
+	}
+	if len(schema.AllOf) > 0 {
+		merged := make(map[string]interface{})
+		for _, sub := range schema.AllOf {
+			if object, ok := mock.generate(sub, depth+1).(map[string]interface{}); ok {
+				for k, v := range object {
+					merged[k] = v
+				}
+			}
+		}
+		return merged
+	}
+	for _, alternatives := range [][]*openAPISchema{schema.OneOf, schema.AnyOf} {
+		if len(alternatives) > 0 {
+			return mock.generate(alternatives[0], depth+1)
+		}
+	}
+
+	switch schema.Type {
+	case "object":
+		object := make(map[string]interface{})
+		for name, property := range schema.Properties {
+			object[name] = mock.generate(property, depth+1)
+		}
+		return object
+	case "array":
+		if schema.Items == nil {
+			return []interface{}{}
+		}
+		return []interface{}{mock.generate(schema.Items, depth+1)}
+	case "integer", "number":
+		if schema.Minimum != nil {
+			return *schema.Minimum
+		}
+		return 0
+	case "boolean":
+		return false
+	case "string":
+		switch schema.Format {
+		case "date-time":
+			return "1970-01-01T00:00:00Z"
+		case "date":
+			return "1970-01-01"
+		case "uuid":
+			return "00000000-0000-0000-0000-000000000000"
+		}
+		text := "string"
+		if schema.MinLength != nil && *schema.MinLength > len(text) {
+			text = strings.Repeat("x", *schema.MinLength)
+		}
+		if schema.MaxLength != nil && *schema.MaxLength < len(text) {
+			text = text[:*schema.MaxLength]
+		}
+		return text
+	}
+	return nil
+}
+
+// parsePrefer parses a Prefer header like "code=404, example=notFound".
+func parsePrefer(header string) map[string]string {
+	prefer := make(map[string]string)
+	for _, part := range strings.FieldsFunc(header, func(r rune) bool { return r == ',' || r == ';' }) {
+		kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
+		if len(kv) == 2 {
+			prefer[strings.ToLower(kv[0])] = strings.Trim(kv[1], `"`)
+		}
+	}
+	return prefer
+}
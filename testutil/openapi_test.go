@@ -0,0 +1,113 @@
+package testutil
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/sbreitf1/errors"
+	shttp "github.com/sbreitf1/http"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpenAPIMock(t *testing.T) {
+	document, readErr := ioutil.ReadFile("testdata/petstore.json")
+	assert.NoError(t, readErr)
+	server := NewOpenAPIServer(t, document)
+	client := server.Client()
+
+	t.Run("Examples", func(t *testing.T) {
+		response, err := client.Get("/pets?limit=10", nil)
+		errors.AssertNil(t, err)
+		AssertStatus(t, response, 200)
+		AssertHeader(t, response, "X-Total-Count", "2")
+		AssertJSONBody(t, response, `[]`)
+
+		response, err = client.Get("/pets", shttp.WithHeader("Prefer", "example=two"))
+		errors.AssertNil(t, err)
+		AssertJSONBody(t, response, `[{"id": 1, "name": "Rex", "tag": "dog"}, {"id": 2, "name": "Tom"}]`)
+
+		response, err = client.Get("/pets/1", nil)
+		errors.AssertNil(t, err)
+		AssertJSONBody(t, response, `{"id": 1, "name": "Rex"}`)
+
+		response, err = client.Get("/pets/mine", shttp.WithHeader("X-Owner", "alice"))
+		errors.AssertNil(t, err)
+		AssertResponse(t, response, 200, "[]")
+	})
+
+	t.Run("Generated", func(t *testing.T) {
+		response, err := client.Post("/pets", "application/json", strings.NewReader(`{"name": "Rex", "tag": "dog"}`), nil)
+		errors.AssertNil(t, err)
+		AssertStatus(t, response, 201)
+		AssertJSONBody(t, response, `{"id": 7, "name": "string", "tag": "dog"}`)
+
+		response, err = client.Get("/pets/1", shttp.WithHeader("Prefer", "code=404"))
+		errors.AssertNil(t, err)
+		AssertStatus(t, response, 404)
+		AssertJSONBody(t, response, `{"code": 404, "message": "string"}`)
+
+		response, err = client.Delete("/pets/1", nil)
+		errors.AssertNil(t, err)
+		AssertResponse(t, response, 204, "")
+	})
+
+	t.Run("Validation", func(t *testing.T) {
+		for _, c := range []struct {
+			name     string
+			do       func() (*shttp.Response, errors.Error)
+			expected string
+		}{
+			{"query range", func() (*shttp.Response, errors.Error) { return client.Get("/pets?limit=0", nil) }, "query parameter limit must be at least 1"},
+			{"path type", func() (*shttp.Response, errors.Error) { return client.Get("/pets/rex", nil) }, "path parameter petId must be a integer"},
+			{"missing header", func() (*shttp.Response, errors.Error) { return client.Get("/pets/mine", nil) }, `missing required header parameter \"X-Owner\"`},
+			{"missing body", func() (*shttp.Response, errors.Error) { return client.Post("/pets", "application/json", nil, nil) }, "missing required request body"},
+			{"required property", func() (*shttp.Response, errors.Error) {
+				return client.Post("/pets", "application/json", strings.NewReader(`{"tag": "dog"}`), nil)
+			}, `body is missing required property \"name\"`},
+			{"enum", func() (*shttp.Response, errors.Error) {
+				return client.Post("/pets", "application/json", strings.NewReader(`{"name": "Rex", "tag": "bird"}`), nil)
+			}, "body.tag must be one of [dog cat]"},
+		} {
+			t.Run(c.name, func(t *testing.T) {
+				response, err := c.do()
+				errors.AssertNil(t, err)
+				AssertStatus(t, response, 400)
+				assert.Contains(t, string(ResponseBody(t, response)), c.expected)
+			})
+		}
+	})
+
+	t.Run("Unknown", func(t *testing.T) {
+		response, err := client.Get("/owners", nil)
+		errors.AssertNil(t, err)
+		AssertStatus(t, response, 404)
+
+		response, err = client.Put("/pets/1", "application/json", nil, nil)
+		errors.AssertNil(t, err)
+		AssertStatus(t, response, 405)
+	})
+}
+
+func TestLoadOpenAPIMock(t *testing.T) {
+	_, err := LoadOpenAPIMock("testdata/petstore.json")
+	errors.AssertNil(t, err)
+	_, err = LoadOpenAPIMock("testdata/missing.json")
+	errors.Assert(t, ErrInvalidOpenAPI, err)
+	_, err = NewOpenAPIMock([]byte(`{"paths": []}`))
+	errors.Assert(t, ErrInvalidOpenAPI, err)
+}
+
+func TestSelectOpenAPIResponse(t *testing.T) {
+	created, fallback := &openAPIResponse{}, &openAPIResponse{}
+	operation := &openAPIOperation{Responses: map[string]*openAPIResponse{"201": created, "default": fallback}}
+
+	status, response := selectOpenAPIResponse(operation, "default")
+	assert.Equal(t, 201, status)
+	assert.True(t, response == created)
+
+	operation.Responses = map[string]*openAPIResponse{"default": fallback}
+	status, response = selectOpenAPIResponse(operation, "default")
+	assert.Equal(t, 200, status)
+	assert.True(t, response == fallback)
+}
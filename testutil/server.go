@@ -0,0 +1,96 @@
+// Package testutil provides helpers to test clients and services built with github.com/sbreitf1/http.
+package testutil
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	shttp "github.com/sbreitf1/http"
+)
+
+// Server is a test server listening on an ephemeral local port. All requests are answered by a handler that can be swapped at any time.
+type Server struct {
+	// URL denotes the base URL of the server without trailing slash, e.g. http://127.0.0.1:41234.
+	URL string
+
+	mutex    sync.RWMutex
+	handler  gin.HandlerFunc
+	requests int64
+	server   *http.Server
+	done     chan struct{}
+}
+
+// NewServer starts a test server that answers all requests with 200 OK until a handler is set. The server is shut down automatically when the test completes.
+func NewServer(t testing.TB) *Server {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("could not start test server: %v", err)
+	}
+
+	s := &Server{URL: "http://" + listener.Addr().String(), done: make(chan struct{})}
+	engine := gin.New()
+	engine.Any("/*route", s.serve)
+	s.server = &http.Server{Handler: engine}
+	go func() {
+		defer close(s.done)
+		s.server.Serve(listener)
+	}()
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *Server) serve(c *gin.Context) {
+	atomic.AddInt64(&s.requests, 1)
+	s.mutex.RLock()
+	handler := s.handler
+	s.mutex.RUnlock()
+	if handler == nil {
+		c.Status(http.StatusOK)
+		return
+	}
+	handler(c)
+}
+
+// Handle replaces the handler for all subsequent requests.
+func (s *Server) Handle(handler gin.HandlerFunc) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.handler = handler
+}
+
+// HandleHTTP replaces the handler for all subsequent requests with a net/http handler.
+func (s *Server) HandleHTTP(handler http.Handler) {
+	s.Handle(gin.WrapH(handler))
+}
+
+// RequestCount returns the number of requests received since the server has been started or the counter has been reset.
+func (s *Server) RequestCount() int {
+	return int(atomic.LoadInt64(&s.requests))
+}
+
+// ResetRequestCount sets the request counter to zero.
+func (s *Server) ResetRequestCount() {
+	atomic.StoreInt64(&s.requests, 0)
+}
+
+// Client returns a new client with the server URL as base URL.
+func (s *Server) Client() *shttp.Client {
+	client := shttp.NewClient()
+	client.BaseURL = s.URL
+	return client
+}
+
+// Close shuts the server down and waits for active requests to complete for at most 5 seconds. It can be called multiple times.
+func (s *Server) Close() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	s.server.Shutdown(ctx)
+	<-s.done
+}
@@ -0,0 +1,39 @@
+package testutil
+
+import (
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sbreitf1/errors"
+	shttp "github.com/sbreitf1/http"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServer(t *testing.T) {
+	server := NewServer(t)
+	client := server.Client()
+
+	response, err := client.Get("/", nil)
+	errors.AssertNil(t, err)
+	assert.Equal(t, 200, response.StatusCode)
+	shttp.DrainAndClose(response)
+
+	server.Handle(func(c *gin.Context) {
+		c.String(418, c.Request.URL.Path)
+	})
+	response, err = client.Get("/teapot", nil)
+	errors.AssertNil(t, err)
+	body, err := shttp.ReadResponseString(response)
+	errors.AssertNil(t, err)
+	assert.Equal(t, 418, response.StatusCode)
+	assert.Equal(t, "/teapot", body)
+	assert.Equal(t, 2, server.RequestCount())
+
+	server.ResetRequestCount()
+	assert.Equal(t, 0, server.RequestCount())
+
+	server.Close()
+	_, err = client.Get("/", nil)
+	errors.Assert(t, shttp.ErrRequestFailed, err)
+	assert.Equal(t, shttp.FailureConnectionRefused, shttp.TransportFailureOf(err))
+}
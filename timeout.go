@@ -0,0 +1,100 @@
+package http
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sbreitf1/errors"
+)
+
+// RouteTimeoutProvider can be implemented by services to declare timeouts for individual routes. The timeouts are read when the service is registered.
+type RouteTimeoutProvider interface {
+	// RouteTimeouts maps routes in the form "METHOD /path" (e.g. "GET /items/:id") to their timeout. A timeout of zero or less disables the deadline, e.g. for streaming endpoints.
+	RouteTimeouts() map[string]time.Duration
+}
+
+// SetRouteTimeout overrides the request timeout for the route with given method and path pattern. A timeout of zero or less disables the deadline, e.g. for streaming endpoints.
+func (server *Server) SetRouteTimeout(method, path string, timeout time.Duration) {
+	server.timeoutMutex.Lock()
+	defer server.timeoutMutex.Unlock()
+	server.routeTimeouts[strings.ToUpper(method)+" "+path] = timeout
+}
+
+// serviceRouteTimeouts returns the validated route timeouts declared by a service, keyed by method and path pattern.
+func serviceRouteTimeouts(name string, provider RouteTimeoutProvider) (map[[2]string]time.Duration, errors.Error) {
+	timeouts := make(map[[2]string]time.Duration)
+	for route, timeout := range provider.RouteTimeouts() {
+		parts := strings.Fields(route)
+		if len(parts) != 2 {
+			return nil, ErrInvalidRoute.Msg("Service %q declares a timeout for malformed route %q", name, route).Make()
+		}
+		timeouts[[2]string{parts[0], parts[1]}] = timeout
+	}
+	return timeouts, nil
+}
+
+// routeTimeout returns the timeout of the route matching the request path or the configured default. The most specific route is used if multiple patterns match.
+func (server *Server) routeTimeout(method, path string) time.Duration {
+	server.timeoutMutex.RLock()
+	defer server.timeoutMutex.RUnlock()
+	if timeout, ok := server.routeTimeouts[method+" "+path]; ok {
+		return timeout
+	}
+
+	timeout, specificity := time.Duration(server.config.RequestTimeout), -1
+	for route, routeTimeout := range server.routeTimeouts {
+		if !strings.HasPrefix(route, method+" ") {
+			continue
+		}
+		if s, ok := matchRoute(strings.TrimPrefix(route, method+" "), path); ok && s > specificity {
+			timeout, specificity = routeTimeout, s
+		}
+	}
+	return timeout
+}
+
+// matchRoute reports whether path matches a gin route pattern with :param and *catchAll segments and returns the number of static segments as specificity.
+func matchRoute(pattern, path string) (int, bool) {
+	patternParts := strings.Split(strings.Trim(pattern, "/"), "/")
+	pathParts := strings.Split(strings.Trim(path, "/"), "/")
+	static := 0
+	for i, part := range patternParts {
+		if strings.HasPrefix(part, "*") {
+			return static, true
+		}
+		if i >= len(pathParts) {
+			return 0, false
+		}
+		if strings.HasPrefix(part, ":") {
+			if len(pathParts[i]) == 0 {
+				return 0, false
+			}
+			continue
+		}
+		if part != pathParts[i] {
+			return 0, false
+		}
+		static++
+	}
+	return static, len(patternParts) == len(pathParts)
+}
+
+// timeoutMiddleware applies the route timeout as deadline to the request context. Requests exceeding the deadline without writing a response receive 503 Service Unavailable.
+func (server *Server) timeoutMiddleware(c *gin.Context) {
+	timeout := server.routeTimeout(c.Request.Method, c.Request.URL.Path)
+	if timeout <= 0 {
+		c.Next()
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+	defer cancel()
+	c.Request = c.Request.WithContext(ctx)
+	c.Next()
+
+	if ctx.Err() == context.DeadlineExceeded && !c.Writer.Written() {
+		c.AbortWithStatus(503)
+	}
+}
@@ -0,0 +1,85 @@
+package http
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sbreitf1/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type timeoutService struct {
+	testService
+	timeouts map[string]time.Duration
+}
+
+func (svc *timeoutService) RegisterRoutes(e *gin.Engine) {
+	handler := func(c *gin.Context) {
+		select {
+		case <-c.Request.Context().Done():
+		case <-time.After(200 * time.Millisecond):
+			c.String(200, "done")
+		}
+	}
+	e.GET("/fast", handler)
+	e.GET("/stream/:id", handler)
+	e.GET("/files/*path", handler)
+}
+
+func (svc *timeoutService) RouteTimeouts() map[string]time.Duration {
+	return svc.timeouts
+}
+
+func TestRouteTimeouts(t *testing.T) {
+	server, err := NewServer(&ServerConfig{GinMode: gin.TestMode, RequestTimeout: Duration(50 * time.Millisecond)})
+	errors.AssertNil(t, err)
+	errors.AssertNil(t, server.RegisterService("timeouts", &timeoutService{timeouts: map[string]time.Duration{
+		"GET /stream/:id":  0,
+		"GET /files/*path": time.Second,
+	}}))
+	server.SetRouteTimeout("get", "/files/large", -1)
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		server.Handler().ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w
+	}
+
+	assert.Equal(t, 503, serve("/fast").Code)
+	assert.Equal(t, 200, serve("/stream/1").Code)
+	assert.Equal(t, 200, serve("/files/a/b").Code)
+	assert.Equal(t, time.Duration(-1), server.routeTimeout("GET", "/files/large"))
+	assert.Equal(t, 50*time.Millisecond, server.routeTimeout("POST", "/stream/1"))
+
+	err = server.RegisterService("invalid", &timeoutService{timeouts: map[string]time.Duration{"/missing-method": 0}})
+	errors.Assert(t, ErrInvalidRoute, err)
+
+	// timeouts of services with conflicting routes are not applied
+	err = server.RegisterService("conflicting", &timeoutService{timeouts: map[string]time.Duration{"GET /fast": 0}})
+	errors.Assert(t, ErrRouteConflict, err)
+	assert.Equal(t, 503, serve("/fast").Code)
+}
+
+func TestMatchRoute(t *testing.T) {
+	for _, tc := range []struct {
+		pattern, path string
+		specificity   int
+		match         bool
+	}{
+		{"/items", "/items", 1, true},
+		{"/items/:id", "/items/1", 1, true},
+		{"/items/:id", "/items", 0, false},
+		{"/items/:id", "/items/1/parts", 0, false},
+		{"/items/:id/parts", "/items/1/parts", 2, true},
+		{"/files/*path", "/files/a/b", 1, true},
+		{"/files/*path", "/other/a", 0, false},
+	} {
+		specificity, match := matchRoute(tc.pattern, tc.path)
+		assert.Equal(t, tc.match, match, "%s %s", tc.pattern, tc.path)
+		if tc.match {
+			assert.Equal(t, tc.specificity, specificity, "%s %s", tc.pattern, tc.path)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package http
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+
+	"github.com/sbreitf1/errors"
+)
+
+var (
+	// ErrInvalidTLSConfig occurs when client certificates or certificate authorities could not be loaded.
+	ErrInvalidTLSConfig = errors.New("Invalid TLS configuration")
+)
+
+// tlsConfig returns the TLS configuration for outgoing connections including client certificates and custom root certificate authorities.
+func (client *Client) tlsConfig() (*tls.Config, errors.Error) {
+	config := &tls.Config{InsecureSkipVerify: client.DisableSSLCheck}
+
+	if len(client.ClientCertFile) > 0 || len(client.ClientKeyFile) > 0 {
+		cert, err := tls.LoadX509KeyPair(client.ClientCertFile, client.ClientKeyFile)
+		if err != nil {
+			return nil, ErrInvalidTLSConfig.Make().Cause(err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	config.RootCAs = client.RootCAs
+	if len(client.RootCAFile) > 0 {
+		data, err := ioutil.ReadFile(client.RootCAFile)
+		if err != nil {
+			return nil, ErrInvalidTLSConfig.Make().Cause(err)
+		}
+		if config.RootCAs == nil {
+			config.RootCAs = x509.NewCertPool()
+		} else {
+			config.RootCAs = config.RootCAs.Clone()
+		}
+		if !config.RootCAs.AppendCertsFromPEM(data) {
+			return nil, ErrInvalidTLSConfig.Msg("No certificates found in %q", client.RootCAFile).Make()
+		}
+	}
+
+	return config, nil
+}
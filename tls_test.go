@@ -0,0 +1,109 @@
+package http
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sbreitf1/errors"
+)
+
+func TestClientMutualTLS(t *testing.T) {
+	certFile, keyFile := generateTestCertificate(t.TempDir())
+
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		panic(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(mustParseCertificate(cert))
+
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	ts.TLS = &tls.Config{Certificates: []tls.Certificate{cert}, ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: pool}
+	ts.StartTLS()
+	defer ts.Close()
+
+	t.Run("Unknown CA", func(t *testing.T) {
+		_, err := NewClient().Do(MethodGet, ts.URL, nil)
+		errors.Assert(t, ErrRequestFailed, err)
+	})
+
+	t.Run("Missing client certificate", func(t *testing.T) {
+		client := NewClient()
+		client.RootCAFile = certFile
+		_, err := client.Do(MethodGet, ts.URL, nil)
+		errors.Assert(t, ErrRequestFailed, err)
+	})
+
+	t.Run("Mutual TLS", func(t *testing.T) {
+		client := NewClient()
+		client.RootCAs = pool
+		client.ClientCertFile = certFile
+		client.ClientKeyFile = keyFile
+		response, err := client.Do(MethodGet, ts.URL, nil)
+		errors.AssertNil(t, err)
+		assertResponse(t, 200, "test-client", response)
+	})
+
+	t.Run("Invalid files", func(t *testing.T) {
+		client := NewClient()
+		client.RootCAFile = keyFile
+		_, err := client.tlsConfig()
+		errors.Assert(t, ErrInvalidTLSConfig, err)
+	})
+}
+
+// generateTestCertificate writes a self-signed certificate for localhost that is valid for server and client authentication.
+func generateTestCertificate(dir string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-client"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		panic(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		panic(err)
+	}
+
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600)
+	return certFile, keyFile
+}
+
+func mustParseCertificate(cert tls.Certificate) *x509.Certificate {
+	c, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
@@ -0,0 +1,142 @@
+package http
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sbreitf1/errors"
+)
+
+var (
+	// ErrTokenFailed occurs when an access token could not be obtained.
+	ErrTokenFailed = errors.New("Could not obtain access token")
+)
+
+// Token denotes an access token that is sent in the Authorization header.
+type Token struct {
+	AccessToken string
+	// TokenType is used as authorization scheme. Defaults to "Bearer" when empty.
+	TokenType string
+	// Expiry denotes when the token expires. The token never expires when zero.
+	Expiry time.Time
+}
+
+// Valid returns true if the token is set and does not expire within the given leeway.
+func (token *Token) Valid(leeway time.Duration) bool {
+	return token != nil && len(token.AccessToken) > 0 && (token.Expiry.IsZero() || time.Now().Add(leeway).Before(token.Expiry))
+}
+
+func (token *Token) authorization() string {
+	tokenType := token.TokenType
+	if len(tokenType) == 0 || strings.EqualFold(tokenType, "bearer") {
+		tokenType = "Bearer"
+	}
+	return tokenType + " " + token.AccessToken
+}
+
+// TokenSource provides access tokens for requests of a Client.
+type TokenSource interface {
+	Token() (*Token, errors.Error)
+}
+
+// TokenSourceFunc is a function that implements TokenSource.
+type TokenSourceFunc func() (*Token, errors.Error)
+
+// Token calls the underlying function.
+func (f TokenSourceFunc) Token() (*Token, errors.Error) {
+	return f()
+}
+
+type cachedTokenSource struct {
+	source TokenSource
+	leeway time.Duration
+
+	mutex sync.Mutex
+	token *Token
+}
+
+// NewCachedTokenSource returns a token source that reuses tokens of source and requests a new token when the current one expires within leeway.
+func NewCachedTokenSource(source TokenSource, leeway time.Duration) TokenSource {
+	return &cachedTokenSource{source: source, leeway: leeway}
+}
+
+func (s *cachedTokenSource) Token() (*Token, errors.Error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if !s.token.Valid(s.leeway) {
+		token, err := s.source.Token()
+		if err != nil {
+			return nil, err
+		}
+		s.token = token
+	}
+	return s.token, nil
+}
+
+// ClientCredentials obtains access tokens using the OAuth2 client credentials grant.
+type ClientCredentials struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	// AuthInBody sends the client credentials as form parameters instead of using basic authentication.
+	AuthInBody bool
+	// Client is used to request tokens. The DefaultClient is used when nil.
+	Client *Client
+}
+
+// TokenSource returns a token source that requests new tokens 30 seconds before the current token expires.
+func (cc *ClientCredentials) TokenSource() TokenSource {
+	return NewCachedTokenSource(cc, 30*time.Second)
+}
+
+// Token requests a new access token from the token endpoint.
+func (cc *ClientCredentials) Token() (*Token, errors.Error) {
+	client := cc.Client
+	if client == nil {
+		client = DefaultClient
+	}
+
+	data := url.Values{"grant_type": {"client_credentials"}}
+	if len(cc.Scopes) > 0 {
+		data.Set("scope", strings.Join(cc.Scopes, " "))
+	}
+	var auth func(*Request) errors.Error
+	if cc.AuthInBody {
+		data.Set("client_id", cc.ClientID)
+		data.Set("client_secret", cc.ClientSecret)
+	} else {
+		auth = WithBasicAuth(url.QueryEscape(cc.ClientID), url.QueryEscape(cc.ClientSecret))
+	}
+
+	response, err := client.PostForm(cc.TokenURL, data, auth)
+	if err != nil {
+		return nil, ErrTokenFailed.Make().Cause(err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return nil, ErrTokenFailed.Make().Cause(unexpectedStatus(response))
+	}
+
+	var result struct {
+		AccessToken string `json:"access_token"`
+		TokenType   string `json:"token_type"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&result); err != nil {
+		return nil, ErrTokenFailed.Make().Cause(err)
+	}
+	if len(result.AccessToken) == 0 {
+		return nil, ErrTokenFailed.Msg("Token response does not contain an access token").Make()
+	}
+
+	token := &Token{AccessToken: result.AccessToken, TokenType: result.TokenType}
+	if result.ExpiresIn > 0 {
+		token.Expiry = time.Now().Add(time.Duration(result.ExpiresIn) * time.Second)
+	}
+	return token, nil
+}
@@ -0,0 +1,62 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sbreitf1/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientCredentials(t *testing.T) {
+	issued := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			user, pass, _ := r.BasicAuth()
+			assert.Equal(t, "my-client", user)
+			assert.Equal(t, "s3cret", pass)
+			assert.Equal(t, "client_credentials", r.PostFormValue("grant_type"))
+			assert.Equal(t, "read write", r.PostFormValue("scope"))
+			issued++
+			json.NewEncoder(w).Encode(map[string]interface{}{"access_token": "token-" + string(rune('0'+issued)), "token_type": "bearer", "expires_in": 1})
+		default:
+			w.Write([]byte(r.Header.Get("Authorization")))
+		}
+	}))
+	defer ts.Close()
+
+	cc := &ClientCredentials{TokenURL: ts.URL + "/token", ClientID: "my-client", ClientSecret: "s3cret", Scopes: []string{"read", "write"}}
+	client := NewClient()
+	client.TokenSource = NewCachedTokenSource(cc, 500*time.Millisecond)
+
+	response, err := client.Do(MethodGet, ts.URL+"/api", nil)
+	errors.AssertNil(t, err)
+	assertResponse(t, 200, "Bearer token-1", response)
+
+	response, err = client.Do(MethodGet, ts.URL+"/api", nil)
+	errors.AssertNil(t, err)
+	assertResponse(t, 200, "Bearer token-1", response)
+
+	// token is refreshed within leeway before expiry
+	time.Sleep(600 * time.Millisecond)
+	response, err = client.Do(MethodGet, ts.URL+"/api", nil)
+	errors.AssertNil(t, err)
+	assertResponse(t, 200, "Bearer token-2", response)
+}
+
+func TestTokenSourceError(t *testing.T) {
+	client := NewClient()
+	client.TokenSource = TokenSourceFunc(func() (*Token, errors.Error) {
+		return nil, ErrTokenFailed.Make()
+	})
+	client.RequestResponder = func(req *Request) (*Response, errors.Error) {
+		t.Error("Request should not be sent")
+		return nil, nil
+	}
+	_, err := client.Do(MethodGet, "http://localhost/", nil)
+	errors.Assert(t, ErrTokenFailed, err)
+}
@@ -0,0 +1,80 @@
+package http
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sbreitf1/errors"
+)
+
+const (
+	// HeaderRequestID denotes the header that carries the request ID between services.
+	HeaderRequestID = "X-Request-ID"
+	// HeaderTraceParent denotes the W3C trace context header.
+	HeaderTraceParent = "traceparent"
+)
+
+type requestIDContextKey struct{}
+type traceParentContextKey struct{}
+
+// ContextWithRequestID returns a copy of ctx carrying the given request ID.
+func ContextWithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDContextKey{}, requestID)
+}
+
+// RequestIDFromContext returns the request ID stored in ctx or an empty string.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDContextKey{}).(string)
+	return id
+}
+
+// ContextWithTraceParent returns a copy of ctx carrying the given W3C traceparent value.
+func ContextWithTraceParent(ctx context.Context, traceParent string) context.Context {
+	return context.WithValue(ctx, traceParentContextKey{}, traceParent)
+}
+
+// TraceParentFromContext returns the W3C traceparent value stored in ctx or an empty string.
+func TraceParentFromContext(ctx context.Context) string {
+	traceParent, _ := ctx.Value(traceParentContextKey{}).(string)
+	return traceParent
+}
+
+// requestIDMiddleware adopts or generates the request ID of incoming requests and stores it together with the trace context in the request context. The request ID is returned in the response headers.
+func requestIDMiddleware(c *gin.Context) {
+	id := c.GetHeader(HeaderRequestID)
+	if len(id) == 0 {
+		id, _ = newUUID()
+	}
+	ctx := ContextWithRequestID(c.Request.Context(), id)
+	if traceParent := c.GetHeader(HeaderTraceParent); len(traceParent) > 0 {
+		ctx = ContextWithTraceParent(ctx, traceParent)
+	}
+	c.Request = c.Request.WithContext(ctx)
+	c.Header(HeaderRequestID, id)
+	c.Next()
+}
+
+// TracePropagationInterceptor returns an interceptor that adds the request ID and trace context of the request context to outgoing requests. Use DoContext with the context of an incoming request to propagate its IDs. The optional traceParent function can provide the traceparent value from a tracing library (e.g. the active OpenTelemetry span) and takes precedence over the value received by the server.
+func TracePropagationInterceptor(traceParent func(context.Context) string) Interceptor {
+	return func(next Responder) Responder {
+		return func(req *Request) (*Response, errors.Error) {
+			ctx := req.Context()
+			if id := RequestIDFromContext(ctx); len(id) > 0 && len(req.Header.Get(HeaderRequestID)) == 0 {
+				req.Header.Set(HeaderRequestID, id)
+			}
+			if len(req.Header.Get(HeaderTraceParent)) == 0 {
+				value := ""
+				if traceParent != nil {
+					value = traceParent(ctx)
+				}
+				if len(value) == 0 {
+					value = TraceParentFromContext(ctx)
+				}
+				if len(value) > 0 {
+					req.Header.Set(HeaderTraceParent, value)
+				}
+			}
+			return next(req)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sbreitf1/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTracePropagation(t *testing.T) {
+	downstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get(HeaderRequestID) + "|" + r.Header.Get(HeaderTraceParent)))
+	}))
+	defer downstream.Close()
+
+	client := NewClient()
+	client.PropagateTrace = true
+
+	server, _ := newTestServer()
+	server.Engine().GET("/forward", func(c *gin.Context) {
+		response, err := client.DoContext(c.Request.Context(), MethodGet, downstream.URL, nil, nil)
+		if err != nil {
+			err.ToRequestAndLog(c)
+			return
+		}
+		c.DataFromReader(200, response.ContentLength, "text/plain", response.Body, map[string]string{})
+	})
+	ts := httptest.NewServer(server.Handler())
+	defer ts.Close()
+
+	t.Run("Adopt incoming IDs", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", ts.URL+"/forward", nil)
+		req.Header.Set(HeaderRequestID, "req-1")
+		req.Header.Set(HeaderTraceParent, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		response, err := http.DefaultClient.Do(req)
+		errors.AssertNil(t, err)
+		assert.Equal(t, "req-1", response.Header.Get(HeaderRequestID))
+		assertResponse(t, 200, "req-1|00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", response)
+	})
+
+	t.Run("Generate request ID", func(t *testing.T) {
+		response, err := http.Get(ts.URL + "/forward")
+		errors.AssertNil(t, err)
+		id := response.Header.Get(HeaderRequestID)
+		assert.Len(t, id, 36)
+		assertResponse(t, 200, id+"|", response)
+	})
+
+	t.Run("Custom trace parent", func(t *testing.T) {
+		client := NewClient()
+		client.Use(TracePropagationInterceptor(func(ctx context.Context) string { return "00-custom" }))
+		response, err := client.DoContext(ContextWithRequestID(context.Background(), "abc"), MethodGet, downstream.URL, nil, nil)
+		errors.AssertNil(t, err)
+		assertResponse(t, 200, "abc|00-custom", response)
+	})
+}
@@ -0,0 +1,77 @@
+package http
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sbreitf1/errors"
+)
+
+// DeclareTrailers announces response trailers in the Trailer header, so clients can expect them after the body. It must be called before the body is written, use SetTrailer to set the values afterwards.
+func DeclareTrailers(c *gin.Context, names ...string) {
+	for _, name := range names {
+		c.Writer.Header().Add("Trailer", http.CanonicalHeaderKey(name))
+	}
+}
+
+// SetTrailer sets the value of a response trailer. Trailers that have not been declared using DeclareTrailers are sent as well, but not announced to the client.
+func SetTrailer(c *gin.Context, name, value string) {
+	// values set before the header has been written would be sent as header
+	c.Writer.WriteHeaderNow()
+	name = http.CanonicalHeaderKey(name)
+	for _, declared := range c.Writer.Header()["Trailer"] {
+		for _, d := range strings.Split(declared, ",") {
+			if http.CanonicalHeaderKey(strings.TrimSpace(d)) == name {
+				c.Writer.Header().Set(name, value)
+				return
+			}
+		}
+	}
+	c.Writer.Header().Set(http.TrailerPrefix+name, value)
+}
+
+// ChecksumTrailer writes a response body and sends its SHA-256 digest as trailer, e.g. for streamed responses whose checksum is unknown in advance.
+type ChecksumTrailer struct {
+	c    *gin.Context
+	name string
+	hash hash.Hash
+}
+
+// NewChecksumTrailer declares the trailer with the given name, e.g. "Digest", and returns a writer for the response body. Call Close after the body has been written to send the digest formatted like "SHA-256=<base64>".
+func NewChecksumTrailer(c *gin.Context, name string) *ChecksumTrailer {
+	DeclareTrailers(c, name)
+	return &ChecksumTrailer{c: c, name: name, hash: sha256.New()}
+}
+
+// Write writes p to the response body.
+func (w *ChecksumTrailer) Write(p []byte) (int, error) {
+	n, err := w.c.Writer.Write(p)
+	w.hash.Write(p[:n])
+	return n, err
+}
+
+// Close sets the trailer to the digest of the written body.
+func (w *ChecksumTrailer) Close() error {
+	SetTrailer(w.c, w.name, "SHA-256="+base64.StdEncoding.EncodeToString(w.hash.Sum(nil)))
+	return nil
+}
+
+// ReadResponseTrailers discards the remaining body of the given response, closes it and returns the received trailers. Trailers are only available after the complete body has been read.
+func ReadResponseTrailers(response *Response) (Header, errors.Error) {
+	if response == nil {
+		return nil, nil
+	}
+	if response.Body != nil {
+		defer response.Body.Close()
+		if _, err := io.Copy(ioutil.Discard, response.Body); err != nil {
+			return nil, ErrInvalidBody.Make().Cause(err)
+		}
+	}
+	return response.Trailer, nil
+}
@@ -0,0 +1,52 @@
+package http
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sbreitf1/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrailers(t *testing.T) {
+	engine := gin.New()
+	engine.GET("/export", func(c *gin.Context) {
+		DeclareTrailers(c, "X-Status")
+		w := NewChecksumTrailer(c, "Digest")
+		w.Write([]byte("first,"))
+		w.Write([]byte("second"))
+		w.Close()
+		SetTrailer(c, "x-status", "complete")
+		SetTrailer(c, "X-Undeclared", "sent")
+	})
+	server := httptest.NewServer(engine)
+	defer server.Close()
+
+	response, err := NewClient().Get(server.URL+"/export", nil)
+	errors.AssertNil(t, err)
+	// declared trailers are known before the body has been read
+	assert.Contains(t, response.Trailer, "Digest")
+	assert.Contains(t, response.Trailer, "X-Status")
+	assert.Empty(t, response.Trailer.Get("Digest"))
+	assert.Empty(t, response.Header.Get("Digest"))
+
+	// trailers are received after the body
+	body, err := ReadResponseString(response)
+	errors.AssertNil(t, err)
+	assert.Equal(t, "first,second", body)
+	sum := sha256.Sum256([]byte(body))
+	assert.Equal(t, "SHA-256="+base64.StdEncoding.EncodeToString(sum[:]), response.Trailer.Get("Digest"))
+	assert.Equal(t, "complete", response.Trailer.Get("X-Status"))
+	assert.Equal(t, "sent", response.Trailer.Get("X-Undeclared"))
+
+	response, err = NewClient().Get(server.URL+"/export", nil)
+	errors.AssertNil(t, err)
+	trailers, err := ReadResponseTrailers(response)
+	errors.AssertNil(t, err)
+	assert.True(t, strings.HasPrefix(trailers.Get("Digest"), "SHA-256="))
+	assert.Equal(t, "complete", trailers.Get("X-Status"))
+}
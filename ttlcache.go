@@ -0,0 +1,89 @@
+package http
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sbreitf1/errors"
+)
+
+// TTLCache caches successful GET responses per URL for a fixed duration regardless of cache headers. It is meant for configuration or discovery endpoints that are polled frequently.
+type TTLCache struct {
+	// TTL denotes how long responses are served from cache.
+	TTL time.Duration
+
+	mutex   sync.Mutex
+	entries map[string]*cacheEntry
+	now     func() time.Time
+}
+
+// NewTTLCache returns a cache that keeps responses for the given duration.
+func NewTTLCache(ttl time.Duration) *TTLCache {
+	return &TTLCache{TTL: ttl, entries: make(map[string]*cacheEntry)}
+}
+
+// Invalidate removes the cached response of the given absolute url.
+func (cache *TTLCache) Invalidate(url string) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	delete(cache.entries, url)
+}
+
+// Clear removes all cached responses.
+func (cache *TTLCache) Clear() {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	cache.entries = make(map[string]*cacheEntry)
+}
+
+// Interceptor returns an interceptor that answers GET requests from cache and stores successful responses.
+func (cache *TTLCache) Interceptor() Interceptor {
+	return func(next Responder) Responder {
+		return func(req *Request) (*Response, errors.Error) {
+			if req.Method != http.MethodGet {
+				return next(req)
+			}
+			key := req.URL.String()
+
+			cache.mutex.Lock()
+			entry, ok := cache.entries[key]
+			if ok && cache.clock().Sub(entry.Stored) >= cache.TTL {
+				delete(cache.entries, key)
+				ok = false
+			}
+			cache.mutex.Unlock()
+			if ok {
+				return entry.response(req, "HIT", 0), nil
+			}
+
+			response, err := next(req)
+			if err != nil || response.StatusCode < 200 || response.StatusCode > 299 {
+				return response, err
+			}
+			body, err := ReadResponseBody(response)
+			if err != nil {
+				return nil, err
+			}
+			response.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+			cache.mutex.Lock()
+			if cache.entries == nil {
+				cache.entries = make(map[string]*cacheEntry)
+			}
+			cache.entries[key] = &cacheEntry{StatusCode: response.StatusCode, Header: response.Header.Clone(), Body: body, Stored: cache.clock()}
+			cache.mutex.Unlock()
+			response.Header.Set(HeaderCache, "MISS")
+			return response, nil
+		}
+	}
+}
+
+func (cache *TTLCache) clock() time.Time {
+	if cache.now != nil {
+		return cache.now()
+	}
+	return time.Now()
+}
@@ -0,0 +1,53 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sbreitf1/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTTLCache(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Cache-Control", "no-store")
+		if r.URL.Path == "/error" {
+			w.WriteHeader(500)
+		}
+	}))
+	defer server.Close()
+
+	now := time.Now()
+	cache := NewTTLCache(time.Minute)
+	cache.now = func() time.Time { return now }
+	client := NewClient()
+	client.TTLCache = cache
+
+	get := func(path string) string {
+		response, err := client.Get(server.URL+path, nil)
+		errors.AssertNil(t, err)
+		DrainAndClose(response)
+		return response.Header.Get(HeaderCache)
+	}
+
+	assert.Equal(t, "MISS", get("/config"))
+	assert.Equal(t, "HIT", get("/config"))
+	assert.Equal(t, 1, requests)
+
+	now = now.Add(time.Minute)
+	assert.Equal(t, "MISS", get("/config"))
+	assert.Equal(t, 2, requests)
+
+	cache.Invalidate(server.URL + "/config")
+	assert.Equal(t, "MISS", get("/config"))
+	cache.Clear()
+	assert.Equal(t, "MISS", get("/config"))
+
+	get("/error")
+	get("/error")
+	assert.Equal(t, 6, requests, "Errors should not be cached")
+}
@@ -0,0 +1,157 @@
+package http
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/sbreitf1/errors"
+)
+
+// ProgressReader reports the progress of reading from an underlying reader and optionally limits the throughput.
+type ProgressReader struct {
+	// Reader denotes the underlying reader.
+	Reader io.Reader
+	// Total denotes the expected number of bytes or -1 if unknown.
+	Total int64
+	// Progress is called after every read.
+	Progress ProgressFunc
+	// BytesPerSecond limits the average throughput. Not limited when zero.
+	BytesPerSecond int64
+	// Context aborts throttling waits when done.
+	Context context.Context
+
+	transferred int64
+	start       time.Time
+}
+
+// NewProgressReader returns a reader that reports the progress of reading r with total expected bytes to progress.
+func NewProgressReader(r io.Reader, total int64, progress ProgressFunc) *ProgressReader {
+	return &ProgressReader{Reader: r, Total: total, Progress: progress}
+}
+
+// Read reads from the underlying reader and reports the progress.
+func (r *ProgressReader) Read(p []byte) (int, error) {
+	if r.start.IsZero() {
+		r.start = time.Now()
+	}
+	if r.BytesPerSecond > 0 {
+		// read small chunks to achieve a steady rate
+		chunk := r.BytesPerSecond / 10
+		if chunk < 1 {
+			chunk = 1
+		}
+		if int64(len(p)) > chunk {
+			p = p[:chunk]
+		}
+	}
+
+	n, err := r.Reader.Read(p)
+	if n > 0 {
+		r.transferred += int64(n)
+		if r.Progress != nil {
+			r.Progress(r.transferred, r.Total)
+		}
+		if waitErr := r.throttle(); waitErr != nil {
+			return n, waitErr
+		}
+	}
+	return n, err
+}
+
+// Close closes the underlying reader if it is closable.
+func (r *ProgressReader) Close() error {
+	if closer, ok := r.Reader.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+func (r *ProgressReader) throttle() error {
+	if r.BytesPerSecond <= 0 {
+		return nil
+	}
+	expected := time.Duration(r.transferred * int64(time.Second) / r.BytesPerSecond)
+	wait := expected - time.Since(r.start)
+	if wait <= 0 {
+		return nil
+	}
+
+	ctx := r.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+type uploadContextKey struct{}
+
+type uploadOptions struct {
+	progress       ProgressFunc
+	bytesPerSecond int64
+}
+
+// WithUploadProgress returns a request callback that reports the upload progress of the request body and limits the throughput to bytesPerSecond if positive. It overrides the upload settings of the client.
+func WithUploadProgress(progress ProgressFunc, bytesPerSecond int64) func(*Request) errors.Error {
+	return func(req *Request) errors.Error {
+		*req = *req.WithContext(context.WithValue(req.Context(), uploadContextKey{}, uploadOptions{progress, bytesPerSecond}))
+		return nil
+	}
+}
+
+// wrapUploadBody wraps the request body, including bodies recreated for retries and redirects, into a ProgressReader according to the per-request or client upload settings. It is applied after signing so the signer reads the plain body.
+func (client *Client) wrapUploadBody(req *Request) {
+	options, ok := req.Context().Value(uploadContextKey{}).(uploadOptions)
+	if !ok {
+		options = uploadOptions{client.UploadProgress, client.UploadBytesPerSecond}
+	}
+	if (options.progress == nil && options.bytesPerSecond <= 0) || req.Body == nil || req.Body == http.NoBody {
+		return
+	}
+
+	total := req.ContentLength
+	if total <= 0 {
+		total = -1
+	}
+	wrap := func(body io.ReadCloser) io.ReadCloser {
+		return &ProgressReader{Reader: body, Total: total, Progress: options.progress, BytesPerSecond: options.bytesPerSecond, Context: req.Context()}
+	}
+
+	req.Body = wrap(req.Body)
+	if getBody := req.GetBody; getBody != nil {
+		req.GetBody = func() (io.ReadCloser, error) {
+			body, err := getBody()
+			if err != nil {
+				return nil, err
+			}
+			return wrap(body), nil
+		}
+	}
+}
+
+// WithExpectContinue returns a request callback that sends Expect: 100-continue, so the request body is only sent after the server accepted the request headers.
+func WithExpectContinue() func(*Request) errors.Error {
+	return func(req *Request) errors.Error {
+		req.Header.Set("Expect", "100-continue")
+		return nil
+	}
+}
+
+// applyExpectContinue sets the Expect header for request bodies exceeding the threshold of the client.
+func (client *Client) applyExpectContinue(req *Request) {
+	if client.ExpectContinueThreshold <= 0 || req.Body == nil || req.Body == http.NoBody || len(req.Header.Get("Expect")) > 0 {
+		return
+	}
+	// a content length of zero denotes an unknown length for requests with body
+	if req.ContentLength == 0 || req.ContentLength >= client.ExpectContinueThreshold {
+		req.Header.Set("Expect", "100-continue")
+	}
+}
@@ -0,0 +1,121 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sbreitf1/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUploadProgress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		w.Write([]byte{byte(len(data) / 1000)})
+	}))
+	defer server.Close()
+	content := bytes.Repeat([]byte("x"), 4000)
+
+	t.Run("Progress", func(t *testing.T) {
+		var lastTransferred, lastTotal int64
+		client := NewClient()
+		client.UploadProgress = func(transferred, total int64) {
+			lastTransferred, lastTotal = transferred, total
+		}
+		response, err := client.Post(server.URL, "text/plain", bytes.NewReader(content), nil)
+		errors.AssertNil(t, err)
+		assertResponse(t, 200, "\x04", response)
+		assert.Equal(t, int64(4000), lastTransferred)
+		assert.Equal(t, int64(4000), lastTotal)
+	})
+
+	t.Run("Throttle", func(t *testing.T) {
+		calls := 0
+		start := time.Now()
+		response, err := NewClient().Post(server.URL, "text/plain", bytes.NewReader(content), WithUploadProgress(func(transferred, total int64) { calls++ }, 20000))
+		errors.AssertNil(t, err)
+		assertResponse(t, 200, "\x04", response)
+		assert.True(t, time.Since(start) >= 180*time.Millisecond, "Upload of 4000 bytes at 20000 B/s should take about 200ms")
+		assert.Equal(t, 2, calls, "Chunks are limited to a tenth of the rate")
+	})
+}
+
+func TestProgressReaderCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	r := &ProgressReader{Reader: bytes.NewReader(make([]byte, 100)), BytesPerSecond: 10, Context: ctx}
+	_, err := ioutil.ReadAll(r)
+	assert.Equal(t, context.Canceled, err)
+}
+
+// countingReader returns size bytes of unknown length and counts the bytes read.
+type countingReader struct {
+	size int64
+	read int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	if r.read >= r.size {
+		return 0, io.EOF
+	}
+	if int64(len(p)) > r.size-r.read {
+		p = p[:r.size-r.read]
+	}
+	r.read += int64(len(p))
+	return len(p), nil
+}
+
+func TestExpectContinue(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Expect", r.Header.Get("Expect"))
+		if r.Header.Get("X-Reject") == "true" {
+			// rejected without reading the body, so no interim response is sent
+			w.WriteHeader(413)
+			return
+		}
+		n, _ := io.Copy(ioutil.Discard, r.Body)
+		w.Write([]byte(strconv.FormatInt(n, 10)))
+	}))
+	defer server.Close()
+
+	client := NewClient()
+	client.ExpectContinueThreshold = 1024
+	client.ExpectContinueTimeout = 10 * time.Second
+
+	body := &countingReader{size: 1 << 20}
+	response, err := client.Post(server.URL, "application/octet-stream", body, WithHeader("X-Reject", "true"))
+	errors.AssertNil(t, err)
+	assert.Equal(t, 413, response.StatusCode)
+	assert.Equal(t, "100-continue", response.Header.Get("X-Expect"))
+	assert.Equal(t, int64(0), body.read)
+	DrainAndClose(response)
+
+	body = &countingReader{size: 1 << 20}
+	response, err = client.Post(server.URL, "application/octet-stream", body, nil)
+	errors.AssertNil(t, err)
+	assert.Equal(t, "100-continue", response.Header.Get("X-Expect"))
+	str, err := ReadResponseString(response)
+	errors.AssertNil(t, err)
+	assert.Equal(t, "1048576", str)
+
+	// small bodies of known length are sent immediately
+	response, err = client.Post(server.URL, "text/plain", strings.NewReader("small"), nil)
+	errors.AssertNil(t, err)
+	assert.Empty(t, response.Header.Get("X-Expect"))
+	DrainAndClose(response)
+
+	response, err = NewClient().Post(server.URL, "text/plain", strings.NewReader("small"), WithExpectContinue())
+	errors.AssertNil(t, err)
+	assert.Equal(t, "100-continue", response.Header.Get("X-Expect"))
+	str, err = ReadResponseString(response)
+	errors.AssertNil(t, err)
+	assert.Equal(t, "5", str)
+}
@@ -0,0 +1,187 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/sbreitf1/errors"
+)
+
+var (
+	// ErrCassette is returned when a VCR cassette cannot be read or written.
+	ErrCassette = errors.New("Cassette could not be accessed")
+	// ErrNoInteraction is returned by a replaying VCR for requests that have not been recorded.
+	ErrNoInteraction = errors.New("No recorded interaction")
+)
+
+// VCRMode defines whether a VCR records or replays interactions.
+type VCRMode int
+
+const (
+	// VCRAuto replays existing cassettes and records new cassettes.
+	VCRAuto VCRMode = iota
+	// VCRReplay only replays recorded interactions and never sends requests.
+	VCRReplay
+	// VCRRecord sends all requests and overwrites the cassette with the received interactions.
+	VCRRecord
+)
+
+// DefaultVCRRedactHeaders contains the headers that are redacted in cassettes by default.
+var DefaultVCRRedactHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key", "X-Amz-Security-Token"}
+
+// VCR records real HTTP interactions to a cassette file and replays them in later test runs.
+type VCR struct {
+	// RedactHeaders lists headers whose values are replaced before writing the cassette.
+	RedactHeaders []string
+
+	mode         VCRMode
+	path         string
+	mutex        sync.Mutex
+	interactions []*vcrInteraction
+	used         []bool
+}
+
+type vcrCassette struct {
+	Interactions []*vcrInteraction `json:"interactions"`
+}
+
+type vcrInteraction struct {
+	Request  vcrRequest  `json:"request"`
+	Response vcrResponse `json:"response"`
+}
+
+type vcrRequest struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	Header Header `json:"header,omitempty"`
+	Body   string `json:"body,omitempty"`
+}
+
+type vcrResponse struct {
+	StatusCode int    `json:"status"`
+	Header     Header `json:"header,omitempty"`
+	Body       string `json:"body,omitempty"`
+}
+
+// NewVCR returns a VCR for the cassette at the given path. In VCRAuto mode the cassette is replayed if it exists and recorded otherwise.
+func NewVCR(path string, mode VCRMode) (*VCR, errors.Error) {
+	vcr := &VCR{RedactHeaders: DefaultVCRRedactHeaders, path: path}
+
+	if mode == VCRAuto {
+		mode = VCRReplay
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			mode = VCRRecord
+		}
+	}
+	vcr.mode = mode
+
+	if mode == VCRReplay {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, ErrCassette.Msg("Cassette %q could not be read", path).Make().Cause(err)
+		}
+		var cassette vcrCassette
+		if err := json.Unmarshal(data, &cassette); err != nil {
+			return nil, ErrCassette.Msg("Cassette %q is malformed", path).Make().Cause(err)
+		}
+		vcr.interactions = cassette.Interactions
+		vcr.used = make([]bool, len(cassette.Interactions))
+	}
+	return vcr, nil
+}
+
+// Mode returns whether the VCR is recording or replaying.
+func (vcr *VCR) Mode() VCRMode {
+	return vcr.mode
+}
+
+// Interceptor returns an interceptor that records or replays requests depending on the mode of the VCR.
+func (vcr *VCR) Interceptor() Interceptor {
+	return func(next Responder) Responder {
+		return func(req *Request) (*Response, errors.Error) {
+			body, err := readRequestBody(req)
+			if err != nil {
+				return nil, errors.Wrap(err)
+			}
+
+			if vcr.mode == VCRReplay {
+				return vcr.replay(req, body)
+			}
+
+			response, sendErr := next(req)
+			if sendErr != nil {
+				return nil, sendErr
+			}
+			return vcr.record(req, body, response)
+		}
+	}
+}
+
+func (vcr *VCR) replay(req *Request, body []byte) (*Response, errors.Error) {
+	vcr.mutex.Lock()
+	defer vcr.mutex.Unlock()
+
+	for i, interaction := range vcr.interactions {
+		if vcr.used[i] || interaction.Request.Method != req.Method || interaction.Request.URL != req.URL.String() || interaction.Request.Body != string(body) {
+			continue
+		}
+		vcr.used[i] = true
+		response := newMockResponse(req, interaction.Response.StatusCode, interaction.Response.Header.Clone(), []byte(interaction.Response.Body))
+		if response.Header == nil {
+			response.Header = make(Header)
+		}
+		return response, nil
+	}
+	return nil, ErrNoInteraction.Msg("No recorded interaction for %s %s", req.Method, req.URL).Make()
+}
+
+func (vcr *VCR) record(req *Request, body []byte, response *Response) (*Response, errors.Error) {
+	responseBody, err := ioutil.ReadAll(response.Body)
+	response.Body.Close()
+	if err != nil {
+		return nil, errors.Wrap(err)
+	}
+	response.Body = ioutil.NopCloser(bytes.NewReader(responseBody))
+
+	interaction := &vcrInteraction{
+		Request:  vcrRequest{Method: req.Method, URL: req.URL.String(), Header: vcr.redact(req.Header), Body: string(body)},
+		Response: vcrResponse{StatusCode: response.StatusCode, Header: vcr.redact(response.Header), Body: string(responseBody)},
+	}
+
+	vcr.mutex.Lock()
+	defer vcr.mutex.Unlock()
+	vcr.interactions = append(vcr.interactions, interaction)
+	if err := vcr.save(); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (vcr *VCR) redact(header Header) Header {
+	redacted := header.Clone()
+	for _, key := range vcr.RedactHeaders {
+		if _, ok := redacted[http.CanonicalHeaderKey(key)]; ok {
+			redacted.Set(key, "REDACTED")
+		}
+	}
+	return redacted
+}
+
+func (vcr *VCR) save() errors.Error {
+	data, err := json.MarshalIndent(vcrCassette{Interactions: vcr.interactions}, "", "  ")
+	if err != nil {
+		return errors.Wrap(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(vcr.path), os.ModePerm); err != nil {
+		return ErrCassette.Msg("Cassette directory for %q could not be created", vcr.path).Make().Cause(err)
+	}
+	if err := ioutil.WriteFile(vcr.path, data, 0644); err != nil {
+		return ErrCassette.Msg("Cassette %q could not be written", vcr.path).Make().Cause(err)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package http
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sbreitf1/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVCR(t *testing.T) {
+	count := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count++
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Header().Set("Set-Cookie", "session=secret")
+		w.Write([]byte("echo:" + string(body)))
+	}))
+	defer server.Close()
+
+	cassette := filepath.Join(t.TempDir(), "fixtures", "echo.json")
+
+	t.Run("Record", func(t *testing.T) {
+		vcr, err := NewVCR(cassette, VCRAuto)
+		errors.AssertNil(t, err)
+		assert.Equal(t, VCRRecord, vcr.Mode())
+
+		client := NewClient()
+		client.Use(vcr.Interceptor())
+		response, err := client.DoWithBody(MethodPost, server.URL, "text/plain", strings.NewReader("hello"), WithBearerToken("top-secret"))
+		errors.AssertNil(t, err)
+		assertResponse(t, 200, "echo:hello", response)
+
+		data, _ := ioutil.ReadFile(cassette)
+		assert.NotContains(t, string(data), "top-secret")
+		assert.NotContains(t, string(data), "session=secret")
+		assert.Contains(t, string(data), "REDACTED")
+	})
+
+	server.Close()
+
+	t.Run("Replay", func(t *testing.T) {
+		vcr, err := NewVCR(cassette, VCRAuto)
+		errors.AssertNil(t, err)
+		assert.Equal(t, VCRReplay, vcr.Mode())
+
+		client := NewClient()
+		client.Use(vcr.Interceptor())
+		response, err := client.DoWithBody(MethodPost, server.URL, "text/plain", strings.NewReader("hello"), nil)
+		errors.AssertNil(t, err)
+		assertResponse(t, 200, "echo:hello", response)
+
+		// every interaction is replayed once
+		_, err = client.DoWithBody(MethodPost, server.URL, "text/plain", strings.NewReader("hello"), nil)
+		errors.Assert(t, ErrNoInteraction, err)
+	})
+
+	assert.Equal(t, 1, count)
+}
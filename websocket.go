@@ -0,0 +1,368 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sbreitf1/errors"
+)
+
+var (
+	// ErrWebSocket is returned when a WebSocket handshake fails or the peer violates the protocol.
+	ErrWebSocket = errors.New("WebSocket failed")
+	// ErrWebSocketClosed is returned when reading from or writing to a closed WebSocket connection.
+	ErrWebSocketClosed = errors.New("WebSocket closed")
+)
+
+// WebSocket message types as defined in RFC 6455.
+const (
+	TextMessage   = 1
+	BinaryMessage = 2
+	CloseMessage  = 8
+	PingMessage   = 9
+	PongMessage   = 10
+)
+
+const (
+	webSocketGUID         = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+	webSocketContinuation = 0
+	webSocketCloseNormal  = 1000
+	webSocketCloseTooBig  = 1009
+
+	// DefaultWebSocketReadLimit limits the size of received messages if no ReadLimit is set.
+	DefaultWebSocketReadLimit = 32 << 20
+)
+
+// WebSocketConn is a WebSocket connection. Messages can be written concurrently to reading, but only one goroutine may read at a time.
+type WebSocketConn struct {
+	// ReadLimit limits the size of received messages. DefaultWebSocketReadLimit is used when zero, negative values disable the limit.
+	ReadLimit int64
+
+	conn        io.ReadWriteCloser
+	reader      *bufio.Reader
+	client      bool
+	subprotocol string
+
+	writeMutex sync.Mutex
+	stateMutex sync.Mutex
+	closeSent  bool
+	lastPong   time.Time
+}
+
+// DialWebSocket opens a WebSocket connection to the given ws:// or wss:// url. The handshake request uses the default headers, authentication, TLS and proxy configuration of the client and can be modified using f, e.g. to request a subprotocol.
+func (client *Client) DialWebSocket(url string, f func(*Request) errors.Error) (*WebSocketConn, *Response, errors.Error) {
+	return client.DialWebSocketContext(context.Background(), url, f)
+}
+
+// DialWebSocketContext opens a WebSocket connection to the given ws:// or wss:// url. The handshake is aborted when ctx is done.
+func (client *Client) DialWebSocketContext(ctx context.Context, url string, f func(*Request) errors.Error) (*WebSocketConn, *Response, errors.Error) {
+	if strings.HasPrefix(url, "ws://") {
+		url = "http://" + strings.TrimPrefix(url, "ws://")
+	} else if strings.HasPrefix(url, "wss://") {
+		url = "https://" + strings.TrimPrefix(url, "wss://")
+	}
+
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, nil, errors.Wrap(err)
+	}
+	key := base64.StdEncoding.EncodeToString(nonce)
+
+	req, err := client.newRequest(ctx, MethodGet, url, nil, func(req *Request) errors.Error {
+		req.Header.Set("Connection", "Upgrade")
+		req.Header.Set("Upgrade", "websocket")
+		req.Header.Set("Sec-WebSocket-Version", "13")
+		req.Header.Set("Sec-WebSocket-Key", key)
+		if f != nil {
+			return f(req)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if client.Jar != nil {
+		for _, cookie := range client.Jar.Cookies(req.URL) {
+			req.AddCookie(cookie)
+		}
+	}
+
+	// the transport is used directly, because the client timeout would also limit the lifetime of the connection
+	c, err := client.getHTTPClient(serverNameFromContext(req.Context()))
+	if err != nil {
+		return nil, nil, err
+	}
+	response, sendErr := c.Transport.RoundTrip(req)
+	if sendErr != nil {
+		return nil, nil, transportError(sendErr)
+	}
+
+	if response.StatusCode != http.StatusSwitchingProtocols {
+		err := unexpectedStatus(response)
+		DrainAndClose(response)
+		return nil, response, ErrWebSocket.Msg("WebSocket handshake failed").Make().Cause(err)
+	}
+	conn, ok := response.Body.(io.ReadWriteCloser)
+	if !ok || !strings.EqualFold(response.Header.Get("Upgrade"), "websocket") || response.Header.Get("Sec-WebSocket-Accept") != webSocketAccept(key) {
+		response.Body.Close()
+		return nil, response, ErrWebSocket.Msg("Invalid WebSocket handshake response").Make()
+	}
+
+	ws := newWebSocketConn(conn, true)
+	ws.subprotocol = response.Header.Get("Sec-WebSocket-Protocol")
+	return ws, response, nil
+}
+
+func newWebSocketConn(conn io.ReadWriteCloser, client bool) *WebSocketConn {
+	return &WebSocketConn{conn: conn, reader: bufio.NewReader(conn), client: client, lastPong: time.Now()}
+}
+
+func webSocketAccept(key string) string {
+	sum := sha1.Sum([]byte(key + webSocketGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// Subprotocol returns the subprotocol selected by the server.
+func (ws *WebSocketConn) Subprotocol() string {
+	return ws.subprotocol
+}
+
+// WriteMessage sends a text or binary message.
+func (ws *WebSocketConn) WriteMessage(messageType int, data []byte) errors.Error {
+	if messageType != TextMessage && messageType != BinaryMessage {
+		return ErrWebSocket.Msg("Invalid message type %d", messageType).Make()
+	}
+	return ws.writeFrame(byte(messageType), data)
+}
+
+// WriteText sends a text message.
+func (ws *WebSocketConn) WriteText(text string) errors.Error {
+	return ws.writeFrame(TextMessage, []byte(text))
+}
+
+// Ping sends a ping with the given payload of at most 125 bytes.
+func (ws *WebSocketConn) Ping(data []byte) errors.Error {
+	return ws.writeFrame(PingMessage, data)
+}
+
+// ReadMessage returns the next text or binary message. Pings are answered and pongs are recorded while reading. ErrWebSocketClosed is returned after the peer closed the connection.
+func (ws *WebSocketConn) ReadMessage() (int, []byte, errors.Error) {
+	var messageType byte
+	var message []byte
+	for {
+		fin, opcode, payload, err := ws.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		switch opcode {
+		case PingMessage:
+			if err := ws.writeFrame(PongMessage, payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case PongMessage:
+			ws.stateMutex.Lock()
+			ws.lastPong = time.Now()
+			ws.stateMutex.Unlock()
+			continue
+		case CloseMessage:
+			code := webSocketCloseNormal
+			if len(payload) >= 2 {
+				code = int(binary.BigEndian.Uint16(payload))
+			}
+			ws.closeWithCode(webSocketCloseNormal)
+			return 0, nil, ErrWebSocketClosed.Msg("WebSocket closed by peer with code %d", code).Make()
+		case webSocketContinuation:
+			if messageType == 0 {
+				return 0, nil, ws.protocolError("Unexpected continuation frame")
+			}
+			message = append(message, payload...)
+		case TextMessage, BinaryMessage:
+			if messageType != 0 {
+				return 0, nil, ws.protocolError("Expected continuation frame")
+			}
+			messageType = opcode
+			message = payload
+		default:
+			return 0, nil, ws.protocolError("Unknown opcode")
+		}
+
+		if limit := ws.readLimit(); int64(len(message)) > limit {
+			ws.closeWithCode(webSocketCloseTooBig)
+			return 0, nil, ErrWebSocket.Msg("Message exceeds read limit of %d bytes", limit).Make()
+		}
+		if fin {
+			return int(messageType), message, nil
+		}
+	}
+}
+
+// KeepAlive sends a ping in the given interval and closes the connection when no pong has been received for longer than interval plus timeout. Pongs are only processed while ReadMessage is called. The returned function stops sending pings.
+func (ws *WebSocketConn) KeepAlive(interval, timeout time.Duration) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				ws.stateMutex.Lock()
+				lastPong := ws.lastPong
+				ws.stateMutex.Unlock()
+				if time.Since(lastPong) > interval+timeout {
+					ws.conn.Close()
+					return
+				}
+				if err := ws.Ping(nil); err != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() { once.Do(func() { close(done) }) }
+}
+
+// Close sends a close frame and closes the underlying connection.
+func (ws *WebSocketConn) Close() errors.Error {
+	return ws.closeWithCode(webSocketCloseNormal)
+}
+
+func (ws *WebSocketConn) closeWithCode(code int) errors.Error {
+	ws.stateMutex.Lock()
+	closeSent := ws.closeSent
+	ws.closeSent = true
+	ws.stateMutex.Unlock()
+
+	if !closeSent {
+		payload := make([]byte, 2)
+		binary.BigEndian.PutUint16(payload, uint16(code))
+		ws.writeFrame(CloseMessage, payload)
+	}
+	return errors.Wrap(ws.conn.Close())
+}
+
+func (ws *WebSocketConn) protocolError(msg string) errors.Error {
+	ws.conn.Close()
+	return ErrWebSocket.Msg("WebSocket protocol error: %s", msg).Make()
+}
+
+func (ws *WebSocketConn) writeFrame(opcode byte, data []byte) errors.Error {
+	if opcode >= CloseMessage && len(data) > 125 {
+		return ErrWebSocket.Msg("Control frame payload exceeds 125 bytes").Make()
+	}
+
+	frame := []byte{0x80 | opcode, 0}
+	switch length := len(data); {
+	case length < 126:
+		frame[1] = byte(length)
+	case length <= 0xFFFF:
+		frame[1] = 126
+		frame = append(frame, 0, 0)
+		binary.BigEndian.PutUint16(frame[2:], uint16(length))
+	default:
+		frame[1] = 127
+		frame = append(frame, make([]byte, 8)...)
+		binary.BigEndian.PutUint64(frame[2:], uint64(length))
+	}
+
+	payload := data
+	if ws.client {
+		// frames sent by clients must be masked
+		frame[1] |= 0x80
+		mask := make([]byte, 4)
+		if _, err := rand.Read(mask); err != nil {
+			return errors.Wrap(err)
+		}
+		frame = append(frame, mask...)
+		payload = make([]byte, len(data))
+		for i := range data {
+			payload[i] = data[i] ^ mask[i%4]
+		}
+	}
+
+	ws.writeMutex.Lock()
+	defer ws.writeMutex.Unlock()
+	if _, err := ws.conn.Write(append(frame, payload...)); err != nil {
+		return ErrWebSocketClosed.Make().Cause(err)
+	}
+	return nil
+}
+
+// readLimit returns the effective size limit of received messages.
+func (ws *WebSocketConn) readLimit() int64 {
+	if ws.ReadLimit == 0 {
+		return DefaultWebSocketReadLimit
+	}
+	if ws.ReadLimit < 0 {
+		return math.MaxInt
+	}
+	return ws.ReadLimit
+}
+
+func (ws *WebSocketConn) readFrame() (bool, byte, []byte, errors.Error) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(ws.reader, header); err != nil {
+		return false, 0, nil, ErrWebSocketClosed.Make().Cause(err)
+	}
+	fin := header[0]&0x80 != 0
+	opcode := header[0] & 0x0F
+	masked := header[1]&0x80 != 0
+
+	length := uint64(header[1] & 0x7F)
+	switch length {
+	case 126:
+		ext := make([]byte, 2)
+		if _, err := io.ReadFull(ws.reader, ext); err != nil {
+			return false, 0, nil, ErrWebSocketClosed.Make().Cause(err)
+		}
+		length = uint64(binary.BigEndian.Uint16(ext))
+	case 127:
+		ext := make([]byte, 8)
+		if _, err := io.ReadFull(ws.reader, ext); err != nil {
+			return false, 0, nil, ErrWebSocketClosed.Make().Cause(err)
+		}
+		length = binary.BigEndian.Uint64(ext)
+	}
+	if opcode >= CloseMessage && (!fin || length > 125) {
+		return false, 0, nil, ws.protocolError("Invalid control frame")
+	}
+	// the length is checked before allocating the payload, so a single frame header cannot exhaust the memory
+	if limit := ws.readLimit(); length > uint64(limit) {
+		ws.closeWithCode(webSocketCloseTooBig)
+		return false, 0, nil, ErrWebSocket.Msg("Message exceeds read limit of %d bytes", limit).Make()
+	}
+
+	var mask []byte
+	if masked {
+		mask = make([]byte, 4)
+		if _, err := io.ReadFull(ws.reader, mask); err != nil {
+			return false, 0, nil, ErrWebSocketClosed.Make().Cause(err)
+		}
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(ws.reader, payload); err != nil {
+		return false, 0, nil, ErrWebSocketClosed.Make().Cause(err)
+	}
+	if mask != nil {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return fin, opcode, payload, nil
+}
@@ -0,0 +1,148 @@
+package http
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sbreitf1/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// newWebSocketEchoServer returns a server that echoes all WebSocket messages and closes the connection on "close".
+func newWebSocketEchoServer(t *testing.T, tls bool) *httptest.Server {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Upgrade") != "websocket" || r.Header.Get("X-Test") != "default" {
+			w.WriteHeader(400)
+			return
+		}
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		if !assert.NoError(t, err) {
+			return
+		}
+		rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+		rw.WriteString("Sec-WebSocket-Protocol: " + r.Header.Get("Sec-WebSocket-Protocol") + "\r\n")
+		rw.WriteString("Sec-WebSocket-Accept: " + webSocketAccept(r.Header.Get("Sec-WebSocket-Key")) + "\r\n\r\n")
+		rw.Flush()
+
+		ws := newWebSocketConn(conn, false)
+		for {
+			messageType, data, err := ws.ReadMessage()
+			if err != nil {
+				return
+			}
+			if string(data) == "close" {
+				ws.Close()
+				return
+			}
+			ws.WriteMessage(messageType, data)
+		}
+	})
+	if tls {
+		server := httptest.NewUnstartedServer(handler)
+		server.EnableHTTP2 = true
+		server.StartTLS()
+		return server
+	}
+	return httptest.NewServer(handler)
+}
+
+func TestWebSocket(t *testing.T) {
+	for _, tls := range []bool{false, true} {
+		server := newWebSocketEchoServer(t, tls)
+		defer server.Close()
+
+		client := NewClient()
+		client.DisableSSLCheck = true
+		client.DefaultHeader.Set("X-Test", "default")
+		url := "ws" + strings.TrimPrefix(server.URL, "http")
+
+		ws, response, err := client.DialWebSocket(url+"/echo", WithHeader("Sec-WebSocket-Protocol", "chat"))
+		errors.AssertNil(t, err)
+		assert.Equal(t, 101, response.StatusCode)
+		assert.Equal(t, "chat", ws.Subprotocol())
+
+		errors.AssertNil(t, ws.WriteText("hello"))
+		messageType, data, err := ws.ReadMessage()
+		errors.AssertNil(t, err)
+		assert.Equal(t, TextMessage, messageType)
+		assert.Equal(t, "hello", string(data))
+
+		large := bytes.Repeat([]byte{1, 2, 3}, 30000)
+		errors.AssertNil(t, ws.WriteMessage(BinaryMessage, large))
+		messageType, data, err = ws.ReadMessage()
+		errors.AssertNil(t, err)
+		assert.Equal(t, BinaryMessage, messageType)
+		assert.Equal(t, large, data)
+
+		// the pong is processed while waiting for the next message
+		errors.AssertNil(t, ws.Ping([]byte("ping")))
+		before := time.Now()
+		ws.WriteText("after ping")
+		_, data, err = ws.ReadMessage()
+		errors.AssertNil(t, err)
+		assert.Equal(t, "after ping", string(data))
+		assert.False(t, ws.lastPong.Before(before))
+
+		ws.WriteText("close")
+		_, _, err = ws.ReadMessage()
+		errors.Assert(t, ErrWebSocketClosed, err)
+	}
+}
+
+func TestWebSocketHandshakeFailure(t *testing.T) {
+	server := newWebSocketEchoServer(t, false)
+	defer server.Close()
+
+	_, response, err := NewClient().DialWebSocket("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	errors.Assert(t, ErrWebSocket, err)
+	assert.Equal(t, 400, response.StatusCode)
+}
+
+func TestWebSocketKeepAlive(t *testing.T) {
+	server := newWebSocketEchoServer(t, false)
+	defer server.Close()
+
+	client := NewClient()
+	client.DefaultHeader.Set("X-Test", "default")
+	ws, _, err := client.DialWebSocket("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	errors.AssertNil(t, err)
+
+	// without reading no pongs are processed and the connection is closed
+	stop := ws.KeepAlive(20*time.Millisecond, 20*time.Millisecond)
+	defer stop()
+	time.Sleep(100 * time.Millisecond)
+	assert.NotNil(t, ws.WriteText("closed"))
+}
+
+type webSocketTestConn struct {
+	io.Reader
+}
+
+func (webSocketTestConn) Write(p []byte) (int, error) { return ioutil.Discard.Write(p) }
+func (webSocketTestConn) Close() error                { return nil }
+
+func TestWebSocketReadLimit(t *testing.T) {
+	// a frame header declaring an 8 EiB payload must be rejected before allocating it
+	frame := []byte{0x82, 0x7F, 0x7F, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}
+	ws := newWebSocketConn(webSocketTestConn{bytes.NewReader(frame)}, true)
+	_, _, err := ws.ReadMessage()
+	errors.Assert(t, ErrWebSocket, err)
+
+	frame = append([]byte{0x82, 0x7E, 0x00, 0x10}, make([]byte, 16)...)
+	ws = newWebSocketConn(webSocketTestConn{bytes.NewReader(frame)}, true)
+	ws.ReadLimit = 8
+	_, _, err = ws.ReadMessage()
+	errors.Assert(t, ErrWebSocket, err)
+
+	ws = newWebSocketConn(webSocketTestConn{bytes.NewReader(frame)}, true)
+	messageType, data, err := ws.ReadMessage()
+	errors.AssertNil(t, err)
+	assert.Equal(t, BinaryMessage, messageType)
+	assert.Len(t, data, 16)
+}